@@ -0,0 +1,374 @@
+// Package alarm 按点位表中的报警规则判断每次轮询的结果
+//
+// 每条规则对点位的每个值各有一个报警。报警产生后处于报警状态, 操作员确认后为已确认;
+// 条件消失时已确认的报警直接恢复正常, 未确认的报警进入已恢复状态, 确认后才从列表中移除,
+// 因此短暂出现又消失的报警也不会被遗漏。状态变化都写入日志。
+package alarm
+
+import (
+	"fmt"
+	"math"
+	"modbusbaby/internal/config"
+	"modbusbaby/internal/logger"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State 报警状态
+type State int
+
+const (
+	StateNormal       State = iota // 正常, 不在报警列表中
+	StateActive                    // 报警中, 未确认
+	StateAcknowledged              // 报警中, 已确认
+	StateCleared                   // 已恢复, 未确认
+)
+
+// String 返回状态名称
+func (s State) String() string {
+	switch s {
+	case StateActive:
+		return "报警"
+	case StateAcknowledged:
+		return "已确认"
+	case StateCleared:
+		return "已恢复"
+	default:
+		return "正常"
+	}
+}
+
+// EventKind 报警事件类型
+type EventKind int
+
+const (
+	EventRaised       EventKind = iota // 产生报警
+	EventCleared                       // 条件消失
+	EventAcknowledged                  // 操作员确认
+)
+
+// String 返回事件名称
+func (k EventKind) String() string {
+	switch k {
+	case EventRaised:
+		return "报警"
+	case EventCleared:
+		return "恢复"
+	default:
+		return "确认"
+	}
+}
+
+// Alarm 一个报警的当前状态
+type Alarm struct {
+	ID       string // 点名/规则序号, 唯一标识一个报警
+	Point    string // 点名, 多个值的点位为 名称[序号]
+	Tag      string // 点位名称
+	Rule     config.AlarmRule
+	Message  string
+	State    State
+	Value    float64   // 最近一次判断时的数值, 通讯中断报警为连续失败次数
+	Raised   time.Time // 产生时间
+	Acked    time.Time // 确认时间, 未确认时为零值
+	Cleared  time.Time // 恢复时间, 未恢复时为零值
+	Severity string
+}
+
+// Event 报警状态变化
+type Event struct {
+	Kind  EventKind
+	Time  time.Time
+	Alarm Alarm
+}
+
+// Value 点位的一个值
+type Value struct {
+	Point string  // 点名, 与历史数据和记录文件中的名称相同
+	Value float64 // 工程值, 用于限值和变化率规则
+	Raw   float64 // 缩放前的原始值, 用于按位规则
+}
+
+// tagRules 一个点位的规则
+type tagRules struct {
+	tag   string
+	unit  string
+	rules []config.AlarmRule
+}
+
+// sample 变化率规则使用的上一次数值
+type sample struct {
+	time  time.Time
+	value float64
+}
+
+// Engine 报警引擎, 可被多个 goroutine 同时使用
+type Engine struct {
+	mu       sync.Mutex
+	tags     map[string]*tagRules // 小写点位名称 -> 规则
+	alarms   map[string]*Alarm
+	last     map[string]sample
+	failures map[string]int
+}
+
+// NewEngine 由点位表创建报警引擎, 没有报警规则的点位不参与判断
+func NewEngine(tags []config.Tag) *Engine {
+	e := &Engine{
+		tags:     make(map[string]*tagRules),
+		alarms:   make(map[string]*Alarm),
+		last:     make(map[string]sample),
+		failures: make(map[string]int),
+	}
+	for _, tag := range tags {
+		if len(tag.Alarms) > 0 {
+			e.tags[strings.ToLower(tag.Name)] = &tagRules{tag: tag.Name, unit: tag.Unit, rules: tag.Alarms}
+		}
+	}
+	return e
+}
+
+// RuleCount 返回规则总数
+func (e *Engine) RuleCount() int {
+	n := 0
+	for _, t := range e.tags {
+		n += len(t.rules)
+	}
+	return n
+}
+
+// HasRules 判断点位是否有报警规则
+func (e *Engine) HasRules(tag string) bool {
+	_, ok := e.tags[strings.ToLower(tag)]
+	return ok
+}
+
+// Evaluate 按点位的规则判断一次成功读取的数值, 同时清除该点位的通讯中断计数
+func (e *Engine) Evaluate(t time.Time, tag string, values []Value) []Event {
+	rules, ok := e.tags[strings.ToLower(tag)]
+	if !ok {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []Event
+	failures := e.failures[rules.tag]
+	delete(e.failures, rules.tag)
+	for i, rule := range rules.rules {
+		if rule.Type == config.AlarmCommLoss {
+			events = e.update(events, t, rules, i, rules.tag, false, float64(failures))
+			continue
+		}
+		for _, v := range values {
+			active, ok := e.check(rule, e.isActive(alarmID(v.Point, i)), v, t)
+			if ok {
+				events = e.update(events, t, rules, i, v.Point, active, v.Value)
+			}
+		}
+	}
+	for _, v := range values {
+		e.last[v.Point] = sample{time: t, value: v.Value}
+	}
+	return events
+}
+
+// Fail 记录点位的一次读取失败, 连续失败次数达到通讯中断规则的设定时产生报警
+func (e *Engine) Fail(t time.Time, tag string) []Event {
+	rules, ok := e.tags[strings.ToLower(tag)]
+	if !ok {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.failures[rules.tag]++
+	failures := e.failures[rules.tag]
+	var events []Event
+	for i, rule := range rules.rules {
+		if rule.Type == config.AlarmCommLoss {
+			events = e.update(events, t, rules, i, rules.tag, failures >= rule.Failures, float64(failures))
+		}
+	}
+	return events
+}
+
+// check 判断规则条件是否成立, 已报警时按回差判断。ok 为 false 表示无法判断 (如变化率缺少上一次数值)
+func (e *Engine) check(rule config.AlarmRule, active bool, v Value, t time.Time) (bool, bool) {
+	switch rule.Type {
+	case config.AlarmHigh:
+		limit := rule.Limit
+		if active {
+			limit -= rule.Deadband
+		}
+		return v.Value > limit, !math.IsNaN(v.Value)
+	case config.AlarmLow:
+		limit := rule.Limit
+		if active {
+			limit += rule.Deadband
+		}
+		return v.Value < limit, !math.IsNaN(v.Value)
+	case config.AlarmRate:
+		prev, ok := e.last[v.Point]
+		dt := t.Sub(prev.time).Seconds()
+		if !ok || dt <= 0 {
+			return false, false
+		}
+		limit := rule.Limit
+		if active {
+			limit -= rule.Deadband
+		}
+		rate := math.Abs(v.Value-prev.value) / dt
+		return rate > limit, !math.IsNaN(rate)
+	case config.AlarmBitSet, config.AlarmBitClear:
+		set := uint64(int64(v.Raw))>>uint(rule.Bit)&1 == 1
+		return set == (rule.Type == config.AlarmBitSet), true
+	}
+	return false, false
+}
+
+// isActive 判断报警是否处于报警中 (含已确认)
+func (e *Engine) isActive(id string) bool {
+	a, ok := e.alarms[id]
+	return ok && (a.State == StateActive || a.State == StateAcknowledged)
+}
+
+// update 按条件是否成立推进报警状态, 产生的事件追加到 events 并写入日志
+func (e *Engine) update(events []Event, t time.Time, rules *tagRules, index int, point string, active bool, value float64) []Event {
+	id := alarmID(point, index)
+	a, ok := e.alarms[id]
+	switch {
+	case active && (!ok || a.State == StateCleared):
+		rule := rules.rules[index]
+		message := rule.Message
+		if message == "" {
+			message = rule.Describe(rules.unit)
+		}
+		a = &Alarm{
+			ID: id, Point: point, Tag: rules.tag, Rule: rule, Message: message,
+			State: StateActive, Value: value, Raised: t, Severity: rule.Severity,
+		}
+		e.alarms[id] = a
+		return e.emit(events, EventRaised, t, a)
+	case active:
+		a.Value = value
+	case ok && a.State == StateActive:
+		a.State, a.Value, a.Cleared = StateCleared, value, t
+		return e.emit(events, EventCleared, t, a)
+	case ok && a.State == StateAcknowledged:
+		a.State, a.Value, a.Cleared = StateNormal, value, t
+		delete(e.alarms, id)
+		return e.emit(events, EventCleared, t, a)
+	}
+	return events
+}
+
+// Acknowledge 确认报警, 已恢复的报警确认后从列表中移除
+func (e *Engine) Acknowledge(t time.Time, id string) (Event, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	events := e.acknowledge(nil, t, id)
+	if len(events) == 0 {
+		return Event{}, false
+	}
+	return events[0], true
+}
+
+// AcknowledgeAll 确认所有未确认的报警
+func (e *Engine) AcknowledgeAll(t time.Time) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var events []Event
+	for _, id := range e.sortedIDs() {
+		events = e.acknowledge(events, t, id)
+	}
+	return events
+}
+
+func (e *Engine) acknowledge(events []Event, t time.Time, id string) []Event {
+	a, ok := e.alarms[id]
+	if !ok {
+		return events
+	}
+	switch a.State {
+	case StateActive:
+		a.State, a.Acked = StateAcknowledged, t
+	case StateCleared:
+		a.State, a.Acked = StateNormal, t
+		delete(e.alarms, id)
+	default:
+		return events
+	}
+	return e.emit(events, EventAcknowledged, t, a)
+}
+
+// Alarms 返回报警列表, 最新的在前
+func (e *Engine) Alarms() []Alarm {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := e.sortedIDs()
+	alarms := make([]Alarm, len(ids))
+	for i, id := range ids {
+		alarms[i] = *e.alarms[id]
+	}
+	return alarms
+}
+
+// Unacknowledged 返回未确认的报警数
+func (e *Engine) Unacknowledged() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, a := range e.alarms {
+		if a.State == StateActive || a.State == StateCleared {
+			n++
+		}
+	}
+	return n
+}
+
+// sortedIDs 按产生时间从新到旧排列报警
+func (e *Engine) sortedIDs() []string {
+	ids := make([]string, 0, len(e.alarms))
+	for id := range e.alarms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := e.alarms[ids[i]], e.alarms[ids[j]]
+		if !a.Raised.Equal(b.Raised) {
+			return a.Raised.After(b.Raised)
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// emit 追加事件并写入日志
+func (e *Engine) emit(events []Event, kind EventKind, t time.Time, a *Alarm) []Event {
+	ev := Event{Kind: kind, Time: t, Alarm: *a}
+	text := ev.String()
+	switch {
+	case kind != EventRaised:
+		logger.Info(text)
+	case a.Severity == config.SeverityCritical:
+		logger.Error(text)
+	case a.Severity == config.SeverityWarning:
+		logger.Warn(text)
+	default:
+		logger.Info(text)
+	}
+	return append(events, ev)
+}
+
+// String 返回事件的日志文本
+func (ev Event) String() string {
+	a := ev.Alarm
+	if a.Rule.Type == config.AlarmCommLoss {
+		return fmt.Sprintf("%s [%s] %s: %s", ev.Kind, a.Severity, a.Point, a.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s: %s, 数值 %g", ev.Kind, a.Severity, a.Point, a.Message, a.Value)
+}
+
+func alarmID(point string, index int) string {
+	return fmt.Sprintf("%s/%d", point, index)
+}
@@ -0,0 +1,190 @@
+package alarm
+
+import (
+	"math"
+	"modbusbaby/internal/config"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// at 返回 base 之后第 i 秒
+func at(i int) time.Time {
+	return base.Add(time.Duration(i) * time.Second)
+}
+
+// newEngine 由一个点位的规则创建引擎, 规则经过点位表的校验和默认值填充
+func newEngine(t *testing.T, dataType string, rules ...config.AlarmRule) *Engine {
+	t.Helper()
+	db, err := config.NewTagDatabase([]config.Tag{{Name: "Temp", Table: "holding", DataType: dataType, Unit: "°C", Alarms: rules}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEngine(db.Tags)
+}
+
+// kinds 返回事件类型的名称, 如 "报警 恢复"
+func kinds(events []Event) string {
+	names := make([]string, len(events))
+	for i, ev := range events {
+		names[i] = ev.Kind.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// state 返回报警的状态, 不在列表中时为正常
+func state(e *Engine, id string) State {
+	for _, a := range e.Alarms() {
+		if a.ID == id {
+			return a.State
+		}
+	}
+	return StateNormal
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType string
+		rule     config.AlarmRule
+		values   []float64 // 每秒一个工程值, 原始值相同
+		want     []string  // 每次判断产生的事件
+	}{
+		{"high", "UINT16", config.AlarmRule{Type: "high", Limit: 80},
+			[]float64{70, 81, 90, 80, 81}, []string{"", "报警", "", "恢复", "报警"}},
+		{"high deadband", "UINT16", config.AlarmRule{Type: "high", Limit: 80, Deadband: 5},
+			[]float64{81, 78, 76, 75, 81}, []string{"报警", "", "", "恢复", "报警"}},
+		{"low deadband", "INT16", config.AlarmRule{Type: "low", Limit: 0, Deadband: 2},
+			[]float64{1, -1, 1, 2, -1}, []string{"", "报警", "", "恢复", "报警"}},
+		{"nan ignored", "FLOAT32", config.AlarmRule{Type: "high", Limit: 80},
+			[]float64{90, math.NaN(), 70}, []string{"报警", "", "恢复"}},
+		{"rate", "FLOAT32", config.AlarmRule{Type: "roc", Limit: 5},
+			[]float64{100, 104, 110, 112}, []string{"", "", "报警", "恢复"}},
+		{"bit set", "UINT16", config.AlarmRule{Type: "bit_set", Bit: 2},
+			[]float64{0, 4, 5, 3}, []string{"", "报警", "", "恢复"}},
+		{"bit clear", "UINT32", config.AlarmRule{Type: "bit_clear", Bit: 17},
+			[]float64{1 << 17, 0, 1<<17 | 1}, []string{"", "报警", "恢复"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEngine(t, tt.dataType, tt.rule)
+			var got []string
+			for i, v := range tt.values {
+				got = append(got, kinds(e.Evaluate(at(i), "Temp", []Value{{Point: "Temp", Value: v, Raw: v}})))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBitUsesRaw 按位规则使用缩放前的原始值, 限值规则使用工程值
+func TestBitUsesRaw(t *testing.T) {
+	e := newEngine(t, "UINT16", config.AlarmRule{Type: "bit_set", Bit: 0}, config.AlarmRule{Type: "high", Limit: 10})
+	events := e.Evaluate(at(0), "Temp", []Value{{Point: "Temp", Value: 0.1, Raw: 1}})
+	if len(events) != 1 || events[0].Alarm.Rule.Type != config.AlarmBitSet {
+		t.Errorf("events = %+v, want only the bit alarm", events)
+	}
+	events = e.Evaluate(at(1), "Temp", []Value{{Point: "Temp", Value: 20, Raw: 200}})
+	if kinds(events) != "恢复 报警" {
+		t.Errorf("events = %q, want the bit alarm cleared and the high alarm raised", kinds(events))
+	}
+}
+
+func TestCommLoss(t *testing.T) {
+	e := newEngine(t, "UINT16", config.AlarmRule{Type: "comm_loss", Failures: 3})
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, kinds(e.Fail(at(i), "temp")))
+	}
+	got = append(got, kinds(e.Evaluate(at(4), "Temp", []Value{{Point: "Temp", Value: 1}})))
+	if want := []string{"", "", "报警", ""}; !reflect.DeepEqual(got[:4], want) {
+		t.Errorf("Fail events = %q, want %q", got[:4], want)
+	}
+	if got[4] != "恢复" {
+		t.Errorf("Evaluate after failures: %q, want 恢复", got[4])
+	}
+	alarms := e.Alarms()
+	if len(alarms) != 1 || alarms[0].State != StateCleared || alarms[0].Value != 4 {
+		t.Errorf("alarms = %+v", alarms)
+	}
+
+	// 成功读取后计数清零, 重新计数
+	if events := e.Fail(at(5), "Temp"); len(events) != 0 {
+		t.Errorf("first failure after a good read raised %q", kinds(events))
+	}
+}
+
+// TestAcknowledge 已确认的报警恢复后移除, 未确认的恢复后保留到确认
+func TestAcknowledge(t *testing.T) {
+	e := newEngine(t, "UINT16", config.AlarmRule{Type: "high", Limit: 80, Severity: "critical"})
+	evaluate := func(i int, v float64) string {
+		return kinds(e.Evaluate(at(i), "Temp", []Value{{Point: "Temp", Value: v}}))
+	}
+	const id = "Temp/0"
+
+	evaluate(0, 90)
+	if state(e, id) != StateActive || e.Unacknowledged() != 1 {
+		t.Fatalf("state %s, %d unacknowledged", state(e, id), e.Unacknowledged())
+	}
+	if ev, ok := e.Acknowledge(at(1), id); !ok || ev.Kind != EventAcknowledged || !ev.Alarm.Acked.Equal(at(1)) {
+		t.Errorf("Acknowledge = %+v, %v", ev, ok)
+	}
+	if _, ok := e.Acknowledge(at(1), id); ok {
+		t.Error("second Acknowledge succeeded")
+	}
+	if state(e, id) != StateAcknowledged || e.Unacknowledged() != 0 {
+		t.Errorf("state %s, %d unacknowledged", state(e, id), e.Unacknowledged())
+	}
+	if got := evaluate(2, 70); got != "恢复" || len(e.Alarms()) != 0 {
+		t.Errorf("clearing an acknowledged alarm: %q, %d alarms left", got, len(e.Alarms()))
+	}
+
+	// 未确认即恢复, 再次报警时重新产生
+	evaluate(3, 90)
+	evaluate(4, 70)
+	if state(e, id) != StateCleared || e.Unacknowledged() != 1 {
+		t.Errorf("state %s, %d unacknowledged", state(e, id), e.Unacknowledged())
+	}
+	if got := evaluate(5, 95); got != "报警" {
+		t.Errorf("raising a cleared alarm: %q", got)
+	}
+	evaluate(6, 70)
+	if events := e.AcknowledgeAll(at(7)); len(events) != 1 || len(e.Alarms()) != 0 {
+		t.Errorf("AcknowledgeAll: %d events, %d alarms left", len(events), len(e.Alarms()))
+	}
+}
+
+func TestEngineRules(t *testing.T) {
+	db, err := config.NewTagDatabase([]config.Tag{
+		{Name: "Temp", Table: "holding", Alarms: []config.AlarmRule{{Type: "high", Limit: 1}, {Type: "low"}}},
+		{Name: "Plain", Table: "holding"},
+		{Name: "Pumps", Table: "coil", Count: 2, Alarms: []config.AlarmRule{{Type: "bit_set", Message: "运行"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(db.Tags)
+	if e.RuleCount() != 3 || !e.HasRules("TEMP") || e.HasRules("Plain") {
+		t.Errorf("RuleCount = %d, HasRules(TEMP) = %v, HasRules(Plain) = %v", e.RuleCount(), e.HasRules("TEMP"), e.HasRules("Plain"))
+	}
+	if events := e.Evaluate(at(0), "Plain", []Value{{Point: "Plain", Value: 100}}); events != nil {
+		t.Errorf("a tag without rules raised %q", kinds(events))
+	}
+
+	// 多个值的点位每个值各有一个报警
+	events := e.Evaluate(at(0), "Pumps", []Value{{Point: "Pumps[0]", Value: 1, Raw: 1}, {Point: "Pumps[1]", Value: 1, Raw: 1}})
+	if len(events) != 2 || events[0].Alarm.ID != "Pumps[0]/0" || events[1].Alarm.ID != "Pumps[1]/0" {
+		t.Fatalf("events = %+v", events)
+	}
+	if got, want := events[0].String(), "报警 [warning] Pumps[0]: 运行, 数值 1"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	if got := e.Alarms()[0].Message; got != "运行" {
+		t.Errorf("Message = %q", got)
+	}
+}
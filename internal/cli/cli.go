@@ -0,0 +1,1253 @@
+// Package cli 命令行模式: 不启动界面, 直接读写设备并输出结果
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"modbusbaby/internal/alarm"
+	"modbusbaby/internal/config"
+	"modbusbaby/internal/historian"
+	"modbusbaby/internal/modbus"
+	"modbusbaby/internal/poller"
+	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/modicon"
+	"modbusbaby/pkg/sunspec"
+	"modbusbaby/pkg/utils"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(env *env, args []string) error
+}
+
+// env 命令执行环境
+type env struct {
+	cfg    *config.Config
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var commands = []command{
+	{"read", "读取寄存器或线圈", runRead},
+	{"write", "写入寄存器或线圈", runWrite},
+	{"tags", "列出点位表中的点位", runTags},
+	{"templates", "列出或安装设备模板", runTemplates},
+	{"import", "将厂商寄存器表 (CSV/XLSX) 导入为点位表", runImport},
+	{"sunspec", "发现并解码 SunSpec 设备的模型", runSunSpec},
+	{"detect", "按期望值识别数据类型和字节/字序", runDetect},
+	{"history", "导出历史数据库中的数据", runHistory},
+	{"poll", "按点位的轮询周期持续读取并输出", runPoll},
+}
+
+// Run 执行命令行参数, 返回进程退出码
+func Run(args []string, cfg *config.Config) int {
+	e := &env{cfg: cfg, stdout: os.Stdout, stderr: os.Stderr}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		e.printUsage()
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			if err := cmd.run(e, args[1:]); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintf(e.stderr, "错误: %v\n", err)
+				}
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(e.stderr, "未知命令: %s\n", args[0])
+	e.printUsage()
+	return 2
+}
+
+func (e *env) printUsage() {
+	fmt.Fprintln(e.stderr, "用法: modbusbaby <命令> [选项]")
+	fmt.Fprintln(e.stderr, "不带参数运行时启动图形界面。")
+	fmt.Fprintln(e.stderr, "")
+	fmt.Fprintln(e.stderr, "命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(e.stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(e.stderr, "")
+	fmt.Fprintln(e.stderr, "使用 \"modbusbaby <命令> -h\" 查看命令选项。")
+}
+
+// connOptions 连接选项
+type connOptions struct {
+	tcp      string
+	rtu      string
+	baudRate int
+	parity   string
+	dataBits int
+	stopBits float64
+	unit     int
+}
+
+func (o *connOptions) register(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&o.tcp, "tcp", "", "TCP 设备地址 host[:port]")
+	fs.StringVar(&o.rtu, "rtu", "", "RTU 串口, 如 COM3 或 /dev/ttyUSB0")
+	fs.IntVar(&o.baudRate, "baud", cfg.RTU.BaudRate, "波特率")
+	fs.StringVar(&o.parity, "parity", cfg.RTU.Parity, "校验: None/Even/Odd/Mark/Space")
+	fs.IntVar(&o.dataBits, "databits", cfg.RTU.DataBits, "数据位")
+	fs.Float64Var(&o.stopBits, "stopbits", cfg.RTU.StopBits, "停止位: 1/1.5/2")
+	fs.IntVar(&o.unit, "unit", -1, "从站地址, 省略时使用配置文件中所用连接类型 (TCP/RTU) 的从站地址")
+}
+
+// useRTU 判断 connect 是否使用 RTU 连接
+func (o *connOptions) useRTU(cfg *config.Config) bool {
+	if o.rtu != "" || o.tcp != "" {
+		return o.rtu != ""
+	}
+	return strings.EqualFold(cfg.DefaultConnType, "RTU")
+}
+
+// resolveUnit 未指定 -unit 时按所用的连接类型取配置文件中的从站地址
+func (o *connOptions) resolveUnit(cfg *config.Config) error {
+	if o.unit < 0 {
+		o.unit = cfg.TCP.SlaveID
+		if o.useRTU(cfg) {
+			o.unit = cfg.RTU.SlaveID
+		}
+	}
+	if o.unit < 0 || o.unit > 255 {
+		return fmt.Errorf("从站地址无效: %d", o.unit)
+	}
+	return nil
+}
+
+// connect 按选项连接设备, 未指定 -tcp/-rtu 时使用配置文件中的默认连接
+func (o *connOptions) connect(cfg *config.Config) (*modbus.Client, error) {
+	if err := o.resolveUnit(cfg); err != nil {
+		return nil, err
+	}
+
+	client := modbus.NewClient()
+	switch {
+	case o.rtu != "":
+		rtu := cfg.RTU
+		rtu.Port = o.rtu
+		rtu.BaudRate = o.baudRate
+		rtu.Parity = o.parity
+		rtu.DataBits = o.dataBits
+		rtu.StopBits = o.stopBits
+		rtu.SlaveID = o.unit
+		return client, client.ConnectRTU(rtu)
+	case o.tcp != "":
+		host, port, err := splitHostPort(o.tcp, cfg.TCP.Port)
+		if err != nil {
+			return nil, err
+		}
+		return client, client.ConnectTCP(host, port)
+	case strings.EqualFold(cfg.DefaultConnType, "RTU"):
+		return client, client.ConnectRTU(cfg.RTU)
+	default:
+		return client, client.ConnectTCP(cfg.TCP.IP, cfg.TCP.Port)
+	}
+}
+
+func splitHostPort(addr string, defaultPort int) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		// 未带端口
+		return addr, defaultPort, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("端口无效: %s", portStr)
+	}
+	return host, port, nil
+}
+
+// pointOptions 寄存器地址和数据格式选项
+type pointOptions struct {
+	tagName  string
+	tagFile  string
+	template string
+	tmplDir  string
+	tag      *config.Tag // 按点位名称访问时解析出的点位
+	table    string
+	addrText string
+	address  int
+	hasBit   bool  // -addr 带位号, 如 40010.3
+	bit      uint8 // 位号 0-15
+	count    int
+	dataType string
+	order    string
+	timeZone string
+	strLen   int
+	padding  string
+}
+
+func (o *pointOptions) register(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&o.table, "table", "holding", "寄存器类型: holding/input/coil/discrete")
+	fs.StringVar(&o.addrText, "addr", "0", "起始地址: 0起始的协议地址、0x十六进制、40001/400001 或 HR100 (后两种自带寄存器类型), 加 .位号 表示寄存器中的一位")
+	fs.IntVar(&o.count, "count", 1, "读取数量 (寄存器或线圈个数, 带位号时为位数)")
+	fs.StringVar(&o.dataType, "type", "UINT16", "数据类型, 如 INT16/FLOAT32/UTF8")
+	fs.StringVar(&o.order, "order", "ABCD", "字节/字序, 如 ABCD/CDAB/BADC/DCBA/GHEFCDAB")
+	fs.StringVar(&o.timeZone, "tz", cfg.TimeZone, "时间类型的时区")
+	fs.IntVar(&o.strLen, "strlen", 0, "字符串字段长度 (寄存器), 0 表示整段")
+	fs.StringVar(&o.padding, "pad", "NUL", "字符串填充: NUL/SPACE")
+	fs.StringVar(&o.tagName, "tag", "", "按点位名称访问, 覆盖 -table/-addr/-count/-type/-order")
+	fs.StringVar(&o.tagFile, "tags", cfg.TagPath(), "点位表文件 (YAML/JSON/CSV)")
+	fs.StringVar(&o.template, "template", "", "使用设备模板的点位表, 代替 -tags")
+	o.tmplDir = cfg.TemplatePath()
+}
+
+// resolve 按 -tag 查找点位, 用点位定义覆盖地址、类型和从站地址; 未指定点位时解析 -addr
+func (o *pointOptions) resolve(conn *connOptions) error {
+	if o.tagName == "" {
+		return o.resolveAddress()
+	}
+	var db *config.TagDatabase
+	var err error
+	if o.template != "" {
+		db, err = loadTemplateTags(o.tmplDir, o.template)
+	} else {
+		db, err = loadTagFile(o.tagFile)
+	}
+	if err != nil {
+		return err
+	}
+	tag, ok := db.Lookup(o.tagName)
+	if !ok {
+		return fmt.Errorf("未找到点位: %s", o.tagName)
+	}
+
+	o.tag = &tag
+	o.table = tag.Table
+	o.address = tag.Address
+	o.count = tag.RegisterCount()
+	o.dataType = tag.DataType
+	if tag.UnitID != 0 {
+		conn.unit = tag.UnitID
+	}
+	return nil
+}
+
+// resolveAddress 解析 -addr, 地址写法自带寄存器类型时覆盖 -table
+func (o *pointOptions) resolveAddress() error {
+	table, err := o.tableName()
+	if err != nil {
+		return err
+	}
+	t, err := modicon.ParseTable(table)
+	if err != nil {
+		return err
+	}
+	addr, err := modicon.Parse(o.addrText, t)
+	if err != nil {
+		return err
+	}
+	o.table = addr.Table.Name()
+	o.address = int(addr.Offset)
+	o.hasBit, o.bit = addr.HasBit, addr.Bit
+	return nil
+}
+
+// loadTagFile 加载点位表文件
+func loadTagFile(path string) (*config.TagDatabase, error) {
+	if path == "" {
+		return nil, fmt.Errorf("未指定点位表文件, 请使用 -tags 或在配置中设置 tag_file")
+	}
+	db, err := config.LoadTags(path)
+	if err != nil {
+		return nil, fmt.Errorf("加载点位表失败: %w", err)
+	}
+	return db, nil
+}
+
+// loadTemplateTags 加载设备模板的点位表
+func loadTemplateTags(dir, name string) (*config.TagDatabase, error) {
+	templates, _ := config.LoadTemplates(dir)
+	t, ok := config.FindTemplate(templates, name)
+	if !ok {
+		return nil, fmt.Errorf("未找到设备模板: %s", name)
+	}
+	return t.TagDatabase()
+}
+
+// tableName 规范化寄存器类型名称
+func (o *pointOptions) tableName() (string, error) {
+	return config.NormalizeTable(o.table)
+}
+
+// scaling 返回点位的缩放设置, 未使用点位时不缩放
+func (o *pointOptions) scaling() datatypes.Scaling {
+	if o.tag == nil {
+		return datatypes.Scaling{}
+	}
+	return o.tag.Scaling()
+}
+
+// apply 将数据格式选项应用到客户端, 返回数据类型
+func (o *pointOptions) apply(client *modbus.Client) (datatypes.DataType, error) {
+	dataType, err := datatypes.ParseDataType(o.dataType)
+	if err != nil {
+		return dataType, err
+	}
+	var byteOrder datatypes.ByteOrder
+	var wordOrder datatypes.WordOrder
+	if o.tag != nil {
+		byteOrder, wordOrder = o.tag.Orders()
+	} else if byteOrder, wordOrder, err = datatypes.ParseOrderPreset(o.order); err != nil {
+		return dataType, err
+	}
+	client.SetDataConverter(byteOrder, wordOrder)
+
+	loc, err := datatypes.ParseLocation(o.timeZone)
+	if err != nil {
+		return dataType, err
+	}
+	client.SetTimeLocation(loc)
+
+	padding, err := datatypes.ParseStringPadding(o.padding)
+	if err != nil {
+		return dataType, err
+	}
+	client.SetStringOptions(datatypes.StringOptions{FieldLength: o.strLen, Padding: padding})
+	return dataType, nil
+}
+
+func (o *pointOptions) checkRange() error {
+	if o.address < 0 || o.address > 65535 {
+		return fmt.Errorf("地址无效: %d", o.address)
+	}
+	registers := o.count
+	if o.hasBit {
+		registers = (int(o.bit) + o.count + 15) / 16
+	}
+	if o.count < 1 || o.address+registers > 65536 {
+		return fmt.Errorf("数量无效: %d", o.count)
+	}
+	return nil
+}
+
+func runRead(e *env, args []string) error {
+	fs := flag.NewFlagSet("read", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var conn connOptions
+	var point pointOptions
+	conn.register(fs, e.cfg)
+	point.register(fs, e.cfg)
+	radixList := fs.String("radix", "dec", "显示进制, 逗号分隔: dec,hex,bin,oct,raw")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	radixes, err := datatypes.ParseRadixList(*radixList)
+	if err != nil {
+		return err
+	}
+	if err := point.resolve(&conn); err != nil {
+		return err
+	}
+	if point.tag != nil && !point.tag.Readable() {
+		return fmt.Errorf("点位 %s 不可读", point.tag.Name)
+	}
+	table, err := point.tableName()
+	if err != nil {
+		return err
+	}
+	if err := point.checkRange(); err != nil {
+		return err
+	}
+
+	client, err := conn.connect(e.cfg)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Disconnect()
+
+	if point.hasBit {
+		return readBits(e, client, byte(conn.unit), table, &point)
+	}
+
+	dataType, err := point.apply(client)
+	if err != nil {
+		return err
+	}
+
+	unit, addr, count := byte(conn.unit), uint16(point.address), uint16(point.count)
+	var values interface{}
+	var registers []uint16
+	switch table {
+	case "holding", "input":
+		if registers, err = client.ReadRawRegisters(unit, table == "input", addr, count); err == nil {
+			values, err = client.ConvertRegisters(registers, dataType)
+		}
+	case "coil":
+		values, err = client.ReadCoils(unit, addr, count)
+		dataType = datatypes.BOOL
+	case "discrete":
+		values, err = client.ReadDiscreteInputs(unit, addr, count)
+		dataType = datatypes.BOOL
+	}
+	if err != nil {
+		return fmt.Errorf("读取失败: %w", err)
+	}
+
+	rows, err := datatypes.FormatRows(values, registers, dataType, radixes)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"ADDRESS"}
+	for _, radix := range radixes {
+		header = append(header, radix.String())
+	}
+	// 点位带缩放时增加工程值列
+	if scaling := point.scaling(); !scaling.IsIdentity() && (table == "holding" || table == "input") {
+		scaled, err := scaling.ApplyValues(values)
+		if err != nil {
+			return err
+		}
+		header = append(header, "VALUE")
+		for i := range rows {
+			rows[i].Columns = append(rows[i].Columns, scaling.Format(scaled[i]))
+		}
+	}
+	return writeRows(e.stdout, point.address, rows, header)
+}
+
+// readBits 读取寄存器中从指定位开始的连续位, 每位一行
+func readBits(e *env, client *modbus.Client, unit byte, table string, point *pointOptions) error {
+	bits, err := client.ReadRegisterBits(unit, table == "input", uint16(point.address), point.bit, point.count)
+	if err != nil {
+		return fmt.Errorf("读取失败: %w", err)
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tVALUE")
+	for i, on := range bits {
+		n := int(point.bit) + i
+		value := 0
+		if on {
+			value = 1
+		}
+		fmt.Fprintf(tw, "%d.%d\t%d\n", point.address+n/16, n%16, value)
+	}
+	return tw.Flush()
+}
+
+// writeRows 以对齐的表格输出读取结果
+func writeRows(out io.Writer, start int, rows []datatypes.FormattedRow, header []string) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, row.Address(start)+"\t"+strings.Join(row.Columns, "\t"))
+	}
+	return tw.Flush()
+}
+
+func runWrite(e *env, args []string) error {
+	fs := flag.NewFlagSet("write", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var conn connOptions
+	var point pointOptions
+	conn.register(fs, e.cfg)
+	point.register(fs, e.cfg)
+	value := fs.String("value", "", "写入的值, 语法与界面数值框相同, 如 \"1 2 0x10\" 或 \"0*10\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := point.resolve(&conn); err != nil {
+		return err
+	}
+	if point.tag != nil && !point.tag.Writable() {
+		return fmt.Errorf("点位 %s 为只读", point.tag.Name)
+	}
+	table, err := point.tableName()
+	if err != nil {
+		return err
+	}
+	if err := point.checkRange(); err != nil {
+		return err
+	}
+	if *value == "" && fs.NArg() > 0 {
+		*value = strings.Join(fs.Args(), " ")
+	}
+
+	client, err := conn.connect(e.cfg)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Disconnect()
+
+	dataType, err := point.apply(client)
+	if err != nil {
+		return err
+	}
+
+	unit, addr := byte(conn.unit), uint16(point.address)
+	var writeErr error
+	switch {
+	case point.hasBit && table == "holding":
+		values, err := datatypes.ParseStringToType(*value, datatypes.BOOL)
+		if err != nil {
+			return err
+		}
+		bits := values.([]bool)
+		if len(bits) != 1 {
+			return fmt.Errorf("带位号的地址只能写入一个值 (on/off/1/0)")
+		}
+		method, err := client.WriteRegisterBit(unit, addr, point.bit, bits[0])
+		if err != nil {
+			return fmt.Errorf("写入失败: %w", err)
+		}
+		fmt.Fprintf(e.stdout, "OK (%s)\n", method)
+		return nil
+	case table == "holding":
+		values, err := parseHoldingValues(*value, dataType, point.scaling())
+		if err != nil {
+			return err
+		}
+		writeErr = client.WriteHoldingRegisters(unit, addr, values, dataType)
+	case table == "coil":
+		values, err := datatypes.ParseStringToType(*value, datatypes.BOOL)
+		if err != nil {
+			return err
+		}
+		writeErr = client.WriteCoils(unit, addr, values.([]bool))
+	default:
+		return fmt.Errorf("%s 不可写", table)
+	}
+	if writeErr != nil {
+		return fmt.Errorf("写入失败: %w", writeErr)
+	}
+	fmt.Fprintln(e.stdout, "OK")
+	return nil
+}
+
+// parseHoldingValues 解析待写入的寄存器值, 点位带缩放时输入为工程值
+func parseHoldingValues(text string, dataType datatypes.DataType, scaling datatypes.Scaling) (interface{}, error) {
+	if scaling.IsIdentity() {
+		return datatypes.ParseStringToType(text, dataType)
+	}
+	parsed, err := datatypes.ParseStringToType(text, datatypes.FLOAT64)
+	if err != nil {
+		return nil, err
+	}
+	return scaling.InverseValues(parsed.([]float64), dataType)
+}
+
+func runTags(e *env, args []string) error {
+	fs := flag.NewFlagSet("tags", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	tagFile := fs.String("tags", e.cfg.TagPath(), "点位表文件 (YAML/JSON/CSV)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := loadTagFile(*tagFile)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tUNIT_ID\tTABLE\tADDRESS\tCOUNT\tTYPE\tORDER\tSCALE\tOFFSET\tUNIT\tACCESS\tPOLL\tDESCRIPTION")
+	for _, tag := range db.Tags {
+		scale := tag.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		poll := "-"
+		if tag.PollMs > 0 {
+			poll = tag.PollInterval().String()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%d\t%s\t%s/%s\t%g\t%g\t%s\t%s\t%s\t%s\n",
+			tag.Name, tag.UnitID, tag.Table, tag.Address, tag.Count, tag.DataType,
+			tag.ByteOrder, tag.WordOrder, scale, tag.Offset, tag.Unit, tag.Access, poll, tag.Description)
+	}
+	return tw.Flush()
+}
+
+func runTemplates(e *env, args []string) error {
+	fs := flag.NewFlagSet("templates", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	dir := fs.String("dir", e.cfg.TemplatePath(), "用户模板目录")
+	install := fs.Bool("install", false, "将内置模板复制到用户模板目录以便修改")
+	show := fs.String("show", "", "显示指定模板的连接参数和点位")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *install {
+		if *dir == "" {
+			return fmt.Errorf("未指定用户模板目录")
+		}
+		n, err := config.InstallBuiltinTemplates(*dir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "已复制 %d 个内置模板到 %s\n", n, *dir)
+		return nil
+	}
+
+	templates, errs := config.LoadTemplates(*dir)
+	for _, err := range errs {
+		fmt.Fprintf(e.stderr, "跳过模板 %v\n", err)
+	}
+
+	if *show != "" {
+		t, ok := config.FindTemplate(templates, *show)
+		if !ok {
+			return fmt.Errorf("未找到设备模板: %s", *show)
+		}
+		c := t.Connection
+		fmt.Fprintf(e.stdout, "%s (%s %s)\n", t.Name, t.Manufacturer, t.Model)
+		conn := fmt.Sprintf("%s 从站=%d", c.Type, c.SlaveID)
+		if c.Type == "RTU" {
+			conn += fmt.Sprintf(" %d %d-%s-%g", c.BaudRate, c.DataBits, c.Parity, c.StopBits)
+		} else if c.Port != 0 {
+			conn += fmt.Sprintf(" 端口=%d", c.Port)
+		}
+		fmt.Fprintf(e.stdout, "连接: %s, 字节/字序: %s/%s\n", conn, t.ByteOrder, t.WordOrder)
+		db, err := t.TagDatabase()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTABLE\tADDRESS\tTYPE\tSCALE\tUNIT\tACCESS\tDESCRIPTION")
+		for _, tag := range db.Tags {
+			scale := tag.Scale
+			if scale == 0 {
+				scale = 1
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%g\t%s\t%s\t%s\n",
+				tag.Name, tag.Table, tag.Address, tag.DataType, scale, tag.Unit, tag.Access, tag.Description)
+		}
+		return tw.Flush()
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCATEGORY\tMANUFACTURER\tMODEL\tCONN\tORDER\tTAGS\tSOURCE")
+	for _, t := range templates {
+		source := "内置"
+		if !t.Builtin() {
+			source = t.Path
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s/%s\t%d\t%s\n",
+			t.Name, t.Category, t.Manufacturer, t.Model, t.Connection.Type, t.ByteOrder, t.WordOrder, len(t.Tags), source)
+	}
+	return tw.Flush()
+}
+
+func runImport(e *env, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	in := fs.String("in", "", "厂商寄存器表 (.csv/.xlsx)")
+	out := fs.String("out", "", "输出点位表 (.yaml/.json/.csv), 省略时只显示导入结果")
+	mapping := fs.String("map", "", "列映射, 如 \"name=B,address=A,data_type=Type\", 值为列字母或表头名称; 省略的字段按表头猜测")
+	headerRows := fs.Int("header", 1, "表头行数")
+	oneBased := fs.Bool("one-based", true, "不带 4xxxx/3xxxx 前缀的地址从1开始")
+	modiconWithTable := fs.Bool("modicon", false, "有寄存器类型列时, 5位/6位数字地址仍按 Modicon 写法 (40001/300001) 解析")
+	table := fs.String("table", "holding", "无法从地址判断时的寄存器类型")
+	unit := fs.Int("unit", 0, "导入点位的从站地址, 0 表示使用连接设置")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("请使用 -in 指定厂商寄存器表")
+	}
+
+	rows, err := utils.ReadSpreadsheet(*in)
+	if err != nil {
+		return err
+	}
+	header := config.ImportHeader(rows, *headerRows)
+	opts := config.ImportOptions{
+		Mapping:          config.GuessColumnMapping(header),
+		HeaderRows:       *headerRows,
+		OneBased:         *oneBased,
+		DefaultTable:     *table,
+		UnitID:           *unit,
+		ModiconWithTable: *modiconWithTable,
+	}
+	if err := parseColumnMapping(*mapping, header, opts.Mapping); err != nil {
+		return err
+	}
+	for _, field := range config.ImportColumns {
+		if col, ok := opts.Mapping[field]; ok && col < len(header) {
+			fmt.Fprintf(e.stderr, "%-12s <- %s\n", field, header[col])
+		}
+	}
+
+	result, err := config.ImportTags(rows, opts)
+	if err != nil {
+		return err
+	}
+	for _, msg := range result.Skipped {
+		fmt.Fprintln(e.stderr, "跳过 "+msg)
+	}
+	if _, err := config.NewTagDatabase(result.Tags); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTABLE\tADDRESS\tCOUNT\tTYPE\tSCALE\tUNIT\tACCESS")
+		for _, tag := range result.Tags {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%g\t%s\t%s\n",
+				tag.Name, tag.Table, tag.Address, tag.Count, tag.DataType, tag.Scale, tag.Unit, tag.Access)
+		}
+		return tw.Flush()
+	}
+	if err := config.SaveTags(*out, result.Tags); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "已导入 %d 个点位到 %s, 跳过 %d 行\n", len(result.Tags), *out, len(result.Skipped))
+	return nil
+}
+
+// parseColumnMapping 解析 -map 参数, 列可用字母 (A/B/AA) 或表头名称指定
+func parseColumnMapping(spec string, header []string, mapping config.ColumnMapping) error {
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("列映射格式错误: %s", part)
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.TrimSpace(column)
+
+		known := false
+		for _, f := range config.ImportColumns {
+			known = known || f == field
+		}
+		if !known {
+			return fmt.Errorf("未知字段: %s (可用: %s)", field, strings.Join(config.ImportColumns, ", "))
+		}
+
+		col := -1
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			col = columnLetterIndex(column)
+		}
+		if col < 0 {
+			return fmt.Errorf("未找到列: %s", column)
+		}
+		mapping[field] = col
+	}
+	return nil
+}
+
+// columnLetterIndex 将列字母 (A、AB) 转换为0起始的列号, 无效时返回 -1
+func columnLetterIndex(s string) int {
+	if s == "" {
+		return -1
+	}
+	col := 0
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			return -1
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+func runSunSpec(e *env, args []string) error {
+	fs := flag.NewFlagSet("sunspec", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var conn connOptions
+	conn.register(fs, e.cfg)
+	baseList := fs.String("base", "40000,0,50000", "查找 SunS 标记的基地址 (0起始), 逗号分隔")
+	modelList := fs.String("model", "", "只显示指定的模型ID, 逗号分隔")
+	modelDir := fs.String("models", "", "额外的模型定义目录 (model_*.json)")
+	all := fs.Bool("all", false, "显示未实现的点位")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	bases, err := sunspec.ParseBases(*baseList)
+	if err != nil {
+		return err
+	}
+	filter := map[int]bool{}
+	for _, part := range strings.Split(*modelList, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("模型ID无效: %s", part)
+		}
+		filter[id] = true
+	}
+	models := sunspec.DefaultModels()
+	if *modelDir != "" {
+		if err := models.LoadDir(*modelDir); err != nil {
+			return err
+		}
+	}
+
+	client, err := conn.connect(e.cfg)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Disconnect()
+
+	unit := byte(conn.unit)
+	dev, walkErr := sunspec.Discover(func(address, count uint16) ([]uint16, error) {
+		return client.ReadRawRegisters(unit, false, address, count)
+	}, bases)
+	if dev == nil {
+		return walkErr
+	}
+
+	fmt.Fprintf(e.stdout, "SunS @ %d, %d 个模型\n", dev.Base, len(dev.Blocks))
+	for _, mv := range sunspec.DecodeDevice(dev, models) {
+		if len(filter) > 0 && !filter[mv.Block.ID] {
+			continue
+		}
+		fmt.Fprintf(e.stdout, "\n模型 %d %s @ %d, L=%d\n", mv.Block.ID, models.Name(mv.Block.ID), mv.Block.Address, mv.Block.Length)
+		if mv.Def == nil {
+			fmt.Fprintln(e.stdout, "  (无模型定义)")
+			continue
+		}
+		tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  POINT\tADDRESS\tVALUE\tUNITS\tLABEL")
+		mv.Root.Walk(func(path string, p *sunspec.PointValue) {
+			if !p.Implemented && !*all {
+				return
+			}
+			fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\t%s\n", path, p.Address, p.Text(), p.Def.Units, p.Def.Label)
+		})
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if walkErr != nil {
+		return fmt.Errorf("模型链遍历中断: %w", walkErr)
+	}
+	return nil
+}
+
+func runDetect(e *env, args []string) error {
+	fs := flag.NewFlagSet("detect", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var conn connOptions
+	conn.register(fs, e.cfg)
+	regs := fs.String("regs", "", "原始寄存器, 如 \"0x4366 0x8000\"; 省略时从设备读取")
+	table := fs.String("table", "holding", "从设备读取时的寄存器类型: holding/input")
+	addrText := fs.String("addr", "0", "从设备读取时的起始地址")
+	count := fs.Int("count", 4, "从设备读取的寄存器数")
+	expect := fs.String("expect", "", "期望值: 230、230±2、230±0.5% 或 220..240")
+	scales := fs.Bool("scales", true, "整数类型同时尝试 ×0.1/×0.01/×0.001/×10 缩放")
+	limit := fs.Int("n", 20, "最多显示的结果数")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	exp, err := datatypes.ParseExpectation(*expect)
+	if err != nil {
+		return fmt.Errorf("期望值无效 (-expect): %w", err)
+	}
+
+	var registers []uint16
+	if *regs != "" {
+		if registers, err = datatypes.ParseRegisterList(*regs); err != nil {
+			return err
+		}
+	} else {
+		point := pointOptions{table: *table, addrText: *addrText, count: *count}
+		if err := point.resolveAddress(); err != nil {
+			return err
+		}
+		if err := point.checkRange(); err != nil {
+			return err
+		}
+		client, err := conn.connect(e.cfg)
+		if err != nil {
+			return fmt.Errorf("连接失败: %w", err)
+		}
+		defer client.Disconnect()
+
+		unit, addr, n := byte(conn.unit), uint16(point.address), uint16(point.count)
+		if point.table != "holding" && point.table != "input" {
+			return fmt.Errorf("只能识别保持寄存器或输入寄存器")
+		}
+		if registers, err = client.ReadRawRegisters(unit, point.table == "input", addr, n); err != nil {
+			return fmt.Errorf("读取失败: %w", err)
+		}
+	}
+
+	candidates := datatypes.DetectFormats(registers, exp, datatypes.DetectOptions{TryScales: *scales, MaxResults: *limit})
+	if len(candidates) == 0 {
+		return fmt.Errorf("没有符合期望的解释")
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tORDER\tBYTE/WORD\tOFFSET\tSCALE\tVALUE")
+	for _, c := range candidates {
+		fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%d\t%g\t%s\n",
+			c.DataType, c.Order(), c.ByteOrder, c.WordOrder, c.Offset, c.Scale, strconv.FormatFloat(c.Value, 'g', 10, 64))
+	}
+	return tw.Flush()
+}
+
+func runHistory(e *env, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	dbPath := fs.String("db", e.cfg.HistorianPath(), "历史数据库文件")
+	list := fs.Bool("list", false, "列出数据库中的点")
+	points := fs.String("point", "", "导出的点, 逗号分隔; 省略时导出所有点")
+	from := fs.String("from", "-24h", "起始时间: 2006-01-02 15:04:05、RFC 3339 或相对当前的时长 (如 -2h)")
+	to := fs.String("to", "", "结束时间, 格式同 -from, 省略时为当前时间")
+	interval := fs.Duration("interval", 0, "聚合间隔 (如 1m、15m), 输出每个间隔的最小/最大/平均值, 包含已汇总的数据; 0 表示只导出原始样本")
+	out := fs.String("out", "", "输出 CSV 文件, 省略时输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return fmt.Errorf("历史数据库不存在: %s", *dbPath)
+	}
+	h, err := historian.Open(*dbPath, historian.Options{})
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	names, err := h.Points()
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, name := range names {
+			fmt.Fprintln(e.stdout, name)
+		}
+		return nil
+	}
+	if *points != "" {
+		names = strings.Split(*points, ",")
+	}
+
+	now := time.Now()
+	start, err := parseHistoryTime(*from, now)
+	if err != nil {
+		return err
+	}
+	end := now
+	if *to != "" {
+		if end, err = parseHistoryTime(*to, now); err != nil {
+			return err
+		}
+	}
+
+	w := e.stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	cw := csv.NewWriter(w)
+	const timeLayout = "2006-01-02 15:04:05.000"
+	if *interval > 0 {
+		cw.Write([]string{"time", "point", "min", "max", "avg", "count"})
+	} else {
+		cw.Write([]string{"time", "point", "value"})
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if *interval > 0 {
+			aggregates, err := h.Downsample(name, start, end, *interval)
+			if err != nil {
+				return err
+			}
+			for _, a := range aggregates {
+				cw.Write([]string{a.Time.Format(timeLayout), name, formatFloat(a.Min), formatFloat(a.Max), formatFloat(a.Avg), strconv.FormatInt(a.Count, 10)})
+			}
+			continue
+		}
+		samples, err := h.Query(name, start, end)
+		if err != nil {
+			return err
+		}
+		if rolled, err := h.HasRollups(name, start, end); err != nil {
+			return err
+		} else if rolled {
+			fmt.Fprintf(e.stderr, "警告: %s 在所选时间范围内有超过原始数据保留期、已汇总的数据, 原始样本中不包含这部分; 使用 -interval 导出汇总后的最小/最大/平均值\n", name)
+		}
+		for _, s := range samples {
+			cw.Write([]string{s.Time.Format(timeLayout), name, formatFloat(s.Value)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseHistoryTime 解析本地时间、RFC 3339 时间或相对 now 的时长
+func parseHistoryTime(text string, now time.Time) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if d, err := time.ParseDuration(text); err == nil {
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("时间无效: %s", text)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// runPoll 按点位的轮询周期持续读取, 每个值输出一行 CSV (time,point,value), 直到 Ctrl+C 或达到 -duration。
+// 读取错误、报警和结束时的统计输出到标准错误
+func runPoll(e *env, args []string) error {
+	fs := flag.NewFlagSet("poll", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var conn connOptions
+	var point pointOptions
+	conn.register(fs, e.cfg)
+	point.register(fs, e.cfg)
+	interval := fs.Duration("interval", time.Duration(e.cfg.PollingInterval)*time.Millisecond, "未设置 poll_ms 的点位的轮询周期")
+	jitter := fs.Duration("jitter", time.Duration(e.cfg.PollJitter)*time.Millisecond, "每次轮询在计划时间后随机延迟的上限")
+	duration := fs.Duration("duration", 0, "轮询时长, 0 表示直到 Ctrl+C")
+	out := fs.String("out", "", "输出 CSV 文件, 省略时输出到标准输出")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "用法: modbusbaby poll [选项]")
+		fmt.Fprintln(e.stderr, "轮询点位表中的点位 (-tag 可用逗号分隔多个), 或用 -addr 指定单个地址。")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tags, err := pollTargets(fs, &point)
+	if err != nil {
+		return err
+	}
+	schedules := poller.ScheduleTags(tags, *interval)
+	if len(schedules) == 0 {
+		return fmt.Errorf("没有可读的点位")
+	}
+	if err := conn.resolveUnit(e.cfg); err != nil {
+		return err
+	}
+	loc, err := datatypes.ParseLocation(point.timeZone)
+	if err != nil {
+		return err
+	}
+
+	w := e.stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	client, err := conn.connect(e.cfg)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Disconnect()
+	client.SetTimeLocation(loc)
+
+	// 所有组在同一个 goroutine 中执行, 输出和报警判断无需加锁
+	alarms := alarm.NewEngine(tags)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "point", "value"})
+	cw.Flush()
+	var groups []poller.Group
+	for _, s := range schedules {
+		tags := s.Tags
+		groups = append(groups, poller.Group{
+			Name:     s.Name(),
+			Interval: s.Interval,
+			Jitter:   *jitter,
+			Read: func(ctx context.Context) error {
+				return pollTagsOnce(ctx, e, client, byte(conn.unit), tags, cw, alarms)
+			},
+		})
+	}
+	p, err := poller.New(groups)
+	if err != nil {
+		return err
+	}
+	p.OnOverrun = func(name string, took time.Duration, skipped int) {
+		fmt.Fprintf(e.stderr, "轮询组 %s 超时: 耗时 %s, 跳过 %d 个周期\n", name, took.Round(time.Millisecond), skipped)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
+
+	if err := p.Start(); err != nil {
+		return err
+	}
+	select {
+	case <-stop:
+	case <-timeout:
+	}
+	p.Stop()
+
+	tw := tabwriter.NewWriter(e.stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tINTERVAL\tCYCLES\tERRORS\tOVERRUNS\tSKIPPED\tMIN\tMEAN\tMAX\tMAX_LATE")
+	for _, s := range p.Stats() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n", s.Name, s.Interval, s.Cycles, s.Errors, s.Overruns, s.Skipped,
+			s.Min.Round(time.Microsecond), s.Mean.Round(time.Microsecond), s.Max.Round(time.Microsecond), s.MaxLate.Round(time.Microsecond))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// pollTargets 返回要轮询的点位: 指定 -addr 时为该地址, 否则为点位表中 -tag 列出的点位 (省略时为全部)
+func pollTargets(fs *flag.FlagSet, point *pointOptions) ([]config.Tag, error) {
+	addrSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "addr" {
+			addrSet = true
+		}
+	})
+
+	if addrSet {
+		if err := point.resolveAddress(); err != nil {
+			return nil, err
+		}
+		if point.hasBit {
+			return nil, fmt.Errorf("poll 不支持带位号的地址, 请读取整个寄存器")
+		}
+		dataType, err := datatypes.ParseDataType(point.dataType)
+		if err != nil {
+			return nil, err
+		}
+		byteOrder, wordOrder, err := datatypes.ParseOrderPreset(point.order)
+		if err != nil {
+			return nil, err
+		}
+		count := point.count / dataType.RegistersPerValue()
+		if count < 1 {
+			count = 1
+		}
+		db, err := config.NewTagDatabase([]config.Tag{{
+			Name: point.addrText, Table: point.table, Address: point.address, Count: count,
+			DataType: dataType.String(), ByteOrder: byteOrder.String(), WordOrder: wordOrder.String(),
+		}})
+		if err != nil {
+			return nil, err
+		}
+		return db.Tags, nil
+	}
+
+	var db *config.TagDatabase
+	var err error
+	if point.template != "" {
+		db, err = loadTemplateTags(point.tmplDir, point.template)
+	} else {
+		db, err = loadTagFile(point.tagFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if point.tagName == "" {
+		return db.Tags, nil
+	}
+	var tags []config.Tag
+	for _, name := range strings.Split(point.tagName, ",") {
+		tag, ok := db.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("未找到点位: %s", strings.TrimSpace(name))
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// pollTagsOnce 读取一组点位并输出, 有点位失败时返回最后一个错误
+func pollTagsOnce(ctx context.Context, e *env, client *modbus.Client, unit byte, tags []config.Tag, cw *csv.Writer, alarms *alarm.Engine) error {
+	const timeLayout = "2006-01-02 15:04:05.000"
+	var lastErr error
+	for _, tag := range tags {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r, err := client.ReadTag(tag, unit)
+		if err != nil {
+			lastErr = err
+			fmt.Fprintf(e.stderr, "%s 读取点位 %s 失败: %v\n", r.Time.Format(timeLayout), tag.Name, err)
+			printAlarmEvents(e, alarms.Fail(r.Time, tag.Name))
+			continue
+		}
+		if len(r.Scaled) == 0 {
+			// 字符串和时间类型按格式化后的文本输出
+			rows, err := datatypes.FormatRows(r.Values, r.Registers, tag.Type(), []datatypes.Radix{datatypes.RadixDecimal})
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			for i, row := range rows {
+				cw.Write([]string{r.Time.Format(timeLayout), pollPointName(tag.Name, i, len(rows)), row.Columns[0]})
+			}
+			continue
+		}
+
+		raw, _ := datatypes.ToFloat64Slice(r.Values)
+		values := make([]alarm.Value, len(r.Scaled))
+		for i, v := range r.Scaled {
+			name := pollPointName(tag.Name, i, len(r.Scaled))
+			cw.Write([]string{r.Time.Format(timeLayout), name, formatFloat(v)})
+			values[i] = alarm.Value{Point: name, Value: v, Raw: raw[i]}
+		}
+		printAlarmEvents(e, alarms.Evaluate(r.Time, tag.Name, values))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return lastErr
+}
+
+// pollPointName 与界面记录中的点名一致: 只有一个值时为点位名称, 否则为 名称[序号]
+func pollPointName(name string, i, n int) string {
+	if n == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s[%d]", name, i)
+}
+
+func printAlarmEvents(e *env, events []alarm.Event) {
+	for _, ev := range events {
+		fmt.Fprintf(e.stderr, "%s %s\n", ev.Time.Format("2006-01-02 15:04:05.000"), ev)
+	}
+}
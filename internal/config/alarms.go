@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"modbusbaby/pkg/datatypes"
+	"strings"
+)
+
+// 报警规则类型
+const (
+	AlarmHigh     = "high"      // 数值高于 limit
+	AlarmLow      = "low"       // 数值低于 limit
+	AlarmRate     = "roc"       // 变化率 (每秒变化量的绝对值) 高于 limit
+	AlarmBitSet   = "bit_set"   // 指定位为 1, 线圈和离散输入为 ON
+	AlarmBitClear = "bit_clear" // 指定位为 0, 线圈和离散输入为 OFF
+	AlarmCommLoss = "comm_loss" // 连续 failures 次读取失败
+)
+
+// 报警等级
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// AlarmRule 点位的报警规则。数值类规则使用缩放后的工程值, 按位规则使用缩放前的原始值;
+// 报警在数值越过 limit 时产生, 回到 limit 另一侧超过 deadband 后才解除, 避免在限值附近反复报警
+type AlarmRule struct {
+	Type     string  `json:"type" yaml:"type"`                             // high/low/roc/bit_set/bit_clear/comm_loss
+	Limit    float64 `json:"limit,omitempty" yaml:"limit,omitempty"`       // 限值, roc 为每秒变化量
+	Deadband float64 `json:"deadband,omitempty" yaml:"deadband,omitempty"` // 解除报警的回差
+	Bit      int     `json:"bit,omitempty" yaml:"bit,omitempty"`           // bit_set/bit_clear 检查的位, 0 为最低位
+	Failures int     `json:"failures,omitempty" yaml:"failures,omitempty"` // comm_loss 的连续失败次数, 默认 1
+	Severity string  `json:"severity,omitempty" yaml:"severity,omitempty"` // info/warning/critical, 默认 warning
+	Message  string  `json:"message,omitempty" yaml:"message,omitempty"`   // 报警文本, 默认按规则生成
+}
+
+// normalize 校验规则并填充默认值, tag 为所属点位
+func (r *AlarmRule) normalize(tag *Tag) error {
+	r.Type = strings.ToLower(strings.TrimSpace(r.Type))
+	switch r.Type {
+	case AlarmHigh, AlarmLow:
+	case AlarmRate:
+		if r.Limit <= 0 {
+			return fmt.Errorf("变化率报警的 limit 必须大于 0")
+		}
+	case AlarmBitSet, AlarmBitClear:
+		dataType := tag.Type()
+		if tag.IsBit() {
+			r.Bit = 0
+		} else if !bitAlarmAllowed(dataType) {
+			return fmt.Errorf("%s 类型不支持按位报警, 请使用整数类型", tag.DataType)
+		} else if bits := dataType.RegistersPerValue() * 16; r.Bit < 0 || r.Bit >= bits {
+			return fmt.Errorf("报警位 %d 超出 %s 的范围 (0-%d)", r.Bit, tag.DataType, bits-1)
+		}
+	case AlarmCommLoss:
+		if r.Failures <= 0 {
+			r.Failures = 1
+		}
+	default:
+		return fmt.Errorf("未知报警类型: %s", r.Type)
+	}
+	if r.Deadband < 0 {
+		return fmt.Errorf("报警回差不能为负数: %g", r.Deadband)
+	}
+
+	r.Severity = strings.ToLower(strings.TrimSpace(r.Severity))
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("未知报警等级: %s", r.Severity)
+	}
+	return nil
+}
+
+// bitAlarmAllowed 判断数据类型的原始值能否按位检查: 浮点、字符串和时间类型的值不是寄存器中的位
+func bitAlarmAllowed(dataType datatypes.DataType) bool {
+	switch dataType {
+	case datatypes.FLOAT16, datatypes.FLOAT32, datatypes.FLOAT64:
+		return false
+	}
+	return !dataType.IsStringType() && !dataType.IsTimeType()
+}
+
+// Describe 返回规则的默认说明, 如 "高于 80 °C"
+func (r AlarmRule) Describe(unit string) string {
+	if unit != "" {
+		unit = " " + unit
+	}
+	switch r.Type {
+	case AlarmHigh:
+		return fmt.Sprintf("高于 %g%s", r.Limit, unit)
+	case AlarmLow:
+		return fmt.Sprintf("低于 %g%s", r.Limit, unit)
+	case AlarmRate:
+		return fmt.Sprintf("变化率超过 %g%s/s", r.Limit, unit)
+	case AlarmBitSet:
+		return fmt.Sprintf("位 %d 置位", r.Bit)
+	case AlarmBitClear:
+		return fmt.Sprintf("位 %d 复位", r.Bit)
+	case AlarmCommLoss:
+		return fmt.Sprintf("通讯中断 (连续 %d 次读取失败)", r.Failures)
+	}
+	return r.Type
+}
@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestAlarmRuleNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		dataType string
+		rule     AlarmRule
+		wantErr  bool
+	}{
+		{"defaults", TableHolding, "UINT16", AlarmRule{Type: " HIGH "}, false},
+		{"bit in range", TableHolding, "UINT32", AlarmRule{Type: AlarmBitSet, Bit: 31}, false},
+		{"bit out of range", TableHolding, "UINT16", AlarmRule{Type: AlarmBitSet, Bit: 16}, true},
+		{"coil bit", TableCoil, "", AlarmRule{Type: AlarmBitClear, Bit: 5}, false},
+		{"float bit", TableHolding, "FLOAT32", AlarmRule{Type: AlarmBitSet}, true},
+		{"double bit", TableInput, "FLOAT64", AlarmRule{Type: AlarmBitClear, Bit: 3}, true},
+		{"string bit", TableHolding, "STRING_UTF8", AlarmRule{Type: AlarmBitSet}, true},
+		{"float limit", TableHolding, "FLOAT32", AlarmRule{Type: AlarmHigh, Limit: 1.5}, false},
+		{"rate without limit", TableHolding, "UINT16", AlarmRule{Type: AlarmRate}, true},
+		{"negative deadband", TableHolding, "UINT16", AlarmRule{Type: AlarmLow, Deadband: -1}, true},
+		{"severity", TableHolding, "UINT16", AlarmRule{Type: AlarmHigh, Severity: "fatal"}, true},
+		{"type", TableHolding, "UINT16", AlarmRule{Type: "above"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewTagDatabase([]Tag{{Name: "x", Table: tt.table, DataType: tt.dataType, Alarms: []AlarmRule{tt.rule}}})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewTagDatabase succeeded with %+v", db.Tags[0].Alarms[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule := db.Tags[0].Alarms[0]
+			if rule.Severity != SeverityWarning || (tt.table == TableCoil && rule.Bit != 0) {
+				t.Errorf("normalized rule = %+v", rule)
+			}
+		})
+	}
+}
@@ -4,16 +4,25 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Config 应用配置结构
 type Config struct {
-	TCP             TCPConfig `json:"tcp"`
-	RTU             RTUConfig `json:"rtu"`
-	PollingInterval int       `json:"polling_interval"`
-	DefaultConnType string    `json:"default_connection_type"`
-	LogLevel        string    `json:"log_level"`
-	Theme           string    `json:"theme"`
+	TCP             TCPConfig       `json:"tcp"`
+	RTU             RTUConfig       `json:"rtu"`
+	PollingInterval int             `json:"polling_interval"`
+	PollJitter      int             `json:"poll_jitter"` // 每次轮询在计划时间后随机延迟的上限 (ms), 0 表示不延迟
+	DefaultConnType string          `json:"default_connection_type"`
+	LogLevel        string          `json:"log_level"`
+	Theme           string          `json:"theme"`
+	Gateway         GatewayConfig   `json:"gateway"`
+	Recorder        RecorderConfig  `json:"recorder"`
+	Historian       HistorianConfig `json:"historian"`
+	TimeZone        string          `json:"time_zone"`    // 时间类型数据的时区: Local、UTC、Asia/Shanghai 或 UTC+8
+	TagFile         string          `json:"tag_file"`     // 点位表文件 (YAML/JSON/CSV), 相对路径以配置文件所在目录为基准
+	TemplateDir     string          `json:"template_dir"` // 用户设备模板目录, 为空时使用 ~/.modbusbaby/templates
+	HistorySize     int             `json:"history_size"` // 趋势图每个点保留的样本数, 0 表示 3600
 }
 
 // TCPConfig TCP连接配置
@@ -25,12 +34,63 @@ type TCPConfig struct {
 
 // RTUConfig RTU连接配置
 type RTUConfig struct {
-	Port     string `json:"port"`
-	BaudRate int    `json:"baud_rate"`
-	DataBits int    `json:"data_bits"`
-	StopBits int    `json:"stop_bits"`
-	Parity   string `json:"parity"`
-	SlaveID  int    `json:"slave_id"`
+	Port     string      `json:"port"`
+	BaudRate int         `json:"baud_rate"` // 支持任意波特率
+	DataBits int         `json:"data_bits"`
+	StopBits float64     `json:"stop_bits"` // 1, 1.5 或 2
+	Parity   string      `json:"parity"`    // None, Even, Odd, Mark, Space
+	SlaveID  int         `json:"slave_id"`
+	RS485    RS485Config `json:"rs485"`
+}
+
+// RS485Config RS-485 收发方向控制配置 (用于无自动换向的USB-485转换器)
+type RS485Config struct {
+	Enabled         bool `json:"enabled"`
+	RTSOnSend       bool `json:"rts_on_send"`       // 发送时RTS电平: true=高, false=低
+	DelayBeforeSend int  `json:"delay_before_send"` // 切换RTS后到开始发送的延时 (ms)
+	DelayAfterSend  int  `json:"delay_after_send"`  // 发送完成后到恢复RTS的延时 (ms)
+}
+
+// RecorderConfig 轮询结果 CSV 记录配置
+type RecorderConfig struct {
+	Dir              string `json:"dir"`               // 记录目录, 为空时使用 ~/.modbusbaby/records
+	MaxSizeMB        int    `json:"max_size_mb"`       // 单个文件超过该大小 (MB) 时换新文件, 0 表示不限
+	RotateMinutes    int    `json:"rotate_minutes"`    // 单个文件记录满该时长 (分钟) 时换新文件, 0 表示不限
+	DecimalSeparator string `json:"decimal_separator"` // 小数点: "." 或 ","
+	Delimiter        string `json:"delimiter"`         // 列分隔符, 为空时小数点为 "," 则用 ";", 否则用 ","
+	TimeFormat       string `json:"time_format"`       // Go 时间格式, 或 unix、unix_ms、rfc3339、excel
+}
+
+// HistorianConfig 历史数据库配置
+type HistorianConfig struct {
+	Enabled             bool   `json:"enabled"`               // 启动后即将轮询结果存入数据库
+	Path                string `json:"path"`                  // 数据库文件, 为空时使用 ~/.modbusbaby/history.db
+	RawRetentionHours   int    `json:"raw_retention_hours"`   // 原始样本保留时长 (小时), 之后只保留汇总值; 0 表示永久保留
+	RollupSeconds       int    `json:"rollup_seconds"`        // 汇总间隔 (秒), 0 表示 60
+	RollupRetentionDays int    `json:"rollup_retention_days"` // 汇总值保留时长 (天), 0 表示永久保留
+}
+
+// Retention 返回原始样本保留时长、汇总间隔和汇总值保留时长
+func (h HistorianConfig) Retention() (raw, interval, rollup time.Duration) {
+	return time.Duration(h.RawRetentionHours) * time.Hour,
+		time.Duration(h.RollupSeconds) * time.Second,
+		time.Duration(h.RollupRetentionDays) * 24 * time.Hour
+}
+
+// GatewayConfig Modbus TCP 网关配置
+type GatewayConfig struct {
+	ListenAddr string         `json:"listen_addr"`
+	CacheTTL   int            `json:"cache_ttl"` // 读请求缓存时间 (ms), 0 表示不缓存
+	Routes     []GatewayRoute `json:"routes"`
+}
+
+// GatewayRoute 按单元标识符转发到上游设备的路由
+type GatewayRoute struct {
+	UnitID       int       `json:"unit_id"`        // 网关侧单元标识符
+	TargetUnitID int       `json:"target_unit_id"` // 上游从站地址, 0 表示与 UnitID 相同
+	Type         string    `json:"type"`           // TCP 或 RTU
+	TCP          TCPConfig `json:"tcp"`
+	RTU          RTUConfig `json:"rtu"`
 }
 
 // Default 返回默认配置
@@ -48,11 +108,30 @@ func Default() *Config {
 			StopBits: 1,
 			Parity:   "None",
 			SlaveID:  1,
+			RS485: RS485Config{
+				RTSOnSend: true,
+			},
 		},
 		PollingInterval: 1000,
 		DefaultConnType: "TCP",
 		LogLevel:        "INFO",
 		Theme:           "auto",
+		TimeZone:        "Local",
+		HistorySize:     3600,
+		Recorder: RecorderConfig{
+			MaxSizeMB:        10,
+			DecimalSeparator: ".",
+			TimeFormat:       "2006-01-02 15:04:05.000",
+		},
+		Historian: HistorianConfig{
+			RawRetentionHours:   72,
+			RollupSeconds:       60,
+			RollupRetentionDays: 365,
+		},
+		Gateway: GatewayConfig{
+			ListenAddr: ":5020",
+			CacheTTL:   0,
+		},
 	}
 }
 
@@ -101,4 +180,33 @@ func getConfigPath() string {
 	exeDir := filepath.Dir(exePath)
 	// Look for config.json in the same directory as the executable
 	return filepath.Join(exeDir, "config.json")
-}
\ No newline at end of file
+}
+// RecordPath 返回 CSV 记录目录, 相对路径以配置文件所在目录为基准
+func (c *Config) RecordPath() string {
+	if c.Recorder.Dir != "" {
+		if filepath.IsAbs(c.Recorder.Dir) {
+			return c.Recorder.Dir
+		}
+		return filepath.Join(filepath.Dir(getConfigPath()), c.Recorder.Dir)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".modbusbaby", "records")
+}
+
+// HistorianPath 返回历史数据库文件路径, 相对路径以配置文件所在目录为基准
+func (c *Config) HistorianPath() string {
+	if c.Historian.Path != "" {
+		if filepath.IsAbs(c.Historian.Path) {
+			return c.Historian.Path
+		}
+		return filepath.Join(filepath.Dir(getConfigPath()), c.Historian.Path)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".modbusbaby", "history.db")
+}
@@ -0,0 +1,336 @@
+package config
+
+import (
+	"fmt"
+	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/modicon"
+	"strconv"
+	"strings"
+)
+
+// 厂商寄存器表导入
+//
+// 厂商通常以表格提供寄存器列表, 列名和写法各不相同。导入时按列映射取出名称、地址、类型、
+// 缩放和读写权限, 转换为点位表格式。地址支持 Modicon 写法 (40001、300001 等, 首位表示寄存器类型)、
+// 前缀写法 (HR100) 以及不带类型的偏移, 后者按 OneBased 决定是否从1开始计数。
+// 行中有寄存器类型列时, 纯数字地址默认是该类型中的偏移 (如 30775 是第30775个保持寄存器),
+// 设置 ModiconWithTable 后仍按 Modicon 写法解析。
+
+// 列映射中的字段名
+const (
+	ColumnName        = "name"
+	ColumnAddress     = "address"
+	ColumnTable       = "table"
+	ColumnDataType    = "data_type"
+	ColumnCount       = "count"
+	ColumnScale       = "scale"
+	ColumnUnit        = "unit"
+	ColumnAccess      = "access"
+	ColumnDescription = "description"
+)
+
+// ImportColumns 可映射的字段, 顺序与映射对话框一致
+var ImportColumns = []string{ColumnName, ColumnAddress, ColumnTable, ColumnDataType, ColumnCount, ColumnScale, ColumnUnit, ColumnAccess, ColumnDescription}
+
+// importColumnKeywords 按列名猜测映射时使用的关键字 (小写)
+var importColumnKeywords = map[string][]string{
+	ColumnName:        {"name", "tag", "parameter", "variable", "名称", "参数", "变量", "点位"},
+	ColumnAddress:     {"address", "addr", "register", "reg", "offset", "地址", "寄存器"},
+	ColumnTable:       {"table", "register type", "reg type", "function", "area", "fc", "功能码", "区域", "寄存器类型"},
+	ColumnDataType:    {"data type", "datatype", "type", "format", "类型", "格式"},
+	ColumnCount:       {"count", "length", "size", "quantity", "words", "长度", "数量"},
+	ColumnScale:       {"scale", "factor", "multiplier", "gain", "ratio", "系数", "倍率", "比例"},
+	ColumnUnit:        {"unit", "units", "单位"},
+	ColumnAccess:      {"access", "r/w", "rw", "mode", "permission", "读写", "权限"},
+	ColumnDescription: {"description", "desc", "comment", "remark", "说明", "描述", "备注"},
+}
+
+// ColumnMapping 字段到表格列号 (0起始) 的映射, 未映射的字段不出现
+type ColumnMapping map[string]int
+
+// GuessColumnMapping 根据表头猜测列映射, 每列最多映射一个字段
+func GuessColumnMapping(header []string) ColumnMapping {
+	mapping := make(ColumnMapping)
+	used := make(map[int]bool)
+	// 先精确匹配, 再按包含关系匹配, 避免 "Register Type" 被当作地址列
+	for _, exact := range []bool{true, false} {
+		for _, field := range ImportColumns {
+			if _, ok := mapping[field]; ok {
+				continue
+			}
+			for col, name := range header {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if used[col] || name == "" {
+					continue
+				}
+				if matchKeyword(name, importColumnKeywords[field], exact) {
+					mapping[field] = col
+					used[col] = true
+					break
+				}
+			}
+		}
+	}
+	return mapping
+}
+
+func matchKeyword(name string, keywords []string, exact bool) bool {
+	for _, kw := range keywords {
+		if (exact && name == kw) || (!exact && strings.Contains(name, kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Mapping      ColumnMapping
+	HeaderRows   int    // 跳过的表头行数
+	OneBased     bool   // 不带类型前缀的地址从1开始计数
+	DefaultTable string // 地址和类型列都无法确定寄存器类型时使用, 默认 holding
+	UnitID       int    // 导入点位的从站地址
+
+	ModiconWithTable bool // 寄存器类型列有值时, 5位/6位纯数字地址仍按 Modicon 写法解析
+}
+
+// ImportHeader 返回用于列映射的表头, 多行表头时取最后一行 (紧挨数据的一行)
+func ImportHeader(rows [][]string, headerRows int) []string {
+	if headerRows <= 0 || headerRows > len(rows) {
+		return nil
+	}
+	return rows[headerRows-1]
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Tags    []Tag
+	Skipped []string // 跳过的行及原因
+}
+
+// ImportTags 按列映射将表格行转换为点位, 无法解析的行记录在 Skipped 中, 重名点位追加地址后缀
+func ImportTags(rows [][]string, opts ImportOptions) (*ImportResult, error) {
+	if _, ok := opts.Mapping[ColumnAddress]; !ok {
+		return nil, fmt.Errorf("未映射地址列")
+	}
+	defaultTable := TableHolding
+	if opts.DefaultTable != "" {
+		table, err := NormalizeTable(opts.DefaultTable)
+		if err != nil {
+			return nil, err
+		}
+		defaultTable = table
+	}
+
+	result := &ImportResult{}
+	names := make(map[string]bool)
+	for i := opts.HeaderRows; i < len(rows); i++ {
+		row := rows[i]
+		field := func(name string) string {
+			if col, ok := opts.Mapping[name]; ok && col >= 0 && col < len(row) {
+				return strings.TrimSpace(row[col])
+			}
+			return ""
+		}
+		skip := func(format string, args ...interface{}) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("第 %d 行: ", i+1)+fmt.Sprintf(format, args...))
+		}
+
+		addressText := field(ColumnAddress)
+		if addressText == "" {
+			continue // 空行或分组标题行
+		}
+
+		table, useModicon := defaultTable, true
+		if text := field(ColumnTable); text != "" {
+			t, err := parseVendorTable(text)
+			if err != nil {
+				skip("%v", err)
+				continue
+			}
+			table, useModicon = t, opts.ModiconWithTable
+		}
+		table, address, err := ParseVendorAddress(addressText, table, opts.OneBased, useModicon)
+		if err != nil {
+			skip("%v", err)
+			continue
+		}
+
+		tag := Tag{
+			Name:        field(ColumnName),
+			UnitID:      opts.UnitID,
+			Table:       table,
+			Address:     address,
+			Unit:        field(ColumnUnit),
+			Description: field(ColumnDescription),
+		}
+		if tag.Name == "" {
+			tag.Name = fmt.Sprintf("%s_%d", table, address)
+		}
+
+		if !tag.IsBit() {
+			dataType, err := ParseVendorDataType(field(ColumnDataType))
+			if err != nil {
+				skip("%v", err)
+				continue
+			}
+			tag.DataType = dataType.String()
+
+			if text := field(ColumnCount); text != "" {
+				words, err := strconv.Atoi(text)
+				if err != nil || words <= 0 {
+					skip("长度无效: %s", text)
+					continue
+				}
+				// 长度列为寄存器个数
+				tag.Count = words / dataType.RegistersPerValue()
+				if tag.Count == 0 {
+					tag.Count = 1
+				}
+			}
+		}
+
+		if text := field(ColumnScale); text != "" {
+			scale, err := ParseVendorScale(text)
+			if err != nil {
+				skip("%v", err)
+				continue
+			}
+			tag.Scale = scale
+		}
+
+		if text := field(ColumnAccess); text != "" {
+			access, err := ParseVendorAccess(text)
+			if err != nil {
+				skip("%v", err)
+				continue
+			}
+			tag.Access = access
+		}
+		if table == TableInput || table == TableDiscrete {
+			tag.Access = AccessRead // 厂商表中输入寄存器常标为 R/W, 以寄存器类型为准
+		}
+
+		if err := tag.normalize(); err != nil {
+			skip("%s: %v", tag.Name, err)
+			continue
+		}
+		if names[strings.ToLower(tag.Name)] {
+			tag.Name = fmt.Sprintf("%s_%d", tag.Name, tag.Address)
+		}
+		names[strings.ToLower(tag.Name)] = true
+		result.Tags = append(result.Tags, tag)
+	}
+	return result, nil
+}
+
+// ParseVendorAddress 解析厂商表中的地址, 返回寄存器类型和0起始的协议地址。
+// 前缀写法 (HR100、4x0001) 和 useModicon 时的 Modicon 写法 (40001、300001) 自带寄存器类型;
+// 其他地址 (含 0x 十六进制) 视为 table 中的偏移, oneBased 时减1。
+func ParseVendorAddress(text, table string, oneBased, useModicon bool) (string, int, error) {
+	t, err := modicon.ParseTable(table)
+	if err != nil {
+		return "", 0, err
+	}
+	parse := modicon.ParseIn
+	if useModicon {
+		parse = modicon.Parse
+	}
+	addr, err := parse(text, t)
+	if err != nil {
+		return "", 0, err
+	}
+	if addr.Explicit {
+		return addr.Table.Name(), int(addr.Offset), nil
+	}
+	if oneBased {
+		if addr.Offset == 0 {
+			return "", 0, fmt.Errorf("从1开始的地址不能为0: %s", text)
+		}
+		addr.Offset--
+	}
+	return table, int(addr.Offset), nil
+}
+
+// parseVendorTable 解析厂商表中的寄存器类型列, 支持功能码 (03/04/01/02) 和常见名称
+func parseVendorTable(text string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "03", "06", "16", "0x03", "fc3", "fc03", "holding registers", "保持寄存器":
+		return TableHolding, nil
+	case "04", "0x04", "fc4", "fc04", "input registers", "输入寄存器":
+		return TableInput, nil
+	case "01", "05", "15", "0x01", "fc1", "fc01", "coils", "线圈":
+		return TableCoil, nil
+	case "02", "0x02", "fc2", "fc02", "discrete inputs", "离散输入":
+		return TableDiscrete, nil
+	}
+	return NormalizeTable(text)
+}
+
+// vendorDataTypes 厂商表中常见的数据类型写法
+var vendorDataTypes = map[string]datatypes.DataType{
+	"U16": datatypes.UINT16, "UINT": datatypes.UINT16, "WORD": datatypes.UINT16, "UNSIGNED": datatypes.UINT16,
+	"S16": datatypes.INT16, "I16": datatypes.INT16, "INT": datatypes.INT16, "SHORT": datatypes.INT16, "SIGNED": datatypes.INT16,
+	"U32": datatypes.UINT32, "UDINT": datatypes.UINT32, "DWORD": datatypes.UINT32, "ACC32": datatypes.UINT32,
+	"S32": datatypes.INT32, "I32": datatypes.INT32, "DINT": datatypes.INT32, "LONG": datatypes.INT32,
+	"U64": datatypes.UINT64, "ULINT": datatypes.UINT64, "ACC64": datatypes.UINT64,
+	"S64": datatypes.INT64, "I64": datatypes.INT64, "LINT": datatypes.INT64,
+	"F32": datatypes.FLOAT32, "FLOAT": datatypes.FLOAT32, "REAL": datatypes.FLOAT32, "IEEE754": datatypes.FLOAT32,
+	"F64": datatypes.FLOAT64, "DOUBLE": datatypes.FLOAT64, "LREAL": datatypes.FLOAT64,
+	"STRING": datatypes.ASCII, "STR": datatypes.ASCII, "CHAR": datatypes.ASCII,
+	"BITFIELD16": datatypes.UINT16, "ENUM16": datatypes.UINT16, "SUNSSF": datatypes.INT16,
+	"BITFIELD32": datatypes.UINT32, "ENUM32": datatypes.UINT32,
+}
+
+// ParseVendorDataType 解析厂商表中的数据类型, 空值视为 UINT16
+func ParseVendorDataType(text string) (datatypes.DataType, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return datatypes.UINT16, nil
+	}
+	if dt, err := datatypes.ParseDataType(text); err == nil {
+		return dt, nil
+	}
+	key := strings.ToUpper(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(text))
+	if dt, ok := vendorDataTypes[key]; ok {
+		return dt, nil
+	}
+	if dt, err := datatypes.ParseDataType(key); err == nil {
+		return dt, nil
+	}
+	return datatypes.UINT16, fmt.Errorf("未知数据类型: %s", text)
+}
+
+// ParseVendorScale 解析缩放系数, 支持 0.1、x0.1、×0.1、1/10 和 10^-1
+func ParseVendorScale(text string) (float64, error) {
+	s := strings.TrimSpace(text)
+	s = strings.TrimLeft(s, "xX×*")
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		d, err2 := strconv.ParseFloat(strings.TrimSpace(den), 64)
+		if err1 == nil && err2 == nil && d != 0 {
+			return n / d, nil
+		}
+	} else if base, exp, ok := strings.Cut(s, "^"); ok && strings.TrimSpace(base) == "10" {
+		if e, err := strconv.Atoi(strings.TrimSpace(exp)); err == nil {
+			return strconv.ParseFloat(fmt.Sprintf("1e%d", e), 64)
+		}
+	} else if v, err := strconv.ParseFloat(s, 64); err == nil && v != 0 {
+		return v, nil
+	}
+	return 0, fmt.Errorf("缩放系数无效: %s", text)
+}
+
+// ParseVendorAccess 解析读写权限列
+func ParseVendorAccess(text string) (string, error) {
+	switch strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(text), " ", "")) {
+	case "R", "RO", "READ", "READONLY", "只读", "读":
+		return AccessRead, nil
+	case "W", "WO", "WRITE", "WRITEONLY", "只写", "写":
+		return AccessWrite, nil
+	case "RW", "R/W", "WR", "READ/WRITE", "READWRITE", "读写", "可读写":
+		return AccessReadWrite, nil
+	default:
+		return "", fmt.Errorf("未知读写权限: %s", text)
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"modbusbaby/pkg/datatypes"
+	"reflect"
+	"testing"
+)
+
+func TestGuessColumnMapping(t *testing.T) {
+	header := []string{"Register Type", "Address", "Parameter Name", "Data Type", "Scale", "Units", "R/W", "Remark"}
+	want := ColumnMapping{
+		ColumnTable: 0, ColumnAddress: 1, ColumnName: 2, ColumnDataType: 3,
+		ColumnScale: 4, ColumnUnit: 5, ColumnAccess: 6, ColumnDescription: 7,
+	}
+	if got := GuessColumnMapping(header); !reflect.DeepEqual(got, want) {
+		t.Errorf("GuessColumnMapping = %v, want %v", got, want)
+	}
+}
+
+func TestImportHeader(t *testing.T) {
+	rows := [][]string{{"Modbus map v2"}, {"Name", "Address"}, {"Voltage", "40001"}}
+	tests := []struct {
+		headerRows int
+		want       []string
+	}{
+		{0, nil},
+		{1, []string{"Modbus map v2"}},
+		{2, []string{"Name", "Address"}},
+		{4, nil},
+	}
+	for _, tt := range tests {
+		if got := ImportHeader(rows, tt.headerRows); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ImportHeader(%d) = %q, want %q", tt.headerRows, got, tt.want)
+		}
+	}
+}
+
+func TestParseVendorAddress(t *testing.T) {
+	tests := []struct {
+		text       string
+		table      string
+		oneBased   bool
+		useModicon bool
+		wantTable  string
+		wantAddr   int
+	}{
+		{"40001", TableHolding, true, true, TableHolding, 0},
+		{"300101", TableHolding, true, true, TableInput, 100},
+		{"30775", TableHolding, false, true, TableInput, 774},
+		{"30775", TableHolding, false, false, TableHolding, 30775},
+		{"30775", TableHolding, true, false, TableHolding, 30774},
+		{"IR100", TableHolding, true, false, TableInput, 100},
+		{"4x0010", TableInput, false, false, TableHolding, 9},
+		{"0x0010", TableInput, false, true, TableInput, 16},
+		{"100", TableCoil, true, true, TableCoil, 99},
+		{"100", TableCoil, false, true, TableCoil, 100},
+	}
+	for _, tt := range tests {
+		table, addr, err := ParseVendorAddress(tt.text, tt.table, tt.oneBased, tt.useModicon)
+		if err != nil {
+			t.Errorf("ParseVendorAddress(%q, %s, %v, %v): %v", tt.text, tt.table, tt.oneBased, tt.useModicon, err)
+			continue
+		}
+		if table != tt.wantTable || addr != tt.wantAddr {
+			t.Errorf("ParseVendorAddress(%q, %s, %v, %v) = %s %d, want %s %d",
+				tt.text, tt.table, tt.oneBased, tt.useModicon, table, addr, tt.wantTable, tt.wantAddr)
+		}
+	}
+
+	for _, text := range []string{"0", "abc", "400000"} {
+		if _, _, err := ParseVendorAddress(text, TableHolding, true, true); err == nil {
+			t.Errorf("ParseVendorAddress(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestImportTags(t *testing.T) {
+	rows := [][]string{
+		{"Device XYZ register map"},
+		{"Name", "Address", "Function", "Format", "Words", "Scale", "Unit", "Access"},
+		{"Voltage", "30001", "", "F32", "", "", "V", "R"},
+		{"Energy", "775", "04", "U32", "2", "1/10", "kWh", "RW"},
+		{"Setpoint", "30775", "Holding Registers", "S16", "", "x0.1", "°C", "RW"},
+		{"", "", "", "", "", "", "", ""},
+		{"Serial", "HR100", "03", "STRING", "8", "", "", "R"},
+		{"Voltage", "40010", "", "", "", "", "", ""},
+		{"Bad", "40020", "", "complex", "", "", "", ""},
+		{"Relay", "00005", "", "", "", "", "", "W"},
+	}
+	opts := ImportOptions{
+		Mapping:    GuessColumnMapping(ImportHeader(rows, 2)),
+		HeaderRows: 2,
+		OneBased:   false,
+		UnitID:     3,
+	}
+	result, err := ImportTags(rows, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name     string
+		table    string
+		address  int
+		count    int
+		dataType datatypes.DataType
+		scale    float64
+		access   string
+	}{
+		{"Voltage", TableInput, 0, 1, datatypes.FLOAT32, 0, AccessRead},
+		{"Energy", TableInput, 775, 1, datatypes.UINT32, 0.1, AccessRead},
+		{"Setpoint", TableHolding, 30775, 1, datatypes.INT16, 0.1, AccessReadWrite},
+		{"Serial", TableHolding, 100, 8, datatypes.ASCII, 0, AccessRead},
+		{"Voltage_9", TableHolding, 9, 1, datatypes.UINT16, 0, AccessReadWrite},
+		{"Relay", TableCoil, 4, 1, datatypes.BOOL, 0, AccessWrite},
+	}
+	if len(result.Tags) != len(want) {
+		t.Fatalf("imported %d tags, want %d: %+v", len(result.Tags), len(want), result.Tags)
+	}
+	for i, w := range want {
+		tag := result.Tags[i]
+		if tag.Name != w.name || tag.Table != w.table || tag.Address != w.address || tag.Count != w.count ||
+			tag.DataType != w.dataType.String() || tag.Scale != w.scale || tag.Access != w.access || tag.UnitID != 3 {
+			t.Errorf("tag %d = %+v, want %+v", i, tag, w)
+		}
+	}
+	if len(result.Skipped) != 1 {
+		t.Errorf("skipped = %q, want the row with an unknown data type", result.Skipped)
+	}
+
+	// 勾选后有类型列的行也按 Modicon 写法解析
+	opts.ModiconWithTable = true
+	result, err = ImportTags(rows, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag := result.Tags[2]; tag.Table != TableInput || tag.Address != 774 {
+		t.Errorf("with ModiconWithTable: %s %d, want %s 774", tag.Table, tag.Address, TableInput)
+	}
+
+	if _, err := ImportTags(rows, ImportOptions{Mapping: ColumnMapping{ColumnName: 0}}); err == nil {
+		t.Error("ImportTags without an address column succeeded, want an error")
+	}
+}
+
+func TestParseVendorValues(t *testing.T) {
+	dataTypes := map[string]datatypes.DataType{
+		"":        datatypes.UINT16,
+		"float32": datatypes.FLOAT32,
+		"S32":     datatypes.INT32,
+		"u 16":    datatypes.UINT16,
+		"dint":    datatypes.INT32,
+		"ACC64":   datatypes.UINT64,
+		"string":  datatypes.ASCII,
+	}
+	for text, want := range dataTypes {
+		if got, err := ParseVendorDataType(text); err != nil || got != want {
+			t.Errorf("ParseVendorDataType(%q) = %s, %v, want %s", text, got, err, want)
+		}
+	}
+
+	scales := map[string]float64{"0.1": 0.1, "x0.01": 0.01, "×10": 10, "1/10": 0.1, "10^-2": 0.01}
+	for text, want := range scales {
+		if got, err := ParseVendorScale(text); err != nil || got != want {
+			t.Errorf("ParseVendorScale(%q) = %g, %v, want %g", text, got, err, want)
+		}
+	}
+
+	access := map[string]string{"R": AccessRead, "read only": AccessRead, "W": AccessWrite, "R/W": AccessReadWrite, "读写": AccessReadWrite}
+	for text, want := range access {
+		if got, err := ParseVendorAccess(text); err != nil || got != want {
+			t.Errorf("ParseVendorAccess(%q) = %s, %v, want %s", text, got, err, want)
+		}
+	}
+
+	for _, text := range []string{"complex", "0", "1/0", "execute"} {
+		_, errType := ParseVendorDataType(text)
+		_, errScale := ParseVendorScale(text)
+		_, errAccess := ParseVendorAccess(text)
+		if errType == nil && errScale == nil && errAccess == nil {
+			t.Errorf("%q parsed as data type, scale and access", text)
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"modbusbaby/pkg/datatypes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 点位表 (寄存器映射) 文件
+//
+// 每个点位以名称引用, 描述从站地址、寄存器类型、地址、数据类型、字节/字序、缩放和读写权限,
+// 界面、轮询和命令行都可以按名称读写点位, 同一份点位表可在团队内共享。
+// 支持 YAML、JSON (点位数组或 {"tags": [...]}) 和带表头的 CSV 三种格式。
+
+// 寄存器类型的规范名称
+const (
+	TableHolding  = "holding"
+	TableInput    = "input"
+	TableCoil     = "coil"
+	TableDiscrete = "discrete"
+)
+
+// 读写权限
+const (
+	AccessRead      = "R"
+	AccessWrite     = "W"
+	AccessReadWrite = "RW"
+)
+
+// Tag 点位定义
+type Tag struct {
+	Name        string      `json:"name" yaml:"name"`
+	UnitID      int         `json:"unit_id" yaml:"unit_id"`                             // 从站地址, 0 表示使用连接设置中的从站地址
+	Table       string      `json:"table" yaml:"table"`                                 // holding/input/coil/discrete
+	Address     int         `json:"address" yaml:"address"`                             // 0起始的协议地址
+	Count       int         `json:"count,omitempty" yaml:"count,omitempty"`             // 值的个数, 字符串类型为寄存器个数, 默认 1
+	DataType    string      `json:"data_type,omitempty" yaml:"data_type,omitempty"`     // 默认 UINT16, 线圈和离散输入固定为 BOOL
+	ByteOrder   string      `json:"byte_order,omitempty" yaml:"byte_order,omitempty"`   // AB/BA, 默认 AB
+	WordOrder   string      `json:"word_order,omitempty" yaml:"word_order,omitempty"`   // 1234/4321/2143/3412, 默认 1234
+	Scale       float64     `json:"scale,omitempty" yaml:"scale,omitempty"`             // 线性系数, 0 视为 1
+	Offset      float64     `json:"offset,omitempty" yaml:"offset,omitempty"`           // 偏移量
+	Unit        string      `json:"unit,omitempty" yaml:"unit,omitempty"`               // 工程单位
+	Access      string      `json:"access,omitempty" yaml:"access,omitempty"`           // R/W/RW, 默认按寄存器类型
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"` // 说明
+	PollMs      int         `json:"poll_ms,omitempty" yaml:"poll_ms,omitempty"`         // 轮询周期 (毫秒), 0 表示使用全局轮询间隔
+	Alarms      []AlarmRule `json:"alarms,omitempty" yaml:"alarms,omitempty"`           // 报警规则, CSV 点位表不支持
+}
+
+// TagDatabase 点位表
+type TagDatabase struct {
+	Tags   []Tag
+	byName map[string]int
+}
+
+// NormalizeTable 规范化寄存器类型名称, 接受 holding/hr/4、input/ir/3、coil/co/0、discrete/di/1 等写法
+func NormalizeTable(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "holding", "holding register", "holding_register", "hr", "4":
+		return TableHolding, nil
+	case "input", "input register", "input_register", "ir", "3":
+		return TableInput, nil
+	case "coil", "coils", "co", "0":
+		return TableCoil, nil
+	case "discrete", "discrete input", "discrete_input", "di", "1":
+		return TableDiscrete, nil
+	default:
+		return "", fmt.Errorf("未知寄存器类型: %s", s)
+	}
+}
+
+// normalize 填充默认值并校验点位定义
+func (t *Tag) normalize() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("点位名称不能为空")
+	}
+
+	table, err := NormalizeTable(t.Table)
+	if err != nil {
+		return err
+	}
+	t.Table = table
+
+	if t.UnitID < 0 || t.UnitID > 255 {
+		return fmt.Errorf("从站地址无效: %d", t.UnitID)
+	}
+	if t.Count == 0 {
+		t.Count = 1
+	}
+	if t.Count < 0 {
+		return fmt.Errorf("数量无效: %d", t.Count)
+	}
+
+	if t.IsBit() {
+		t.DataType = datatypes.BOOL.String()
+	} else {
+		if t.DataType == "" {
+			t.DataType = datatypes.UINT16.String()
+		}
+		dataType, err := datatypes.ParseDataType(t.DataType)
+		if err != nil {
+			return err
+		}
+		t.DataType = dataType.String()
+	}
+
+	if t.ByteOrder == "" {
+		t.ByteOrder = "AB"
+	}
+	byteOrder, err := datatypes.ParseByteOrder(t.ByteOrder)
+	if err != nil {
+		return err
+	}
+	t.ByteOrder = byteOrder.String()
+
+	if t.WordOrder == "" {
+		t.WordOrder = "1234"
+	}
+	wordOrder, err := datatypes.ParseWordOrder(t.WordOrder)
+	if err != nil {
+		return err
+	}
+	t.WordOrder = wordOrder.String()
+
+	if t.Address < 0 || t.Address+t.RegisterCount() > 65536 {
+		return fmt.Errorf("地址超出范围: %d", t.Address)
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(t.Access)) {
+	case "":
+		t.Access = AccessRead
+		if t.Table == TableHolding || t.Table == TableCoil {
+			t.Access = AccessReadWrite
+		}
+	case "R", "RO", "READ":
+		t.Access = AccessRead
+	case "W", "WO", "WRITE":
+		t.Access = AccessWrite
+	case "RW", "READWRITE", "READ/WRITE":
+		t.Access = AccessReadWrite
+	default:
+		return fmt.Errorf("未知读写权限: %s", t.Access)
+	}
+	if t.Writable() && (t.Table == TableInput || t.Table == TableDiscrete) {
+		return fmt.Errorf("%s 不可写", t.Table)
+	}
+	if t.PollMs < 0 {
+		return fmt.Errorf("轮询周期无效: %d", t.PollMs)
+	}
+	for i := range t.Alarms {
+		if err := t.Alarms[i].normalize(t); err != nil {
+			return fmt.Errorf("第 %d 条报警规则: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// IsBit 判断点位是否为线圈或离散输入
+func (t Tag) IsBit() bool {
+	return t.Table == TableCoil || t.Table == TableDiscrete
+}
+
+// Readable 判断点位是否可读
+func (t Tag) Readable() bool {
+	return t.Access != AccessWrite
+}
+
+// Writable 判断点位是否可写
+func (t Tag) Writable() bool {
+	return t.Access != AccessRead
+}
+
+// Type 返回点位的数据类型
+func (t Tag) Type() datatypes.DataType {
+	dataType, _ := datatypes.ParseDataType(t.DataType)
+	return dataType
+}
+
+// Orders 返回点位的字节序和字序
+func (t Tag) Orders() (datatypes.ByteOrder, datatypes.WordOrder) {
+	byteOrder, _ := datatypes.ParseByteOrder(t.ByteOrder)
+	wordOrder, _ := datatypes.ParseWordOrder(t.WordOrder)
+	return byteOrder, wordOrder
+}
+
+// RegisterCount 返回点位占用的寄存器 (或线圈) 数量
+func (t Tag) RegisterCount() int {
+	if t.IsBit() {
+		return t.Count
+	}
+	return t.Count * t.Type().RegistersPerValue()
+}
+
+// PollInterval 返回点位的轮询周期, 0 表示使用全局轮询间隔
+func (t Tag) PollInterval() time.Duration {
+	return time.Duration(t.PollMs) * time.Millisecond
+}
+
+// Scaling 返回点位的缩放设置
+func (t Tag) Scaling() datatypes.Scaling {
+	return datatypes.Scaling{Scale: t.Scale, Offset: t.Offset, Unit: t.Unit}
+}
+
+// NewTagDatabase 校验点位定义并建立名称索引, 名称不区分大小写且不能重复
+func NewTagDatabase(tags []Tag) (*TagDatabase, error) {
+	db := &TagDatabase{Tags: make([]Tag, len(tags)), byName: make(map[string]int)}
+	for i, tag := range tags {
+		if err := tag.normalize(); err != nil {
+			if tag.Name != "" {
+				return nil, fmt.Errorf("点位 %s: %w", tag.Name, err)
+			}
+			return nil, fmt.Errorf("第 %d 个点位: %w", i+1, err)
+		}
+		key := strings.ToLower(tag.Name)
+		if _, ok := db.byName[key]; ok {
+			return nil, fmt.Errorf("点位名称重复: %s", tag.Name)
+		}
+		db.byName[key] = i
+		db.Tags[i] = tag
+	}
+	return db, nil
+}
+
+// Lookup 按名称查找点位
+func (db *TagDatabase) Lookup(name string) (Tag, bool) {
+	if db == nil {
+		return Tag{}, false
+	}
+	i, ok := db.byName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Tag{}, false
+	}
+	return db.Tags[i], true
+}
+
+// Names 返回按文件顺序排列的点位名称
+func (db *TagDatabase) Names() []string {
+	if db == nil {
+		return nil
+	}
+	names := make([]string, len(db.Tags))
+	for i, tag := range db.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// LoadTags 按扩展名 (.yaml/.yml/.json/.csv) 加载点位表文件
+func LoadTags(path string) (*TagDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTags(data, filepath.Ext(path))
+}
+
+// ParseTags 解析点位表, format 为 yaml、json 或 csv (可带前导点)
+func ParseTags(data []byte, format string) (*TagDatabase, error) {
+	var tags []Tag
+	var err error
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "yaml", "yml":
+		tags, err = parseTagsYAML(data)
+	case "json":
+		tags, err = parseTagsJSON(data)
+	case "csv":
+		tags, err = parseTagsCSV(data)
+	default:
+		return nil, fmt.Errorf("不支持的点位表格式: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewTagDatabase(tags)
+}
+
+// tagFile YAML/JSON 点位表的对象形式
+type tagFile struct {
+	Tags []Tag `json:"tags" yaml:"tags"`
+}
+
+// parseTagsYAML 按文档顶层是列表还是映射选择格式, 点位数组中的错误不会被对象形式的错误掩盖
+func parseTagsYAML(data []byte) ([]Tag, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil // 空文件
+	}
+	switch node := root.Content[0]; node.Kind {
+	case yaml.SequenceNode:
+		var tags []Tag
+		err := node.Decode(&tags)
+		return tags, err
+	case yaml.MappingNode:
+		var file tagFile
+		err := node.Decode(&file)
+		return file.Tags, err
+	default:
+		return nil, fmt.Errorf("第 %d 行: 点位表应为点位列表或包含 tags 的对象", node.Line)
+	}
+}
+
+func parseTagsJSON(data []byte) ([]Tag, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var tags []Tag
+		err := json.Unmarshal(data, &tags)
+		return tags, err
+	}
+	var file tagFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Tags, nil
+}
+
+// parseTagsCSV 解析带表头的CSV, 列名与JSON字段名相同, 列顺序任意, # 开头的行为注释。
+// 整数列按十进制解析 (前导0不表示八进制), 以 0x 开头时按十六进制解析
+func parseTagsCSV(data []byte) ([]Tag, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取CSV表头失败: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("CSV缺少 name 列")
+	}
+
+	var tags []Tag
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		intField := func(name string) (int, error) {
+			text := field(name)
+			if text == "" {
+				return 0, nil
+			}
+			base, digits := 10, text
+			if len(text) > 2 && (text[:2] == "0x" || text[:2] == "0X") {
+				base, digits = 16, text[2:]
+			}
+			v, err := strconv.ParseInt(digits, base, 32)
+			if err != nil {
+				return 0, fmt.Errorf("第 %d 行 %s 无效: %s", line, name, text)
+			}
+			return int(v), nil
+		}
+		floatField := func(name string) (float64, error) {
+			text := field(name)
+			if text == "" {
+				return 0, nil
+			}
+			v, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return 0, fmt.Errorf("第 %d 行 %s 无效: %s", line, name, text)
+			}
+			return v, nil
+		}
+
+		tag := Tag{
+			Name:        field("name"),
+			Table:       field("table"),
+			DataType:    field("data_type"),
+			ByteOrder:   field("byte_order"),
+			WordOrder:   field("word_order"),
+			Unit:        field("unit"),
+			Access:      field("access"),
+			Description: field("description"),
+		}
+		if tag.Name == "" {
+			continue // 空行
+		}
+		if tag.UnitID, err = intField("unit_id"); err != nil {
+			return nil, err
+		}
+		if tag.Address, err = intField("address"); err != nil {
+			return nil, err
+		}
+		if tag.Count, err = intField("count"); err != nil {
+			return nil, err
+		}
+		if tag.Scale, err = floatField("scale"); err != nil {
+			return nil, err
+		}
+		if tag.Offset, err = floatField("offset"); err != nil {
+			return nil, err
+		}
+		if tag.PollMs, err = intField("poll_ms"); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// SaveTags 按扩展名保存点位表, CSV 按固定列顺序输出
+func SaveTags(path string, tags []Tag) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(tagFile{Tags: tags})
+	case ".csv":
+		data, err = formatTagsCSV(tags)
+	default:
+		data, err = json.MarshalIndent(tagFile{Tags: tags}, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// tagCSVColumns CSV 点位表的列
+var tagCSVColumns = []string{"name", "unit_id", "table", "address", "count", "data_type", "byte_order", "word_order", "scale", "offset", "unit", "access", "description", "poll_ms"}
+
+func formatTagsCSV(tags []Tag) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(tagCSVColumns)
+	formatFloat := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	formatInt := func(v int) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.Itoa(v)
+	}
+	for _, tag := range tags {
+		w.Write([]string{
+			tag.Name,
+			strconv.Itoa(tag.UnitID),
+			tag.Table,
+			strconv.Itoa(tag.Address),
+			strconv.Itoa(tag.Count),
+			tag.DataType,
+			tag.ByteOrder,
+			tag.WordOrder,
+			formatFloat(tag.Scale),
+			formatFloat(tag.Offset),
+			tag.Unit,
+			tag.Access,
+			tag.Description,
+			formatInt(tag.PollMs),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// TagPath 返回配置中的点位表路径, 相对路径以配置文件所在目录为基准
+func (c *Config) TagPath() string {
+	if c.TagFile == "" || filepath.IsAbs(c.TagFile) {
+		return c.TagFile
+	}
+	return filepath.Join(filepath.Dir(getConfigPath()), c.TagFile)
+}
+
+// LoadTags 加载配置中指定的点位表, 未配置时返回空的点位表
+func (c *Config) LoadTags() (*TagDatabase, error) {
+	path := c.TagPath()
+	if path == "" {
+		return NewTagDatabase(nil)
+	}
+	return LoadTags(path)
+}
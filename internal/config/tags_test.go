@@ -0,0 +1,187 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseTagsFormats 三种格式解析出相同的点位, 并填充默认值
+func TestParseTagsFormats(t *testing.T) {
+	want := []Tag{
+		{Name: "Voltage", UnitID: 1, Table: TableInput, Address: 10, Count: 1, DataType: "FLOAT32", ByteOrder: "AB", WordOrder: "4321",
+			Scale: 0.1, Unit: "V", Access: AccessRead},
+		{Name: "Setpoint", Table: TableHolding, Address: 255, Count: 2, DataType: "UINT16", ByteOrder: "AB", WordOrder: "1234",
+			Access: AccessReadWrite, PollMs: 500},
+	}
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"yaml list", "yaml", `
+- name: Voltage
+  unit_id: 1
+  table: ir
+  address: 10
+  data_type: float32
+  word_order: "4321"
+  scale: 0.1
+  unit: V
+- {name: Setpoint, table: holding, address: 0xFF, count: 2, poll_ms: 500}
+`},
+		{"yaml object", ".yml", `
+tags:
+  - {name: Voltage, unit_id: 1, table: input, address: 10, data_type: FLOAT32, word_order: "4321", scale: 0.1, unit: V}
+  - {name: Setpoint, table: "4", address: 255, count: 2, poll_ms: 500}
+`},
+		{"json list", "json", `[
+  {"name": "Voltage", "unit_id": 1, "table": "input", "address": 10, "data_type": "FLOAT32", "word_order": "4321", "scale": 0.1, "unit": "V"},
+  {"name": "Setpoint", "table": "hr", "address": 255, "count": 2, "poll_ms": 500}
+]`},
+		{"json object", ".JSON", ` {"tags": [
+  {"name": "Voltage", "unit_id": 1, "table": "input", "address": 10, "data_type": "FLOAT32", "word_order": "4321", "scale": 0.1, "unit": "V"},
+  {"name": "Setpoint", "table": "holding", "address": 255, "count": 2, "poll_ms": 500}
+]}`},
+		{"csv", "csv", "\xef\xbb\xbfName,table,address,unit_id,data_type,word_order,scale,unit,count,poll_ms\n" +
+			"# 注释\n" +
+			"Voltage,input,010,1,FLOAT32,4321,0.1,V,,\n" +
+			",,,,,,,,,\n" +
+			"Setpoint, holding, 0xFF,,,,,,2,500\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := ParseTags([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(db.Tags, want) {
+				t.Errorf("tags = %+v\nwant %+v", db.Tags, want)
+			}
+		})
+	}
+}
+
+func TestParseTagsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		wantErr string
+	}{
+		{"yaml list error not masked", "yaml", "- name: A\n  address: x\n", "cannot unmarshal !!str `x` into int"},
+		{"yaml object error", "yaml", "tags:\n  - name: A\n    count: many\n", "cannot unmarshal !!str `many` into int"},
+		{"yaml scalar", "yaml", "just text\n", "第 1 行"},
+		{"json error", "json", `[{"name": "A", "address": "x"}]`, "cannot unmarshal"},
+		{"csv without name", "csv", "address,table\n1,hr\n", "缺少 name 列"},
+		{"csv bad integer", "csv", "name,address\nA,12a\n", "第 2 行 address 无效: 12a"},
+		{"csv octal digits", "csv", "name,address\nA,0o17\n", "address 无效"},
+		{"csv bad float", "csv", "name,scale\nA,x\n", "第 2 行 scale 无效"},
+		{"unknown format", "xml", "", "不支持的点位表格式"},
+		{"duplicate name", "yaml", "- {name: Pump, table: coil}\n- {name: PUMP, table: coil, address: 1}\n", "点位名称重复: PUMP"},
+		{"empty name", "json", `[{"name": " ", "table": "coil"}]`, "第 1 个点位: 点位名称不能为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTags([]byte(tt.data), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	db, err := ParseTags(nil, "yaml")
+	if err != nil || len(db.Tags) != 0 {
+		t.Errorf("empty yaml = %+v, %v", db, err)
+	}
+}
+
+func TestTagNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     Tag
+		want    Tag // 只比较非空字段
+		wantErr string
+	}{
+		{"coil is bool and writable", Tag{Table: "co", DataType: "FLOAT32"}, Tag{DataType: "BOOL", Access: AccessReadWrite}, ""},
+		{"discrete is read-only", Tag{Table: "di"}, Tag{Access: AccessRead}, ""},
+		{"access aliases", Tag{Table: "hr", Access: "read/write"}, Tag{Access: AccessReadWrite}, ""},
+		{"write-only", Tag{Table: "hr", Access: "wo"}, Tag{Access: AccessWrite}, ""},
+		{"input not writable", Tag{Table: "input", Access: "RW"}, Tag{}, "input 不可写"},
+		{"discrete not writable", Tag{Table: "discrete", Access: "W"}, Tag{}, "discrete 不可写"},
+		{"unknown access", Tag{Table: "hr", Access: "X"}, Tag{}, "未知读写权限: X"},
+		{"unknown table", Tag{Table: "register"}, Tag{}, "未知寄存器类型: register"},
+		{"missing table", Tag{}, Tag{}, "未知寄存器类型"},
+		{"unit id", Tag{Table: "hr", UnitID: 256}, Tag{}, "从站地址无效"},
+		{"negative count", Tag{Table: "hr", Count: -1}, Tag{}, "数量无效"},
+		{"negative poll", Tag{Table: "hr", PollMs: -1}, Tag{}, "轮询周期无效"},
+		{"data type", Tag{Table: "hr", DataType: "INT24"}, Tag{}, "INT24"},
+		{"last register", Tag{Table: "hr", Address: 65535}, Tag{Address: 65535}, ""},
+		{"last two registers", Tag{Table: "hr", Address: 65534, DataType: "UINT32"}, Tag{Address: 65534}, ""},
+		{"past the last register", Tag{Table: "hr", Address: 65535, DataType: "UINT32"}, Tag{}, "地址超出范围: 65535"},
+		{"count past the end", Tag{Table: "coil", Address: 65530, Count: 7}, Tag{}, "地址超出范围"},
+		{"count to the end", Tag{Table: "coil", Address: 65530, Count: 6}, Tag{Count: 6}, ""},
+		{"negative address", Tag{Table: "hr", Address: -1}, Tag{}, "地址超出范围"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag := tt.tag
+			tag.Name = "x"
+			err := tag.normalize()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (tt.want.DataType != "" && tag.DataType != tt.want.DataType) ||
+				(tt.want.Access != "" && tag.Access != tt.want.Access) ||
+				(tt.want.Address != 0 && tag.Address != tt.want.Address) ||
+				(tt.want.Count != 0 && tag.Count != tt.want.Count) {
+				t.Errorf("normalized = %+v, want %+v", tag, tt.want)
+			}
+		})
+	}
+}
+
+// TestTagLookup 名称查找不区分大小写并忽略首尾空格
+func TestTagLookup(t *testing.T) {
+	db, err := NewTagDatabase([]Tag{{Name: " Pump ", Table: "coil"}, {Name: "Speed", Table: "hr"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag, ok := db.Lookup(" PUMP"); !ok || tag.Name != "Pump" {
+		t.Errorf("Lookup(PUMP) = %+v, %v", tag, ok)
+	}
+	if _, ok := db.Lookup("Level"); ok {
+		t.Error("Lookup(Level) found a tag")
+	}
+	if names := db.Names(); !reflect.DeepEqual(names, []string{"Pump", "Speed"}) {
+		t.Errorf("Names = %q", names)
+	}
+}
+
+// TestTagsCSVRoundTrip 保存的 CSV 重新加载后不变
+func TestTagsCSVRoundTrip(t *testing.T) {
+	db, err := NewTagDatabase([]Tag{
+		{Name: "Energy", UnitID: 3, Table: "ir", Address: 8, DataType: "UINT48", WordOrder: "4321", Scale: 0.001, Unit: "kWh", Description: "total, import"},
+		{Name: "Run", Table: "coil", Address: 65535, PollMs: 250},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := formatTagsCSV(db.Tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ParseTags(data, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Tags, db.Tags) {
+		t.Errorf("loaded = %+v\nwant %+v", loaded.Tags, db.Tags)
+	}
+}
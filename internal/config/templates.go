@@ -0,0 +1,294 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"modbusbaby/pkg/datatypes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 设备模板
+//
+// 设备模板描述一种设备的默认连接参数、字节/字序和点位表, 选择模板即可完成该设备的常用配置。
+// 程序内置常见电表、变频器和逆变器的模板; 用户模板为模板目录中的 YAML 文件, 与内置模板同名时覆盖内置模板。
+
+//go:embed templates/*.yaml
+var builtinTemplateFS embed.FS
+
+// DeviceTemplate 设备模板
+type DeviceTemplate struct {
+	Name         string             `json:"name" yaml:"name"`
+	Manufacturer string             `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Model        string             `json:"model,omitempty" yaml:"model,omitempty"`
+	Category     string             `json:"category,omitempty" yaml:"category,omitempty"` // meter/drive/inverter 等
+	Description  string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Connection   TemplateConnection `json:"connection" yaml:"connection"`
+	ByteOrder    string             `json:"byte_order,omitempty" yaml:"byte_order,omitempty"` // 点位未指定字节序时使用, 默认 AB
+	WordOrder    string             `json:"word_order,omitempty" yaml:"word_order,omitempty"` // 点位未指定字序时使用, 默认 1234
+	Tags         []Tag              `json:"tags" yaml:"tags"`
+
+	Path string `json:"-" yaml:"-"` // 模板文件路径, 内置模板为空
+}
+
+// TemplateConnection 模板的默认连接参数, 零值表示不修改当前配置。
+// 不包含 IP 地址和串口名称, 这两项因现场而异。
+type TemplateConnection struct {
+	Type     string  `json:"type,omitempty" yaml:"type,omitempty"` // TCP 或 RTU
+	Port     int     `json:"port,omitempty" yaml:"port,omitempty"` // TCP 端口
+	SlaveID  int     `json:"slave_id,omitempty" yaml:"slave_id,omitempty"`
+	BaudRate int     `json:"baud_rate,omitempty" yaml:"baud_rate,omitempty"`
+	DataBits int     `json:"data_bits,omitempty" yaml:"data_bits,omitempty"`
+	StopBits float64 `json:"stop_bits,omitempty" yaml:"stop_bits,omitempty"`
+	Parity   string  `json:"parity,omitempty" yaml:"parity,omitempty"`
+}
+
+// Builtin 判断是否为内置模板
+func (t *DeviceTemplate) Builtin() bool {
+	return t.Path == ""
+}
+
+// Orders 返回模板的默认字节序和字序
+func (t *DeviceTemplate) Orders() (datatypes.ByteOrder, datatypes.WordOrder) {
+	byteOrder, _ := datatypes.ParseByteOrder(t.ByteOrder)
+	wordOrder, _ := datatypes.ParseWordOrder(t.WordOrder)
+	return byteOrder, wordOrder
+}
+
+// TagDatabase 返回模板的点位表, 未指定字节/字序的点位使用模板的设置
+func (t *DeviceTemplate) TagDatabase() (*TagDatabase, error) {
+	tags := make([]Tag, len(t.Tags))
+	for i, tag := range t.Tags {
+		if tag.ByteOrder == "" {
+			tag.ByteOrder = t.ByteOrder
+		}
+		if tag.WordOrder == "" {
+			tag.WordOrder = t.WordOrder
+		}
+		tags[i] = tag
+	}
+	return NewTagDatabase(tags)
+}
+
+// Apply 将模板的连接参数写入配置, 同时设置 TCP 和 RTU 的从站地址
+func (t *DeviceTemplate) Apply(cfg *Config) {
+	conn := t.Connection
+	if conn.Type != "" {
+		cfg.DefaultConnType = strings.ToUpper(conn.Type)
+	}
+	if conn.Port != 0 {
+		cfg.TCP.Port = conn.Port
+	}
+	if conn.SlaveID != 0 {
+		cfg.TCP.SlaveID = conn.SlaveID
+		cfg.RTU.SlaveID = conn.SlaveID
+	}
+	if conn.BaudRate != 0 {
+		cfg.RTU.BaudRate = conn.BaudRate
+	}
+	if conn.DataBits != 0 {
+		cfg.RTU.DataBits = conn.DataBits
+	}
+	if conn.StopBits != 0 {
+		cfg.RTU.StopBits = conn.StopBits
+	}
+	if conn.Parity != "" {
+		cfg.RTU.Parity = conn.Parity
+	}
+}
+
+// templateParities 校验方式的写法, 值与界面下拉框的选项一致
+var templateParities = map[string]string{
+	"none": "None", "n": "None",
+	"even": "Even", "e": "Even",
+	"odd": "Odd", "o": "Odd",
+	"mark": "Mark", "m": "Mark",
+	"space": "Space", "s": "Space",
+}
+
+// validate 填充默认值并校验模板
+func (t *DeviceTemplate) validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("模板名称不能为空")
+	}
+	// 规范化为界面下拉框的选项写法, 如 "ba" → "BA"
+	if t.ByteOrder == "" {
+		t.ByteOrder = "AB"
+	}
+	byteOrder, err := datatypes.ParseByteOrder(t.ByteOrder)
+	if err != nil {
+		return err
+	}
+	t.ByteOrder = byteOrder.String()
+	if t.WordOrder == "" {
+		t.WordOrder = "1234"
+	}
+	wordOrder, err := datatypes.ParseWordOrder(t.WordOrder)
+	if err != nil {
+		return err
+	}
+	t.WordOrder = wordOrder.String()
+	switch strings.ToUpper(t.Connection.Type) {
+	case "", "TCP", "RTU":
+	default:
+		return fmt.Errorf("未知连接类型: %s", t.Connection.Type)
+	}
+	if t.Connection.Parity != "" {
+		parity, ok := templateParities[strings.ToLower(strings.TrimSpace(t.Connection.Parity))]
+		if !ok {
+			return fmt.Errorf("未知校验方式: %s", t.Connection.Parity)
+		}
+		t.Connection.Parity = parity
+	}
+	if _, err := t.TagDatabase(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseTemplate 解析 YAML 设备模板
+func ParseTemplate(data []byte) (*DeviceTemplate, error) {
+	var t DeviceTemplate
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// LoadTemplate 加载设备模板文件
+func LoadTemplate(path string) (*DeviceTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ParseTemplate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	t.Path = path
+	return t, nil
+}
+
+// SaveTemplate 以 YAML 保存设备模板
+func SaveTemplate(path string, t *DeviceTemplate) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// BuiltinTemplates 返回内置的设备模板
+func BuiltinTemplates() []*DeviceTemplate {
+	var templates []*DeviceTemplate
+	entries, _ := builtinTemplateFS.ReadDir("templates")
+	for _, entry := range entries {
+		data, err := builtinTemplateFS.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		if t, err := ParseTemplate(data); err == nil {
+			templates = append(templates, t)
+		}
+	}
+	return templates
+}
+
+// LoadTemplates 返回内置模板和目录中的用户模板 (*.yaml/*.yml), 按名称排序。
+// 无法解析的用户模板不影响其他模板, 其错误在 errs 中返回。
+func LoadTemplates(dir string) (templates []*DeviceTemplate, errs []error) {
+	byName := make(map[string]*DeviceTemplate)
+	for _, t := range BuiltinTemplates() {
+		byName[strings.ToLower(t.Name)] = t
+	}
+
+	if dir != "" {
+		var files []string
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			files = append(files, matches...)
+		}
+		for _, file := range files {
+			t, err := LoadTemplate(file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			byName[strings.ToLower(t.Name)] = t
+		}
+	}
+
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return strings.ToLower(templates[i].Name) < strings.ToLower(templates[j].Name)
+	})
+	return templates, errs
+}
+
+// FindTemplate 按名称 (不区分大小写) 查找模板
+func FindTemplate(templates []*DeviceTemplate, name string) (*DeviceTemplate, bool) {
+	for _, t := range templates {
+		if strings.EqualFold(t.Name, strings.TrimSpace(name)) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// InstallBuiltinTemplates 将内置模板文件复制到目录, 已存在的文件不覆盖, 返回复制的文件数
+func InstallBuiltinTemplates(dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	entries, err := builtinTemplateFS.ReadDir("templates")
+	if err != nil {
+		return 0, err
+	}
+	installed := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		data, err := builtinTemplateFS.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			return installed, err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return installed, err
+		}
+		installed++
+	}
+	return installed, nil
+}
+
+// TemplatePath 返回用户模板目录: 配置中的 template_dir (相对路径以配置文件所在目录为基准),
+// 未配置时为 ~/.modbusbaby/templates
+func (c *Config) TemplatePath() string {
+	if c.TemplateDir != "" {
+		if filepath.IsAbs(c.TemplateDir) {
+			return c.TemplateDir
+		}
+		return filepath.Join(filepath.Dir(getConfigPath()), c.TemplateDir)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".modbusbaby", "templates")
+}
+
+// LoadTemplates 加载内置模板和配置中用户模板目录的模板
+func (c *Config) LoadTemplates() ([]*DeviceTemplate, []error) {
+	return LoadTemplates(c.TemplatePath())
+}
@@ -0,0 +1,81 @@
+package config
+
+import "testing"
+
+// TestParseTemplateNormalizes 字节/字序和校验方式规范化为界面下拉框的选项写法
+func TestParseTemplateNormalizes(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(`
+name: Meter
+connection:
+  type: rtu
+  parity: even
+byte_order: ba
+word_order: " 4321"
+tags:
+  - name: Voltage
+    table: input
+    address: 0
+    data_type: FLOAT32
+  - name: Energy
+    table: input
+    address: 2
+    data_type: UINT32
+    word_order: "1234"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ByteOrder != "BA" || tmpl.WordOrder != "4321" || tmpl.Connection.Parity != "Even" {
+		t.Errorf("byte order %q, word order %q, parity %q; want BA, 4321, Even", tmpl.ByteOrder, tmpl.WordOrder, tmpl.Connection.Parity)
+	}
+
+	db, err := tmpl.TagDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"Voltage": "BA/4321", "Energy": "BA/1234"} {
+		tag, _ := db.Lookup(name)
+		if got := tag.ByteOrder + "/" + tag.WordOrder; got != want {
+			t.Errorf("%s orders = %s, want %s", name, got, want)
+		}
+	}
+
+	cfg := Default()
+	tmpl.Apply(cfg)
+	if cfg.DefaultConnType != "RTU" || cfg.RTU.Parity != "Even" {
+		t.Errorf("Apply: connection %q, parity %q", cfg.DefaultConnType, cfg.RTU.Parity)
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := map[string]string{
+		"no name":         "byte_order: AB",
+		"byte order":      "name: x\nbyte_order: CD",
+		"word order":      "name: x\nword_order: 1243",
+		"connection type": "name: x\nconnection: {type: udp}",
+		"parity":          "name: x\nconnection: {parity: sometimes}",
+		"tag":             "name: x\ntags: [{name: a, table: output}]",
+	}
+	for name, data := range tests {
+		if _, err := ParseTemplate([]byte(data)); err == nil {
+			t.Errorf("%s: ParseTemplate succeeded, want an error", name)
+		}
+	}
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	// 无法解析的内置模板会被跳过, 个数不一致说明有模板无效
+	entries, err := builtinTemplateFS.ReadDir("templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := BuiltinTemplates()
+	if len(templates) == 0 || len(templates) != len(entries) {
+		t.Fatalf("%d of %d builtin templates parsed", len(templates), len(entries))
+	}
+	for _, tmpl := range templates {
+		if _, err := tmpl.TagDatabase(); err != nil {
+			t.Errorf("%s: %v", tmpl.Name, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCacheEntries 缓存条目的上限。达到上限时不再缓存新的请求, 直到过期条目被清除
+const maxCacheEntries = 10000
+
+// cacheEntry 缓存的响应
+type cacheEntry struct {
+	response []byte
+	expires  time.Time
+}
+
+// responseCache 读请求响应缓存, 按单元标识符和请求PDU索引。
+// 过期条目在读取时删除, 另外写入时每隔一个 ttl 清除一次全部过期条目
+type responseCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[byte]map[string]cacheEntry
+	size      int       // 所有单元的条目数
+	nextSweep time.Time // 下次清除过期条目的时间
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		entries: make(map[byte]map[string]cacheEntry),
+	}
+}
+
+func (c *responseCache) get(unitID byte, pdu []byte) ([]byte, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[unitID][string(pdu)]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries[unitID], string(pdu))
+		c.size--
+		return nil, false
+	}
+	return entry.response, true
+}
+
+func (c *responseCache) put(unitID byte, pdu, response []byte) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if !now.Before(c.nextSweep) {
+		c.sweep(now)
+		c.nextSweep = now.Add(c.ttl)
+	}
+
+	if _, ok := c.entries[unitID][string(pdu)]; !ok {
+		if c.size >= maxCacheEntries {
+			return
+		}
+		c.size++
+	}
+	unitEntries, ok := c.entries[unitID]
+	if !ok {
+		unitEntries = make(map[string]cacheEntry)
+		c.entries[unitID] = unitEntries
+	}
+	unitEntries[string(pdu)] = cacheEntry{
+		response: append([]byte(nil), response...),
+		expires:  now.Add(c.ttl),
+	}
+}
+
+// sweep 删除所有过期条目, 调用方须持有 mu
+func (c *responseCache) sweep(now time.Time) {
+	for unitID, unitEntries := range c.entries {
+		for key, entry := range unitEntries {
+			if now.After(entry.expires) {
+				delete(unitEntries, key)
+				c.size--
+			}
+		}
+		if len(unitEntries) == 0 {
+			delete(c.entries, unitID)
+		}
+	}
+}
+
+// invalidate 清除某个单元的全部缓存
+func (c *responseCache) invalidate(unitID byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size -= len(c.entries[unitID])
+	delete(c.entries, unitID)
+}
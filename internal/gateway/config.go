@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"fmt"
+	"modbusbaby/internal/config"
+	"modbusbaby/internal/modbus"
+	"strings"
+	"time"
+)
+
+// Open 根据配置创建网关并连接所有上游设备。
+// 指向同一串口或同一TCP地址的路由共享一个上游连接, 网关停止时一并关闭。
+func Open(cfg config.GatewayConfig) (*Gateway, error) {
+	if err := validateRoutes(cfg.Routes); err != nil {
+		return nil, err
+	}
+	g := New(cfg.ListenAddr, time.Duration(cfg.CacheTTL)*time.Millisecond)
+
+	upstreams := make(map[string]*modbus.Client)
+	closeAll := func() {
+		for _, client := range upstreams {
+			client.Disconnect()
+		}
+	}
+
+	for _, route := range cfg.Routes {
+		key, err := upstreamKey(route)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		client, ok := upstreams[key]
+		if !ok {
+			client = modbus.NewClient()
+			if strings.EqualFold(route.Type, "RTU") {
+				err = client.ConnectRTU(route.RTU)
+			} else {
+				err = client.ConnectTCP(route.TCP.IP, route.TCP.Port)
+			}
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("failed to connect upstream %s: %w", key, err)
+			}
+			upstreams[key] = client
+		}
+
+		g.AddRoute(byte(route.UnitID), client, byte(route.TargetUnitID))
+	}
+
+	g.onClose = closeAll
+	return g, nil
+}
+
+// validateRoutes 在连接任何上游设备之前检查路由。同一串口上的路由共享一个连接,
+// 因此必须使用相同的串口参数, 否则后面的路由会静默沿用第一个路由的参数
+func validateRoutes(routes []config.GatewayRoute) error {
+	serialSettings := make(map[string]config.RTUConfig)
+	for _, route := range routes {
+		if route.UnitID < 0 || route.UnitID > 255 || route.TargetUnitID < 0 || route.TargetUnitID > 255 {
+			return fmt.Errorf("invalid unit ID in gateway route: %d -> %d", route.UnitID, route.TargetUnitID)
+		}
+		if _, err := upstreamKey(route); err != nil {
+			return err
+		}
+		if !strings.EqualFold(route.Type, "RTU") {
+			continue
+		}
+		// 从站地址由路由决定, 不属于串口参数
+		settings := route.RTU
+		settings.SlaveID = 0
+		if previous, ok := serialSettings[settings.Port]; ok && previous != settings {
+			return fmt.Errorf("gateway routes on %s use different serial settings: %+v and %+v (unit %d)",
+				settings.Port, previous, settings, route.UnitID)
+		}
+		serialSettings[settings.Port] = settings
+	}
+	return nil
+}
+
+// upstreamKey 返回上游连接的唯一标识
+func upstreamKey(route config.GatewayRoute) (string, error) {
+	switch strings.ToUpper(route.Type) {
+	case "RTU":
+		return "rtu:" + route.RTU.Port, nil
+	case "TCP", "":
+		return fmt.Sprintf("tcp:%s:%d", route.TCP.IP, route.TCP.Port), nil
+	default:
+		return "", fmt.Errorf("unknown upstream type: %s", route.Type)
+	}
+}
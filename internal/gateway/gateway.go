@@ -0,0 +1,339 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"modbusbaby/internal/logger"
+	"net"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Modbus 异常码
+const (
+	exceptionIllegalFunction      = 0x01
+	exceptionIllegalDataValue     = 0x03
+	exceptionGatewayPathFailed    = 0x0A // 网关路径不可用
+	exceptionGatewayTargetTimeout = 0x0B // 网关目标设备无响应
+)
+
+// mbapHeaderSize MBAP 报文头长度
+const mbapHeaderSize = 7
+
+// Upstream 上游设备, modbus.Client 实现了该接口
+type Upstream interface {
+	SendRawPDU(slaveID byte, pdu []byte) ([]byte, error)
+}
+
+// Route 单元标识符到上游设备的路由
+type Route struct {
+	Upstream     Upstream
+	TargetUnitID byte // 转发到上游时使用的从站地址
+}
+
+// Stats 网关运行统计
+type Stats struct {
+	Requests    uint64
+	CacheHits   uint64
+	Errors      uint64
+	Connections int
+}
+
+// Gateway Modbus TCP 网关: 监听 Modbus TCP 请求并按单元标识符转发到上游 RTU 总线或 TCP 设备
+type Gateway struct {
+	listenAddr string
+	cache      *responseCache
+
+	routesMutex  sync.RWMutex
+	routes       map[byte]Route
+	defaultRoute *Route
+
+	// forwardMutex 转发期间持有读锁, RemoveUpstream 借此等待已开始的转发结束
+	forwardMutex sync.RWMutex
+
+	listener  net.Listener
+	connMutex sync.Mutex
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+
+	requests  atomic.Uint64
+	cacheHits atomic.Uint64
+	errors    atomic.Uint64
+
+	// onClose 在网关停止时调用, 用于关闭由网关自己打开的上游连接
+	onClose func()
+}
+
+// New 创建网关, cacheTTL 为0时不缓存读请求
+func New(listenAddr string, cacheTTL time.Duration) *Gateway {
+	return &Gateway{
+		listenAddr: listenAddr,
+		cache:      newResponseCache(cacheTTL),
+		routes:     make(map[byte]Route),
+		conns:      make(map[net.Conn]struct{}),
+	}
+}
+
+// AddRoute 添加路由, targetUnitID 为0时使用与请求相同的单元标识符
+func (g *Gateway) AddRoute(unitID byte, upstream Upstream, targetUnitID byte) {
+	if targetUnitID == 0 {
+		targetUnitID = unitID
+	}
+	g.routesMutex.Lock()
+	defer g.routesMutex.Unlock()
+	g.routes[unitID] = Route{Upstream: upstream, TargetUnitID: targetUnitID}
+}
+
+// SetDefaultRoute 设置未匹配任何路由时使用的上游设备 (单元标识符原样转发)
+func (g *Gateway) SetDefaultRoute(upstream Upstream) {
+	g.routesMutex.Lock()
+	defer g.routesMutex.Unlock()
+	if upstream == nil {
+		g.defaultRoute = nil
+		return
+	}
+	g.defaultRoute = &Route{Upstream: upstream}
+}
+
+// RemoveUpstream 删除所有指向 upstream 的路由 (包括默认路由), 并等待已转发到该设备的请求结束,
+// 之后即可安全地断开该设备。删除后发往这些单元的请求以网关路径不可用响应
+func (g *Gateway) RemoveUpstream(upstream Upstream) int {
+	g.routesMutex.Lock()
+	removed := 0
+	for unitID, r := range g.routes {
+		if r.Upstream == upstream {
+			delete(g.routes, unitID)
+			removed++
+		}
+	}
+	if g.defaultRoute != nil && g.defaultRoute.Upstream == upstream {
+		g.defaultRoute = nil
+		removed++
+	}
+	g.routesMutex.Unlock()
+
+	g.forwardMutex.Lock()
+	g.forwardMutex.Unlock()
+	return removed
+}
+
+// route 查找单元标识符对应的路由
+func (g *Gateway) route(unitID byte) (Route, bool) {
+	g.routesMutex.RLock()
+	defer g.routesMutex.RUnlock()
+	if r, ok := g.routes[unitID]; ok {
+		return r, true
+	}
+	if g.defaultRoute != nil {
+		return Route{Upstream: g.defaultRoute.Upstream, TargetUnitID: unitID}, true
+	}
+	return Route{}, false
+}
+
+// Start 开始监听
+func (g *Gateway) Start() error {
+	listener, err := net.Listen("tcp", g.listenAddr)
+	if err != nil {
+		return err
+	}
+	g.listener = listener
+	logger.Info(fmt.Sprintf("Gateway listening on %s", listener.Addr()))
+
+	g.wg.Add(1)
+	go g.acceptLoop()
+	return nil
+}
+
+// Addr 返回实际监听地址
+func (g *Gateway) Addr() net.Addr {
+	if g.listener == nil {
+		return nil
+	}
+	return g.listener.Addr()
+}
+
+// Stop 停止监听并断开所有客户端连接
+func (g *Gateway) Stop() error {
+	if g.listener == nil {
+		return nil
+	}
+	err := g.listener.Close()
+
+	g.connMutex.Lock()
+	for conn := range g.conns {
+		conn.Close()
+	}
+	g.connMutex.Unlock()
+
+	g.wg.Wait()
+	g.listener = nil
+	if g.onClose != nil {
+		g.onClose()
+	}
+	logger.Info("Gateway stopped")
+	return err
+}
+
+// Stats 返回运行统计
+func (g *Gateway) Stats() Stats {
+	g.connMutex.Lock()
+	connections := len(g.conns)
+	g.connMutex.Unlock()
+	return Stats{
+		Requests:    g.requests.Load(),
+		CacheHits:   g.cacheHits.Load(),
+		Errors:      g.errors.Load(),
+		Connections: connections,
+	}
+}
+
+func (g *Gateway) acceptLoop() {
+	defer g.wg.Done()
+	for {
+		conn, err := g.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error("Gateway accept failed:", err)
+			}
+			return
+		}
+
+		g.connMutex.Lock()
+		g.conns[conn] = struct{}{}
+		g.connMutex.Unlock()
+		logger.Info(fmt.Sprintf("Gateway client connected: %s", conn.RemoteAddr()))
+
+		g.wg.Add(1)
+		go g.serve(conn)
+	}
+}
+
+// serve 处理单个客户端连接上的所有请求。处理请求时发生 panic 只断开该连接, 不影响网关的其他连接
+func (g *Gateway) serve(conn net.Conn) {
+	defer g.wg.Done()
+	defer func() {
+		g.connMutex.Lock()
+		delete(g.conns, conn)
+		g.connMutex.Unlock()
+		conn.Close()
+		logger.Info(fmt.Sprintf("Gateway client disconnected: %s", conn.RemoteAddr()))
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			g.errors.Add(1)
+			logger.Error(fmt.Sprintf("Gateway: panic while serving %s: %v\n%s", conn.RemoteAddr(), r, debug.Stack()))
+		}
+	}()
+
+	header := make([]byte, mbapHeaderSize)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		transactionID := binary.BigEndian.Uint16(header[0:2])
+		protocolID := binary.BigEndian.Uint16(header[2:4])
+		length := int(binary.BigEndian.Uint16(header[4:6]))
+		unitID := header[6]
+
+		if protocolID != 0 || length < 2 || length > 254 {
+			logger.Warn(fmt.Sprintf("Gateway: invalid MBAP header from %s: %x", conn.RemoteAddr(), header))
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		response := g.handle(conn.RemoteAddr(), unitID, pdu)
+
+		adu := make([]byte, mbapHeaderSize+len(response))
+		binary.BigEndian.PutUint16(adu[0:2], transactionID)
+		binary.BigEndian.PutUint16(adu[4:6], uint16(len(response)+1))
+		adu[6] = unitID
+		copy(adu[mbapHeaderSize:], response)
+		if _, err := conn.Write(adu); err != nil {
+			return
+		}
+	}
+}
+
+// handle 转发一个请求PDU并返回响应PDU, 失败时返回对应的异常响应
+func (g *Gateway) handle(client net.Addr, unitID byte, pdu []byte) []byte {
+	g.requests.Add(1)
+	start := time.Now()
+	functionCode := pdu[0]
+
+	g.forwardMutex.RLock()
+	defer g.forwardMutex.RUnlock()
+	route, ok := g.route(unitID)
+	if !ok {
+		g.errors.Add(1)
+		logger.Warn(fmt.Sprintf("Gateway [%s] unit %d: no route, request %x", client, unitID, pdu))
+		return []byte{functionCode | 0x80, exceptionGatewayPathFailed}
+	}
+
+	if functionCode&0x80 != 0 {
+		g.errors.Add(1)
+		return []byte{functionCode | 0x80, exceptionIllegalFunction}
+	}
+	if !lengthValid(pdu) {
+		g.errors.Add(1)
+		logger.Warn(fmt.Sprintf("Gateway [%s] unit %d: request %x too short for function 0x%02X", client, unitID, pdu, functionCode))
+		return []byte{functionCode | 0x80, exceptionIllegalDataValue}
+	}
+
+	if cached, ok := g.cache.get(unitID, pdu); ok {
+		g.cacheHits.Add(1)
+		logger.Info(fmt.Sprintf("Gateway [%s] unit %d->%d: request %x, response %x (cached)", client, unitID, route.TargetUnitID, pdu, cached))
+		return cached
+	}
+
+	response, err := route.Upstream.SendRawPDU(route.TargetUnitID, pdu)
+	elapsed := time.Since(start)
+	if err != nil {
+		g.errors.Add(1)
+		logger.Error(fmt.Sprintf("Gateway [%s] unit %d->%d: request %x failed after %v: %v", client, unitID, route.TargetUnitID, pdu, elapsed, err))
+		return []byte{functionCode | 0x80, exceptionGatewayTargetTimeout}
+	}
+
+	if isReadFunction(functionCode) && response[0] == functionCode {
+		g.cache.put(unitID, pdu, response)
+	} else if !isReadFunction(functionCode) {
+		// 写操作后该单元的缓存数据可能已过期
+		g.cache.invalidate(unitID)
+	}
+
+	logger.Info(fmt.Sprintf("Gateway [%s] unit %d->%d: request %x, response %x (%v)", client, unitID, route.TargetUnitID, pdu, response, elapsed))
+	return response
+}
+
+// lengthValid 检查请求PDU是否包含功能码要求的全部字段, 带字节数的请求还须带齐其后的数据。
+// 不认识的功能码原样转发, 由上游设备判断
+func lengthValid(pdu []byte) bool {
+	switch pdu[0] {
+	case 0x01, 0x02, 0x03, 0x04, 0x05, 0x06:
+		return len(pdu) >= 5
+	case 0x0F, 0x10:
+		return len(pdu) >= 6 && len(pdu) >= 6+int(pdu[5])
+	case 0x16:
+		return len(pdu) >= 7
+	case 0x17:
+		return len(pdu) >= 10 && len(pdu) >= 10+int(pdu[9])
+	default:
+		return true
+	}
+}
+
+// isReadFunction 判断功能码是否为只读操作
+func isReadFunction(functionCode byte) bool {
+	switch functionCode {
+	case 0x01, 0x02, 0x03, 0x04:
+		return true
+	default:
+		return false
+	}
+}
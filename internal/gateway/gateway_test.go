@@ -0,0 +1,377 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"modbusbaby/internal/config"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUpstream 记录收到的请求, 按 respond 返回响应
+type fakeUpstream struct {
+	mu      sync.Mutex
+	calls   []fakeCall
+	respond func(slaveID byte, pdu []byte) ([]byte, error)
+}
+
+type fakeCall struct {
+	slaveID byte
+	pdu     []byte
+}
+
+func (u *fakeUpstream) SendRawPDU(slaveID byte, pdu []byte) ([]byte, error) {
+	u.mu.Lock()
+	u.calls = append(u.calls, fakeCall{slaveID, append([]byte(nil), pdu...)})
+	u.mu.Unlock()
+	return u.respond(slaveID, pdu)
+}
+
+func (u *fakeUpstream) callCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.calls)
+}
+
+// echoRegisters 读寄存器时返回 quantity 个值为从站地址的寄存器, 写操作原样回显
+func echoRegisters(slaveID byte, pdu []byte) ([]byte, error) {
+	if pdu[0] == 0x03 || pdu[0] == 0x04 {
+		n := int(binary.BigEndian.Uint16(pdu[3:5]))
+		response := []byte{pdu[0], byte(2 * n)}
+		for i := 0; i < n; i++ {
+			response = append(response, 0, slaveID)
+		}
+		return response, nil
+	}
+	return append([]byte(nil), pdu[:5]...), nil
+}
+
+func startGateway(t *testing.T, cacheTTL time.Duration) (*Gateway, net.Conn) {
+	t.Helper()
+	g := New("127.0.0.1:0", cacheTTL)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", g.Addr().String())
+	if err != nil {
+		g.Stop()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		g.Stop()
+	})
+	return g, conn
+}
+
+// exchange 发送一个请求并返回响应PDU
+func exchange(t *testing.T, conn net.Conn, transactionID uint16, unitID byte, pdu []byte) []byte {
+	t.Helper()
+	response, err := roundTrip(conn, transactionID, unitID, pdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// roundTrip 发送一个请求并读取响应, 检查响应的 MBAP 头与请求一致
+func roundTrip(conn net.Conn, transactionID uint16, unitID byte, pdu []byte) ([]byte, error) {
+	adu := make([]byte, mbapHeaderSize, mbapHeaderSize+len(pdu))
+	binary.BigEndian.PutUint16(adu[0:2], transactionID)
+	binary.BigEndian.PutUint16(adu[4:6], uint16(len(pdu)+1))
+	adu[6] = unitID
+	if _, err := conn.Write(append(adu, pdu...)); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	header := make([]byte, mbapHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if got := binary.BigEndian.Uint16(header[0:2]); got != transactionID {
+		return nil, fmt.Errorf("transaction ID = %d, want %d", got, transactionID)
+	}
+	if header[6] != unitID {
+		return nil, fmt.Errorf("unit ID = %d, want %d", header[6], unitID)
+	}
+	response := make([]byte, int(binary.BigEndian.Uint16(header[4:6]))-1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+var readTwo = []byte{0x03, 0x00, 0x10, 0x00, 0x02}
+
+func TestGatewayRoutes(t *testing.T) {
+	g, conn := startGateway(t, 0)
+	routed := &fakeUpstream{respond: echoRegisters}
+	fallback := &fakeUpstream{respond: echoRegisters}
+	g.AddRoute(1, routed, 7)
+	g.AddRoute(2, routed, 0)
+
+	tests := []struct {
+		name   string
+		unitID byte
+		want   []byte
+	}{
+		{"mapped unit", 1, []byte{0x03, 4, 0, 7, 0, 7}},
+		{"same unit", 2, []byte{0x03, 4, 0, 2, 0, 2}},
+		{"no route", 3, []byte{0x83, exceptionGatewayPathFailed}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exchange(t, conn, uint16(i+1), tt.unitID, readTwo); !bytes.Equal(got, tt.want) {
+				t.Errorf("response = % X, want % X", got, tt.want)
+			}
+		})
+	}
+
+	g.SetDefaultRoute(fallback)
+	if got, want := exchange(t, conn, 10, 3, readTwo), []byte{0x03, 4, 0, 3, 0, 3}; !bytes.Equal(got, want) {
+		t.Errorf("default route response = % X, want % X", got, want)
+	}
+	if n := fallback.callCount(); n != 1 {
+		t.Errorf("default route calls = %d, want 1", n)
+	}
+}
+
+func TestGatewayUpstreamError(t *testing.T) {
+	g, conn := startGateway(t, 0)
+	g.AddRoute(1, &fakeUpstream{respond: func(byte, []byte) ([]byte, error) {
+		return nil, errors.New("timeout")
+	}}, 0)
+
+	if got, want := exchange(t, conn, 1, 1, readTwo), []byte{0x83, exceptionGatewayTargetTimeout}; !bytes.Equal(got, want) {
+		t.Errorf("response = % X, want % X", got, want)
+	}
+	if stats := g.Stats(); stats.Requests != 1 || stats.Errors != 1 {
+		t.Errorf("stats = %+v, want 1 request and 1 error", stats)
+	}
+}
+
+func TestGatewayExceptionPassthrough(t *testing.T) {
+	g, conn := startGateway(t, time.Minute)
+	upstream := &fakeUpstream{respond: func(_ byte, pdu []byte) ([]byte, error) {
+		return []byte{pdu[0] | 0x80, 0x02}, nil
+	}}
+	g.AddRoute(1, upstream, 0)
+
+	for i := 0; i < 2; i++ {
+		if got, want := exchange(t, conn, uint16(i), 1, readTwo), []byte{0x83, 0x02}; !bytes.Equal(got, want) {
+			t.Errorf("response = % X, want % X", got, want)
+		}
+	}
+	// 异常响应不缓存
+	if n := upstream.callCount(); n != 2 {
+		t.Errorf("upstream calls = %d, want 2", n)
+	}
+}
+
+func TestGatewayCache(t *testing.T) {
+	g, conn := startGateway(t, time.Minute)
+	upstream := &fakeUpstream{respond: echoRegisters}
+	g.AddRoute(1, upstream, 0)
+
+	exchange(t, conn, 1, 1, readTwo)
+	exchange(t, conn, 2, 1, readTwo)
+	if n := upstream.callCount(); n != 1 {
+		t.Fatalf("upstream calls after repeated read = %d, want 1", n)
+	}
+	if stats := g.Stats(); stats.CacheHits != 1 {
+		t.Errorf("cache hits = %d, want 1", stats.CacheHits)
+	}
+
+	// 写入使该单元的缓存失效
+	exchange(t, conn, 3, 1, []byte{0x06, 0x00, 0x10, 0x00, 0x01})
+	exchange(t, conn, 4, 1, readTwo)
+	if n := upstream.callCount(); n != 3 {
+		t.Errorf("upstream calls after write = %d, want 3", n)
+	}
+}
+
+func TestGatewayInvalidHeaderClosesConnection(t *testing.T) {
+	_, conn := startGateway(t, 0)
+	// 协议号不为0
+	if _, err := conn.Write([]byte{0, 1, 0, 1, 0, 6, 1, 0x03, 0, 0, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("read %d bytes after invalid header, want the connection closed", n)
+	}
+}
+
+func TestRemoveUpstreamWaitsForForward(t *testing.T) {
+	g, conn := startGateway(t, 0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	upstream := &fakeUpstream{respond: func(slaveID byte, pdu []byte) ([]byte, error) {
+		close(started)
+		<-release
+		return echoRegisters(slaveID, pdu)
+	}}
+	g.SetDefaultRoute(upstream)
+	g.AddRoute(5, upstream, 0)
+
+	responses := make(chan []byte, 1)
+	go func() {
+		response, err := roundTrip(conn, 1, 1, readTwo)
+		if err != nil {
+			t.Error(err)
+		}
+		responses <- response
+	}()
+	<-started
+
+	removed := make(chan int, 1)
+	go func() {
+		removed <- g.RemoveUpstream(upstream)
+	}()
+	select {
+	case <-removed:
+		t.Fatal("RemoveUpstream returned while a request was being forwarded")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if n := <-removed; n != 2 {
+		t.Errorf("removed routes = %d, want 2", n)
+	}
+	if got, want := <-responses, []byte{0x03, 4, 0, 1, 0, 1}; !bytes.Equal(got, want) {
+		t.Errorf("in-flight response = % X, want % X", got, want)
+	}
+	if got, want := exchange(t, conn, 2, 5, readTwo), []byte{0x83, exceptionGatewayPathFailed}; !bytes.Equal(got, want) {
+		t.Errorf("response after removal = % X, want % X", got, want)
+	}
+}
+
+// TestGatewayShortRequest 缺少字段的请求以非法数据值响应, 不转发到上游 (RTU 上游曾因此崩溃)
+func TestGatewayShortRequest(t *testing.T) {
+	g, conn := startGateway(t, 0)
+	upstream := &fakeUpstream{respond: func(_ byte, pdu []byte) ([]byte, error) {
+		return []byte{pdu[0], 0}, nil
+	}}
+	g.AddRoute(1, upstream, 0)
+
+	tests := []struct {
+		name string
+		pdu  []byte
+	}{
+		{"read without quantity", []byte{0x03, 0x00}},
+		{"read with half a quantity", []byte{0x01, 0x00, 0x00, 0x00}},
+		{"write single without value", []byte{0x06, 0x00, 0x10}},
+		{"write multiple without byte count", []byte{0x10, 0x00, 0x10, 0x00, 0x01}},
+		{"write multiple missing data", []byte{0x10, 0x00, 0x10, 0x00, 0x01, 0x02, 0x00}},
+		{"mask write without OR mask", []byte{0x16, 0x00, 0x10, 0xFF, 0xFF}},
+		{"read/write missing data", []byte{0x17, 0, 0, 0, 1, 0, 0, 0, 1, 2}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := []byte{tt.pdu[0] | 0x80, exceptionIllegalDataValue}
+			if got := exchange(t, conn, uint16(i), 1, tt.pdu); !bytes.Equal(got, want) {
+				t.Errorf("response = % X, want % X", got, want)
+			}
+		})
+	}
+	if n := upstream.callCount(); n != 0 {
+		t.Errorf("upstream calls = %d, want 0", n)
+	}
+
+	// 不认识的功能码原样转发
+	if got, want := exchange(t, conn, 100, 1, []byte{0x11}), []byte{0x11, 0}; !bytes.Equal(got, want) {
+		t.Errorf("report server ID response = % X, want % X", got, want)
+	}
+	if n := upstream.callCount(); n != 1 {
+		t.Errorf("upstream calls after report server ID = %d, want 1", n)
+	}
+}
+
+// TestGatewayRecoversPanic 处理请求时的 panic 只断开该客户端, 网关继续服务其他连接
+func TestGatewayRecoversPanic(t *testing.T) {
+	g, conn := startGateway(t, 0)
+	g.AddRoute(1, &fakeUpstream{respond: func(byte, []byte) ([]byte, error) {
+		panic("index out of range")
+	}}, 0)
+	g.AddRoute(2, &fakeUpstream{respond: echoRegisters}, 0)
+
+	if _, err := roundTrip(conn, 1, 1, readTwo); err == nil {
+		t.Error("got a response from a panicking upstream, want the connection closed")
+	}
+
+	other, err := net.Dial("tcp", g.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if got, want := exchange(t, other, 2, 2, readTwo), []byte{0x03, 4, 0, 2, 0, 2}; !bytes.Equal(got, want) {
+		t.Errorf("response after panic = % X, want % X", got, want)
+	}
+	if stats := g.Stats(); stats.Errors != 1 {
+		t.Errorf("errors = %d, want 1", stats.Errors)
+	}
+}
+
+// TestResponseCacheSweep 写入时清除其他请求的过期条目, 条目数不超过上限
+func TestResponseCacheSweep(t *testing.T) {
+	c := newResponseCache(10 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		c.put(byte(i%3), []byte{0x03, 0, byte(i), 0, 1}, []byte{0x03, 2, 0, 0})
+	}
+	if c.size != 100 {
+		t.Fatalf("size = %d, want 100", c.size)
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.put(7, readTwo, []byte{0x03, 2, 0, 0})
+	if c.size != 1 || len(c.entries) != 1 {
+		t.Errorf("after sweep: size %d, %d units, want only the new entry", c.size, len(c.entries))
+	}
+	c.invalidate(7)
+	if c.size != 0 {
+		t.Errorf("size after invalidate = %d", c.size)
+	}
+
+	c = newResponseCache(time.Minute)
+	for i := 0; i < maxCacheEntries; i++ {
+		c.put(1, []byte{0x03, byte(i >> 8), byte(i), 0, 1}, []byte{0x03, 2, 0, 0})
+	}
+	c.put(2, readTwo, []byte{0x03, 2, 0, 0})
+	if _, ok := c.get(2, readTwo); ok || c.size != maxCacheEntries {
+		t.Errorf("cached beyond the limit: size %d", c.size)
+	}
+	// 已有的条目仍可更新
+	c.put(1, []byte{0x03, 0, 0, 0, 1}, []byte{0x03, 2, 0, 9})
+	if got, _ := c.get(1, []byte{0x03, 0, 0, 0, 1}); !bytes.Equal(got, []byte{0x03, 2, 0, 9}) {
+		t.Errorf("updated entry = % X", got)
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	rtu := func(unitID int, port string, baud int, parity string) config.GatewayRoute {
+		return config.GatewayRoute{UnitID: unitID, Type: "RTU", RTU: config.RTUConfig{Port: port, BaudRate: baud, Parity: parity, SlaveID: unitID}}
+	}
+	tcp := config.GatewayRoute{UnitID: 9, Type: "tcp", TCP: config.TCPConfig{IP: "10.0.0.1", Port: 502}}
+	tests := []struct {
+		name    string
+		routes  []config.GatewayRoute
+		wantErr bool
+	}{
+		{"same port, same settings", []config.GatewayRoute{rtu(1, "COM1", 9600, "N"), rtu(2, "COM1", 9600, "N"), tcp}, false},
+		{"different ports", []config.GatewayRoute{rtu(1, "COM1", 9600, "N"), rtu(2, "COM2", 19200, "E")}, false},
+		{"different baud rate", []config.GatewayRoute{rtu(1, "COM1", 9600, "N"), rtu(2, "COM1", 19200, "N")}, true},
+		{"different parity", []config.GatewayRoute{rtu(1, "COM1", 9600, "N"), tcp, rtu(2, "COM1", 9600, "E")}, true},
+		{"bad unit", []config.GatewayRoute{rtu(256, "COM1", 9600, "N")}, true},
+		{"unknown type", []config.GatewayRoute{{UnitID: 1, Type: "UDP"}}, true},
+	}
+	for _, tt := range tests {
+		if err := validateRoutes(tt.routes); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
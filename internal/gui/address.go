@@ -0,0 +1,93 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/pkg/modicon"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// regTypeTables 界面寄存器类型与地址写法中寄存器类型的对应关系
+var regTypeTables = map[string]modicon.Table{
+	"Holding Register": modicon.HoldingRegister,
+	"Input Register":   modicon.InputRegister,
+	"Coil":             modicon.Coil,
+	"Discrete Input":   modicon.DiscreteInput,
+}
+
+// tableRegTypes 地址写法中的寄存器类型对应的界面寄存器类型
+var tableRegTypes = map[modicon.Table]string{
+	modicon.HoldingRegister: "Holding Register",
+	modicon.InputRegister:   "Input Register",
+	modicon.Coil:            "Coil",
+	modicon.DiscreteInput:   "Discrete Input",
+}
+
+// createAddressElements 创建地址写法提示
+func (a *AppRefined) createAddressElements() {
+	a.addressHintLabel = widget.NewLabel("")
+	a.startAddressInput.PlaceHolder = "40001/HR0/0x0"
+	a.startAddressInput.OnChanged = func(string) { a.updateAddressHint() }
+	a.registerTypeCombo.OnChanged = func(string) {
+		if !a.syncingRegisterType {
+			a.registerTypeChosen = true
+		}
+		a.updateAddressHint()
+	}
+	a.updateAddressHint()
+}
+
+// selectRegisterType 由程序切换寄存器类型 (如按地址写法或点位), 不视为用户手动选择
+func (a *AppRefined) selectRegisterType(regType string) {
+	a.syncingRegisterType = true
+	defer func() { a.syncingRegisterType = false }()
+	a.registerTypeCombo.SetSelected(regType)
+}
+
+// parseAddress 解析地址输入。用户手动选择过寄存器类型时, 纯数字是 table 中的协议地址,
+// 只有前缀写法 (HR100、3x0775) 能改变寄存器类型; 否则5位/6位数字按 Modicon 写法解析
+func (a *AppRefined) parseAddress(text string, table modicon.Table) (modicon.Address, error) {
+	if a.registerTypeChosen {
+		return modicon.ParseIn(text, table)
+	}
+	return modicon.Parse(text, table)
+}
+
+// updateAddressHint 在地址输入框旁显示起始地址的等价写法
+func (a *AppRefined) updateAddressHint() {
+	if a.addressHintLabel == nil {
+		return
+	}
+	addr, err := a.parseAddress(a.startAddressInput.Text, regTypeTables[a.registerTypeCombo.Selected])
+	if err != nil {
+		a.addressHintLabel.SetText("")
+		return
+	}
+	a.addressHintLabel.SetText("= " + strings.Join(addr.Notations(), " / "))
+}
+
+// parseStartAddress 解析起始地址, 地址写法中包含寄存器类型 (如 40001、HR100) 时同步切换寄存器类型
+func (a *AppRefined) parseStartAddress() (modicon.Address, error) {
+	addr, err := a.parseAddress(a.startAddressInput.Text, regTypeTables[a.registerTypeCombo.Selected])
+	if err != nil {
+		return addr, fmt.Errorf("起始地址无效: %w", err)
+	}
+	if regType := tableRegTypes[addr.Table]; addr.Explicit && regType != a.registerTypeCombo.Selected {
+		a.selectRegisterType(regType)
+		a.appendLog(fmt.Sprintf("地址 %s 为 %s, 已切换寄存器类型", strings.TrimSpace(a.startAddressInput.Text), regType))
+	}
+	return addr, nil
+}
+
+// parseEndAddress 解析结束地址, 带寄存器类型时必须与起始地址一致
+func (a *AppRefined) parseEndAddress(start modicon.Address) (modicon.Address, error) {
+	addr, err := a.parseAddress(a.endAddressInput.Text, start.Table)
+	if err != nil {
+		return addr, fmt.Errorf("结束地址无效: %w", err)
+	}
+	if addr.Table != start.Table {
+		return addr, fmt.Errorf("结束地址 %s 与起始地址的寄存器类型不一致", strings.TrimSpace(a.endAddressInput.Text))
+	}
+	return addr, nil
+}
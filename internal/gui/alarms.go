@@ -0,0 +1,241 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/alarm"
+	"modbusbaby/internal/config"
+	"modbusbaby/internal/recorder"
+	"modbusbaby/pkg/datatypes"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// alarmColumns 报警列表的列名和宽度
+var alarmColumns = []struct {
+	title string
+	width float32
+}{
+	{"状态", 70}, {"等级", 80}, {"点", 140}, {"报警", 260}, {"数值", 100},
+	{"报警时间", 110}, {"恢复时间", 110}, {"确认时间", 110},
+}
+
+// severityNames 报警等级的显示名称
+var severityNames = map[string]string{
+	config.SeverityInfo:     "提示",
+	config.SeverityWarning:  "警告",
+	config.SeverityCritical: "严重",
+}
+
+// createAlarmElements 创建报警引擎和报警列表, 点位表加载后按其中的规则重建引擎
+func (a *AppRefined) createAlarmElements() {
+	a.alarmEngine = alarm.NewEngine(nil)
+	a.alarmTable = widget.NewTable(
+		func() (int, int) {
+			return len(a.alarmList) + 1, len(alarmColumns)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			label.TextStyle = fyne.TextStyle{Bold: id.Row == 0}
+			label.Importance = widget.MediumImportance
+			if id.Row > 0 && id.Row <= len(a.alarmList) {
+				label.Importance = alarmImportance(a.alarmList[id.Row-1])
+			}
+			label.SetText(a.alarmCell(id.Row, id.Col))
+		},
+	)
+	for i, col := range alarmColumns {
+		a.alarmTable.SetColumnWidth(i, col.width)
+	}
+	a.alarmTable.OnSelected = func(id widget.TableCellID) {
+		a.alarmSelected = ""
+		if id.Row > 0 && id.Row <= len(a.alarmList) {
+			a.alarmSelected = a.alarmList[id.Row-1].ID
+		}
+	}
+
+	a.alarmAckBtn = widget.NewButton("确认", a.acknowledgeAlarm)
+	a.alarmAckAllBtn = widget.NewButton("全部确认", a.acknowledgeAllAlarms)
+}
+
+// createAlarmLayout 创建报警页
+func (a *AppRefined) createAlarmLayout() fyne.CanvasObject {
+	header := container.NewHBox(
+		widget.NewLabel("轮询时按点位表中的报警规则判断, 报警、恢复和确认写入日志文件; 只轮询当前设置时仅判断当前点位"),
+		layout.NewSpacer(),
+		a.alarmAckBtn,
+		a.alarmAckAllBtn,
+	)
+	return container.NewBorder(header, nil, nil, nil, a.alarmTable)
+}
+
+// setAlarmRules 按点位表重建报警引擎, 原有报警随之清除
+func (a *AppRefined) setAlarmRules(db *config.TagDatabase) {
+	a.alarmEngine = alarm.NewEngine(db.Tags)
+	a.alarmSelected = ""
+	a.refreshAlarms()
+	if n := a.alarmEngine.RuleCount(); n > 0 {
+		a.appendLog(fmt.Sprintf("已加载 %d 条报警规则", n))
+	}
+}
+
+// evaluateAlarms 按当前点位的报警规则判断一次轮询结果。readStart 之后没有成功读取视为读取失败。
+// 默认轮询只读取界面当前的地址, 因此只判断当前点位; 点位表中其他点位的规则在勾选"轮询点位表"后判断,
+// 见 showTagReadings
+func (a *AppRefined) evaluateAlarms(readStart time.Time, points []recorder.Point, ok bool) {
+	tag := a.activeTag()
+	if tag == nil || !a.alarmEngine.HasRules(tag.Name) {
+		return
+	}
+
+	var events []alarm.Event
+	r := a.lastResult
+	switch {
+	case r.values == nil || r.readAt.Before(readStart):
+		events = a.alarmEngine.Fail(time.Now(), tag.Name)
+	case ok:
+		events = a.alarmEngine.Evaluate(r.readAt, tag.Name, alarmValues(points, r.values))
+	}
+	a.handleAlarmEvents(events)
+}
+
+// alarmValues 由点的工程值和未缩放的读取结果生成报警判断的输入
+func alarmValues(points []recorder.Point, rawValues interface{}) []alarm.Value {
+	raw, _ := datatypes.ToFloat64Slice(rawValues)
+	values := make([]alarm.Value, len(points))
+	for i, p := range points {
+		values[i] = alarm.Value{Point: p.Name, Value: p.Value, Raw: p.Value}
+		if len(raw) == len(points) {
+			values[i].Raw = raw[i]
+		}
+	}
+	return values
+}
+
+// handleAlarmEvents 刷新报警列表, 新报警 (提示等级除外) 发送桌面通知; 事件已由报警引擎写入日志文件
+func (a *AppRefined) handleAlarmEvents(events []alarm.Event) {
+	if len(events) == 0 {
+		return
+	}
+	for _, ev := range events {
+		if ev.Kind == alarm.EventRaised && ev.Alarm.Severity != config.SeverityInfo {
+			title := fmt.Sprintf("ModbusBaby %s报警", severityNames[ev.Alarm.Severity])
+			a.fyneApp.SendNotification(fyne.NewNotification(title, fmt.Sprintf("%s: %s", ev.Alarm.Point, ev.Alarm.Message)))
+		}
+	}
+	a.refreshAlarms()
+}
+
+// acknowledgeAlarm 确认选中的报警
+func (a *AppRefined) acknowledgeAlarm() {
+	if a.alarmSelected == "" {
+		a.appendLog("请先在报警列表中选择要确认的报警")
+		return
+	}
+	if ev, ok := a.alarmEngine.Acknowledge(time.Now(), a.alarmSelected); ok {
+		a.handleAlarmEvents([]alarm.Event{ev})
+	}
+	a.alarmSelected = ""
+	a.alarmTable.UnselectAll()
+}
+
+// acknowledgeAllAlarms 确认所有未确认的报警
+func (a *AppRefined) acknowledgeAllAlarms() {
+	a.handleAlarmEvents(a.alarmEngine.AcknowledgeAll(time.Now()))
+	a.alarmSelected = ""
+	a.alarmTable.UnselectAll()
+}
+
+// refreshAlarms 刷新报警列表, 报警页标题显示未确认的报警数
+func (a *AppRefined) refreshAlarms() {
+	if a.alarmTable == nil {
+		return
+	}
+	a.alarmList = a.alarmEngine.Alarms()
+	a.alarmTable.Refresh()
+	if a.alarmTab == nil {
+		return
+	}
+	title := "报警"
+	if n := a.alarmEngine.Unacknowledged(); n > 0 {
+		title = fmt.Sprintf("报警 (%d)", n)
+	}
+	if a.alarmTab.Text != title {
+		a.alarmTab.Text = title
+		a.infoTabs.Refresh()
+	}
+}
+
+// alarmCell 返回报警列表的单元格文本, 第0行为表头
+func (a *AppRefined) alarmCell(row, col int) string {
+	if row == 0 {
+		return alarmColumns[col].title
+	}
+	if row > len(a.alarmList) {
+		return ""
+	}
+	al := a.alarmList[row-1]
+	switch col {
+	case 0:
+		return al.State.String()
+	case 1:
+		return severityNames[al.Severity]
+	case 2:
+		return al.Point
+	case 3:
+		return al.Message
+	case 4:
+		if al.Rule.Type == config.AlarmCommLoss {
+			return fmt.Sprintf("%g 次失败", al.Value)
+		}
+		return fmt.Sprintf("%g", al.Value)
+	case 5:
+		return alarmTime(al.Raised)
+	case 6:
+		return alarmTime(al.Cleared)
+	default:
+		return alarmTime(al.Acked)
+	}
+}
+
+// alarmImportance 未确认的严重报警显示为红色, 警告显示为黄色
+func alarmImportance(al alarm.Alarm) widget.Importance {
+	if al.State != alarm.StateActive {
+		return widget.MediumImportance
+	}
+	switch al.Severity {
+	case config.SeverityCritical:
+		return widget.DangerImportance
+	case config.SeverityWarning:
+		return widget.WarningImportance
+	}
+	return widget.MediumImportance
+}
+
+func alarmTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("15:04:05")
+}
+
+// otherAlarmTags 返回点位表中除当前点位外有报警规则的点位数, 默认轮询不判断这些点位
+func (a *AppRefined) otherAlarmTags() int {
+	if a.tagDB == nil {
+		return 0
+	}
+	active := a.activeTag()
+	n := 0
+	for _, tag := range a.tagDB.Tags {
+		if len(tag.Alarms) > 0 && (active == nil || tag.Name != active.Name) {
+			n++
+		}
+	}
+	return n
+}
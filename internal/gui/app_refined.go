@@ -1,17 +1,29 @@
 package gui
 
 import (
+	"errors"
 	"fmt"
+	"modbusbaby/internal/alarm"
 	"modbusbaby/internal/config"
+	"modbusbaby/internal/gateway"
+	"modbusbaby/internal/historian"
 	"modbusbaby/internal/modbus"
+	"modbusbaby/internal/poller"
+	"modbusbaby/internal/recorder"
 	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/dissect"
+	"modbusbaby/pkg/history"
+	"modbusbaby/pkg/modicon"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	"go.bug.st/serial"
@@ -31,6 +43,8 @@ type AppRefined struct {
 	// === 连接设置区域 ===
 	connectionType *widget.Select
 	connectBtn     *widget.Button
+	gatewayBtn     *widget.Button
+	sunspecBtn     *widget.Button
 
 	// TCP设置
 	ipAddressEntry *widget.Entry
@@ -39,11 +53,17 @@ type AppRefined struct {
 
 	// RTU设置
 	serialPort *widget.Select
-	baudRate   *widget.Select
+	baudRate   *widget.SelectEntry
 	dataBits   *widget.Select
 	stopBits   *widget.Select
 	parity     *widget.Select
 
+	// RS-485 方向控制
+	rs485Check          *widget.Check
+	rtsLevelSelect      *widget.Select
+	rtsDelayBeforeInput *widget.Entry
+	rtsDelayAfterInput  *widget.Entry
+
 
 	// === 寄存器操作区域 ===
 	slaveIdTcp        *widget.Entry
@@ -55,23 +75,115 @@ type AppRefined struct {
 	byteOrderCombo    *widget.Select
 	wordOrderCombo    *widget.Select
 	valueInput        *widget.Entry
+	addressHintLabel  *widget.Label // 起始地址的等价写法
+	orderAssistBtn    *widget.Button
 	readButton        *widget.Button
 	writeButton       *widget.Button
 
+	// 用户手动选择了寄存器类型, 此后纯数字地址是该类型中的协议地址, 不按 Modicon 写法解析
+	registerTypeChosen  bool
+	syncingRegisterType bool // 程序正在切换寄存器类型
+
+	// === 缩放设置 ===
+	scaleInput      *widget.Entry
+	offsetInput     *widget.Entry
+	sfRegisterInput *widget.Entry
+	minInput        *widget.Entry
+	maxInput        *widget.Entry
+	unitInput       *widget.Entry
+	timeZoneEntry   *widget.SelectEntry
+
+	// === 字符串设置 ===
+	stringLengthInput   *widget.Entry
+	stringPaddingSelect *widget.Select
+
+	// === 设备模板 ===
+	templateSelect    *widget.Select
+	templateSaveBtn   *widget.Button
+	templateInfoLabel *widget.Label
+	templates         []*config.DeviceTemplate
+
+	// === 点位表 ===
+	tagSelect    *widget.Select
+	tagLoadBtn   *widget.Button
+	tagImportBtn *widget.Button
+	tagInfoLabel *widget.Label
+	tagDB        *config.TagDatabase
+
+	// === 位域/枚举定义 ===
+	bitFieldSelect  *widget.Select
+	bitFieldLoadBtn *widget.Button
+	bitFieldDefs    []datatypes.BitFieldDef
+
 	// === 显示区域 ===
 	logOutput             *widget.Entry
 	sentPacketDisplay     *widget.Entry
 	receivedPacketDisplay *widget.Entry
 	clearInfoButton       *widget.Button
 
+	// === 报文分析 ===
+	packetList      *widget.List
+	packetTree      *widget.Tree
+	packetBytes     *widget.RichText
+	packetInfoLabel *widget.Label
+	packetFrames    []packetFrame
+	packetSelected  int            // 选中的报文序号, 未选中时为 -1
+	packetField     *dissect.Field // 树中选中的字段
+
+	// === 数据表 ===
+	radixCheckGroup *widget.CheckGroup
+	tagTable        *widget.Table
+	tagRows         []tagRow
+	tagRadixes      []datatypes.Radix
+	tagChanges      map[string]tagChange
+	lastResult      resultSnapshot
+
+	// === 趋势 ===
+	history          *history.Store
+	trendChart       *trendChart
+	trendSeriesCheck *widget.CheckGroup
+
+	// === CSV 记录 ===
+	csvCheck       *widget.Check
+	csvStatusLabel *widget.Label
+	csvRecorder    *recorder.Recorder
+	historianCheck *widget.Check
+	historian      *historian.Historian
+
+	// === 报警 ===
+	alarmEngine    *alarm.Engine
+	alarmTable     *widget.Table
+	alarmList      []alarm.Alarm
+	alarmSelected  string
+	alarmAckBtn    *widget.Button
+	alarmAckAllBtn *widget.Button
+	alarmTab       *container.TabItem
+	infoTabs       *container.AppTabs
+
 	// === 轮询设置 ===
 	pollingIntervalInput *widget.Entry
 	startPollingButton   *widget.Button
 	stopPollingButton    *widget.Button
+	pollTagsCheck        *widget.Check
+	pollStatsLabel       *widget.Label
+	pollStatsBtn         *widget.Button
+	pollStats            []poller.Stats // 最近一次停止的轮询的统计
+
+	// === 后台读写 ===
+	ioTask      *ioTask        // 正在进行且未取消的读写
+	ioPending   int            // 仍在后台运行的读写数, 含已取消的
+	ioWait      sync.WaitGroup // 断开连接前等待后台读写结束
+	ioProgress  *widget.ProgressBarInfinite
+	ioLabel     *widget.Label
+	ioCancelBtn *widget.Button
+	ioBox       *fyne.Container
 
 	// 状态管理
-	isConnected bool
-	pollingStop chan bool
+	isConnected   bool
+	disconnecting bool
+	poller        *poller.Poller
+	gateway       *gateway.Gateway
+	gatewayToThis bool // 网关的默认路由指向当前连接, 断开时移除, 重新连接后恢复
 
 	// 从站地址字节
 	slaveIDByte byte  
@@ -91,7 +203,6 @@ func NewAppRefined(cfg *config.Config, version, author string) *AppRefined {
 		modbus:      modbus.NewClient(),
 		version:     version,
 		author:      author,
-		pollingStop: make(chan bool),
 	}
 }
 
@@ -109,6 +220,8 @@ func (a *AppRefined) initUI() {
 
 	// 设置按钮事件
 	a.connectBtn.OnTapped = a.toggleConnection
+	a.gatewayBtn.OnTapped = a.showGatewayDialog
+	a.sunspecBtn.OnTapped = a.showSunSpecDialog
 	a.readButton.OnTapped = func() {
 		if a.connectionType.Selected == "Modbus TCP" {
 			if a.slaveIdTcp.Text != "" {
@@ -148,6 +261,7 @@ func (a *AppRefined) initUI() {
 		a.startPolling(a.slaveIDByte)
 	}
 	a.stopPollingButton.OnTapped = a.stopPolling
+	a.orderAssistBtn.OnTapped = a.showOrderAssistant
 
 	a.clearInfoButton.OnTapped = a.clearAll
 
@@ -177,6 +291,8 @@ func (a *AppRefined) createUIElements() {
 	a.connectionType.SetSelected("Modbus TCP")
 
 	a.connectBtn = widget.NewButton("连接", nil)
+	a.gatewayBtn = widget.NewButton("网关", nil)
+	a.sunspecBtn = widget.NewButton("SunSpec", nil)
 
 	// === TCP设置元素 ===
 	a.ipAddressEntry = widget.NewEntry()
@@ -193,17 +309,46 @@ func (a *AppRefined) createUIElements() {
 	a.serialPort = widget.NewSelect([]string{}, nil)
 	a.serialPort.PlaceHolder = "Select Serial Port"
 
-	a.baudRate = widget.NewSelect([]string{"2400", "4800", "9600", "19200", "38400", "57600", "115200","230400"}, nil)
-	a.baudRate.SetSelected("9600")
+	// 可直接输入非标准波特率
+	a.baudRate = widget.NewSelectEntry([]string{"1200", "2400", "4800", "9600", "19200", "38400", "57600", "115200", "230400", "460800", "921600"})
+	a.baudRate.SetText(strconv.Itoa(a.config.RTU.BaudRate))
 
 	a.dataBits = widget.NewSelect([]string{"8", "7"}, nil)
 	a.dataBits.SetSelected("8")
 
-	a.stopBits = widget.NewSelect([]string{"1", "2"}, nil)
-	a.stopBits.SetSelected("1")
+	a.stopBits = widget.NewSelect([]string{"1", "1.5", "2"}, nil)
+	a.stopBits.SetSelected(strconv.FormatFloat(a.config.RTU.StopBits, 'f', -1, 64))
+
+	a.parity = widget.NewSelect([]string{"None", "Even", "Odd", "Mark", "Space"}, nil)
+	a.parity.SetSelected(a.config.RTU.Parity)
+
+	// === RS-485 方向控制元素 ===
+	a.rtsDelayBeforeInput = widget.NewEntry()
+	a.rtsDelayBeforeInput.SetText(strconv.Itoa(a.config.RTU.RS485.DelayBeforeSend))
+
+	a.rtsDelayAfterInput = widget.NewEntry()
+	a.rtsDelayAfterInput.SetText(strconv.Itoa(a.config.RTU.RS485.DelayAfterSend))
 
-	a.parity = widget.NewSelect([]string{"None", "Even", "Odd"}, nil)
-	a.parity.SetSelected("None")
+	a.rtsLevelSelect = widget.NewSelect([]string{"高电平", "低电平"}, nil)
+	if a.config.RTU.RS485.RTSOnSend {
+		a.rtsLevelSelect.SetSelected("高电平")
+	} else {
+		a.rtsLevelSelect.SetSelected("低电平")
+	}
+
+	a.rs485Check = widget.NewCheck("RS-485 (RTS 换向)", func(enabled bool) {
+		if enabled {
+			a.rtsLevelSelect.Enable()
+			a.rtsDelayBeforeInput.Enable()
+			a.rtsDelayAfterInput.Enable()
+		} else {
+			a.rtsLevelSelect.Disable()
+			a.rtsDelayBeforeInput.Disable()
+			a.rtsDelayAfterInput.Disable()
+		}
+	})
+	a.rs485Check.SetChecked(a.config.RTU.RS485.Enabled)
+	a.rs485Check.OnChanged(a.config.RTU.RS485.Enabled)
 
 	a.slaveIdRtu = widget.NewEntry()
 	a.slaveIdRtu.SetText("1")
@@ -221,24 +366,35 @@ func (a *AppRefined) createUIElements() {
 	a.registerTypeCombo.PlaceHolder = "Select Register Type"
 	a.registerTypeCombo.SetSelected("Holding Register")
 
-	a.dataTypeCombo = widget.NewSelect([]string{
-		"BYTE", "INT16", "UINT16", "INT32", "UINT32", "INT64", "UINT64",
-		"FLOAT32", "FLOAT64", "BOOL", "ASCII", "UNIX_TIMESTAMP",
-	}, nil)
+	var dataTypeNames []string
+	for _, dt := range datatypes.AllDataTypes() {
+		dataTypeNames = append(dataTypeNames, dt.String())
+	}
+	a.dataTypeCombo = widget.NewSelect(dataTypeNames, nil)
 	a.dataTypeCombo.SetSelected("UINT16")
 
 	a.byteOrderCombo = widget.NewSelect([]string{"AB", "BA"}, nil)
 	a.byteOrderCombo.SetSelected("AB")
 
-	a.wordOrderCombo = widget.NewSelect([]string{"1234", "4321"}, nil)
+	a.wordOrderCombo = widget.NewSelect([]string{"1234", "4321", "2143", "3412"}, nil)
 	a.wordOrderCombo.SetSelected("1234")
 
+	a.orderAssistBtn = widget.NewButton("识别...", nil)
+
 	a.readButton = widget.NewButton("读取", nil)
 	a.readButton.Disable()
 
+	a.createAddressElements()
+	a.createScalingElements()
+	a.createBitFieldElements()
+	a.createStringElements()
+	a.createResultsElements()
+	a.createTrendElements()
+
 	a.valueInput = widget.NewMultiLineEntry()
 	a.valueInput.Wrapping = fyne.TextWrapWord
 	a.valueInput.SetMinRowsVisible(3)
+	a.valueInput.SetPlaceHolder("如 1, 0x1F, 0b1010, 0*10, 1..5, \"文本\"")
 
 	a.writeButton = widget.NewButton("写入", nil)
 	a.writeButton.Disable()
@@ -247,11 +403,7 @@ func (a *AppRefined) createUIElements() {
 	a.logOutput = widget.NewMultiLineEntry()
 	a.logOutput.Wrapping = fyne.TextWrapWord
 
-	a.sentPacketDisplay = widget.NewMultiLineEntry()
-	a.sentPacketDisplay.Wrapping = fyne.TextWrapWord
-
-	a.receivedPacketDisplay = widget.NewMultiLineEntry()
-	a.receivedPacketDisplay.Wrapping = fyne.TextWrapWord
+	a.createPacketElements()
 
 	a.clearInfoButton = widget.NewButton("清空", nil)
 
@@ -266,6 +418,13 @@ func (a *AppRefined) createUIElements() {
 	a.stopPollingButton = widget.NewButton("停止轮询", nil)
 	a.stopPollingButton.Disable()
 
+	a.createPollingElements()
+	a.createIOElements()
+	a.createRecorderElements()
+	a.createAlarmElements()
+	a.createTemplateElements()
+	a.createTagElements() // 点位表加载结果输出到日志, 须在日志区域创建之后
+
 	a.populateSerialPorts() // Populate serial ports after all UI elements are created
 }
 
@@ -295,6 +454,8 @@ func (a *AppRefined) addSettingsArea() fyne.CanvasObject {
 		widget.NewLabel("连接类型:"),
 		a.connectionType,
 		layout.NewSpacer(),
+		a.sunspecBtn,
+		a.gatewayBtn,
 		a.connectBtn,
 	)
 
@@ -315,7 +476,12 @@ func (a *AppRefined) addSettingsArea() fyne.CanvasObject {
 		}
 	}
 
+	templateLayout := a.createTemplateLayout()
+	tagLayout := a.createTagLayout()
 	registerLayout := a.createRegisterLayout()
+	scalingLayout := a.createScalingLayout()
+	bitFieldLayout := a.createBitFieldLayout()
+	stringLayout := a.createStringLayout()
 
 	valueLayout := container.NewBorder(
 		nil, nil, widget.NewLabel("数值:"), a.writeButton, a.valueInput,
@@ -324,7 +490,12 @@ func (a *AppRefined) addSettingsArea() fyne.CanvasObject {
 	settingsContent := container.NewVBox(
 		connectionLayout,
 		settingsContainer,
+		templateLayout,
+		tagLayout,
 		registerLayout,
+		scalingLayout,
+		bitFieldLayout,
+		stringLayout,
 		valueLayout,
 	)
 
@@ -356,8 +527,11 @@ func (a *AppRefined) createRTUSettingsLayout() fyne.CanvasObject {
 	stopBitsContainer := container.New(&fixedWidthLayout{width: 70}, a.stopBits)
 	parityContainer := container.New(&fixedWidthLayout{width: 90}, a.parity)
 	slaveIDContainer := container.New(&fixedWidthLayout{width: 80}, a.slaveIdRtu)
+	rtsLevelContainer := container.New(&fixedWidthLayout{width: 100}, a.rtsLevelSelect)
+	delayBeforeContainer := container.New(&fixedWidthLayout{width: 70}, a.rtsDelayBeforeInput)
+	delayAfterContainer := container.New(&fixedWidthLayout{width: 70}, a.rtsDelayAfterInput)
 
-	return container.NewHBox(
+	serialRow := container.NewHBox(
 		widget.NewLabel("串口:"),
 		serialPortContainer, // Min width
 		widget.NewLabel("波特率:"),
@@ -372,6 +546,19 @@ func (a *AppRefined) createRTUSettingsLayout() fyne.CanvasObject {
 		slaveIDContainer, // Fixed width
 		layout.NewSpacer(),
 	)
+
+	rs485Row := container.NewHBox(
+		a.rs485Check,
+		widget.NewLabel("发送时RTS:"),
+		rtsLevelContainer, // Fixed width
+		widget.NewLabel("发送前延时 (ms):"),
+		delayBeforeContainer, // Fixed width
+		widget.NewLabel("发送后延时 (ms):"),
+		delayAfterContainer, // Fixed width
+		layout.NewSpacer(),
+	)
+
+	return container.NewVBox(serialRow, rs485Row)
 }
 
 // createRegisterLayout 创建寄存器操作行
@@ -395,6 +582,8 @@ func (a *AppRefined) createRegisterLayout() fyne.CanvasObject {
 		byteOrderContainer, // Fixed
 		widget.NewLabel("字序:"),
 		wordOrderContainer, // Fixed
+		a.orderAssistBtn,
+		a.addressHintLabel,
 		layout.NewSpacer(),
 		a.readButton,
 	)
@@ -408,19 +597,15 @@ func (a *AppRefined) addDisplayArea() fyne.CanvasObject {
 		a.clearInfoButton,
 	)
 	infoContainer := container.NewBorder(infoHeader, nil, nil, nil, a.logOutput)
-
-	sentWithLabel := container.NewBorder(
-		container.NewHBox(widget.NewLabel("发送的报文:")), nil, nil, nil, a.sentPacketDisplay,
+	a.alarmTab = container.NewTabItem("报警", a.createAlarmLayout())
+	a.infoTabs = container.NewAppTabs(
+		container.NewTabItem("数据表", a.createResultsLayout()),
+		container.NewTabItem("趋势", a.createTrendLayout()),
+		a.alarmTab,
+		container.NewTabItem("信息", infoContainer),
 	)
 
-	receivedWithLabel := container.NewBorder(
-		container.NewHBox(widget.NewLabel("接收的报文:")), nil, nil, nil, a.receivedPacketDisplay,
-	)
-
-	packetSplitter := container.NewHSplit(sentWithLabel, receivedWithLabel)
-	packetSplitter.SetOffset(0.5)
-
-	mainSplitter := container.NewVSplit(infoContainer, packetSplitter)
+	mainSplitter := container.NewVSplit(a.infoTabs, a.createPacketLayout())
 	mainSplitter.SetOffset(0.6)
 
 	return mainSplitter
@@ -430,11 +615,16 @@ func (a *AppRefined) addDisplayArea() fyne.CanvasObject {
 func (a *AppRefined) addPollingSettings() fyne.CanvasObject {
 	pollingIntervalContainer := container.New(&minWidthLayout{width: 120}, a.pollingIntervalInput)
 	return container.NewHBox(
+		a.ioBox,
 		layout.NewSpacer(),
 		widget.NewLabel("轮询间隔 (ms):"),
 		pollingIntervalContainer, 
+		a.pollTagsCheck,
 		a.startPollingButton,
 		a.stopPollingButton,
+		a.pollStatsLabel,
+		a.pollStatsBtn,
+		a.createRecorderLayout(),
 		layout.NewSpacer(),
 	)
 }
@@ -454,296 +644,528 @@ func (a *AppRefined) toggleConnection() {
 	}
 }
 
+// connectToDevice 按界面设置在后台连接设备, 取消后如连接已建立则立即断开
 func (a *AppRefined) connectToDevice() {
-	a.connectBtn.SetText("连接中...")
-	a.connectBtn.Disable()
+	if a.ioPending > 0 {
+		a.appendLog("上一次操作仍在等待设备响应，请稍后再连接。")
+		return
+	}
 
-	connType := a.connectionType.Selected
-	var err error
-	switch connType {
+	var connect func() error
+	switch connType := a.connectionType.Selected; connType {
 	case "Modbus TCP":
 		ip := a.ipAddressEntry.Text
 		port, _ := strconv.Atoi(a.portEntry.Text)
-		err = a.modbus.ConnectTCP(ip, port, )
+		connect = func() error { return a.modbus.ConnectTCP(ip, port) }
 	case "Modbus RTU":
-		portName := a.serialPort.Selected
-		baudRate, _ := strconv.Atoi(a.baudRate.Selected)
-		dataBits, _ := strconv.Atoi(a.dataBits.Selected)
-		stopBits, _ := strconv.Atoi(a.stopBits.Selected)
-		parity := a.parity.Selected
-		err = a.modbus.ConnectRTU(portName, baudRate, dataBits, stopBits, parity)
+		rtuCfg, err := a.rtuConfigFromUI()
+		if err != nil {
+			a.appendLog(fmt.Sprintf("连接失败: %v", err))
+			return
+		}
+		a.config.RTU = rtuCfg
+		connect = func() error { return a.modbus.ConnectRTU(rtuCfg) }
 	default:
-		err = fmt.Errorf("未知连接类型: %s", connType)
+		a.appendLog(fmt.Sprintf("连接失败: 未知连接类型: %s", connType))
+		return
 	}
 
-	if err != nil {
-		a.appendLog(fmt.Sprintf("连接失败: %v", err))
-		a.isConnected = false
-	} else {
-		a.appendLog("Connection successful!")
-		a.isConnected = true
+	var err error
+	a.runIO("正在连接", func() {
+		err = connect()
+	}, func(cancelled bool) {
+		switch {
+		case cancelled:
+			if err == nil {
+				a.modbus.Disconnect()
+			}
+		case err != nil:
+			a.appendLog(fmt.Sprintf("连接失败: %v", err))
+		default:
+			a.appendLog("Connection successful!")
+			a.isConnected = true
+			if a.gateway != nil && a.gatewayToThis {
+				a.gateway.SetDefaultRoute(a.modbus)
+				a.appendLog("网关恢复转发到当前连接。")
+			}
+		}
+		a.updateConnectionStateUI()
+	})
+}
+
+// rtuConfigFromUI 根据界面输入生成RTU配置
+func (a *AppRefined) rtuConfigFromUI() (config.RTUConfig, error) {
+	cfg := a.config.RTU
+	cfg.Port = a.serialPort.Selected
+
+	baudRate, err := strconv.Atoi(strings.TrimSpace(a.baudRate.Text))
+	if err != nil || baudRate <= 0 {
+		return cfg, fmt.Errorf("波特率无效: %s", a.baudRate.Text)
+	}
+	cfg.BaudRate = baudRate
+	cfg.DataBits, _ = strconv.Atoi(a.dataBits.Selected)
+	cfg.StopBits, _ = strconv.ParseFloat(a.stopBits.Selected, 64)
+	cfg.Parity = a.parity.Selected
+	if slaveID, err := strconv.Atoi(a.slaveIdRtu.Text); err == nil {
+		cfg.SlaveID = slaveID
 	}
 
-	a.updateConnectionStateUI()
+	cfg.RS485.Enabled = a.rs485Check.Checked
+	cfg.RS485.RTSOnSend = a.rtsLevelSelect.Selected != "低电平"
+	if cfg.RS485.Enabled {
+		before, err := strconv.Atoi(a.rtsDelayBeforeInput.Text)
+		if err != nil || before < 0 {
+			return cfg, fmt.Errorf("发送前延时无效: %s", a.rtsDelayBeforeInput.Text)
+		}
+		after, err := strconv.Atoi(a.rtsDelayAfterInput.Text)
+		if err != nil || after < 0 {
+			return cfg, fmt.Errorf("发送后延时无效: %s", a.rtsDelayAfterInput.Text)
+		}
+		cfg.RS485.DelayBeforeSend = before
+		cfg.RS485.DelayAfterSend = after
+	}
+	return cfg, nil
 }
 
+// disconnectFromDevice 先停止轮询, 等后台读写 (含已取消的) 结束后断开连接
 func (a *AppRefined) disconnectFromDevice() {
-	if a.modbus.IsConnected() {
-		err := a.modbus.Disconnect()
-		if err != nil {
-			a.appendLog(fmt.Sprintf("断开连接失败: %v", err))
-		} else {
-			a.appendLog("连接已断开。")
-		}
-	}
-	a.isConnected = false
-	a.stopPolling() // Stop polling when disconnected
+	a.disconnecting = true
 	a.updateConnectionStateUI()
+	a.stopPollingThen(func() {
+		gw := a.gateway
+		if gw != nil && a.gatewayToThis {
+			a.appendLog("网关暂停转发到当前连接。")
+		}
+		go func() {
+			// 与轮询一样, 先让网关不再使用当前连接并等待已转发的请求结束
+			if gw != nil {
+				gw.RemoveUpstream(a.modbus)
+			}
+			a.ioWait.Wait()
+			var err error
+			connected := a.modbus.IsConnected()
+			if connected {
+				err = a.modbus.Disconnect()
+			}
+			fyne.Do(func() {
+				if err != nil {
+					a.appendLog(fmt.Sprintf("断开连接失败: %v", err))
+				} else if connected {
+					a.appendLog("连接已断开。")
+				}
+				a.disconnecting = false
+				a.isConnected = false
+				a.updateConnectionStateUI()
+			})
+		}()
+	})
 }
 
+// updateConnectionStateUI 按连接、读写和轮询状态启用或禁用按钮
 func (a *AppRefined) updateConnectionStateUI() {
-	if a.isConnected {
+	busy := a.ioBusy() || a.disconnecting
+	polling := a.poller != nil
+	switch {
+	case a.disconnecting:
+		a.connectBtn.SetText("断开中...")
+	case a.isConnected:
 		a.connectBtn.SetText("断开")
-		a.connectBtn.Enable()
-		a.readButton.Enable()
-		a.writeButton.Enable()
-		a.startPollingButton.Enable()
-		a.stopPollingButton.Disable() // Initially disable stop polling
-	} else {
+	case busy:
+		a.connectBtn.SetText("连接中...")
+	default:
 		a.connectBtn.SetText("连接")
-		a.connectBtn.Enable()
-		a.readButton.Disable()
-		a.writeButton.Disable()
-		a.startPollingButton.Disable()
-		a.stopPollingButton.Disable()
 	}
+	setEnabled(a.connectBtn, !busy)
+	setEnabled(a.readButton, a.isConnected && !busy)
+	setEnabled(a.writeButton, a.isConnected && !busy)
+	setEnabled(a.startPollingButton, a.isConnected && !a.disconnecting && !polling)
+	setEnabled(a.stopPollingButton, polling)
 	a.window.Content().Refresh()
 }
 
+// setEnabled 启用或禁用按钮
+func setEnabled(btn *widget.Button, enabled bool) {
+	if enabled {
+		btn.Enable()
+	} else {
+		btn.Disable()
+	}
+}
+
+// readRequest 一次读取的设置, 在界面线程中收集, 读取本身在后台进行
+type readRequest struct {
+	slaveID  byte
+	start    modicon.Address
+	count    int // 寄存器/线圈数, 带位号的地址为位数
+	regType  string
+	dataType datatypes.DataType
+	scaling  datatypes.Scaling
+	bitField *datatypes.BitFieldDef
+}
+
+// readResult 后台读取的结果
+type readResult struct {
+	scaling        datatypes.Scaling // 已读取SF寄存器的缩放设置
+	scalingErr     error
+	values         interface{}
+	registers      []uint16
+	bits           []bool
+	err            error
+	exchanges      []modbus.Exchange // 本次读取的所有事务, 含SF寄存器的读取
+}
+
+// readRegister 按界面设置在后台读取, 完成后显示结果
 func (a *AppRefined) readRegister(slaveIDByte byte) {
+	req, ok := a.readRequestFromUI(slaveIDByte)
+	if !ok {
+		return
+	}
+	var res readResult
+	a.runIO("正在读取", func() {
+		res = a.execRead(req)
+	}, func(cancelled bool) {
+		if !cancelled {
+			a.showRead(req, res)
+		}
+	})
+}
+
+// readRequestFromUI 检查界面上的读取设置, 设置无效时输出原因并返回 false
+func (a *AppRefined) readRequestFromUI(slaveIDByte byte) (readRequest, bool) {
+	req := readRequest{slaveID: slaveIDByte}
 	if !a.modbus.IsConnected() {
 		a.appendLog("设备未连接，无法读取寄存器。")
-		return
+		return req, false
 	}
-	startAddr, err := strconv.ParseUint(a.startAddressInput.Text, 10, 16)
+	start, err := a.parseStartAddress()
 	if err != nil {
-		a.appendLog(fmt.Sprintf("起始地址无效: %v", err))
-		return
+		a.appendLog(err.Error())
+		return req, false
 	}
-	endAddr, err := strconv.ParseUint(a.endAddressInput.Text, 10, 16)
+	end, err := a.parseEndAddress(start)
 	if err != nil {
-		a.appendLog(fmt.Sprintf("结束地址无效: %v", err))
-		return
+		a.appendLog(err.Error())
+		return req, false
+	}
+	req.start = start
+	req.regType = a.registerTypeCombo.Selected
+	if start.HasBit {
+		if req.count, err = bitCount(start, end); err != nil {
+			a.appendLog(err.Error())
+			return req, false
+		}
+		a.appendLog(fmt.Sprintf("正在读取: %s 的位, 地址: %s, 位数: %d", req.regType, start, req.count))
+		return req, true
 	}
 
-	if endAddr < startAddr {
+	if end.Offset < start.Offset {
 		a.appendLog("结束地址不能小于起始地址。")
-		return
+		return req, false
+	}
+	req.count = int(end.Offset-start.Offset) + 1
+	req.dataType = stringToDataType(a.dataTypeCombo.Selected)
+
+	isRegister := req.regType == "Holding Register" || req.regType == "Input Register"
+	if isRegister {
+		if req.scaling, err = a.scalingFromUI(); err != nil {
+			a.appendLog(fmt.Sprintf("缩放设置无效: %v", err))
+			return req, false
+		}
+		req.bitField = a.selectedBitField()
 	}
-	count := uint16(endAddr - startAddr + 1)
 
-	regType := a.registerTypeCombo.Selected
-	dataTypeStr := a.dataTypeCombo.Selected
-	dataType := stringToDataType(dataTypeStr)
+	if err := a.applyStringOptions(req.dataType); err != nil {
+		a.appendLog(err.Error())
+		return req, false
+	}
 
-	var result interface{}
-	var readErr error
+	if tag := a.activeTag(); tag != nil {
+		if !tag.Readable() {
+			a.appendLog(fmt.Sprintf("点位 %s 不可读", tag.Name))
+			return req, false
+		}
+		a.appendLog(fmt.Sprintf("正在读取点位: %s", tag.Name))
+	}
+	a.appendLog(fmt.Sprintf("正在读取: %s, 地址: %d, 数量: %d", req.regType, start.Offset, req.count))
+	return req, true
+}
 
-	a.appendLog(fmt.Sprintf("正在读取: %s, 地址: %d, 数量: %d", regType, startAddr, count))
+// execRead 执行读取, 原始寄存器和报文都取自本次读取的事务, 不受同时进行的轮询影响。在后台执行, 不访问界面
+func (a *AppRefined) execRead(req readRequest) (res readResult) {
+	trace := &modbus.Trace{}
+	client := a.modbus.WithTrace(trace)
+	defer func() {
+		res.exchanges = trace.Exchanges
+	}()
+	address, count := req.start.Offset, uint16(req.count)
+	if req.start.HasBit {
+		res.bits, res.err = client.ReadRegisterBits(req.slaveID, req.start.Table == modicon.InputRegister, address, req.start.Bit, req.count)
+		return res
+	}
 
-	switch regType {
-	case "Holding Register":
-		result, readErr = a.modbus.ReadHoldingRegisters(slaveIDByte, uint16(startAddr), count, dataType)
-	case "Input Register":
-		result, readErr = a.modbus.ReadInputRegisters(slaveIDByte, uint16(startAddr), count, dataType)
+	if res.scaling, res.scalingErr = a.resolveScaling(client, req.slaveID, req.regType, req.scaling); res.scalingErr != nil {
+		return res
+	}
+
+	switch req.regType {
+	case "Holding Register", "Input Register":
+		res.registers, res.err = client.ReadRawRegisters(req.slaveID, req.regType == "Input Register", address, count)
+		if res.err == nil {
+			res.values, res.err = client.ConvertRegisters(res.registers, req.dataType)
+		}
 	case "Coil":
-		result, readErr = a.modbus.ReadCoils(slaveIDByte, uint16(startAddr), count)
+		res.values, res.err = client.ReadCoils(req.slaveID, address, count)
 	case "Discrete Input":
-		result, readErr = a.modbus.ReadDiscreteInputs(slaveIDByte, uint16(startAddr), count)
+		res.values, res.err = client.ReadDiscreteInputs(req.slaveID, address, count)
 	default:
-		readErr = fmt.Errorf("不支持的寄存器类型: %s", regType)
+		res.err = fmt.Errorf("不支持的寄存器类型: %s", req.regType)
 	}
+	return res
+}
 
-	if readErr != nil {
-		a.appendLog(fmt.Sprintf("读取失败: %v", readErr))
+// showRead 输出读取结果, 刷新数据表和报文显示区
+func (a *AppRefined) showRead(req readRequest, res readResult) {
+	if req.start.HasBit {
+		if res.err != nil {
+			a.appendLog(fmt.Sprintf("读取失败: %v", res.err))
+		} else {
+			a.logRegisterBits(req.start, res.bits)
+		}
+		a.appendExchanges(res.exchanges)
+		return
+	}
+	if res.scalingErr != nil {
+		a.appendLog(fmt.Sprintf("缩放设置无效: %v", res.scalingErr))
+		a.appendExchanges(res.exchanges)
+		return
+	}
+	a.logScaleFactor(res.scaling)
+
+	// 寄存器数值按缩放设置换算为工程值, 原始值保留给读取结果表格的其他进制列
+	result := res.values
+	scaling := res.scaling
+	isRegister := req.regType == "Holding Register" || req.regType == "Input Register"
+	var scaledValues []float64
+	if res.err == nil && isRegister && !scaling.IsIdentity() {
+		if scaled, err := scaling.ApplyValues(result); err == nil {
+			result = scaled
+			scaledValues = scaled
+		} else {
+			a.appendLog(fmt.Sprintf("缩放未应用: %v", err))
+		}
+	}
+	unit := scaling.Unit
+
+	if res.err != nil {
+		a.appendLog(fmt.Sprintf("读取失败: %v", res.err))
 	} else {
-		a.appendLog(fmt.Sprintf("读取成功: %v", result))
-		// Format and display the result in valueInput
-		displayValue := ""
-		switch v := result.(type) {
-		case []uint16:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatUint(uint64(val), 10)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []int16:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatInt(int64(val), 10)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []int32:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatInt(int64(val), 10)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []uint32:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatUint(uint64(val), 10)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []int64:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatInt(int64(val), 10)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []uint64:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatUint(uint64(val), 10)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []float32:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatFloat(float64(val), 'f', -1, 32)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []float64:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatFloat(val, 'f', -1, 64)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case []bool:
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				strValues[i] = strconv.FormatBool(val)
-			}
-			displayValue = strings.Join(strValues, ",")
-		case string: // ASCII
-			displayValue = v
-		default:
-			displayValue = fmt.Sprintf("%v", result) // Fallback for unknown types
+		if unit != "" {
+			a.appendLog(fmt.Sprintf("读取成功: %v %s", result, unit))
+		} else {
+			a.appendLog(fmt.Sprintf("读取成功: %v", result))
+		}
+		a.showResults(req.slaveID, req.regType, int(req.start.Offset), res.values, res.registers, req.dataType, scaledValues, unit)
+
+		if req.bitField != nil && scaling.IsIdentity() {
+			a.logBitFieldStates(req.bitField, req.dataType, result)
 		}
-		a.valueInput.SetText(displayValue)
 	}
+	a.appendExchanges(res.exchanges)
+}
+
+// writeRequest 一次写入的设置, 在界面线程中收集并解析, 写入本身在后台进行
+type writeRequest struct {
+	slaveID  byte
+	start    modicon.Address
+	regType  string
+	dataType datatypes.DataType
+	valueStr string
+	scaling  datatypes.Scaling
+	bitField *datatypes.BitFieldDef // 按 "字段=标签" 写入的位域
+	bit      bool                   // 带位号地址的写入值
+	coils    []bool
+}
 
-	sent, received := a.modbus.GetLastPackets()
-	timestamp := time.Now().Format("15:04:05.000")
-	a.sentPacketDisplay.SetText(a.sentPacketDisplay.Text + fmt.Sprintf("[%s] Sent: %X\n", timestamp, sent))
-	a.receivedPacketDisplay.SetText(a.receivedPacketDisplay.Text + fmt.Sprintf("[%s] Received: %X\n", timestamp, received))
+// writeResult 后台写入的结果
+type writeResult struct {
+	scaling        datatypes.Scaling
+	message        string // 写入位或位域的说明
+	parseErr       error  // 数值解析失败, 未写入
+	err            error
+	exchanges      []modbus.Exchange // 本次写入的所有事务, 含SF寄存器和读-改-写的读取
 }
 
+// writeRegister 按界面设置在后台写入
 func (a *AppRefined) writeRegister(slaveIDByte byte) {
-	if !a.modbus.IsConnected() {
-		a.appendLog("设备未连接，无法写入寄存器。")
+	req, ok := a.writeRequestFromUI(slaveIDByte)
+	if !ok {
 		return
 	}
-	regType := a.registerTypeCombo.Selected
-	dataTypeStr := a.dataTypeCombo.Selected
-	dataType := stringToDataType(dataTypeStr)
-	valueStr := a.valueInput.Text
+	var res writeResult
+	a.runIO("正在写入", func() {
+		res = a.execWrite(req)
+	}, func(cancelled bool) {
+		if !cancelled {
+			a.showWrite(res)
+		}
+	})
+}
 
-	startAddr, err := strconv.ParseUint(a.startAddressInput.Text, 10, 16)
+// writeRequestFromUI 检查并解析界面上的写入设置, 无效时输出原因并返回 false
+func (a *AppRefined) writeRequestFromUI(slaveIDByte byte) (writeRequest, bool) {
+	req := writeRequest{slaveID: slaveIDByte}
+	if !a.modbus.IsConnected() {
+		a.appendLog("设备未连接，无法写入寄存器。")
+		return req, false
+	}
+	start, err := a.parseStartAddress()
 	if err != nil {
-		a.appendLog(fmt.Sprintf("起始地址无效: %v", err))
-		return
+		a.appendLog(err.Error())
+		return req, false
+	}
+	req.start = start
+	req.regType = a.registerTypeCombo.Selected
+	req.dataType = stringToDataType(a.dataTypeCombo.Selected)
+	req.valueStr = a.valueInput.Text
+
+	if err := a.applyStringOptions(req.dataType); err != nil {
+		a.appendLog(err.Error())
+		return req, false
 	}
 
-	var writeErr error
-	a.appendLog(fmt.Sprintf("正在写入: %s, 地址: %d", regType, startAddr))
+	if tag := a.activeTag(); tag != nil {
+		if !tag.Writable() {
+			a.appendLog(fmt.Sprintf("点位 %s 为只读", tag.Name))
+			return req, false
+		}
+		a.appendLog(fmt.Sprintf("正在写入点位: %s", tag.Name))
+	}
+	a.appendLog(fmt.Sprintf("正在写入: %s, 地址: %d", req.regType, start.Offset))
 
-	switch regType {
+	switch req.regType {
 	case "Holding Register":
-		values, err := datatypes.ParseStringToType(valueStr, dataType)
-		if err != nil {
+		if start.HasBit {
+			if req.bit, err = parseBitValue(req.valueStr); err != nil {
+				a.appendLog(fmt.Sprintf("写入失败: %v", err))
+				a.highlightParseError(err)
+				return req, false
+			}
+			break
+		}
+		if def := a.selectedBitField(); def != nil && strings.Contains(req.valueStr, "=") {
+			req.bitField = def
+			break
+		}
+		if req.scaling, err = a.scalingFromUI(); err != nil {
 			a.appendLog(fmt.Sprintf("解析数值失败: %v", err))
-			return
+			return req, false
 		}
-		writeErr = a.modbus.WriteHoldingRegisters(slaveIDByte, uint16(startAddr), values)
 	case "Coil":
-		values, err := datatypes.ParseStringToType(valueStr, dataType)
+		// 线圈始终按布尔解析, 支持 on/off/1/0
+		values, err := datatypes.ParseStringToType(req.valueStr, datatypes.BOOL)
 		if err != nil {
 			a.appendLog(fmt.Sprintf("解析线圈数值失败: %v", err))
-			return
+			a.highlightParseError(err)
+			return req, false
 		}
 		boolValues, ok := values.([]bool)
 		if !ok {
 			a.appendLog(fmt.Sprintf("内部错误: 无法将解析结果转换为 []bool 类型: %T", values))
-			return
+			return req, false
 		}
-		writeErr = a.modbus.WriteCoils(slaveIDByte, uint16(startAddr), boolValues)
+		req.coils = boolValues
 	default:
-		writeErr = fmt.Errorf("不支持的写入寄存器类型: %s", regType)
+		a.appendLog(fmt.Sprintf("写入失败: 不支持的写入寄存器类型: %s", req.regType))
+		return req, false
 	}
+	return req, true
+}
 
-	if writeErr != nil {
-		a.appendLog(fmt.Sprintf("写入失败: %v", writeErr))
-	} else {
-		a.appendLog("写入成功！")
+// execWrite 执行写入, 启用缩放时先读取SF寄存器再换算; 报文取自本次写入的事务。在后台执行, 不访问界面
+func (a *AppRefined) execWrite(req writeRequest) (res writeResult) {
+	trace := &modbus.Trace{}
+	client := a.modbus.WithTrace(trace)
+	defer func() {
+		res.exchanges = trace.Exchanges
+	}()
+	address := req.start.Offset
+	switch {
+	case req.regType == "Coil":
+		res.err = client.WriteCoils(req.slaveID, address, req.coils)
+	case req.start.HasBit:
+		res.message, res.err = a.writeRegisterBit(client, req.slaveID, req.start, req.bit)
+	case req.bitField != nil:
+		res.message, res.err = a.writeBitField(client, req.slaveID, address, req.bitField, req.valueStr)
+	default:
+		if res.scaling, res.parseErr = a.resolveScaling(client, req.slaveID, req.regType, req.scaling); res.parseErr != nil {
+			return res
+		}
+		var values interface{}
+		if values, res.parseErr = parseWriteValues(req.valueStr, req.dataType, res.scaling); res.parseErr != nil {
+			return res
+		}
+		res.err = client.WriteHoldingRegisters(req.slaveID, address, values, req.dataType)
 	}
-
-	sent, received := a.modbus.GetLastPackets()
-	timestamp := time.Now().Format("15:04:05.000")
-	a.sentPacketDisplay.SetText(a.sentPacketDisplay.Text + fmt.Sprintf("[%s] Sent: %X\n", timestamp, sent))
-	a.receivedPacketDisplay.SetText(a.receivedPacketDisplay.Text + fmt.Sprintf("[%s] Received: %X\n", timestamp, received))
+	return res
 }
 
-func (a *AppRefined) startPolling(slaveIDByte byte) {
-	if !a.isConnected {
-		a.appendLog("设备未连接，无法开始轮询。")
+// showWrite 输出写入结果和报文
+func (a *AppRefined) showWrite(res writeResult) {
+	if res.parseErr != nil {
+		a.appendLog(fmt.Sprintf("解析数值失败: %v", res.parseErr))
+		a.highlightParseError(res.parseErr)
 		return
 	}
-	intervalStr := a.pollingIntervalInput.Text
-	intervalMs, err := strconv.Atoi(intervalStr)
-	if err != nil || intervalMs <= 0 {
-		//a.appendLog("轮询间隔无效，请输入正整数。")
-		return
+	a.logScaleFactor(res.scaling)
+	if res.message != "" {
+		a.appendLog(res.message)
 	}
-	// 确保没有重复的轮询goroutine
-	if a.pollingStop != nil {
-		a.stopPolling()
+	if res.err != nil {
+		a.appendLog(fmt.Sprintf("写入失败: %v", res.err))
+	} else {
+		a.appendLog("写入成功！")
 	}
-	a.pollingStop = make(chan bool)
+	a.appendExchanges(res.exchanges)
+}
 
-	a.appendLog(fmt.Sprintf("开始轮询，间隔 %d ms...", intervalMs))
-	a.startPollingButton.Disable()
-	a.stopPollingButton.Enable()
-
-	go func() {
-		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-a.pollingStop:
-				a.appendLog("轮询已停止。")
-				return
-			case <-ticker.C:
-				// 执行读取操作
-				a.readRegister(slaveIDByte) // Re-use existing read logic
-			}
-		}
-	}()	
+// parseWriteValues 解析待写入的数值, 启用缩放时输入为工程值并换算回原始值
+func parseWriteValues(valueStr string, dataType datatypes.DataType, scaling datatypes.Scaling) (interface{}, error) {
+	if scaling.IsIdentity() {
+		return datatypes.ParseStringToType(valueStr, dataType)
+	}
 
+	parsed, err := datatypes.ParseStringToType(valueStr, datatypes.FLOAT64)
+	if err != nil {
+		return nil, err
+	}
+	return scaling.InverseValues(parsed.([]float64), dataType)
 }
 
-func (a *AppRefined) stopPolling(	) {
-	if a.pollingStop == nil {
-		a.startPollingButton.Enable()
-		a.stopPollingButton.Disable()
+// highlightParseError 选中数值输入框中解析出错的记号
+func (a *AppRefined) highlightParseError(err error) {
+	var parseErr *datatypes.ParseError
+	if !errors.As(err, &parseErr) {
+		return
 	}
+	a.window.Canvas().Focus(a.valueInput)
+	a.valueInput.CursorRow = parseErr.Line - 1
+	a.valueInput.CursorColumn = parseErr.Column - 1
+	a.valueInput.Refresh()
+
+	// Entry 没有设置选区的接口, 按住 Shift 向右移动光标来选中记号
+	shift := &fyne.KeyEvent{Name: desktop.KeyShiftLeft}
+	a.valueInput.KeyDown(shift)
+	for i := utf8.RuneCountInString(parseErr.Token); i > 0; i-- {
+		a.valueInput.TypedKey(&fyne.KeyEvent{Name: fyne.KeyRight})
+	}
+	a.valueInput.KeyUp(shift)
 }
 
 func (a *AppRefined) clearAll() {
 	a.logOutput.SetText("")
 	a.sentPacketDisplay.SetText("")
 	a.receivedPacketDisplay.SetText("")
+	a.clearPackets()
 }
 
 func (a *AppRefined) appendLog(message string) {
@@ -792,56 +1214,18 @@ func (a *AppRefined) populateSerialPorts() {
 // Helper functions for string to enum conversion
 
 func stringToByteOrder(s string) datatypes.ByteOrder {
-	switch s {
-	case "AB":
-		return datatypes.AB
-	case "BA":
-		return datatypes.BA
-	default:
-		return datatypes.AB // Default to AB
-	}
+	byteOrder, _ := datatypes.ParseByteOrder(s) // Default to AB
+	return byteOrder
 }
 
 func stringToWordOrder(s string) datatypes.WordOrder {
-	switch s {
-	case "1234":
-		return datatypes.WORD_1234
-	case "4321":
-		return datatypes.WORD_4321
-	default:
-		return datatypes.WORD_1234 // Default to 1234
-	}
+	wordOrder, _ := datatypes.ParseWordOrder(s) // Default to 1234
+	return wordOrder
 }
 
 func stringToDataType(s string) datatypes.DataType {
-	switch s {
-	case "BYTE":
-		return datatypes.BYTE
-	case "INT16":
-		return datatypes.INT16
-	case "UINT16":
-		return datatypes.UINT16
-	case "INT32":
-		return datatypes.INT32
-	case "UINT32":
-		return datatypes.UINT32
-	case "INT64":
-		return datatypes.INT64
-	case "UINT64":
-		return datatypes.UINT64
-	case "FLOAT32":
-		return datatypes.FLOAT32
-	case "FLOAT64":
-		return datatypes.FLOAT64
-	case "BOOL":
-		return datatypes.BOOL
-	case "ASCII":
-		return datatypes.ASCII
-	case "UNIX_TIMESTAMP":
-		return datatypes.UNIX_TIMESTAMP
-	default:
-		return datatypes.UINT16 // Default to UINT16
-	}
+	dataType, _ := datatypes.ParseDataType(s) // Default to UINT16
+	return dataType
 }
 
 
@@ -0,0 +1,87 @@
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 设备读写都在后台 goroutine 中进行, 界面线程只负责收集设置和显示结果, 超时不会卡住窗口。
+// 结果通过 fyne.Do 回到界面线程; 同一时间只有一个未取消的读写, 期间禁用读写和连接按钮。
+// 底层库无法中止已发出的请求, 取消只是放弃结果: 之后的请求要等设备响应或超时后才会发出。
+
+// ioTask 一次后台读写
+type ioTask struct {
+	title     string
+	cancelled bool // 只在界面线程中读写
+}
+
+// createIOElements 创建后台读写的进度显示和取消按钮, 空闲时隐藏
+func (a *AppRefined) createIOElements() {
+	a.ioProgress = widget.NewProgressBarInfinite()
+	a.ioProgress.Stop()
+	a.ioLabel = widget.NewLabel("")
+	a.ioCancelBtn = widget.NewButton("取消", a.cancelIO)
+	a.ioBox = container.NewHBox(
+		container.New(&fixedWidthLayout{width: 120}, a.ioProgress),
+		a.ioLabel,
+		a.ioCancelBtn,
+	)
+	a.ioBox.Hide()
+}
+
+// runIO 在后台执行 work, 完成后在界面线程中调用 done。cancelled 为 true 表示用户已取消,
+// 这时界面已恢复, done 不应再显示结果。work 中不能访问界面元素
+func (a *AppRefined) runIO(title string, work func(), done func(cancelled bool)) {
+	task := &ioTask{title: title}
+	a.ioTask = task
+	a.ioPending++
+	a.ioWait.Add(1)
+	a.showIOProgress(title)
+	a.updateConnectionStateUI()
+
+	go func() {
+		defer a.ioWait.Done()
+		work()
+		fyne.Do(func() {
+			a.ioPending--
+			if a.ioTask == task {
+				a.ioTask = nil
+				a.hideIOProgress()
+				a.updateConnectionStateUI()
+			}
+			done(task.cancelled)
+		})
+	}()
+}
+
+// cancelIO 放弃正在进行的读写并恢复界面
+func (a *AppRefined) cancelIO() {
+	task := a.ioTask
+	if task == nil {
+		return
+	}
+	task.cancelled = true
+	a.ioTask = nil
+	a.hideIOProgress()
+	a.updateConnectionStateUI()
+	a.appendLog(fmt.Sprintf("已取消: %s。设备响应或超时之前, 之后的请求会排队等待", task.title))
+}
+
+// ioBusy 判断是否有未取消的读写正在进行
+func (a *AppRefined) ioBusy() bool {
+	return a.ioTask != nil
+}
+
+func (a *AppRefined) showIOProgress(title string) {
+	a.ioLabel.SetText(title + "...")
+	a.ioProgress.Start()
+	a.ioBox.Show()
+}
+
+func (a *AppRefined) hideIOProgress() {
+	a.ioProgress.Stop()
+	a.ioBox.Hide()
+}
@@ -0,0 +1,119 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/modbus"
+	"modbusbaby/pkg/datatypes"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const bitFieldNone = "无"
+
+// createBitFieldElements 创建位域定义选择元素
+func (a *AppRefined) createBitFieldElements() {
+	a.bitFieldSelect = widget.NewSelect([]string{bitFieldNone}, nil)
+	a.bitFieldSelect.SetSelected(bitFieldNone)
+	a.bitFieldLoadBtn = widget.NewButton("加载位域定义...", a.loadBitFieldDefs)
+}
+
+// createBitFieldLayout 创建位域定义行
+func (a *AppRefined) createBitFieldLayout() fyne.CanvasObject {
+	return container.NewHBox(
+		widget.NewLabel("位域/枚举:"),
+		container.New(&minWidthLayout{width: 150}, a.bitFieldSelect),
+		a.bitFieldLoadBtn,
+		widget.NewLabel("写入格式: 字段=标签, 如 Run=ON, Mode=Auto"),
+		layout.NewSpacer(),
+	)
+}
+
+// loadBitFieldDefs 从JSON文件加载位域定义
+func (a *AppRefined) loadBitFieldDefs() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		defs, err := datatypes.LoadBitFieldDefs(reader.URI().Path())
+		if err != nil {
+			a.appendLog(fmt.Sprintf("加载位域定义失败: %v", err))
+			return
+		}
+
+		a.bitFieldDefs = defs
+		options := []string{bitFieldNone}
+		for _, def := range defs {
+			options = append(options, def.Name)
+		}
+		a.bitFieldSelect.Options = options
+		a.bitFieldSelect.SetSelected(options[len(options)-1])
+		a.appendLog(fmt.Sprintf("已加载 %d 个位域定义", len(defs)))
+	}, a.window)
+}
+
+// selectedBitField 返回当前选中的位域定义, 未选中时返回 nil
+func (a *AppRefined) selectedBitField() *datatypes.BitFieldDef {
+	for i := range a.bitFieldDefs {
+		if a.bitFieldDefs[i].Name == a.bitFieldSelect.Selected {
+			return &a.bitFieldDefs[i]
+		}
+	}
+	return nil
+}
+
+// logBitFieldStates 按位域定义解码读取到的整数值并输出到日志
+func (a *AppRefined) logBitFieldStates(def *datatypes.BitFieldDef, dataType datatypes.DataType, result interface{}) {
+	if dataType.RegistersPerValue() != def.RegisterCount() {
+		a.appendLog(fmt.Sprintf("位域 %s 需要 %d 个寄存器的整数类型 (如 UINT16/UINT32)", def.Name, def.RegisterCount()))
+		return
+	}
+	values, ok := datatypes.ToFloat64Slice(result)
+	if !ok {
+		return
+	}
+
+	// 有符号类型的负数按补码截取到寄存器位宽
+	mask := uint32(uint64(1)<<uint(16*def.RegisterCount()) - 1)
+	for i, v := range values {
+		var labels []string
+		for _, state := range def.Decode(uint32(int64(v)) & mask) {
+			labels = append(labels, state.String())
+		}
+		a.appendLog(fmt.Sprintf("%s[%d]: %s", def.Name, i, strings.Join(labels, ", ")))
+	}
+}
+
+// writeBitField 经 client 读取当前状态字, 按 "字段=标签" 修改后写回, 返回修改说明。在后台执行, 不访问界面
+func (a *AppRefined) writeBitField(client *modbus.Client, slaveIDByte byte, address uint16, def *datatypes.BitFieldDef, valueStr string) (string, error) {
+	dataType := datatypes.UINT16
+	if def.RegisterCount() == 2 {
+		dataType = datatypes.UINT32
+	}
+
+	current, err := client.ReadHoldingRegisters(slaveIDByte, address, uint16(def.RegisterCount()), dataType)
+	if err != nil {
+		return "", fmt.Errorf("读取当前状态字失败: %w", err)
+	}
+	values, _ := datatypes.ToFloat64Slice(current)
+	if len(values) == 0 {
+		return "", fmt.Errorf("读取当前状态字失败: 无数据")
+	}
+
+	value, err := def.ComposeString(uint32(values[0]), valueStr)
+	if err != nil {
+		return "", err
+	}
+	message := fmt.Sprintf("位域 %s: 0x%X -> 0x%X", def.Name, uint32(values[0]), value)
+
+	if dataType == datatypes.UINT32 {
+		return message, client.WriteHoldingRegisters(slaveIDByte, address, []uint32{value}, dataType)
+	}
+	return message, client.WriteHoldingRegisters(slaveIDByte, address, []uint16{uint16(value)}, dataType)
+}
@@ -0,0 +1,65 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/modbus"
+	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/modicon"
+)
+
+// bitCount 返回要读取的位数。结束地址也带位号时读取两者之间的所有位, 否则只读取起始位
+func bitCount(start, end modicon.Address) (int, error) {
+	if !end.HasBit {
+		return 1, nil
+	}
+	first := int(start.Offset)*16 + int(start.Bit)
+	last := int(end.Offset)*16 + int(end.Bit)
+	if last < first {
+		return 0, fmt.Errorf("结束地址不能小于起始地址。")
+	}
+	return last - first + 1, nil
+}
+
+// logRegisterBits 逐位输出从 start 开始读取到的位
+func (a *AppRefined) logRegisterBits(start modicon.Address, bits []bool) {
+	addr := start
+	for _, on := range bits {
+		a.appendLog(fmt.Sprintf("  %s = %s", addr, bitText(on)))
+		if addr.Bit == 15 {
+			addr.Offset++
+			addr.Bit = 0
+		} else {
+			addr.Bit++
+		}
+	}
+}
+
+// parseBitValue 解析带位号地址的写入值 (on/off/1/0)
+func parseBitValue(valueStr string) (bool, error) {
+	values, err := datatypes.ParseStringToType(valueStr, datatypes.BOOL)
+	if err != nil {
+		return false, fmt.Errorf("解析位数值失败: %w", err)
+	}
+	boolValues, ok := values.([]bool)
+	if !ok || len(boolValues) != 1 {
+		return false, fmt.Errorf("带位号的地址只能写入一个数值 (on/off/1/0)")
+	}
+	return boolValues[0], nil
+}
+
+// writeRegisterBit 经 client 写入带位号的保持寄存器地址, 成功时返回写入说明。在后台执行, 不访问界面
+func (a *AppRefined) writeRegisterBit(client *modbus.Client, slaveIDByte byte, start modicon.Address, on bool) (string, error) {
+	method, err := client.WriteRegisterBit(slaveIDByte, start.Offset, start.Bit, on)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已将 %s 设为 %s (%s)", start, bitText(on), method), nil
+}
+
+// bitText 位的显示文本
+func bitText(on bool) string {
+	if on {
+		return "1 (ON)"
+	}
+	return "0 (OFF)"
+}
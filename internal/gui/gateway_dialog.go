@@ -0,0 +1,105 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/gateway"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showGatewayDialog 显示网关设置对话框
+// 网关使用配置文件中的路由, 并可选择将其余单元标识符转发到当前连接
+func (a *AppRefined) showGatewayDialog() {
+	listenEntry := widget.NewEntry()
+	listenEntry.SetText(a.config.Gateway.ListenAddr)
+
+	cacheEntry := widget.NewEntry()
+	cacheEntry.SetText(strconv.Itoa(a.config.Gateway.CacheTTL))
+
+	useCurrentCheck := widget.NewCheck("其余单元转发到当前连接", nil)
+	useCurrentCheck.SetChecked(true)
+
+	statusLabel := widget.NewLabel("")
+	var startStopButton *widget.Button
+
+	updateStatus := func() {
+		if a.gateway == nil {
+			statusLabel.SetText("网关未运行")
+			startStopButton.SetText("启动网关")
+			return
+		}
+		stats := a.gateway.Stats()
+		statusLabel.SetText(fmt.Sprintf("监听 %s | 客户端: %d | 请求: %d | 缓存命中: %d | 错误: %d",
+			a.gateway.Addr(), stats.Connections, stats.Requests, stats.CacheHits, stats.Errors))
+		startStopButton.SetText("停止网关")
+	}
+
+	startStopButton = widget.NewButton("", func() {
+		if a.gateway != nil {
+			a.stopGateway()
+			updateStatus()
+			return
+		}
+
+		cacheTTL, err := strconv.Atoi(cacheEntry.Text)
+		if err != nil || cacheTTL < 0 {
+			a.appendLog(fmt.Sprintf("缓存时间无效: %s", cacheEntry.Text))
+			return
+		}
+		a.config.Gateway.ListenAddr = listenEntry.Text
+		a.config.Gateway.CacheTTL = cacheTTL
+
+		gw, err := gateway.Open(a.config.Gateway)
+		if err != nil {
+			a.appendLog(fmt.Sprintf("网关启动失败: %v", err))
+			return
+		}
+		if useCurrentCheck.Checked && a.isConnected {
+			gw.SetDefaultRoute(a.modbus)
+		}
+		if err := gw.Start(); err != nil {
+			a.appendLog(fmt.Sprintf("网关启动失败: %v", err))
+			return
+		}
+		a.gateway = gw
+		a.gatewayToThis = useCurrentCheck.Checked
+		a.appendLog(fmt.Sprintf("网关已启动, 监听 %s", gw.Addr()))
+		updateStatus()
+	})
+	updateStatus()
+
+	refreshButton := widget.NewButton("刷新统计", updateStatus)
+
+	form := widget.NewForm(
+		widget.NewFormItem("监听地址", listenEntry),
+		widget.NewFormItem("读缓存 (ms)", cacheEntry),
+		widget.NewFormItem("", useCurrentCheck),
+	)
+	content := container.NewVBox(
+		form,
+		widget.NewLabel(fmt.Sprintf("配置文件中的路由: %d 条", len(a.config.Gateway.Routes))),
+		statusLabel,
+		container.NewHBox(startStopButton, refreshButton),
+	)
+
+	d := dialog.NewCustom("Modbus 网关", "关闭", content, a.window)
+	d.Resize(fyne.NewSize(520, 0))
+	d.Show()
+}
+
+// stopGateway 停止正在运行的网关
+func (a *AppRefined) stopGateway() {
+	if a.gateway == nil {
+		return
+	}
+	stats := a.gateway.Stats()
+	if err := a.gateway.Stop(); err != nil {
+		a.appendLog(fmt.Sprintf("网关停止时出错: %v", err))
+	}
+	a.gateway = nil
+	a.appendLog(fmt.Sprintf("网关已停止 (共处理 %d 个请求)", stats.Requests))
+}
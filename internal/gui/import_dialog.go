@@ -0,0 +1,215 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/config"
+	"modbusbaby/pkg/utils"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+const columnNone = "(无)"
+
+// importColumnLabels 列映射对话框中各字段的名称
+var importColumnLabels = map[string]string{
+	config.ColumnName:        "名称",
+	config.ColumnAddress:     "地址",
+	config.ColumnTable:       "寄存器类型",
+	config.ColumnDataType:    "数据类型",
+	config.ColumnCount:       "长度 (寄存器)",
+	config.ColumnScale:       "缩放系数",
+	config.ColumnUnit:        "单位",
+	config.ColumnAccess:      "读写",
+	config.ColumnDescription: "说明",
+}
+
+// importVendorMap 选择厂商寄存器表 (CSV/XLSX) 并打开列映射对话框
+func (a *AppRefined) importVendorMap() {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		rows, err := utils.ReadSpreadsheet(reader.URI().Path())
+		if err != nil {
+			a.appendLog(fmt.Sprintf("读取厂商表失败: %v", err))
+			return
+		}
+		if len(rows) == 0 {
+			a.appendLog("厂商表为空")
+			return
+		}
+		a.showImportDialog(rows)
+	}, a.window)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".xlsx", ".xlsm", ".txt"}))
+	open.Show()
+}
+
+// showImportDialog 显示列映射对话框, 确认后导入点位并提示保存为点位表文件
+func (a *AppRefined) showImportDialog(rows [][]string) {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	selects := make(map[string]*widget.Select)
+	var items []*widget.FormItem
+	for _, field := range config.ImportColumns {
+		sel := widget.NewSelect(nil, nil)
+		selects[field] = sel
+		items = append(items, widget.NewFormItem(importColumnLabels[field], sel))
+	}
+
+	// applyHeader 按表头行数取最后一行表头, 重新生成列选项并猜测映射
+	applyHeader := func(headerRows int) {
+		header := config.ImportHeader(rows, headerRows)
+		options := []string{columnNone}
+		for i := 0; i < columns; i++ {
+			name := ""
+			if i < len(header) {
+				name = header[i]
+			}
+			options = append(options, columnOption(i, name))
+		}
+		guess := config.GuessColumnMapping(header)
+		for _, field := range config.ImportColumns {
+			sel := selects[field]
+			sel.Options = options
+			if col, ok := guess[field]; ok {
+				sel.SetSelected(options[col+1])
+			} else {
+				sel.SetSelected(columnNone)
+			}
+		}
+	}
+	applyHeader(1)
+
+	headerRowsEntry := widget.NewEntry()
+	headerRowsEntry.SetText("1")
+	headerRowsEntry.OnChanged = func(text string) {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err == nil && n >= 0 && n <= len(rows) {
+			applyHeader(n)
+		}
+	}
+	oneBasedCheck := widget.NewCheck("不带 4xxxx/3xxxx 前缀的地址从1开始", nil)
+	oneBasedCheck.SetChecked(true)
+	modiconCheck := widget.NewCheck("有寄存器类型列时仍按 Modicon 写法解析 40001/300001", nil)
+	tableSelect := widget.NewSelect([]string{"Holding Register", "Input Register", "Coil", "Discrete Input"}, nil)
+	tableSelect.SetSelected("Holding Register")
+	unitEntry := widget.NewEntry()
+	unitEntry.SetText(strconv.Itoa(int(a.slaveIDByte)))
+
+	items = append(items,
+		widget.NewFormItem("表头行数", headerRowsEntry),
+		widget.NewFormItem("地址基准", oneBasedCheck),
+		widget.NewFormItem("", modiconCheck),
+		widget.NewFormItem("默认寄存器类型", tableSelect),
+		widget.NewFormItem("从站地址", unitEntry),
+	)
+
+	form := widget.NewForm(items...)
+	info := widget.NewLabel(fmt.Sprintf("共 %d 行, %d 列。没有寄存器类型列时, Modicon 地址 (40001/300001) 的首位决定寄存器类型。", len(rows), columns))
+	content := container.NewBorder(info, nil, nil, nil, container.NewVScroll(form))
+
+	d := dialog.NewCustomConfirm("导入厂商寄存器表", "导入", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		opts := config.ImportOptions{
+			Mapping:          make(config.ColumnMapping),
+			OneBased:         oneBasedCheck.Checked,
+			ModiconWithTable: modiconCheck.Checked,
+		}
+		for field, sel := range selects {
+			if col := columnFromOption(sel.Selected); col >= 0 {
+				opts.Mapping[field] = col
+			}
+		}
+		var err error
+		if opts.HeaderRows, err = strconv.Atoi(strings.TrimSpace(headerRowsEntry.Text)); err != nil || opts.HeaderRows < 0 {
+			a.appendLog(fmt.Sprintf("表头行数无效: %s", headerRowsEntry.Text))
+			return
+		}
+		if opts.UnitID, err = strconv.Atoi(strings.TrimSpace(unitEntry.Text)); err != nil {
+			a.appendLog(fmt.Sprintf("从站地址无效: %s", unitEntry.Text))
+			return
+		}
+		for table, regType := range tagRegisterTypes {
+			if regType == tableSelect.Selected {
+				opts.DefaultTable = table
+			}
+		}
+
+		a.importTags(rows, opts)
+	}, a.window)
+	d.Resize(fyne.NewSize(520, 560))
+	d.Show()
+}
+
+// importTags 导入点位, 记录跳过的行, 并提示将结果保存为点位表文件
+func (a *AppRefined) importTags(rows [][]string, opts config.ImportOptions) {
+	result, err := config.ImportTags(rows, opts)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("导入失败: %v", err))
+		return
+	}
+	for _, msg := range result.Skipped {
+		a.appendLog("跳过 " + msg)
+	}
+
+	db, err := config.NewTagDatabase(result.Tags)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("导入失败: %v", err))
+		return
+	}
+	a.setTagDatabase(db)
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		writer.Close()
+		if err := config.SaveTags(writer.URI().Path(), db.Tags); err != nil {
+			a.appendLog(fmt.Sprintf("保存点位表失败: %v", err))
+			return
+		}
+		a.appendLog(fmt.Sprintf("点位表已保存: %s", writer.URI().Path()))
+	}, a.window)
+	save.SetFileName("tags.yaml")
+	save.Show()
+}
+
+// columnOption 返回列选项文本, 如 "C: Data Type"
+func columnOption(col int, name string) string {
+	return fmt.Sprintf("%s: %s", columnLetter(col), strings.TrimSpace(name))
+}
+
+// columnFromOption 从列选项文本解析列号, "(无)" 返回 -1
+func columnFromOption(option string) int {
+	letters, _, ok := strings.Cut(option, ":")
+	if !ok {
+		return -1
+	}
+	col := 0
+	for _, r := range letters {
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// columnLetter 将0起始的列号转换为表格列字母, 如 0 → A, 27 → AB
+func columnLetter(col int) string {
+	var letters []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		letters = append([]byte{byte('A' + (col-1)%26)}, letters...)
+	}
+	return string(letters)
+}
@@ -0,0 +1,138 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/modicon"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const orderAssistantResults = 50
+
+// showOrderAssistant 显示字节/字序识别助手: 输入原始寄存器和期望值, 列出符合期望的类型和排列,
+// 选中一行后可应用到数据类型、字节序、字序和缩放设置
+func (a *AppRefined) showOrderAssistant() {
+	registersEntry := widget.NewMultiLineEntry()
+	registersEntry.Wrapping = fyne.TextWrapWord
+	registersEntry.SetMinRowsVisible(2)
+	registersEntry.SetPlaceHolder("如 0x4366 0x8000 或 17254, 32768")
+	if regs := a.lastResult.registers; len(regs) > 0 {
+		words := make([]string, len(regs))
+		for i, r := range regs {
+			words[i] = fmt.Sprintf("0x%04X", r)
+		}
+		registersEntry.SetText(strings.Join(words, " "))
+	}
+
+	expectEntry := widget.NewEntry()
+	expectEntry.SetPlaceHolder("230、230±2、230±0.5% 或 220..240")
+	scaleCheck := widget.NewCheck("尝试 ×0.1/×0.01/×0.001/×10 缩放", nil)
+	scaleCheck.SetChecked(true)
+	statusLabel := widget.NewLabel("")
+
+	var candidates []datatypes.Candidate
+	selected := -1
+	headers := []string{"类型", "排列", "偏移", "缩放", "值"}
+	table := widget.NewTable(
+		func() (int, int) { return len(candidates) + 1, len(headers) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(headers[id.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			c := candidates[id.Row-1]
+			switch id.Col {
+			case 0:
+				label.SetText(c.DataType.String())
+			case 1:
+				label.SetText(fmt.Sprintf("%s (%s/%s)", c.Order(), c.ByteOrder, c.WordOrder))
+			case 2:
+				label.SetText(strconv.Itoa(c.Offset))
+			case 3:
+				label.SetText(strconv.FormatFloat(c.Scale, 'g', -1, 64))
+			case 4:
+				label.SetText(strconv.FormatFloat(c.Value, 'g', 10, 64))
+			}
+		},
+	)
+	for col, width := range []float32{110, 170, 50, 60, 150} {
+		table.SetColumnWidth(col, width)
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		selected = id.Row - 1
+	}
+
+	detectButton := widget.NewButton("识别", func() {
+		registers, err := datatypes.ParseRegisterList(registersEntry.Text)
+		if err != nil {
+			statusLabel.SetText(fmt.Sprintf("寄存器无效: %v", err))
+			return
+		}
+		exp, err := datatypes.ParseExpectation(expectEntry.Text)
+		if err != nil {
+			statusLabel.SetText(fmt.Sprintf("期望值无效: %v", err))
+			return
+		}
+		candidates = datatypes.DetectFormats(registers, exp, datatypes.DetectOptions{
+			TryScales:  scaleCheck.Checked,
+			MaxResults: orderAssistantResults,
+		})
+		selected = -1
+		table.UnselectAll()
+		table.Refresh()
+		if len(candidates) == 0 {
+			statusLabel.SetText("没有符合期望的解释, 请放宽期望范围或检查寄存器")
+		} else {
+			statusLabel.SetText(fmt.Sprintf("%d 种解释, 最可信的在前; 选中一行后点击\"应用\"", len(candidates)))
+		}
+	})
+
+	applyButton := widget.NewButton("应用", func() {
+		if selected < 0 || selected >= len(candidates) {
+			statusLabel.SetText("请先选中一行")
+			return
+		}
+		a.applyCandidate(candidates[selected])
+		statusLabel.SetText("已应用")
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("原始寄存器", registersEntry),
+		widget.NewFormItem("期望值", expectEntry),
+		widget.NewFormItem("", scaleCheck),
+	)
+	top := container.NewVBox(form, container.NewHBox(detectButton, applyButton), statusLabel)
+
+	d := dialog.NewCustom("字节/字序识别", "关闭", container.NewBorder(top, nil, nil, nil, table), a.window)
+	d.Resize(fyne.NewSize(640, 560))
+	d.Show()
+}
+
+// applyCandidate 将识别结果应用到数据类型、字节/字序和缩放设置, 起始地址按偏移后移
+func (a *AppRefined) applyCandidate(c datatypes.Candidate) {
+	a.dataTypeCombo.SetSelected(c.DataType.String())
+	a.byteOrderCombo.SetSelected(c.ByteOrder.String())
+	a.wordOrderCombo.SetSelected(c.WordOrder.String())
+	// 不需要缩放时也写入 1, 清除之前的缩放系数
+	a.scaleInput.SetText(strconv.FormatFloat(c.Scale, 'g', -1, 64))
+
+	if c.Offset > 0 && a.lastResult.registers != nil {
+		table := regTypeTables[a.lastResult.regType]
+		start := a.lastResult.start + c.Offset
+		n := c.DataType.RegistersPerValue()
+		a.selectRegisterType(a.lastResult.regType)
+		a.startAddressInput.SetText(modicon.Address{Table: table, Offset: uint16(start)}.Prefixed())
+		a.endAddressInput.SetText(modicon.Address{Table: table, Offset: uint16(start + n - 1)}.Prefixed())
+	}
+	a.appendLog(fmt.Sprintf("已应用识别结果: %s %s, 缩放 %g", c.DataType, c.Order(), c.Scale))
+}
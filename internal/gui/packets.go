@@ -0,0 +1,352 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/modbus"
+	"modbusbaby/pkg/dissect"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 报文分析: 每次读写的请求和响应逐字段解析后列在左侧, 选中后在树中显示各字段,
+// 选中字段时在下方的十六进制区高亮对应的字节, 无效字段 (如 CRC 错误) 的字节显示为红色。
+
+// maxPacketFrames 报文分析保留的报文数, 超出后丢弃最早的
+const maxPacketFrames = 500
+
+// hexDumpWidth 十六进制区每行的字节数
+const hexDumpWidth = 16
+
+// packetFrame 报文分析列表中的一个报文
+type packetFrame struct {
+	time  time.Time
+	frame *dissect.Frame // 没有响应时为 nil
+	sent  bool
+}
+
+// createPacketElements 创建报文显示区和报文分析
+func (a *AppRefined) createPacketElements() {
+	a.sentPacketDisplay = widget.NewMultiLineEntry()
+	a.sentPacketDisplay.Wrapping = fyne.TextWrapWord
+
+	a.receivedPacketDisplay = widget.NewMultiLineEntry()
+	a.receivedPacketDisplay.Wrapping = fyne.TextWrapWord
+
+	a.packetSelected = -1
+	a.packetList = widget.NewList(
+		func() int {
+			return len(a.packetFrames)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			p := a.packetFrames[id]
+			label.Importance = widget.MediumImportance
+			if p.frame == nil || !p.frame.Valid() || p.frame.Exception != 0 {
+				label.Importance = widget.DangerImportance
+			}
+			label.SetText(packetTitle(p))
+		},
+	)
+	a.packetList.OnSelected = a.selectPacket
+
+	a.packetTree = widget.NewTree(
+		func(uid widget.TreeNodeID) []widget.TreeNodeID {
+			var fields []*dissect.Field
+			if uid == "" {
+				if frame := a.selectedFrame(); frame != nil {
+					fields = frame.Fields
+				}
+			} else if field := a.packetFieldByID(uid); field != nil {
+				fields = field.Children
+			}
+			ids := make([]widget.TreeNodeID, len(fields))
+			for i := range fields {
+				ids[i] = packetChildID(uid, i)
+			}
+			return ids
+		},
+		func(uid widget.TreeNodeID) bool {
+			if uid == "" {
+				return true
+			}
+			field := a.packetFieldByID(uid)
+			return field != nil && len(field.Children) > 0
+		},
+		func(bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(uid widget.TreeNodeID, _ bool, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			field := a.packetFieldByID(uid)
+			if field == nil {
+				label.SetText("")
+				return
+			}
+			text := field.Name
+			if field.Value != "" {
+				text += ": " + field.Value
+			}
+			label.Importance = widget.MediumImportance
+			if field.Invalid != "" {
+				text += "  ✗ " + field.Invalid
+				label.Importance = widget.DangerImportance
+			}
+			label.SetText(text)
+		},
+	)
+	a.packetTree.OnSelected = func(uid widget.TreeNodeID) {
+		a.packetField = a.packetFieldByID(uid)
+		a.refreshPacketBytes()
+	}
+
+	a.packetBytes = widget.NewRichText()
+	a.packetInfoLabel = widget.NewLabel("选择一个报文查看各字段")
+}
+
+// createPacketLayout 创建报文区: 原始报文和报文分析两页
+func (a *AppRefined) createPacketLayout() fyne.CanvasObject {
+	sentWithLabel := container.NewBorder(
+		container.NewHBox(widget.NewLabel("发送的报文:")), nil, nil, nil, a.sentPacketDisplay,
+	)
+
+	receivedWithLabel := container.NewBorder(
+		container.NewHBox(widget.NewLabel("接收的报文:")), nil, nil, nil, a.receivedPacketDisplay,
+	)
+
+	packetSplitter := container.NewHSplit(sentWithLabel, receivedWithLabel)
+	packetSplitter.SetOffset(0.5)
+
+	details := container.NewVSplit(
+		a.packetTree,
+		container.NewBorder(a.packetInfoLabel, nil, nil, nil, container.NewScroll(a.packetBytes)),
+	)
+	details.SetOffset(0.6)
+	analyzer := container.NewHSplit(a.packetList, details)
+	analyzer.SetOffset(0.4)
+
+	return container.NewAppTabs(
+		container.NewTabItem("报文", packetSplitter),
+		container.NewTabItem("报文分析", analyzer),
+	)
+}
+
+// appendExchanges 按顺序追加一次操作中各事务的报文
+func (a *AppRefined) appendExchanges(exchanges []modbus.Exchange) {
+	for _, ex := range exchanges {
+		a.appendPackets(ex.Sent, ex.Received)
+	}
+}
+
+// appendPackets 将一次读写的报文追加到报文显示区, 并解析后加入报文分析
+func (a *AppRefined) appendPackets(sent, received []byte) {
+	now := time.Now()
+	timestamp := now.Format("15:04:05.000")
+	a.sentPacketDisplay.SetText(a.sentPacketDisplay.Text + fmt.Sprintf("[%s] Sent: %X\n", timestamp, sent))
+	a.receivedPacketDisplay.SetText(a.receivedPacketDisplay.Text + fmt.Sprintf("[%s] Received: %X\n", timestamp, received))
+
+	if len(sent) == 0 {
+		return
+	}
+	framing := dissect.TCP
+	if a.modbus.GetConnectionType() == modbus.RTU {
+		framing = dissect.RTU
+	}
+	request := dissect.DecodeRequest(sent, framing)
+	response := packetFrame{time: now}
+	if len(received) > 0 {
+		response.frame = dissect.DecodeResponse(received, framing, request)
+	}
+	a.packetFrames = append(a.packetFrames, packetFrame{time: now, frame: request, sent: true}, response)
+	if n := len(a.packetFrames) - maxPacketFrames; n > 0 {
+		a.packetFrames = append([]packetFrame(nil), a.packetFrames[n:]...)
+		switch {
+		case a.packetSelected >= n:
+			// 选中的报文仍保留, 列表选中项随之前移, 字段选择不变
+			a.packetSelected -= n
+			a.packetList.Select(a.packetSelected)
+		case a.packetSelected >= 0:
+			a.packetSelected = -1
+			a.packetList.UnselectAll()
+			a.refreshPacketTree()
+		}
+	}
+	a.packetList.Refresh()
+	if a.packetSelected < 0 {
+		a.packetList.ScrollToBottom()
+	}
+}
+
+// clearPackets 清空报文分析
+func (a *AppRefined) clearPackets() {
+	a.packetFrames = nil
+	a.packetSelected = -1
+	a.packetField = nil
+	a.packetList.UnselectAll()
+	a.packetList.Refresh()
+	a.refreshPacketTree()
+}
+
+// selectPacket 在树中显示选中报文的各字段
+func (a *AppRefined) selectPacket(id widget.ListItemID) {
+	if id == a.packetSelected {
+		return
+	}
+	a.packetSelected = id
+	a.refreshPacketTree()
+}
+
+func (a *AppRefined) refreshPacketTree() {
+	a.packetTree.UnselectAll()
+	a.packetField = nil
+	a.packetTree.Refresh()
+	a.packetTree.OpenAllBranches()
+	a.refreshPacketBytes()
+}
+
+// selectedFrame 返回选中的报文, 未选中或选中的是"无响应"时返回 nil
+func (a *AppRefined) selectedFrame() *dissect.Frame {
+	if a.packetSelected < 0 || a.packetSelected >= len(a.packetFrames) {
+		return nil
+	}
+	return a.packetFrames[a.packetSelected].frame
+}
+
+// packetChildID 树节点的 ID 为各级字段序号的路径, 如 "1/3/0"
+func packetChildID(parent widget.TreeNodeID, i int) widget.TreeNodeID {
+	if parent == "" {
+		return strconv.Itoa(i)
+	}
+	return parent + "/" + strconv.Itoa(i)
+}
+
+// packetFieldByID 按树节点 ID 查找选中报文中的字段
+func (a *AppRefined) packetFieldByID(uid widget.TreeNodeID) *dissect.Field {
+	frame := a.selectedFrame()
+	if frame == nil || uid == "" {
+		return nil
+	}
+	fields := frame.Fields
+	var field *dissect.Field
+	for _, part := range strings.Split(uid, "/") {
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 0 || i >= len(fields) {
+			return nil
+		}
+		field = fields[i]
+		fields = field.Children
+	}
+	return field
+}
+
+// refreshPacketBytes 以十六进制显示选中的报文, 高亮选中字段的字节
+func (a *AppRefined) refreshPacketBytes() {
+	frame := a.selectedFrame()
+	switch {
+	case frame == nil && a.packetSelected >= 0 && a.packetSelected < len(a.packetFrames):
+		a.packetInfoLabel.SetText("没有收到响应 (超时或连接错误)")
+	case frame == nil:
+		a.packetInfoLabel.SetText("选择一个报文查看各字段")
+	case a.packetField != nil:
+		f := a.packetField
+		a.packetInfoLabel.SetText(fmt.Sprintf("%s: 第 %d 字节起, 共 %d 字节", f.Name, f.Offset, f.Length))
+	default:
+		a.packetInfoLabel.SetText(fmt.Sprintf("%s, %d 字节", frame.Framing, len(frame.Raw)))
+	}
+	if frame == nil {
+		a.packetBytes.Segments = nil
+		a.packetBytes.Refresh()
+		return
+	}
+
+	start, end := -1, -1
+	if a.packetField != nil {
+		start, end = a.packetField.Offset, a.packetField.Offset+a.packetField.Length
+	}
+	a.packetBytes.Segments = hexDumpSegments(frame.Raw, start, end, invalidBytes(frame))
+	a.packetBytes.Refresh()
+}
+
+// invalidBytes 标记属于无效字段的字节
+func invalidBytes(frame *dissect.Frame) []bool {
+	invalid := make([]bool, len(frame.Raw))
+	var walk func(fields []*dissect.Field)
+	walk = func(fields []*dissect.Field) {
+		for _, f := range fields {
+			if f.Invalid != "" {
+				for i := f.Offset; i < f.Offset+f.Length && i < len(invalid); i++ {
+					invalid[i] = true
+				}
+			}
+			walk(f.Children)
+		}
+	}
+	walk(frame.Fields)
+	return invalid
+}
+
+// hexDumpSegments 生成十六进制区的内容, 每行前为偏移。[start, end) 内的字节高亮, 无效字段的字节为红色
+func hexDumpSegments(raw []byte, start, end int, invalid []bool) []widget.RichTextSegment {
+	style := func(i int) widget.RichTextStyle {
+		s := widget.RichTextStyle{Inline: true, TextStyle: fyne.TextStyle{Monospace: true}}
+		switch {
+		case i >= start && i < end:
+			s.ColorName = theme.ColorNamePrimary
+			s.TextStyle.Bold = true
+		case invalid[i]:
+			s.ColorName = theme.ColorNameError
+		}
+		return s
+	}
+
+	var segments []widget.RichTextSegment
+	for line := 0; line < len(raw); line += hexDumpWidth {
+		lineEnd := line + hexDumpWidth
+		if lineEnd > len(raw) {
+			lineEnd = len(raw)
+		}
+		segments = append(segments, &widget.TextSegment{
+			Text:  fmt.Sprintf("%04X  ", line),
+			Style: widget.RichTextStyle{Inline: true, ColorName: theme.ColorNameDisabled, TextStyle: fyne.TextStyle{Monospace: true}},
+		})
+		// 相邻且样式相同的字节合并为一段
+		for i := line; i < lineEnd; {
+			s := style(i)
+			j := i + 1
+			for j < lineEnd && style(j) == s {
+				j++
+			}
+			text := fmt.Sprintf("% X", raw[i:j])
+			if j < lineEnd {
+				text += " "
+			}
+			segments = append(segments, &widget.TextSegment{Text: text, Style: s})
+			i = j
+		}
+		// 每行最后一段不是行内段, 使下一段从新的一行开始
+		last := segments[len(segments)-1].(*widget.TextSegment)
+		last.Style.Inline = false
+	}
+	return segments
+}
+
+// packetTitle 报文列表中的一行, 如 "12:00:01.123 → Read Holding Registers: address 0, quantity 4"
+func packetTitle(p packetFrame) string {
+	arrow := "←"
+	if p.sent {
+		arrow = "→"
+	}
+	summary := "(无响应)"
+	if p.frame != nil {
+		summary = p.frame.Summary()
+	}
+	return fmt.Sprintf("%s %s %s", p.time.Format("15:04:05.000"), arrow, summary)
+}
@@ -0,0 +1,363 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"modbusbaby/internal/config"
+	"modbusbaby/internal/modbus"
+	"modbusbaby/internal/poller"
+	"modbusbaby/internal/recorder"
+	"modbusbaby/pkg/datatypes"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 轮询: 默认按界面当前的读取设置周期读取; 勾选"轮询点位表"后按点位的 poll_ms 分组,
+// 各组按自己的周期读取点位表中所有可读的点位, 未设置 poll_ms 的点位使用界面上的轮询间隔。
+// 读取在轮询 goroutine 中进行, 收集设置和显示结果通过 fyne.DoAndWait 在界面线程中完成,
+// 因此停止轮询 (等待读取结束) 不能在界面线程中进行, 见 stopPollingThen。
+
+// pollStatsColumns 轮询统计表的列名和宽度
+var pollStatsColumns = []struct {
+	title string
+	width float32
+}{
+	{"组", 120}, {"周期", 80}, {"次数", 80}, {"错误", 60}, {"超时", 60}, {"跳过", 60},
+	{"最近", 80}, {"最短", 80}, {"平均", 80}, {"最长", 80}, {"最大延迟", 90},
+}
+
+// createPollingElements 创建轮询模式选择和统计显示
+func (a *AppRefined) createPollingElements() {
+	a.pollTagsCheck = widget.NewCheck("轮询点位表", nil)
+	a.pollStatsLabel = widget.NewLabel("")
+	a.pollStatsBtn = widget.NewButton("统计...", a.showPollStats)
+}
+
+// startPolling 按当前模式创建轮询组并开始轮询
+func (a *AppRefined) startPolling(slaveIDByte byte) {
+	if !a.isConnected {
+		a.appendLog("设备未连接，无法开始轮询。")
+		return
+	}
+	intervalMs, err := strconv.Atoi(strings.TrimSpace(a.pollingIntervalInput.Text))
+	if err != nil || intervalMs <= 0 {
+		a.appendLog("轮询间隔无效，请输入正整数。")
+		return
+	}
+	if a.poller != nil {
+		a.appendLog("轮询已在运行。")
+		return
+	}
+	interval := time.Duration(intervalMs) * time.Millisecond
+	jitter := time.Duration(a.config.PollJitter) * time.Millisecond
+
+	var groups []poller.Group
+	if a.pollTagsCheck.Checked {
+		var tags []config.Tag
+		if a.tagDB != nil {
+			tags = a.tagDB.Tags
+		}
+		schedules := poller.ScheduleTags(tags, interval)
+		if len(schedules) == 0 {
+			a.appendLog("点位表中没有可读的点位，无法轮询点位表。")
+			return
+		}
+		var parts []string
+		for _, s := range schedules {
+			tags := s.Tags
+			groups = append(groups, poller.Group{
+				Name:     s.Name(),
+				Interval: s.Interval,
+				Jitter:   jitter,
+				Read: func(ctx context.Context) error {
+					return a.pollTags(ctx, slaveIDByte, tags)
+				},
+			})
+			parts = append(parts, fmt.Sprintf("%s × %d 个点位", s.Name(), len(tags)))
+		}
+		a.appendLog(fmt.Sprintf("开始轮询点位表: %s", strings.Join(parts, ", ")))
+	} else {
+		groups = []poller.Group{{
+			Name:     "当前设置",
+			Interval: interval,
+			Jitter:   jitter,
+			Read: func(ctx context.Context) error {
+				return a.pollOnce(ctx, slaveIDByte)
+			},
+		}}
+		if tag := a.activeTag(); tag != nil {
+			a.appendLog(fmt.Sprintf("开始轮询点位 %s，间隔 %d ms...", tag.Name, intervalMs))
+		} else {
+			a.appendLog(fmt.Sprintf("开始轮询，间隔 %d ms...", intervalMs))
+		}
+		if n := a.otherAlarmTags(); n > 0 {
+			a.appendLog(fmt.Sprintf("点位表中另有 %d 个点位设有报警规则, 勾选\"轮询点位表\"后才会判断", n))
+		}
+	}
+
+	p, err := poller.New(groups)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("无法开始轮询: %v", err))
+		return
+	}
+	// 每组只提示第一次超时, 之后的超时计入统计
+	warned := make(map[string]bool)
+	p.OnOverrun = func(name string, took time.Duration, skipped int) {
+		if warned[name] {
+			return
+		}
+		warned[name] = true
+		message := fmt.Sprintf("轮询组 %s 超时: 耗时 %s 超过周期, 跳过 %d 个周期; 之后的超时只计入统计",
+			name, formatPollDuration(took), skipped)
+		fyne.Do(func() { a.appendLog(message) })
+	}
+	if err := p.Start(); err != nil {
+		a.appendLog(err.Error())
+		return
+	}
+	a.poller = p
+	a.pollStatsLabel.SetText("")
+	a.updateConnectionStateUI()
+	go a.watchPollStats(p)
+}
+
+// stopPolling 停止轮询, 不等待正在进行的读取结束
+func (a *AppRefined) stopPolling() {
+	a.stopPollingThen(nil)
+}
+
+// stopPollingThen 在后台停止轮询, 正在进行的读取结束后在界面线程中输出统计并调用 then (可为空)
+func (a *AppRefined) stopPollingThen(then func()) {
+	p := a.poller
+	if p == nil {
+		if then != nil {
+			then()
+		}
+		return
+	}
+	a.pollStatsLabel.SetText("正在停止轮询...")
+	go func() {
+		p.Stop()
+		fyne.Do(func() {
+			if a.poller == p {
+				a.poller = nil
+				a.pollStats = p.Stats()
+				summary := pollSummary(a.pollStats)
+				a.pollStatsLabel.SetText(summary)
+				a.appendLog(fmt.Sprintf("轮询已停止。%s", summary))
+				a.updateConnectionStateUI()
+			}
+			if then != nil {
+				then()
+			}
+		})
+	}()
+}
+
+// watchPollStats 轮询期间每秒刷新统计摘要
+func (a *AppRefined) watchPollStats(p *poller.Poller) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !p.Running() {
+			return
+		}
+		summary := pollSummary(p.Stats())
+		fyne.Do(func() {
+			if a.poller == p {
+				a.pollStatsLabel.SetText(summary)
+			}
+		})
+	}
+}
+
+// pollOnce 按界面当前的读取设置读取一次, 判断报警并记录结果
+func (a *AppRefined) pollOnce(ctx context.Context, slaveIDByte byte) error {
+	readStart := time.Now()
+	var req readRequest
+	var ok bool
+	fyne.DoAndWait(func() {
+		req, ok = a.readRequestFromUI(slaveIDByte)
+	})
+	var res readResult
+	if ok {
+		res = a.execRead(req)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var failed bool
+	fyne.DoAndWait(func() {
+		if ok {
+			a.showRead(req, res)
+		}
+		a.recordPoll(readStart)
+		failed = a.lastResult.readAt.Before(readStart)
+	})
+	if failed {
+		return fmt.Errorf("读取失败")
+	}
+	return nil
+}
+
+// pollTags 依次读取一组点位, 结果用于判断报警并作为一行记入历史数据和记录; 有点位失败时返回最后一个错误
+func (a *AppRefined) pollTags(ctx context.Context, slaveID byte, tags []config.Tag) error {
+	t := time.Now()
+	readings := make([]modbus.TagReading, len(tags))
+	errs := make([]error, len(tags))
+	var lastErr error
+	for i, tag := range tags {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		readings[i], errs[i] = a.modbus.ReadTag(tag, slaveID)
+		if errs[i] != nil {
+			lastErr = fmt.Errorf("%s: %w", tag.Name, errs[i])
+		}
+	}
+	fyne.DoAndWait(func() {
+		a.showTagReadings(t, readings, errs)
+	})
+	return lastErr
+}
+
+// showTagReadings 输出一组点位的读取结果, 判断报警并记入历史数据和记录
+func (a *AppRefined) showTagReadings(t time.Time, readings []modbus.TagReading, errs []error) {
+	active := a.activeTag()
+	var points []recorder.Point
+	for i, r := range readings {
+		name := r.Tag.Name
+		if errs[i] != nil {
+			a.appendLog(fmt.Sprintf("读取点位 %s 失败: %v", name, errs[i]))
+			a.handleAlarmEvents(a.alarmEngine.Fail(r.Time, name))
+			continue
+		}
+		if active != nil && active.Name == name {
+			a.showTagReading(r)
+		}
+		tagPoints := make([]recorder.Point, len(r.Scaled))
+		for j, v := range r.Scaled {
+			tagPoints[j] = recorder.Point{Name: tagPointName(name, j, len(r.Scaled)), Value: v}
+		}
+		a.handleAlarmEvents(a.alarmEngine.Evaluate(r.Time, name, alarmValues(tagPoints, r.Values)))
+		points = append(points, tagPoints...)
+	}
+	if len(points) > 0 {
+		a.recordPoints(t, points)
+	}
+}
+
+// showTagReading 在数据表中显示当前选中点位的轮询结果
+func (a *AppRefined) showTagReading(r modbus.TagReading) {
+	dataType := r.Tag.Type()
+	if r.Tag.IsBit() {
+		dataType = datatypes.BOOL
+	}
+	var scaled []float64
+	scaling := r.Tag.Scaling()
+	if !r.Tag.IsBit() && !scaling.IsIdentity() {
+		scaled = r.Scaled
+	}
+	a.showResults(r.Unit, tagRegisterTypes[r.Tag.Table], r.Tag.Address, r.Values, r.Registers, dataType, scaled, scaling.Unit)
+}
+
+// pollSummary 汇总各组统计, 如 "共 120 次, 平均 15ms, 最长 40ms, 超时 0, 错误 0"
+func pollSummary(stats []poller.Stats) string {
+	var cycles, errors, overruns int64
+	var total, max time.Duration
+	for _, s := range stats {
+		cycles += s.Cycles
+		errors += s.Errors
+		overruns += s.Overruns
+		total += s.Mean * time.Duration(s.Cycles)
+		if s.Max > max {
+			max = s.Max
+		}
+	}
+	if cycles == 0 {
+		return ""
+	}
+	return fmt.Sprintf("共 %d 次, 平均 %s, 最长 %s, 超时 %d, 错误 %d",
+		cycles, formatPollDuration(total/time.Duration(cycles)), formatPollDuration(max), overruns, errors)
+}
+
+// showPollStats 显示各轮询组的详细统计
+func (a *AppRefined) showPollStats() {
+	stats := a.pollStats
+	if a.poller != nil {
+		stats = a.poller.Stats()
+	}
+	if len(stats) == 0 {
+		a.appendLog("尚未轮询, 没有统计数据")
+		return
+	}
+
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(stats) + 1, len(pollStatsColumns)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			label.TextStyle = fyne.TextStyle{Bold: id.Row == 0}
+			if id.Row == 0 {
+				label.SetText(pollStatsColumns[id.Col].title)
+				return
+			}
+			label.SetText(pollStatsCell(stats[id.Row-1], id.Col))
+		},
+	)
+	var width float32
+	for i, col := range pollStatsColumns {
+		table.SetColumnWidth(i, col.width)
+		width += col.width
+	}
+
+	d := dialog.NewCustom("轮询统计", "关闭", table, a.window)
+	d.Resize(fyne.NewSize(width+40, 160+float32(len(stats))*40))
+	d.Show()
+}
+
+// pollStatsCell 返回统计表的单元格文本
+func pollStatsCell(s poller.Stats, col int) string {
+	switch col {
+	case 0:
+		return s.Name
+	case 1:
+		return s.Interval.String()
+	case 2:
+		return strconv.FormatInt(s.Cycles, 10)
+	case 3:
+		return strconv.FormatInt(s.Errors, 10)
+	case 4:
+		return strconv.FormatInt(s.Overruns, 10)
+	case 5:
+		return strconv.FormatInt(s.Skipped, 10)
+	case 6:
+		return formatPollDuration(s.Last)
+	case 7:
+		return formatPollDuration(s.Min)
+	case 8:
+		return formatPollDuration(s.Mean)
+	case 9:
+		return formatPollDuration(s.Max)
+	default:
+		return formatPollDuration(s.MaxLate)
+	}
+}
+
+// formatPollDuration 以毫秒精度显示耗时
+func formatPollDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,244 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/historian"
+	"modbusbaby/internal/recorder"
+	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/modicon"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// recordPoll 判断报警, 并将一次轮询的结果记入历史数据和 CSV 记录, readStart 之后没有成功读取时不记录
+func (a *AppRefined) recordPoll(readStart time.Time) {
+	t, points, ok := a.pollPoints(readStart)
+	a.evaluateAlarms(readStart, points, ok)
+	if !ok {
+		return
+	}
+	a.recordPoints(t, points)
+}
+
+// recordPoints 将一次轮询的数值记入历史数据、CSV 记录和历史数据库
+func (a *AppRefined) recordPoints(t time.Time, points []recorder.Point) {
+	a.recordHistory(t, points)
+	a.recordCSV(t, points)
+	a.recordDatabase(t, points)
+}
+
+// pollPoints 由最近一次读取结果生成各点的数值。
+// 点位按名称命名, 其他按 "从站:地址" 命名, 如 1:HR100; 字符串和时间类型没有数值, 返回 false
+func (a *AppRefined) pollPoints(readStart time.Time) (time.Time, []recorder.Point, bool) {
+	r := a.lastResult
+	if r.values == nil || r.readAt.Before(readStart) {
+		return r.readAt, nil, false
+	}
+	values, ok := datatypes.ToFloat64Slice(r.values)
+	if len(r.scaled) > 0 {
+		values, ok = r.scaled, true
+	}
+	if !ok {
+		return r.readAt, nil, false
+	}
+	rows, err := datatypes.FormatRows(r.values, r.registers, r.dataType, nil)
+	if err != nil || len(rows) != len(values) {
+		return r.readAt, nil, false
+	}
+
+	tag := a.activeTag()
+	points := make([]recorder.Point, len(rows))
+	for i, row := range rows {
+		var name string
+		if tag != nil {
+			name = tagPointName(tag.Name, i, len(rows))
+		} else {
+			addr := modicon.Address{Table: regTypeTables[r.regType], Offset: uint16(r.start + row.Offset)}
+			if row.Bit >= 0 {
+				addr.HasBit, addr.Bit = true, uint8(row.Bit)
+			}
+			name = fmt.Sprintf("%d:%s", r.slaveID, addr)
+		}
+		points[i] = recorder.Point{Name: name, Value: values[i]}
+	}
+	return r.readAt, points, true
+}
+
+// tagPointName 返回点位第 i 个值的点名, 只有一个值时为点位名称, 否则为 名称[序号]
+func tagPointName(name string, i, n int) string {
+	if n == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s[%d]", name, i)
+}
+
+// createRecorderElements 创建 CSV 记录和历史数据库开关, 配置启用历史数据库时立即打开
+func (a *AppRefined) createRecorderElements() {
+	a.historianCheck = widget.NewCheck("存入数据库", func(on bool) {
+		if on {
+			a.openHistorian()
+		} else {
+			a.closeHistorian()
+		}
+	})
+	a.historianCheck.SetChecked(a.config.Historian.Enabled)
+
+	a.csvStatusLabel = widget.NewLabel("")
+	a.csvCheck = widget.NewCheck("记录 CSV", func(on bool) {
+		if on {
+			a.startCSV()
+		} else {
+			a.stopCSV()
+		}
+	})
+}
+
+// createRecorderLayout 创建轮询行中的 CSV 记录部分
+func (a *AppRefined) createRecorderLayout() fyne.CanvasObject {
+	return container.NewHBox(a.historianCheck, a.csvCheck, a.csvStatusLabel)
+}
+
+// startCSV 按配置创建记录器, 之后每次轮询写入一行
+func (a *AppRefined) startCSV() {
+	cfg := a.config.Recorder
+	opts := recorder.Options{
+		Dir:              a.config.RecordPath(),
+		MaxSize:          int64(cfg.MaxSizeMB) << 20,
+		RotateEvery:      time.Duration(cfg.RotateMinutes) * time.Minute,
+		DecimalSeparator: cfg.DecimalSeparator,
+		TimeFormat:       cfg.TimeFormat,
+	}
+	switch {
+	case cfg.Delimiter == `\t`:
+		opts.Delimiter = '\t'
+	case cfg.Delimiter != "":
+		opts.Delimiter = []rune(cfg.Delimiter)[0]
+	}
+	rec, err := recorder.New(opts)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("无法开始 CSV 记录: %v", err))
+		a.csvCheck.SetChecked(false)
+		return
+	}
+	a.csvRecorder = rec
+	a.csvStatusLabel.SetText(fmt.Sprintf("记录到 %s", opts.Dir))
+	a.appendLog(fmt.Sprintf("开始 CSV 记录, 目录: %s; 轮询时每次读取写入一行", opts.Dir))
+}
+
+// stopCSV 关闭当前记录文件
+func (a *AppRefined) stopCSV() {
+	rec := a.csvRecorder
+	if rec == nil {
+		return
+	}
+	a.csvRecorder = nil
+	path, rows := rec.Path()
+	if err := rec.Close(); err != nil {
+		a.appendLog(err.Error())
+	}
+	a.csvStatusLabel.SetText("")
+	if path != "" {
+		a.appendLog(fmt.Sprintf("CSV 记录已停止, 最后一个文件 %s 共 %d 行", path, rows))
+	} else {
+		a.appendLog("CSV 记录已停止")
+	}
+}
+
+// recordCSV 写入一行, 出错时停止记录
+func (a *AppRefined) recordCSV(t time.Time, points []recorder.Point) {
+	rec := a.csvRecorder
+	if rec == nil {
+		return
+	}
+	if err := rec.Record(t, points); err != nil {
+		a.appendLog(fmt.Sprintf("CSV 记录失败: %v", err))
+		a.csvCheck.SetChecked(false)
+		return
+	}
+	if path, rows := rec.Path(); rows == 1 {
+		a.csvStatusLabel.SetText(fmt.Sprintf("记录到 %s", path))
+		a.appendLog(fmt.Sprintf("CSV 记录文件: %s", path))
+	}
+}
+
+// openHistorian 打开历史数据库, 并将各点最近的样本载入趋势图, 使趋势在重启后仍可查看;
+// 趋势图平移或缩放到内存缓冲区之外时从数据库读取更早的数据
+func (a *AppRefined) openHistorian() {
+	if a.historian != nil {
+		return
+	}
+	raw, interval, rollup := a.config.Historian.Retention()
+	h, err := historian.Open(a.config.HistorianPath(), historian.Options{
+		RawRetention:    raw,
+		RollupInterval:  interval,
+		RollupRetention: rollup,
+	})
+	if err != nil {
+		a.appendLog(fmt.Sprintf("无法打开历史数据库: %v", err))
+		a.historianCheck.SetChecked(false)
+		return
+	}
+	a.historian = h
+	a.trendChart.SetArchive(h)
+	a.appendLog(fmt.Sprintf("轮询结果将存入历史数据库 %s", h.Path()))
+
+	names, err := h.Points()
+	if err != nil {
+		a.appendLog(fmt.Sprintf("读取历史数据库失败: %v", err))
+		return
+	}
+	loaded := 0
+	for _, name := range names {
+		if a.history.Len(name) > 0 {
+			continue // 已有本次运行的样本, 不再插入更早的数据
+		}
+		samples, err := h.Latest(name, a.history.Capacity())
+		if err != nil {
+			a.appendLog(fmt.Sprintf("读取历史数据库失败: %v", err))
+			return
+		}
+		for _, s := range samples {
+			a.history.Add(name, s.Time, s.Value)
+		}
+		loaded++
+	}
+	if loaded > 0 {
+		a.trendSeriesCheck.Options = a.history.Names()
+		a.trendSeriesCheck.Refresh()
+		a.appendLog(fmt.Sprintf("已从历史数据库载入 %d 个点的趋势", loaded))
+	}
+}
+
+// closeHistorian 关闭历史数据库
+func (a *AppRefined) closeHistorian() {
+	h := a.historian
+	if h == nil {
+		return
+	}
+	a.historian = nil
+	a.trendChart.SetArchive(nil)
+	if err := h.Close(); err != nil {
+		a.appendLog(fmt.Sprintf("关闭历史数据库失败: %v", err))
+		return
+	}
+	a.appendLog("已停止存入历史数据库")
+}
+
+// recordDatabase 将一次轮询的数值存入历史数据库, 出错时停止存入
+func (a *AppRefined) recordDatabase(t time.Time, points []recorder.Point) {
+	h := a.historian
+	if h == nil {
+		return
+	}
+	values := make(map[string]float64, len(points))
+	for _, p := range points {
+		values[p.Name] = p.Value
+	}
+	if err := h.Record(t, values); err != nil {
+		a.appendLog(fmt.Sprintf("存入历史数据库失败: %v", err))
+		a.historianCheck.SetChecked(false)
+	}
+}
@@ -0,0 +1,155 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/modbus"
+	"modbusbaby/pkg/datatypes"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// createScalingElements 创建缩放设置元素
+func (a *AppRefined) createScalingElements() {
+	a.scaleInput = widget.NewEntry()
+	a.scaleInput.SetText("1")
+
+	a.offsetInput = widget.NewEntry()
+	a.offsetInput.SetText("0")
+
+	a.sfRegisterInput = widget.NewEntry()
+	a.sfRegisterInput.PlaceHolder = "无"
+
+	a.minInput = widget.NewEntry()
+	a.minInput.PlaceHolder = "无"
+
+	a.maxInput = widget.NewEntry()
+	a.maxInput.PlaceHolder = "无"
+
+	a.unitInput = widget.NewEntry()
+	a.unitInput.PlaceHolder = "kWh"
+
+	// 时区用于时间类型数据的显示和写入 (设置设备时钟)
+	a.timeZoneEntry = widget.NewSelectEntry([]string{"Local", "UTC", "UTC+8", "Asia/Shanghai", "Europe/Berlin", "America/New_York"})
+	a.timeZoneEntry.OnChanged = func(name string) {
+		loc, err := datatypes.ParseLocation(name)
+		if err != nil {
+			return // 输入过程中的不完整时区名称, 保持原时区
+		}
+		a.modbus.SetTimeLocation(loc)
+	}
+	timeZone := a.config.TimeZone
+	if timeZone == "" {
+		timeZone = "Local"
+	}
+	a.timeZoneEntry.SetText(timeZone)
+}
+
+// createScalingLayout 创建缩放设置行: 工程值 = 原始值 × 系数 × 10^SF + 偏移
+func (a *AppRefined) createScalingLayout() fyne.CanvasObject {
+	return container.NewHBox(
+		widget.NewLabel("系数:"),
+		container.New(&fixedWidthLayout{width: 80}, a.scaleInput),
+		widget.NewLabel("偏移:"),
+		container.New(&fixedWidthLayout{width: 80}, a.offsetInput),
+		widget.NewLabel("SF寄存器:"),
+		container.New(&fixedWidthLayout{width: 80}, a.sfRegisterInput),
+		widget.NewLabel("下限:"),
+		container.New(&fixedWidthLayout{width: 80}, a.minInput),
+		widget.NewLabel("上限:"),
+		container.New(&fixedWidthLayout{width: 80}, a.maxInput),
+		widget.NewLabel("单位:"),
+		container.New(&fixedWidthLayout{width: 80}, a.unitInput),
+		widget.NewLabel("时区:"),
+		container.New(&minWidthLayout{width: 150}, a.timeZoneEntry),
+		layout.NewSpacer(),
+	)
+}
+
+// scalingFromUI 根据界面输入生成缩放设置
+func (a *AppRefined) scalingFromUI() (datatypes.Scaling, error) {
+	var scaling datatypes.Scaling
+
+	parseFloat := func(entry *widget.Entry, name string) (*float64, error) {
+		text := strings.TrimSpace(entry.Text)
+		if text == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s无效: %s", name, text)
+		}
+		return &v, nil
+	}
+
+	scale, err := parseFloat(a.scaleInput, "系数")
+	if err != nil {
+		return scaling, err
+	}
+	if scale != nil {
+		if *scale == 0 {
+			return scaling, fmt.Errorf("系数不能为0")
+		}
+		scaling.Scale = *scale
+	}
+
+	offset, err := parseFloat(a.offsetInput, "偏移")
+	if err != nil {
+		return scaling, err
+	}
+	if offset != nil {
+		scaling.Offset = *offset
+	}
+
+	if scaling.Min, err = parseFloat(a.minInput, "下限"); err != nil {
+		return scaling, err
+	}
+	if scaling.Max, err = parseFloat(a.maxInput, "上限"); err != nil {
+		return scaling, err
+	}
+
+	if text := strings.TrimSpace(a.sfRegisterInput.Text); text != "" {
+		addr, err := strconv.ParseUint(text, 10, 16)
+		if err != nil {
+			return scaling, fmt.Errorf("SF寄存器地址无效: %s", text)
+		}
+		sfRegister := uint16(addr)
+		scaling.SFRegister = &sfRegister
+	}
+
+	scaling.Unit = strings.TrimSpace(a.unitInput.Text)
+	return scaling, nil
+}
+
+// resolveScaling 如缩放设置指定了SF寄存器, 经 client 从设备读取比例因子。在后台执行, 不访问界面
+func (a *AppRefined) resolveScaling(client *modbus.Client, slaveIDByte byte, regType string, scaling datatypes.Scaling) (datatypes.Scaling, error) {
+	if scaling.SFRegister == nil {
+		return scaling, nil
+	}
+
+	// SF 寄存器按原始值读取, 不受界面上字节序设置的影响
+	var input bool
+	switch regType {
+	case "Holding Register":
+	case "Input Register":
+		input = true
+	default:
+		return scaling, fmt.Errorf("%s 不支持比例因子寄存器", regType)
+	}
+	raw, err := client.ReadRawRegisters(slaveIDByte, input, *scaling.SFRegister, 1)
+	if err != nil {
+		return scaling, fmt.Errorf("读取SF寄存器失败: %w", err)
+	}
+	return scaling.WithScaleFactorRegister(raw[0])
+}
+
+// logScaleFactor 输出从SF寄存器读取到的比例因子
+func (a *AppRefined) logScaleFactor(scaling datatypes.Scaling) {
+	if scaling.SFRegister != nil {
+		a.appendLog(fmt.Sprintf("比例因子 (地址 %d): 10^%d", *scaling.SFRegister, scaling.ScaleFactor))
+	}
+}
@@ -0,0 +1,63 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/pkg/datatypes"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// createStringElements 创建字符串字段设置元素
+func (a *AppRefined) createStringElements() {
+	a.stringLengthInput = widget.NewEntry()
+	a.stringLengthInput.PlaceHolder = "整段"
+
+	a.stringPaddingSelect = widget.NewSelect([]string{"NUL", "空格"}, nil)
+	a.stringPaddingSelect.SetSelected("NUL")
+}
+
+// createStringLayout 创建字符串设置行, 字节交换使用字节序 BA
+func (a *AppRefined) createStringLayout() fyne.CanvasObject {
+	return container.NewHBox(
+		widget.NewLabel("字符串字段长度 (寄存器):"),
+		container.New(&fixedWidthLayout{width: 80}, a.stringLengthInput),
+		widget.NewLabel("填充:"),
+		a.stringPaddingSelect,
+		widget.NewLabel("多个字段每行一个"),
+		layout.NewSpacer(),
+	)
+}
+
+// stringOptionsFromUI 根据界面输入生成字符串字段设置
+func (a *AppRefined) stringOptionsFromUI() (datatypes.StringOptions, error) {
+	var opts datatypes.StringOptions
+	if text := strings.TrimSpace(a.stringLengthInput.Text); text != "" {
+		length, err := strconv.Atoi(text)
+		if err != nil || length < 0 || length > 125 {
+			return opts, fmt.Errorf("字符串字段长度无效: %s", text)
+		}
+		opts.FieldLength = length
+	}
+	if a.stringPaddingSelect.Selected == "空格" {
+		opts.Padding = datatypes.PadSpace
+	}
+	return opts, nil
+}
+
+// applyStringOptions 字符串类型读写前将界面设置应用到转换器
+func (a *AppRefined) applyStringOptions(dataType datatypes.DataType) error {
+	if !dataType.IsStringType() {
+		return nil
+	}
+	opts, err := a.stringOptionsFromUI()
+	if err != nil {
+		return err
+	}
+	a.modbus.SetStringOptions(opts)
+	return nil
+}
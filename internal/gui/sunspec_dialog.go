@@ -0,0 +1,185 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/pkg/sunspec"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// sunspecNode SunSpec 树的节点
+type sunspecNode struct {
+	label    string
+	children []widget.TreeNodeID
+}
+
+// sunspecTree 以树显示解码后的模型: 模型 → 组 → 点位
+type sunspecTree struct {
+	nodes map[widget.TreeNodeID]*sunspecNode
+}
+
+// build 由解码结果重建树
+func (t *sunspecTree) build(dev *sunspec.Device, values []sunspec.ModelValue, models sunspec.Models) {
+	t.nodes = map[widget.TreeNodeID]*sunspecNode{"": {}}
+	if dev == nil {
+		return
+	}
+	for i := range values {
+		mv := &values[i]
+		id := "m" + strconv.Itoa(i)
+		label := fmt.Sprintf("%d %s @ %d (L=%d)", mv.Block.ID, models.Name(mv.Block.ID), mv.Block.Address, mv.Block.Length)
+		if mv.Def == nil {
+			label += " - 无模型定义"
+		}
+		t.add("", id, label)
+		if mv.Def != nil {
+			t.addGroup(id, &mv.Root)
+		}
+	}
+}
+
+// addGroup 添加组内的点位和嵌套组
+func (t *sunspecTree) addGroup(parent widget.TreeNodeID, g *sunspec.GroupValue) {
+	for i := range g.Points {
+		p := &g.Points[i]
+		text := p.Text()
+		if p.Implemented && p.Def.Units != "" {
+			text += " " + p.Def.Units
+		}
+		t.add(parent, fmt.Sprintf("%s/p%d", parent, i), fmt.Sprintf("%s = %s  [%d]", p.Def.Name, text, p.Address))
+	}
+	for i := range g.Groups {
+		sub := &g.Groups[i]
+		id := fmt.Sprintf("%s/g%d", parent, i)
+		t.add(parent, id, sub.Name())
+		t.addGroup(id, sub)
+	}
+}
+
+func (t *sunspecTree) add(parent, id widget.TreeNodeID, label string) {
+	t.nodes[id] = &sunspecNode{label: label}
+	t.nodes[parent].children = append(t.nodes[parent].children, id)
+}
+
+// showSunSpecDialog 显示 SunSpec 模型浏览对话框, 扫描使用当前连接和从站地址
+func (a *AppRefined) showSunSpecDialog() {
+	models := sunspec.DefaultModels()
+	tree := &sunspecTree{}
+	tree.build(nil, nil, models)
+
+	view := widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			if n, ok := tree.nodes[id]; ok {
+				return n.children
+			}
+			return nil
+		},
+		func(id widget.TreeNodeID) bool {
+			n, ok := tree.nodes[id]
+			return ok && (id == "" || len(n.children) > 0)
+		},
+		func(bool) fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TreeNodeID, _ bool, obj fyne.CanvasObject) {
+			if n, ok := tree.nodes[id]; ok {
+				obj.(*widget.Label).SetText(n.label)
+			}
+		},
+	)
+
+	baseEntry := widget.NewEntry()
+	baseEntry.SetText("40000, 0, 50000")
+	statusLabel := widget.NewLabel("")
+
+	var scanButton *widget.Button
+	scanButton = widget.NewButton("扫描", func() {
+		if !a.isConnected || a.disconnecting {
+			statusLabel.SetText("请先连接设备")
+			return
+		}
+		if a.ioBusy() {
+			statusLabel.SetText("正在进行其他读写, 请稍候")
+			return
+		}
+		bases, err := sunspec.ParseBases(baseEntry.Text)
+		if err != nil {
+			statusLabel.SetText(err.Error())
+			return
+		}
+
+		// 扫描需要多次读取, 在后台进行; 关闭对话框后可在主窗口取消
+		reader := a.sunspecReader(a.currentSlaveID())
+		var dev *sunspec.Device
+		statusLabel.SetText("正在扫描...")
+		scanButton.Disable()
+		a.runIO("正在扫描 SunSpec 模型", func() {
+			dev, err = sunspec.Discover(reader, bases)
+		}, func(cancelled bool) {
+			scanButton.Enable()
+			if cancelled {
+				statusLabel.SetText("扫描已取消")
+				return
+			}
+			if dev == nil {
+				statusLabel.SetText(fmt.Sprintf("扫描失败: %v", err))
+				a.appendLog(fmt.Sprintf("SunSpec 扫描失败: %v", err))
+				return
+			}
+			values := sunspec.DecodeDevice(dev, models)
+			tree.build(dev, values, models)
+			view.Refresh()
+
+			status := fmt.Sprintf("基地址 %d, %d 个模型", dev.Base, len(dev.Blocks))
+			if err != nil {
+				status += fmt.Sprintf(" (遍历中断: %v)", err)
+			}
+			statusLabel.SetText(status)
+			a.appendLog("SunSpec: " + status)
+		})
+	})
+
+	loadButton := widget.NewButton("加载模型目录...", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				return
+			}
+			if err := models.LoadDir(dir.Path()); err != nil {
+				a.appendLog(fmt.Sprintf("加载 SunSpec 模型失败: %v", err))
+				return
+			}
+			statusLabel.SetText(fmt.Sprintf("已加载 %d 个模型定义, 请重新扫描", len(models)))
+		}, a.window)
+	})
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("基地址:"), container.NewHBox(scanButton, loadButton), baseEntry),
+		statusLabel,
+	)
+	d := dialog.NewCustom("SunSpec 模型", "关闭", container.NewBorder(top, nil, nil, nil, view), a.window)
+	d.Resize(fyne.NewSize(700, 600))
+	d.Show()
+}
+
+// sunspecReader 返回读取当前连接保持寄存器原始值的函数
+func (a *AppRefined) sunspecReader(slaveID byte) sunspec.ReadFunc {
+	return func(address, count uint16) ([]uint16, error) {
+		return a.modbus.ReadRawRegisters(slaveID, false, address, count)
+	}
+}
+
+// currentSlaveID 返回当前连接类型对应的从站地址
+func (a *AppRefined) currentSlaveID() byte {
+	text := a.slaveIdRtu.Text
+	if a.connectionType.Selected == "Modbus TCP" {
+		text = a.slaveIdTcp.Text
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || id < 0 || id > 255 {
+		return a.slaveIDByte
+	}
+	return byte(id)
+}
@@ -0,0 +1,315 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/pkg/datatypes"
+	"modbusbaby/pkg/modicon"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 数据表: 每个地址一行, 显示等价地址写法、原始寄存器、各进制的解码值、类型和最后变化时间。
+// 点击保持寄存器或线圈的一行可编辑数值并写回设备。
+
+// tagValueWidths 各进制数值列的宽度
+var tagValueWidths = map[datatypes.Radix]float32{
+	datatypes.RadixDecimal: 160,
+	datatypes.RadixHex:     180,
+	datatypes.RadixBinary:  420,
+	datatypes.RadixOctal:   200,
+}
+
+// tagFixedColumns 数值列以外的列数: 地址、等价地址、原始寄存器、类型、最后变化
+const tagFixedColumns = 5
+
+// tagRow 数据表中的一行
+type tagRow struct {
+	datatypes.FormattedRow
+	address string
+	raw     string
+	value   string // 十进制值 (有缩放时为工程值), 编辑时的初始值
+	changed time.Time
+}
+
+// tagChange 记录每个点最近的值和变化时间
+type tagChange struct {
+	value   string
+	changed time.Time
+}
+
+// resultSnapshot 最近一次读取的结果, 也是写回时的地址和类型来源
+type resultSnapshot struct {
+	slaveID   byte
+	regType   string
+	start     int
+	values    interface{}
+	registers []uint16
+	dataType  datatypes.DataType
+	scaled    []float64
+	unit      string
+	readAt    time.Time
+}
+
+// createResultsElements 创建数据表和显示进制选择
+func (a *AppRefined) createResultsElements() {
+	var options []string
+	for _, radix := range datatypes.AllRadixes() {
+		if radix != datatypes.RadixRaw { // 原始寄存器固定显示为单独一列
+			options = append(options, radix.String())
+		}
+	}
+	a.radixCheckGroup = widget.NewCheckGroup(options, func([]string) {
+		a.refreshResults()
+	})
+	a.radixCheckGroup.Horizontal = true
+	a.radixCheckGroup.SetSelected([]string{"DEC", "HEX"})
+
+	a.tagChanges = make(map[string]tagChange)
+	a.tagTable = widget.NewTable(
+		func() (int, int) {
+			return len(a.tagRows) + 1, len(a.tagRadixes) + tagFixedColumns
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			label.TextStyle = fyne.TextStyle{Bold: id.Row == 0}
+			label.SetText(a.tagCell(id.Row, id.Col))
+		},
+	)
+	a.tagTable.OnSelected = func(id widget.TableCellID) {
+		a.tagTable.UnselectAll()
+		if id.Row > 0 && id.Row <= len(a.tagRows) {
+			a.editTagRow(a.tagRows[id.Row-1])
+		}
+	}
+	a.refreshResults()
+}
+
+// createResultsLayout 创建数据表页
+func (a *AppRefined) createResultsLayout() fyne.CanvasObject {
+	header := container.NewHBox(widget.NewLabel("显示:"), a.radixCheckGroup, widget.NewLabel("点击行编辑并写入"))
+	return container.NewBorder(header, nil, nil, nil, a.tagTable)
+}
+
+// selectedRadixes 返回勾选的显示进制, 顺序与选项一致
+func (a *AppRefined) selectedRadixes() []datatypes.Radix {
+	selected := make(map[string]bool)
+	for _, s := range a.radixCheckGroup.Selected {
+		selected[s] = true
+	}
+	var radixes []datatypes.Radix
+	for _, radix := range datatypes.AllRadixes() {
+		if selected[radix.String()] {
+			radixes = append(radixes, radix)
+		}
+	}
+	return radixes
+}
+
+// showResults 记录最近一次读取的结果并刷新数据表。scaled 非空时十进制列显示工程值
+func (a *AppRefined) showResults(slaveID byte, regType string, start int, values interface{}, registers []uint16, dataType datatypes.DataType, scaled []float64, unit string) {
+	a.lastResult = resultSnapshot{
+		slaveID:   slaveID,
+		regType:   regType,
+		start:     start,
+		values:    values,
+		registers: registers,
+		dataType:  dataType,
+		scaled:    scaled,
+		unit:      unit,
+		readAt:    time.Now(),
+	}
+	a.refreshResults()
+}
+
+// refreshResults 按当前勾选的进制重新格式化结果
+func (a *AppRefined) refreshResults() {
+	if a.tagTable == nil {
+		return
+	}
+	a.tagRadixes = a.selectedRadixes()
+	a.tagRows = nil
+
+	r := a.lastResult
+	if r.values != nil {
+		// 第一列总是十进制, 用于判断数值是否变化
+		radixes := append([]datatypes.Radix{datatypes.RadixDecimal, datatypes.RadixRaw}, a.tagRadixes...)
+		rows, err := datatypes.FormatRows(r.values, r.registers, r.dataType, radixes)
+		if err != nil {
+			a.appendLog(fmt.Sprintf("格式化结果失败: %v", err))
+		}
+
+		// 只保留当前结果集中各点的记录, 切换地址或类型后旧的记录随之丢弃
+		now := time.Now()
+		changes := make(map[string]tagChange, len(rows))
+		for i, row := range rows {
+			if len(r.scaled) == len(rows) {
+				scaledText := datatypes.Scaling{Unit: r.unit}.Format(r.scaled[i])
+				row.Columns[0] = scaledText
+				for col, radix := range a.tagRadixes {
+					if radix == datatypes.RadixDecimal {
+						row.Columns[col+2] = scaledText
+					}
+				}
+			}
+
+			address := row.Address(r.start)
+			// BYTE 每个寄存器两行, 地址相同, 键中加上行号区分
+			key := fmt.Sprintf("%d/%s/%s/%s/%d", r.slaveID, r.regType, address, r.dataType, i)
+			change, ok := a.tagChanges[key]
+			if !ok || change.value != row.Columns[0] {
+				change = tagChange{value: row.Columns[0], changed: now}
+			}
+			changes[key] = change
+
+			a.tagRows = append(a.tagRows, tagRow{
+				FormattedRow: datatypes.FormattedRow{Offset: row.Offset, Bit: row.Bit, Columns: row.Columns[2:]},
+				address:      address,
+				raw:          row.Columns[1],
+				value:        row.Columns[0],
+				changed:      change.changed,
+			})
+		}
+		a.tagChanges = changes
+	}
+
+	a.tagTable.SetColumnWidth(0, 90)
+	a.tagTable.SetColumnWidth(1, 260)
+	a.tagTable.SetColumnWidth(2, 160)
+	for i, radix := range a.tagRadixes {
+		a.tagTable.SetColumnWidth(i+3, tagValueWidths[radix])
+	}
+	a.tagTable.SetColumnWidth(len(a.tagRadixes)+3, 110)
+	a.tagTable.SetColumnWidth(len(a.tagRadixes)+4, 110)
+	a.tagTable.Refresh()
+}
+
+// tagCell 返回单元格文本, 第0行为表头
+func (a *AppRefined) tagCell(row, col int) string {
+	valueCols := len(a.tagRadixes)
+	if row > len(a.tagRows) || col >= valueCols+tagFixedColumns {
+		return ""
+	}
+	if row == 0 {
+		switch {
+		case col == 0:
+			return "地址"
+		case col == 1:
+			return "等价地址"
+		case col == 2:
+			return "原始寄存器"
+		case col < valueCols+3:
+			return a.tagRadixes[col-3].String()
+		case col == valueCols+3:
+			return "类型"
+		default:
+			return "最后变化"
+		}
+	}
+
+	r := a.tagRows[row-1]
+	switch {
+	case col == 0:
+		return r.address
+	case col == 1:
+		addr := modicon.Address{Table: regTypeTables[a.lastResult.regType], Offset: uint16(a.lastResult.start + r.Offset)}
+		return strings.Join(addr.Notations(), " / ")
+	case col == 2:
+		return r.raw
+	case col < valueCols+3:
+		return r.Columns[col-3]
+	case col == valueCols+3:
+		if a.lastResult.regType == "Coil" || a.lastResult.regType == "Discrete Input" {
+			return "BOOL"
+		}
+		return a.lastResult.dataType.String()
+	default:
+		return r.changed.Format("15:04:05.000")
+	}
+}
+
+// editTagRow 弹出编辑框, 确认后将该行的新值写回设备
+func (a *AppRefined) editTagRow(row tagRow) {
+	r := a.lastResult
+	if r.regType != "Holding Register" && r.regType != "Coil" {
+		a.appendLog(fmt.Sprintf("%s 为只读, 无法写入", r.regType))
+		return
+	}
+	if tag := a.activeTag(); tag != nil && !tag.Writable() {
+		a.appendLog(fmt.Sprintf("点位 %s 为只读", tag.Name))
+		return
+	}
+	if row.Bit >= 0 {
+		a.appendLog("按位显示的寄存器不支持单独写入, 请使用 UINT16 类型整体写入")
+		return
+	}
+	if r.regType == "Holding Register" && r.dataType == datatypes.BYTE {
+		// 写入单个字节会覆盖同一寄存器中的另一个字节
+		a.appendLog("BYTE 类型每个寄存器含两个字节, 不支持单独写入, 请使用 UINT16 类型整体写入")
+		return
+	}
+
+	current := strings.TrimSuffix(row.value, " "+r.unit)
+	entry := widget.NewEntry()
+	entry.SetText(current)
+
+	items := []*widget.FormItem{widget.NewFormItem("数值", entry)}
+	dialog.ShowForm(fmt.Sprintf("写入地址 %s", row.address), "写入", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		scaling, err := a.scalingFromUI()
+		if err != nil {
+			a.appendLog(fmt.Sprintf("写入失败: %v", err))
+			return
+		}
+		text := entry.Text
+		var writeErr error
+		a.runIO("正在写入", func() {
+			writeErr = a.writeTagRow(r, row, text, scaling)
+		}, func(cancelled bool) {
+			if cancelled {
+				return
+			}
+			if writeErr != nil {
+				a.appendLog(fmt.Sprintf("写入失败: %v", writeErr))
+				return
+			}
+			a.appendLog(fmt.Sprintf("写入成功: 地址 %s = %s", row.address, text))
+			a.readRegister(r.slaveID) // 重新读取以刷新数据表
+		})
+	}, a.window)
+}
+
+// writeTagRow 通过 Write* 方法写入单行的值。在后台执行, 不访问界面
+func (a *AppRefined) writeTagRow(r resultSnapshot, row tagRow, text string, scaling datatypes.Scaling) error {
+	address, err := strconv.Atoi(row.address)
+	if err != nil {
+		return err
+	}
+
+	if r.regType == "Coil" {
+		values, err := datatypes.ParseStringToType(text, datatypes.BOOL)
+		if err != nil {
+			return err
+		}
+		return a.modbus.WriteCoils(r.slaveID, uint16(address), values.([]bool)[:1])
+	}
+
+	if scaling, err = a.resolveScaling(a.modbus, r.slaveID, r.regType, scaling); err != nil {
+		return err
+	}
+	values, err := parseWriteValues(text, r.dataType, scaling)
+	if err != nil {
+		return err
+	}
+	return a.modbus.WriteHoldingRegisters(r.slaveID, uint16(address), values, r.dataType)
+}
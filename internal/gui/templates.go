@@ -0,0 +1,174 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/config"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const templateNone = "无"
+
+// createTemplateElements 创建设备模板选择元素, 并加载内置模板和用户模板目录
+func (a *AppRefined) createTemplateElements() {
+	a.templateInfoLabel = widget.NewLabel("")
+	a.templateSelect = widget.NewSelect([]string{templateNone}, func(name string) {
+		a.applyTemplate(name)
+	})
+	a.templateSaveBtn = widget.NewButton("保存为模板...", a.saveTemplate)
+	a.reloadTemplates()
+}
+
+// createTemplateLayout 创建设备模板行
+func (a *AppRefined) createTemplateLayout() fyne.CanvasObject {
+	return container.NewHBox(
+		widget.NewLabel("设备模板:"),
+		container.New(&minWidthLayout{width: 200}, a.templateSelect),
+		widget.NewButton("刷新", a.reloadTemplates),
+		a.templateSaveBtn,
+		a.templateInfoLabel,
+		layout.NewSpacer(),
+	)
+}
+
+// reloadTemplates 重新加载模板列表, 无法解析的用户模板输出到日志
+func (a *AppRefined) reloadTemplates() {
+	templates, errs := a.config.LoadTemplates()
+	for _, err := range errs {
+		a.appendLog(fmt.Sprintf("加载设备模板失败: %v", err))
+	}
+	a.templates = templates
+
+	options := []string{templateNone}
+	for _, t := range templates {
+		options = append(options, t.Name)
+	}
+	a.templateSelect.Options = options
+	a.templateSelect.SetSelected(templateNone)
+}
+
+// applyTemplate 按模板设置连接参数、字节/字序和点位表
+func (a *AppRefined) applyTemplate(name string) {
+	t, ok := config.FindTemplate(a.templates, name)
+	if !ok {
+		a.templateInfoLabel.SetText("")
+		return
+	}
+	db, err := t.TagDatabase()
+	if err != nil {
+		a.appendLog(fmt.Sprintf("模板 %s 的点位表无效: %v", t.Name, err))
+		return
+	}
+
+	t.Apply(a.config)
+	if a.config.DefaultConnType == "RTU" {
+		a.connectionType.SetSelected("Modbus RTU")
+	} else {
+		a.connectionType.SetSelected("Modbus TCP")
+	}
+	a.portEntry.SetText(strconv.Itoa(a.config.TCP.Port))
+	a.slaveIdTcp.SetText(strconv.Itoa(a.config.TCP.SlaveID))
+	a.slaveIdRtu.SetText(strconv.Itoa(a.config.RTU.SlaveID))
+	a.baudRate.SetText(strconv.Itoa(a.config.RTU.BaudRate))
+	a.dataBits.SetSelected(strconv.Itoa(a.config.RTU.DataBits))
+	a.stopBits.SetSelected(strconv.FormatFloat(a.config.RTU.StopBits, 'f', -1, 64))
+	a.parity.SetSelected(a.config.RTU.Parity)
+
+	// 字节/字序下拉框的 OnChanged 会同步更新数据转换器
+	byteOrder, wordOrder := t.Orders()
+	a.byteOrderCombo.SetSelected(byteOrder.String())
+	a.wordOrderCombo.SetSelected(wordOrder.String())
+
+	a.setTagDatabase(db)
+
+	info := strings.TrimSpace(t.Manufacturer + " " + t.Model)
+	if t.Description != "" {
+		info += " - " + t.Description
+	}
+	a.templateInfoLabel.SetText(info)
+	a.appendLog(fmt.Sprintf("已应用设备模板 %s", t.Name))
+	if a.isConnected {
+		a.appendLog("连接参数在重新连接后生效")
+	}
+}
+
+// saveTemplate 将当前连接参数、字节/字序和点位表保存为用户模板
+func (a *AppRefined) saveTemplate() {
+	nameEntry := widget.NewEntry()
+	if t, ok := config.FindTemplate(a.templates, a.templateSelect.Selected); ok {
+		nameEntry.SetText(t.Name)
+	}
+	items := []*widget.FormItem{widget.NewFormItem("模板名称", nameEntry)}
+
+	dialog.ShowForm("保存为模板", "保存", "取消", items, func(ok bool) {
+		name := strings.TrimSpace(nameEntry.Text)
+		if !ok || name == "" {
+			return
+		}
+		dir := a.config.TemplatePath()
+		if dir == "" {
+			a.appendLog("无法确定用户模板目录")
+			return
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			a.appendLog(fmt.Sprintf("保存设备模板失败: %v", err))
+			return
+		}
+
+		t := a.templateFromUI(name)
+		path := filepath.Join(dir, templateFileName(name))
+		if err := config.SaveTemplate(path, t); err != nil {
+			a.appendLog(fmt.Sprintf("保存设备模板失败: %v", err))
+			return
+		}
+		a.appendLog(fmt.Sprintf("设备模板已保存到 %s", path))
+		a.reloadTemplates()
+	}, a.window)
+}
+
+// templateFromUI 由当前界面设置生成设备模板
+func (a *AppRefined) templateFromUI(name string) *config.DeviceTemplate {
+	t := &config.DeviceTemplate{
+		Name:      name,
+		ByteOrder: a.byteOrderCombo.Selected,
+		WordOrder: a.wordOrderCombo.Selected,
+	}
+	if a.connectionType.Selected == "Modbus RTU" {
+		t.Connection.Type = "RTU"
+		t.Connection.SlaveID, _ = strconv.Atoi(a.slaveIdRtu.Text)
+		t.Connection.BaudRate, _ = strconv.Atoi(a.baudRate.Text)
+		t.Connection.DataBits, _ = strconv.Atoi(a.dataBits.Selected)
+		t.Connection.StopBits, _ = strconv.ParseFloat(a.stopBits.Selected, 64)
+		t.Connection.Parity = a.parity.Selected
+	} else {
+		t.Connection.Type = "TCP"
+		t.Connection.Port, _ = strconv.Atoi(a.portEntry.Text)
+		t.Connection.SlaveID, _ = strconv.Atoi(a.slaveIdTcp.Text)
+	}
+	if a.tagDB != nil {
+		t.Tags = a.tagDB.Tags
+	}
+	return t
+}
+
+// templateFileName 由模板名称生成文件名, 非字母数字替换为下划线
+func templateFileName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r > 0x7F:
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String() + ".yaml"
+}
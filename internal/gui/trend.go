@@ -0,0 +1,72 @@
+package gui
+
+import (
+	"fmt"
+	"modbusbaby/internal/recorder"
+	"modbusbaby/pkg/history"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// createTrendElements 创建历史数据存储、趋势图和曲线选择
+func (a *AppRefined) createTrendElements() {
+	a.history = history.NewStore(a.config.HistorySize)
+	a.trendChart = newTrendChart(a.history)
+	a.trendSeriesCheck = widget.NewCheckGroup(nil, func(selected []string) {
+		a.trendChart.SetSeries(selected)
+	})
+	a.trendSeriesCheck.Horizontal = true
+}
+
+// createTrendLayout 创建趋势页
+func (a *AppRefined) createTrendLayout() fyne.CanvasObject {
+	header := container.NewBorder(nil, nil,
+		widget.NewLabel("曲线:"),
+		container.NewHBox(
+			widget.NewButton("实时", a.trendChart.Follow),
+			widget.NewButton("全部", a.trendChart.FitAll),
+			widget.NewButton("清空", a.clearHistory),
+		),
+		container.NewHScroll(a.trendSeriesCheck),
+	)
+	hint := container.NewHBox(
+		widget.NewLabel(fmt.Sprintf("轮询时记录, 每个点保留最近 %d 个样本, 存入数据库时可查看更早的数据; 滚轮缩放, 拖动平移, 双击恢复实时", a.history.Capacity())),
+		layout.NewSpacer(),
+	)
+	return container.NewBorder(header, hint, nil, nil, a.trendChart)
+}
+
+// recordHistory 将一次轮询的数值追加到历史数据
+func (a *AppRefined) recordHistory(t time.Time, points []recorder.Point) {
+	var added []string
+	for _, p := range points {
+		if a.history.Add(p.Name, t, p.Value) {
+			added = append(added, p.Name)
+		}
+	}
+
+	// 新出现的点自动勾选, 自动勾选的曲线数不超过颜色数
+	if len(added) > 0 {
+		selected := append([]string(nil), a.trendSeriesCheck.Selected...)
+		for _, name := range added {
+			if len(selected) < len(trendColors) {
+				selected = append(selected, name)
+			}
+		}
+		a.trendSeriesCheck.Options = a.history.Names()
+		a.trendSeriesCheck.SetSelected(selected)
+	}
+	a.trendChart.Refresh()
+}
+
+// clearHistory 清空历史数据和曲线列表
+func (a *AppRefined) clearHistory() {
+	a.history.Clear()
+	a.trendSeriesCheck.Options = nil
+	a.trendSeriesCheck.SetSelected(nil)
+	a.trendChart.Refresh()
+}
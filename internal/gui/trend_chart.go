@@ -0,0 +1,486 @@
+package gui
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"modbusbaby/internal/historian"
+	"modbusbaby/pkg/history"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 趋势图: 横轴为时间, 纵轴按可见数据自动缩放。
+// 滚轮以鼠标位置为中心缩放时间轴, 拖动平移, 双击恢复实时跟随; 鼠标悬停时显示光标处各曲线的值。
+// 时间轴超出内存中最早的样本时, 更早的部分从历史数据库按像素宽度聚合后读取。
+
+const (
+	trendDefaultSpan = 2 * time.Minute
+	trendMinSpan     = time.Second
+	trendMaxSpan     = 24 * time.Hour
+	trendZoomStep    = 1.25
+
+	trendMarginLeft   = 64
+	trendMarginRight  = 12
+	trendMarginTop    = 22
+	trendMarginBottom = 22
+	trendGridLines    = 5
+)
+
+// trendColors 曲线颜色, 按曲线序号循环使用
+var trendColors = []color.Color{
+	color.NRGBA{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff},
+	color.NRGBA{R: 0xff, G: 0x7f, B: 0x0e, A: 0xff},
+	color.NRGBA{R: 0x2c, G: 0xa0, B: 0x2c, A: 0xff},
+	color.NRGBA{R: 0xd6, G: 0x27, B: 0x28, A: 0xff},
+	color.NRGBA{R: 0x94, G: 0x67, B: 0xbd, A: 0xff},
+	color.NRGBA{R: 0x8c, G: 0x56, B: 0x4b, A: 0xff},
+	color.NRGBA{R: 0xe3, G: 0x77, B: 0xc2, A: 0xff},
+	color.NRGBA{R: 0x17, G: 0xbe, B: 0xcf, A: 0xff},
+}
+
+// trendChart 显示 history.Store 中选定曲线的趋势图
+type trendChart struct {
+	widget.BaseWidget
+	store *history.Store
+
+	mu       sync.Mutex
+	series   []string      // 显示的曲线
+	span     time.Duration // 时间轴宽度
+	end      time.Time     // 不跟随时时间轴右端
+	follow   bool          // 右端跟随最新样本
+	cursorX  float32       // 鼠标在控件中的横坐标
+	hovering bool
+
+	archive  *historian.Historian    // 历史数据库, 未打开时为 nil
+	archived map[string]archiveEntry // 按曲线缓存的数据库查询结果
+}
+
+// archiveEntry 一次历史数据库查询的条件和结果, 条件不变时 (如鼠标移动) 不重复查询
+type archiveEntry struct {
+	from, to time.Time
+	interval time.Duration
+	samples  []history.Sample
+}
+
+func newTrendChart(store *history.Store) *trendChart {
+	c := &trendChart{store: store, span: trendDefaultSpan, follow: true}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetSeries 设置显示的曲线
+func (c *trendChart) SetSeries(names []string) {
+	c.mu.Lock()
+	c.series = append([]string(nil), names...)
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// SetArchive 设置内存缓冲区之外的数据来源, nil 表示只显示内存中的样本。
+// 关闭数据库前应先调用 SetArchive(nil), 等待正在进行的查询结束
+func (c *trendChart) SetArchive(h *historian.Historian) {
+	c.mu.Lock()
+	c.archive = h
+	c.archived = nil
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// Follow 恢复实时跟随最新样本
+func (c *trendChart) Follow() {
+	c.mu.Lock()
+	c.follow = true
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// FitAll 缩放时间轴以显示全部样本
+func (c *trendChart) FitAll() {
+	first, last, ok := c.store.Bounds()
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	c.span = clampSpan(last.Sub(first))
+	c.end = last
+	c.follow = true
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// window 返回当前时间轴的起止时间
+func (c *trendChart) window() (time.Time, time.Time) {
+	end := c.end
+	if c.follow {
+		if _, last, ok := c.store.Bounds(); ok {
+			end = last
+		} else {
+			end = time.Now()
+		}
+	}
+	return end.Add(-c.span), end
+}
+
+// plotWidth 返回绘图区宽度
+func (c *trendChart) plotWidth() float32 {
+	return c.Size().Width - trendMarginLeft - trendMarginRight
+}
+
+// Scrolled 以鼠标位置为中心缩放时间轴
+func (c *trendChart) Scrolled(ev *fyne.ScrollEvent) {
+	if ev.Scrolled.DY == 0 {
+		return
+	}
+	width := c.plotWidth()
+	if width <= 0 {
+		return
+	}
+	c.mu.Lock()
+	start, end := c.window()
+	factor := trendZoomStep
+	if ev.Scrolled.DY > 0 {
+		factor = 1 / trendZoomStep
+	}
+	span := clampSpan(time.Duration(float64(c.span) * factor))
+	if !c.follow {
+		ratio := float64((ev.Position.X - trendMarginLeft) / width)
+		ratio = math.Max(0, math.Min(1, ratio))
+		anchor := start.Add(time.Duration(ratio * float64(end.Sub(start))))
+		c.end = anchor.Add(time.Duration((1 - ratio) * float64(span)))
+	}
+	c.span = span
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// Dragged 平移时间轴, 拖过最新样本时恢复实时跟随
+func (c *trendChart) Dragged(ev *fyne.DragEvent) {
+	width := c.plotWidth()
+	if width <= 0 {
+		return
+	}
+	c.mu.Lock()
+	_, end := c.window()
+	end = end.Add(-time.Duration(float64(ev.Dragged.DX/width) * float64(c.span)))
+	c.end, c.follow = end, false
+	if _, last, ok := c.store.Bounds(); ok && !end.Before(last) {
+		c.follow = true
+	}
+	c.cursorX = ev.Position.X
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+func (c *trendChart) DragEnd() {}
+
+// DoubleTapped 恢复默认时间轴宽度和实时跟随
+func (c *trendChart) DoubleTapped(*fyne.PointEvent) {
+	c.mu.Lock()
+	c.span, c.follow = trendDefaultSpan, true
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+func (c *trendChart) MouseIn(ev *desktop.MouseEvent) {
+	c.MouseMoved(ev)
+}
+
+func (c *trendChart) MouseMoved(ev *desktop.MouseEvent) {
+	c.mu.Lock()
+	c.cursorX, c.hovering = ev.Position.X, true
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+func (c *trendChart) MouseOut() {
+	c.mu.Lock()
+	c.hovering = false
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+func (c *trendChart) CreateRenderer() fyne.WidgetRenderer {
+	r := &trendRenderer{chart: c}
+	r.rebuild()
+	return r
+}
+
+// clampSpan 将时间轴宽度限制在允许范围内
+func clampSpan(span time.Duration) time.Duration {
+	return time.Duration(math.Max(float64(trendMinSpan), math.Min(float64(trendMaxSpan), float64(span))))
+}
+
+// trendRenderer 每次刷新时按当前窗口重新生成所有图形对象
+type trendRenderer struct {
+	chart   *trendChart
+	objects []fyne.CanvasObject
+}
+
+func (r *trendRenderer) Layout(fyne.Size) { r.rebuild() }
+
+func (r *trendRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(trendMarginLeft+trendMarginRight+200, trendMarginTop+trendMarginBottom+120)
+}
+
+func (r *trendRenderer) Refresh() {
+	r.rebuild()
+	canvas.Refresh(r.chart)
+}
+
+func (r *trendRenderer) Objects() []fyne.CanvasObject { return r.objects }
+
+func (r *trendRenderer) Destroy() {}
+
+// rebuild 生成背景、网格、坐标、曲线、图例和光标读数
+func (r *trendRenderer) rebuild() {
+	c := r.chart
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := c.Size()
+	fg := theme.Color(theme.ColorNameForeground)
+	gridColor := theme.Color(theme.ColorNameSeparator)
+	textSize := theme.CaptionTextSize()
+
+	bg := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	bg.Resize(size)
+	objects := []fyne.CanvasObject{bg}
+
+	left, top := float32(trendMarginLeft), float32(trendMarginTop)
+	width := size.Width - trendMarginLeft - trendMarginRight
+	height := size.Height - trendMarginTop - trendMarginBottom
+	if width <= 0 || height <= 0 {
+		r.objects = objects
+		return
+	}
+
+	start, end := c.window()
+	span := end.Sub(start)
+	data := make([][]history.Sample, len(c.series))
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for i, name := range c.series {
+		data[i] = c.store.Range(name, start, end)
+		if len(data[i]) == 0 || data[i][0].Time.After(start) {
+			before := end
+			if len(data[i]) > 0 {
+				before = data[i][0].Time
+			}
+			data[i] = append(c.archiveRange(name, start, before, span/time.Duration(width)), data[i]...)
+		}
+		for _, s := range data[i] {
+			if s.Time.Before(start) || s.Time.After(end) {
+				continue
+			}
+			minY, maxY = math.Min(minY, s.Value), math.Max(maxY, s.Value)
+		}
+	}
+	if math.IsInf(minY, 0) {
+		msg := canvas.NewText("暂无数据: 开始轮询后显示所选点的趋势", fg)
+		msg.TextSize = textSize
+		msg.Move(fyne.NewPos(left+8, top+8))
+		r.objects = append(objects, msg)
+		return
+	}
+	if minY == maxY {
+		pad := math.Max(math.Abs(minY)*0.1, 1)
+		minY, maxY = minY-pad, maxY+pad
+	} else {
+		pad := (maxY - minY) * 0.05
+		minY, maxY = minY-pad, maxY+pad
+	}
+
+	toX := func(t time.Time) float32 {
+		return left + float32(float64(t.Sub(start))/float64(span))*width
+	}
+	toY := func(v float64) float32 {
+		return top + float32((maxY-v)/(maxY-minY))*height
+	}
+
+	// 网格和坐标
+	timeFormat := "15:04:05"
+	if span < 10*time.Second {
+		timeFormat = "15:04:05.0"
+	}
+	for i := 0; i <= trendGridLines; i++ {
+		y := top + height*float32(i)/trendGridLines
+		objects = append(objects, newLine(gridColor, left, y, left+width, y))
+		value := maxY - (maxY-minY)*float64(i)/trendGridLines
+		label := canvas.NewText(strconv.FormatFloat(value, 'g', 6, 64), fg)
+		label.TextSize = textSize
+		label.Alignment = fyne.TextAlignTrailing
+		label.Resize(fyne.NewSize(left-6, textSize))
+		label.Move(fyne.NewPos(0, y-textSize/2-2))
+		objects = append(objects, label)
+
+		x := left + width*float32(i)/trendGridLines
+		objects = append(objects, newLine(gridColor, x, top, x, top+height))
+		t := start.Add(time.Duration(float64(span) * float64(i) / trendGridLines))
+		tl := canvas.NewText(t.Format(timeFormat), fg)
+		tl.TextSize = textSize
+		tl.Alignment = fyne.TextAlignCenter
+		tl.Resize(fyne.NewSize(80, textSize))
+		tlX := x - 40
+		if tlX+80 > size.Width {
+			tlX = size.Width - 80
+			tl.Alignment = fyne.TextAlignTrailing
+		}
+		tl.Move(fyne.NewPos(tlX, top+height+4))
+		objects = append(objects, tl)
+	}
+
+	// 曲线: 同一像素列内的样本合并为最小值和最大值, 样本数远多于像素时保持绘制开销有界
+	for i, samples := range data {
+		col := trendColors[i%len(trendColors)]
+		var points []fyne.Position
+		lastCol := math.MinInt
+		for _, s := range samples {
+			p := fyne.NewPos(toX(s.Time), toY(s.Value))
+			px := int(p.X)
+			if px == lastCol {
+				low, high := &points[len(points)-2], &points[len(points)-1]
+				low.Y = float32(math.Max(float64(low.Y), float64(p.Y)))
+				high.Y = float32(math.Min(float64(high.Y), float64(p.Y)))
+				continue
+			}
+			lastCol = px
+			points = append(points, p, p)
+		}
+		for j := 1; j < len(points); j++ {
+			a, b := clipSegment(points[j-1], points[j], left, left+width)
+			if a.X <= b.X {
+				line := newLine(col, a.X, a.Y, b.X, b.Y)
+				line.StrokeWidth = 1.5
+				objects = append(objects, line)
+			}
+		}
+	}
+
+	// 图例
+	x := left
+	for i, name := range c.series {
+		legend := canvas.NewText("■ "+name, trendColors[i%len(trendColors)])
+		legend.TextSize = textSize
+		legend.Move(fyne.NewPos(x, 2))
+		objects = append(objects, legend)
+		x += fyne.MeasureText(legend.Text, textSize, fyne.TextStyle{}).Width + 12
+	}
+
+	// 光标读数
+	if c.hovering && c.cursorX >= left && c.cursorX <= left+width {
+		objects = append(objects, newLine(fg, c.cursorX, top, c.cursorX, top+height))
+		at := start.Add(time.Duration(float64((c.cursorX-left)/width) * float64(span)))
+		lines := []string{at.Format("15:04:05.000")}
+		for i, name := range c.series {
+			if s, ok := sampleAt(data[i], at); ok {
+				lines = append(lines, fmt.Sprintf("%s = %s", name, strconv.FormatFloat(s.Value, 'g', 10, 64)))
+			}
+		}
+		var boxWidth float32
+		for _, line := range lines {
+			boxWidth = float32(math.Max(float64(boxWidth), float64(fyne.MeasureText(line, textSize, fyne.TextStyle{}).Width)))
+		}
+		boxX := c.cursorX + 8
+		if boxX+boxWidth+8 > left+width {
+			boxX = c.cursorX - boxWidth - 16
+		}
+		box := canvas.NewRectangle(theme.Color(theme.ColorNameOverlayBackground))
+		box.StrokeColor = gridColor
+		box.StrokeWidth = 1
+		box.Move(fyne.NewPos(boxX, top+4))
+		box.Resize(fyne.NewSize(boxWidth+8, float32(len(lines))*(textSize+4)+4))
+		objects = append(objects, box)
+		for i, line := range lines {
+			textColor := fg
+			if i > 0 {
+				textColor = trendColors[(i-1)%len(trendColors)]
+			}
+			t := canvas.NewText(line, textColor)
+			t.TextSize = textSize
+			t.Move(fyne.NewPos(boxX+4, top+6+float32(i)*(textSize+4)))
+			objects = append(objects, t)
+		}
+	}
+
+	r.objects = objects
+}
+
+// archiveRange 从历史数据库读取 [from, before) 内的数据, 按 interval (约一个像素) 聚合。
+// 每个间隔依次给出最小值、最大值和平均值, 绘制时合并为一列, 光标读数取平均值
+func (c *trendChart) archiveRange(name string, from, before time.Time, interval time.Duration) []history.Sample {
+	if c.archive == nil || !from.Before(before) {
+		return nil
+	}
+	interval = max(interval.Truncate(time.Millisecond), time.Millisecond)
+	from = from.Truncate(interval)
+	if entry, ok := c.archived[name]; ok && entry.from.Equal(from) && entry.to.Equal(before) && entry.interval == interval {
+		return entry.samples[:len(entry.samples):len(entry.samples)]
+	}
+
+	// 内存中已有的样本不再从数据库读取
+	aggregates, err := c.archive.Downsample(name, from, before.Add(-time.Millisecond), interval)
+	if err != nil {
+		return nil
+	}
+	samples := make([]history.Sample, 0, 3*len(aggregates))
+	for _, a := range aggregates {
+		if a.Count > 1 {
+			samples = append(samples, history.Sample{Time: a.Time, Value: a.Min}, history.Sample{Time: a.Time, Value: a.Max})
+		}
+		samples = append(samples, history.Sample{Time: a.Time, Value: a.Avg})
+	}
+	if c.archived == nil {
+		c.archived = make(map[string]archiveEntry)
+	}
+	c.archived[name] = archiveEntry{from: from, to: before, interval: interval, samples: samples}
+	return samples[:len(samples):len(samples)]
+}
+
+// newLine 创建两点间的线段
+func newLine(col color.Color, x1, y1, x2, y2 float32) *canvas.Line {
+	line := canvas.NewLine(col)
+	line.StrokeWidth = 1
+	line.Position1 = fyne.NewPos(x1, y1)
+	line.Position2 = fyne.NewPos(x2, y2)
+	return line
+}
+
+// clipSegment 将线段裁剪到横坐标 [minX, maxX] 内, 完全在外时返回 a.X > b.X 的线段
+func clipSegment(a, b fyne.Position, minX, maxX float32) (fyne.Position, fyne.Position) {
+	if b.X < minX || a.X > maxX {
+		return fyne.NewPos(1, 0), fyne.NewPos(0, 0)
+	}
+	interp := func(x float32) fyne.Position {
+		if b.X == a.X {
+			return fyne.NewPos(x, a.Y)
+		}
+		return fyne.NewPos(x, a.Y+(b.Y-a.Y)*(x-a.X)/(b.X-a.X))
+	}
+	if a.X < minX {
+		a = interp(minX)
+	}
+	if b.X > maxX {
+		b = interp(maxX)
+	}
+	return a, b
+}
+
+// sampleAt 返回时间 t 时的值, 即 t 之前 (含) 的最后一个样本
+func sampleAt(samples []history.Sample, t time.Time) (history.Sample, bool) {
+	var found history.Sample
+	ok := false
+	for _, s := range samples {
+		if s.Time.After(t) {
+			break
+		}
+		found, ok = s, true
+	}
+	return found, ok
+}
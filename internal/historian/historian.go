@@ -0,0 +1,301 @@
+// Package historian 将轮询结果保存到本地 SQLite 数据库 (纯 Go 驱动, 无需 cgo)
+//
+// 原始样本保存在 samples 表, 时间为 Unix 毫秒。超过原始数据保留期的样本按固定间隔汇总为
+// 最小/最大/平均值存入 rollups 表后删除, 汇总数据超过其保留期后也删除, 数据库大小因此有界。
+// 查询时原始样本和汇总数据一起按所需间隔聚合, 两者的分界对调用方透明。
+package historian
+
+import (
+	"database/sql"
+	"fmt"
+	"modbusbaby/pkg/history"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS points (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS samples (
+	point_id INTEGER NOT NULL,
+	ts       INTEGER NOT NULL,
+	value    REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_point_ts ON samples (point_id, ts);
+CREATE TABLE IF NOT EXISTS rollups (
+	point_id INTEGER NOT NULL,
+	ts       INTEGER NOT NULL,
+	min      REAL NOT NULL,
+	max      REAL NOT NULL,
+	avg      REAL NOT NULL,
+	count    INTEGER NOT NULL,
+	PRIMARY KEY (point_id, ts)
+);
+`
+
+// maintainEvery 记录时执行保留策略的最小间隔
+const maintainEvery = 10 * time.Minute
+
+// Options 保留策略
+type Options struct {
+	RawRetention    time.Duration // 原始样本保留时长, 0 表示永久保留
+	RollupInterval  time.Duration // 汇总间隔, 默认 1 分钟
+	RollupRetention time.Duration // 汇总数据保留时长, 0 表示永久保留
+}
+
+// Aggregate 一个时间间隔内的汇总值
+type Aggregate struct {
+	Time  time.Time // 间隔起始时间
+	Min   float64
+	Max   float64
+	Avg   float64
+	Count int64
+}
+
+// Historian SQLite 历史数据库, 可被多个 goroutine 同时使用
+type Historian struct {
+	db   *sql.DB
+	path string
+	opts Options
+
+	mu           sync.Mutex
+	ids          map[string]int64
+	lastMaintain time.Time
+}
+
+// Open 打开或创建数据库文件
+func Open(path string, opts Options) (*Historian, error) {
+	if opts.RollupInterval <= 0 {
+		opts.RollupInterval = time.Minute
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+	}
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	// SQLite 同一时间只允许一个写入者, 单连接避免 SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库失败: %w", err)
+	}
+	return &Historian{db: db, path: path, opts: opts, ids: make(map[string]int64)}, nil
+}
+
+// Path 返回数据库文件路径
+func (h *Historian) Path() string {
+	return h.path
+}
+
+// Close 关闭数据库
+func (h *Historian) Close() error {
+	return h.db.Close()
+}
+
+// Record 在一个事务中写入一次轮询的所有数值, 并按需执行保留策略
+func (h *Historian) Record(t time.Time, values map[string]float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ts := t.UnixMilli()
+	for name, value := range values {
+		id, err := h.pointID(tx, name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO samples (point_id, ts, value) VALUES (?, ?, ?)`, id, ts, value); err != nil {
+			return fmt.Errorf("写入样本失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("写入样本失败: %w", err)
+	}
+
+	if t.Sub(h.lastMaintain) >= maintainEvery {
+		h.lastMaintain = t
+		return h.maintain(t)
+	}
+	return nil
+}
+
+// pointID 返回点的编号, 不存在时创建
+func (h *Historian) pointID(tx *sql.Tx, name string) (int64, error) {
+	if id, ok := h.ids[name]; ok {
+		return id, nil
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO points (name) VALUES (?)`, name); err != nil {
+		return 0, fmt.Errorf("创建点失败: %w", err)
+	}
+	var id int64
+	if err := tx.QueryRow(`SELECT id FROM points WHERE name = ?`, name).Scan(&id); err != nil {
+		return 0, fmt.Errorf("创建点失败: %w", err)
+	}
+	h.ids[name] = id
+	return id, nil
+}
+
+// Maintain 立即执行保留策略
+func (h *Historian) Maintain(now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastMaintain = now
+	return h.maintain(now)
+}
+
+// maintain 将超过保留期的原始样本汇总后删除, 并删除超过保留期的汇总数据。
+// 只汇总完整的间隔, 跨越分界的间隔留到下次处理
+func (h *Historian) maintain(now time.Time) error {
+	if h.opts.RawRetention > 0 {
+		step := h.opts.RollupInterval.Milliseconds()
+		cutoff := now.Add(-h.opts.RawRetention).UnixMilli() / step * step
+
+		tx, err := h.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		// 与已有汇总合并: 同一间隔可能分几次汇总
+		_, err = tx.Exec(`
+			INSERT INTO rollups (point_id, ts, min, max, avg, count)
+			SELECT point_id, (ts / ?1) * ?1 AS bucket, MIN(value), MAX(value), AVG(value), COUNT(*)
+			FROM samples WHERE ts < ?2 GROUP BY point_id, bucket
+			ON CONFLICT (point_id, ts) DO UPDATE SET
+				min = MIN(min, excluded.min),
+				max = MAX(max, excluded.max),
+				avg = (avg * count + excluded.avg * excluded.count) / (count + excluded.count),
+				count = count + excluded.count`, step, cutoff)
+		if err != nil {
+			return fmt.Errorf("汇总样本失败: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM samples WHERE ts < ?`, cutoff); err != nil {
+			return fmt.Errorf("删除过期样本失败: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("汇总样本失败: %w", err)
+		}
+	}
+	if h.opts.RollupRetention > 0 {
+		cutoff := now.Add(-h.opts.RollupRetention).UnixMilli()
+		if _, err := h.db.Exec(`DELETE FROM rollups WHERE ts < ?`, cutoff); err != nil {
+			return fmt.Errorf("删除过期汇总失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Points 返回所有点的名称
+func (h *Historian) Points() ([]string, error) {
+	rows, err := h.db.Query(`SELECT name FROM points ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Query 按时间顺序返回 [from, to] 内的原始样本, 已汇总的部分不包含在内
+func (h *Historian) Query(name string, from, to time.Time) ([]history.Sample, error) {
+	rows, err := h.db.Query(`
+		SELECT s.ts, s.value FROM samples s JOIN points p ON p.id = s.point_id
+		WHERE p.name = ? AND s.ts BETWEEN ? AND ? ORDER BY s.ts`,
+		name, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return scanSamples(rows)
+}
+
+// HasRollups 返回 [from, to] 内是否有已汇总的数据, 即 Query 不会返回的部分
+func (h *Historian) HasRollups(name string, from, to time.Time) (bool, error) {
+	var found bool
+	err := h.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM rollups r JOIN points p ON p.id = r.point_id
+		WHERE p.name = ? AND r.ts BETWEEN ? AND ?)`,
+		name, from.UnixMilli(), to.UnixMilli()).Scan(&found)
+	return found, err
+}
+
+// Latest 按时间顺序返回点最近的 n 个原始样本
+func (h *Historian) Latest(name string, n int) ([]history.Sample, error) {
+	rows, err := h.db.Query(`
+		SELECT s.ts, s.value FROM samples s JOIN points p ON p.id = s.point_id
+		WHERE p.name = ? ORDER BY s.ts DESC LIMIT ?`, name, n)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := scanSamples(rows)
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, err
+}
+
+func scanSamples(rows *sql.Rows) ([]history.Sample, error) {
+	defer rows.Close()
+	var samples []history.Sample
+	for rows.Next() {
+		var ts int64
+		var value float64
+		if err := rows.Scan(&ts, &value); err != nil {
+			return nil, err
+		}
+		samples = append(samples, history.Sample{Time: time.UnixMilli(ts), Value: value})
+	}
+	return samples, rows.Err()
+}
+
+// Downsample 按 interval 聚合 [from, to] 内的原始样本和汇总数据, 返回每个间隔的最小/最大/平均值。
+// interval 小于汇总间隔时, 已汇总的部分仍按汇总间隔返回
+func (h *Historian) Downsample(name string, from, to time.Time, interval time.Duration) ([]Aggregate, error) {
+	step := interval.Milliseconds()
+	if step <= 0 {
+		return nil, fmt.Errorf("聚合间隔无效: %s", interval)
+	}
+	rows, err := h.db.Query(`
+		SELECT bucket, MIN(mn), MAX(mx), SUM(total) / SUM(n), SUM(n) FROM (
+			SELECT (s.ts / ?1) * ?1 AS bucket, s.value AS mn, s.value AS mx, s.value AS total, 1 AS n
+			FROM samples s WHERE s.point_id = (SELECT id FROM points WHERE name = ?2) AND s.ts BETWEEN ?3 AND ?4
+			UNION ALL
+			SELECT (r.ts / ?1) * ?1, r.min, r.max, r.avg * r.count, r.count
+			FROM rollups r WHERE r.point_id = (SELECT id FROM points WHERE name = ?2) AND r.ts BETWEEN ?3 AND ?4
+		) GROUP BY bucket ORDER BY bucket`,
+		step, name, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []Aggregate
+	for rows.Next() {
+		var bucket int64
+		var a Aggregate
+		if err := rows.Scan(&bucket, &a.Min, &a.Max, &a.Avg, &a.Count); err != nil {
+			return nil, err
+		}
+		a.Time = time.UnixMilli(bucket)
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
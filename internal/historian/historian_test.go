@@ -0,0 +1,203 @@
+package historian
+
+import (
+	"modbusbaby/pkg/history"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// at 返回 base 之后第 i 秒
+func at(i int) time.Time {
+	return base.Add(time.Duration(i) * time.Second)
+}
+
+// open 在临时目录中创建数据库
+func open(t *testing.T, opts Options) *Historian {
+	t.Helper()
+	h, err := Open(filepath.Join(t.TempDir(), "sub", "history.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+// record 每秒写入一次, 第 i 次的值为 i
+func record(t *testing.T, h *Historian, from, to int, names ...string) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		values := make(map[string]float64, len(names))
+		for _, name := range names {
+			values[name] = float64(i)
+		}
+		if err := h.Record(at(i), values); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func values(samples []history.Sample) []float64 {
+	result := make([]float64, len(samples))
+	for i, s := range samples {
+		result[i] = s.Value
+	}
+	return result
+}
+
+func TestRecordQuery(t *testing.T) {
+	h := open(t, Options{})
+	record(t, h, 0, 10, "Voltage", "1:HR100")
+	record(t, h, 10, 12, "Voltage")
+
+	names, err := h.Points()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Errorf("Points = %v", names)
+	}
+
+	tests := []struct {
+		name     string
+		from, to int
+		want     []float64
+	}{
+		{"Voltage", 3, 5, []float64{3, 4, 5}},
+		{"Voltage", 9, 20, []float64{9, 10, 11}},
+		{"1:HR100", 9, 20, []float64{9}},
+		{"Voltage", 20, 30, []float64{}},
+		{"missing", 0, 30, []float64{}},
+	}
+	for _, tt := range tests {
+		samples, err := h.Query(tt.name, at(tt.from), at(tt.to))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := values(samples); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%s, %d, %d) = %v, want %v", tt.name, tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	latest, err := h.Latest("Voltage", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := values(latest); !reflect.DeepEqual(got, []float64{9, 10, 11}) || !latest[0].Time.Equal(at(9)) {
+		t.Errorf("Latest = %v", latest)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	h := open(t, Options{})
+	record(t, h, 0, 25, "p")
+
+	aggregates, err := h.Downsample("p", at(0), at(24), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Aggregate{
+		{Time: at(0), Min: 0, Max: 9, Avg: 4.5, Count: 10},
+		{Time: at(10), Min: 10, Max: 19, Avg: 14.5, Count: 10},
+		{Time: at(20), Min: 20, Max: 24, Avg: 22, Count: 5},
+	}
+	if !equalAggregates(aggregates, want) {
+		t.Errorf("Downsample = %+v, want %+v", aggregates, want)
+	}
+
+	if _, err := h.Downsample("p", at(0), at(24), 0); err == nil {
+		t.Error("Downsample with a zero interval succeeded, want an error")
+	}
+	if got, err := h.Downsample("missing", at(0), at(24), time.Second); err != nil || len(got) != 0 {
+		t.Errorf("Downsample of a missing point = %v, %v", got, err)
+	}
+}
+
+// TestMaintain 超过保留期的原始样本汇总后删除, 查询结果在汇总前后保持一致
+func TestMaintain(t *testing.T) {
+	h := open(t, Options{RawRetention: time.Minute, RollupInterval: 10 * time.Second})
+	record(t, h, 0, 25, "p")
+
+	before, err := h.Downsample("p", at(0), at(100), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分界 at(15) 按汇总间隔取整为 at(10), 只汇总完整的间隔 [0, 10)
+	if err := h.Maintain(at(75)); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := h.Query("p", at(0), at(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 15 || raw[0].Value != 10 {
+		t.Errorf("raw samples after maintain: %v", values(raw))
+	}
+	for _, tt := range []struct {
+		from, to int
+		want     bool
+	}{{0, 100, true}, {10, 100, false}} {
+		if got, err := h.HasRollups("p", at(tt.from), at(tt.to)); err != nil || got != tt.want {
+			t.Errorf("HasRollups(%d, %d) = %v, %v, want %v", tt.from, tt.to, got, err, tt.want)
+		}
+	}
+
+	// 再次汇总时与已有汇总合并
+	if err := h.Maintain(at(200)); err != nil {
+		t.Fatal(err)
+	}
+	after, err := h.Downsample("p", at(0), at(100), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalAggregates(after, before) {
+		t.Errorf("Downsample after maintain = %+v, want %+v", after, before)
+	}
+	if raw, _ := h.Query("p", at(0), at(100)); len(raw) != 0 {
+		t.Errorf("%d raw samples left", len(raw))
+	}
+	whole, err := h.Downsample("p", at(0), at(100), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(whole) != 1 || whole[0].Count != 25 || whole[0].Avg != 12 {
+		t.Errorf("Downsample by minute = %+v", whole)
+	}
+}
+
+func TestRollupRetention(t *testing.T) {
+	h := open(t, Options{RawRetention: time.Minute, RollupInterval: 10 * time.Second, RollupRetention: time.Hour})
+	record(t, h, 0, 30, "p")
+	if err := h.Maintain(at(120)); err != nil {
+		t.Fatal(err)
+	}
+	if rolled, _ := h.HasRollups("p", at(0), at(100)); !rolled {
+		t.Fatal("no rollups after maintain")
+	}
+	if err := h.Maintain(at(3600 + 15)); err != nil {
+		t.Fatal(err)
+	}
+	aggregates, err := h.Downsample("p", at(0), at(100), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aggregates) != 1 || !aggregates[0].Time.Equal(at(20)) {
+		t.Errorf("aggregates after retention = %+v, want only the one at %v", aggregates, at(20))
+	}
+}
+
+func equalAggregates(got, want []Aggregate) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Min != want[i].Min || got[i].Max != want[i].Max ||
+			got[i].Avg != want[i].Avg || got[i].Count != want[i].Count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"modbusbaby/internal/logger"
+
+	"github.com/goburrow/modbus"
+)
+
+// BitWriteMethod 写入寄存器位时实际使用的方式
+type BitWriteMethod int
+
+const (
+	BitWriteMask            BitWriteMethod = iota // 功能码 0x16 Mask Write Register
+	BitWriteReadModifyWrite                       // 读取寄存器, 修改该位后用 0x06 写回
+)
+
+func (m BitWriteMethod) String() string {
+	if m == BitWriteMask {
+		return "Mask Write (0x16)"
+	}
+	return "读-改-写 (0x03+0x06)"
+}
+
+// ReadRegisterBits 读取从 address 的 bit 位开始的 count 个连续位, 跨越寄存器时从下一个寄存器的 bit 0 继续。
+// input 为 true 时读取输入寄存器, 否则读取保持寄存器。
+func (c *Client) ReadRegisterBits(slaveID byte, input bool, address uint16, bit uint8, count int) ([]bool, error) {
+	if bit > 15 {
+		return nil, fmt.Errorf("invalid bit %d", bit)
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("invalid bit count %d", count)
+	}
+	registers := (int(bit) + count + 15) / 16
+	if int(address)+registers > 0x10000 {
+		return nil, fmt.Errorf("bit range exceeds address space")
+	}
+
+	raw, err := c.ReadRawRegisters(slaveID, input, address, uint16(registers))
+	if err != nil {
+		return nil, err
+	}
+
+	bits := make([]bool, count)
+	for i := range bits {
+		n := int(bit) + i
+		bits[i] = raw[n/16]&(1<<uint(n%16)) != 0
+	}
+	return bits, nil
+}
+
+// WriteRegisterBit 设置或清除保持寄存器中的一位。
+//
+// 优先使用 Mask Write Register (0x16), 只改变该位, 不影响设备同时修改的其他位;
+// 从站以非法功能码拒绝 0x16 时记住该从站不支持, 改用读-改-写: 在同一个事务锁内读取寄存器,
+// 值有变化时用 0x06 写回, 再读取一次确认该位已生效。
+func (c *Client) WriteRegisterBit(slaveID byte, address uint16, bit uint8, value bool) (BitWriteMethod, error) {
+	if bit > 15 {
+		return BitWriteMask, fmt.Errorf("invalid bit %d", bit)
+	}
+
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return BitWriteMask, err
+	}
+	defer release()
+
+	orMask := uint16(0)
+	if value {
+		orMask = 1 << bit
+	}
+	method, err := c.maskWriteLocked(slaveID, address, ^uint16(1<<bit), orMask)
+	if err == nil {
+		logger.Info(fmt.Sprintf("successfully wrote register bit by %s: Address=%d, Bit=%d, Value=%v", method, address, bit, value))
+	}
+	return method, err
+}
+
+// maskWriteLocked 只修改保持寄存器中 andMask 为0的位, 改为 orMask 中对应的位, 其余位保持设备上的值。
+// 方式与 WriteRegisterBit 相同: 优先 0x16, 从站不支持时读-改-写并回读确认。调用方须已通过 selectSlave 持有事务锁
+func (c *Client) maskWriteLocked(slaveID byte, address, andMask, orMask uint16) (BitWriteMethod, error) {
+	orMask &^= andMask
+	if !c.maskWriteUnsupported[slaveID] {
+		_, err := c.client.MaskWriteRegister(address, andMask, orMask)
+		if err == nil {
+			return BitWriteMask, nil
+		}
+		if modbusErr, ok := err.(*modbus.ModbusError); !ok || modbusErr.ExceptionCode != modbus.ExceptionCodeIllegalFunction {
+			return BitWriteMask, fmt.Errorf("failed to mask write register: %w", err)
+		}
+		logger.Info(fmt.Sprintf("SlaveID %d does not support Mask Write Register, falling back to read-modify-write", slaveID))
+		if c.maskWriteUnsupported == nil {
+			c.maskWriteUnsupported = make(map[byte]bool)
+		}
+		c.maskWriteUnsupported[slaveID] = true
+	}
+
+	current, err := c.readHoldingRegisterLocked(address)
+	if err != nil {
+		return BitWriteReadModifyWrite, err
+	}
+	next := current&andMask | orMask
+	if next != current {
+		if _, err := c.client.WriteSingleRegister(address, next); err != nil {
+			return BitWriteReadModifyWrite, fmt.Errorf("failed to write single holding register: %w", err)
+		}
+	}
+
+	verify, err := c.readHoldingRegisterLocked(address)
+	if err != nil {
+		return BitWriteReadModifyWrite, fmt.Errorf("verify: %w", err)
+	}
+	if verify&^andMask != orMask {
+		return BitWriteReadModifyWrite, fmt.Errorf("verify failed: register %d is 0x%04X after writing 0x%04X", address, verify, next)
+	}
+	logger.Debug(fmt.Sprintf("read-modify-write: Address=%d, 0x%04X -> 0x%04X", address, current, next))
+	return BitWriteReadModifyWrite, nil
+}
+
+// readHoldingRegisterLocked 读取单个保持寄存器的原始值, 调用方须已通过 selectSlave 持有事务锁
+func (c *Client) readHoldingRegisterLocked(address uint16) (uint16, error) {
+	results, err := c.client.ReadHoldingRegisters(address, 1)
+	if err != nil || len(results) < 2 {
+		if err == nil {
+			err = fmt.Errorf("empty response")
+		}
+		return 0, fmt.Errorf("failed to read holding register: %w", err)
+	}
+	return binary.BigEndian.Uint16(results), nil
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"modbusbaby/internal/config"
 	"modbusbaby/internal/logger"
 	"modbusbaby/pkg/datatypes"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goburrow/modbus"
@@ -56,29 +58,45 @@ func (rt RegisterType) String() string {
 	}
 }
 
-// Client Modbus客户端
+// Client Modbus客户端。WithTrace 返回的句柄与原客户端共用连接, 只是另外记录自己的报文
 type Client struct {
+	*link
+	trace *Trace // 非 nil 时记录经此句柄进行的事务
+}
+
+// link 一个连接的共享状态
+type link struct {
 	client         modbus.Client
 	handler        io.Closer // Store the handler for closing
+	rtuPackager    *modbus.RTUClientHandler
+	packager       modbus.Packager
+	transporter    *captureTransporter
 	connectionType ConnectionType
-	isConnected    bool
+	isConnected    atomic.Bool // 只在持有 ioMutex 时修改
 
-	// data Converter 数据转换器
-	converter *datatypes.Converter
+	// ioMutex 串行化同一连接上的事务以及连接和断开 (网关等场景下会被多个goroutine共享)
+	ioMutex sync.Mutex
 
-	// telemetry log 报文记录
-	lastSentPacket     []byte
-	lastReceivedPacket []byte
-	packetMutex        sync.RWMutex
-	transactionID      uint16
-	transactionIDMutex sync.Mutex
+	// converterMu 保护 converter 指针。转换器发布后不再修改, 更改设置时换成新的转换器,
+	// 后台 goroutine 正在使用的转换器因此不受界面同时更改设置的影响
+	converterMu sync.Mutex
+	converter   *datatypes.Converter
+
+	// maskWriteUnsupported 已知不支持 Mask Write Register (0x16) 的从站, 断开连接时清空
+	maskWriteUnsupported map[byte]bool
 }
 
 // NewClient 创建新的Modbus客户端
 func NewClient() *Client {
-	return &Client{
+	return &Client{link: &link{
 		converter: datatypes.NewConverter(datatypes.AB, datatypes.WORD_1234),
-	}
+	}}
+}
+
+// WithTrace 返回与 c 共用连接的句柄, 经此句柄进行的每个事务的报文按顺序记录到 trace。
+// 其他 goroutine 同时经 c 进行的事务不会混入, 因此应使用此方法取得一次操作的报文
+func (c *Client) WithTrace(trace *Trace) *Client {
+	return &Client{link: c.link, trace: trace}
 }
 
 // ConnectTCP 连接TCP设备
@@ -91,55 +109,64 @@ func (c *Client) ConnectTCP(host string, port int) error {
 		return err
 	}
 
-	c.client = modbus.NewClient(handler)
+	capture := &captureTransporter{Transporter: handler}
+	c.ioMutex.Lock()
+	c.client = modbus.NewClient2(handler, capture)
 	c.handler = handler
+	c.packager = handler
+	c.transporter = capture
 	c.connectionType = TCP
-	c.isConnected = true
+	c.isConnected.Store(true)
+	c.ioMutex.Unlock()
 
 	logger.Info(fmt.Sprintf("TCP Connection successful: %s:%d", host, port))
 	return nil
 }
 
 // ConnectRTU 连接RTU设备
-func (c *Client) ConnectRTU(port string, baudRate int, dataBits, stopBits int, parity string) error {
-	handler := modbus.NewRTUClientHandler(port)
-	handler.BaudRate = baudRate
-	handler.DataBits = dataBits
-	handler.StopBits = stopBits
-	handler.Timeout = 10 * time.Second
-
-	switch parity {
-	case "Even":
-		handler.Parity = "E"
-	case "Odd":
-		handler.Parity = "O"
-	default:
-		handler.Parity = "N"
-	}
-
-	err := handler.Connect()
+func (c *Client) ConnectRTU(cfg config.RTUConfig) error {
+	transporter, err := newRTUTransporter(cfg, 10*time.Second)
 	if err != nil {
 		logger.Error("RTU Connection failed:", err)
 		return err
 	}
 
-	c.client = modbus.NewClient(handler)
-	c.handler = handler
+	// 仅使用 goburrow 的 RTU packager 进行报文封装与CRC校验, 传输由 rtuTransporter 负责
+	packager := modbus.NewRTUClientHandler(cfg.Port)
+	packager.SlaveId = byte(cfg.SlaveID)
+
+	capture := &captureTransporter{Transporter: transporter}
+	c.ioMutex.Lock()
+	c.client = modbus.NewClient2(packager, capture)
+	c.handler = transporter
+	c.rtuPackager = packager
+	c.packager = packager
+	c.transporter = capture
 	c.connectionType = RTU
-	c.isConnected = true
+	c.isConnected.Store(true)
+	c.ioMutex.Unlock()
 
-	logger.Info(fmt.Sprintf("RTU Connection successful: %s, BaudRate: %d", port, baudRate))
+	logger.Info(fmt.Sprintf("RTU Connection successful: %s, BaudRate: %d, DataBits: %d, StopBits: %v, Parity: %s, RS485: %v",
+		cfg.Port, cfg.BaudRate, cfg.DataBits, cfg.StopBits, cfg.Parity, cfg.RS485.Enabled))
 	return nil
 }
 
-// Disconnect 断开连接
+// Disconnect 断开连接, 等待正在进行的事务结束后关闭
 func (c *Client) Disconnect() error {
+	c.ioMutex.Lock()
 	if c.handler == nil {
+		c.ioMutex.Unlock()
 		return nil
 	}
 	err := c.handler.Close()
 	c.handler = nil
 	c.client = nil
+	c.rtuPackager = nil
+	c.packager = nil
+	c.transporter = nil
+	c.isConnected.Store(false)
+	c.maskWriteUnsupported = nil
+	c.ioMutex.Unlock()
 	if err != nil {
 		logger.Error("Disconnection failed:", err)
 		return err
@@ -153,35 +180,129 @@ func (c *Client) IsClientReady() bool {
 	return c.client != nil
 }
 
+// selectSlave 开始一次事务: 加锁并设置本次请求使用的从站地址,
+// 返回用于记录本次的报文、恢复原地址并释放锁的函数。未连接 (或已在等待锁时断开) 时返回错误, 不持有锁
+func (c *Client) selectSlave(slaveID byte) (func(), error) {
+	c.ioMutex.Lock()
+	if !c.isConnected.Load() {
+		c.ioMutex.Unlock()
+		return nil, fmt.Errorf("device not connected")
+	}
+	restore := func() {}
+	switch c.connectionType {
+	case TCP:
+		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
+			originalSlaveID := tcpHandler.SlaveId
+			tcpHandler.SlaveId = slaveID
+			logger.Debug(fmt.Sprintf("selectSlave (TCP): Setting handler SlaveId to %d", slaveID))
+			restore = func() { tcpHandler.SlaveId = originalSlaveID }
+		} else {
+			logger.Warn("TCP handler type assertion failed. Unit ID might not be set.")
+		}
+	case RTU:
+		if c.rtuPackager != nil {
+			originalSlaveID := c.rtuPackager.SlaveId
+			c.rtuPackager.SlaveId = slaveID
+			logger.Debug(fmt.Sprintf("selectSlave (RTU): Setting packager SlaveId to %d", slaveID))
+			restore = func() { c.rtuPackager.SlaveId = originalSlaveID }
+		}
+	}
+	return func() {
+		c.recordExchanges()
+		restore()
+		c.ioMutex.Unlock()
+	}, nil
+}
+
+// SendRawPDU 原样发送一个PDU (功能码+数据) 并返回响应PDU。
+// 异常响应 (功能码|0x80) 作为正常结果返回, 由调用方自行解释, 主要用于网关转发。
+func (c *Client) SendRawPDU(slaveID byte, pdu []byte) ([]byte, error) {
+	if len(pdu) == 0 {
+		return nil, fmt.Errorf("empty PDU")
+	}
+
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	request := &modbus.ProtocolDataUnit{FunctionCode: pdu[0], Data: pdu[1:]}
+	aduRequest, err := c.packager.Encode(request)
+	if err != nil {
+		return nil, err
+	}
+	aduResponse, err := c.transporter.Send(aduRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.packager.Verify(aduRequest, aduResponse); err != nil {
+		return nil, err
+	}
+	response, err := c.packager.Decode(aduResponse)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{response.FunctionCode}, response.Data...), nil
+}
+
 // SetDataConverter 设置数据转换器
 func (c *Client) SetDataConverter(byteOrder datatypes.ByteOrder, wordOrder datatypes.WordOrder) {
+	c.converterMu.Lock()
+	defer c.converterMu.Unlock()
+	previous := c.converter
 	c.converter = datatypes.NewConverter(byteOrder, wordOrder)
+	c.converter.SetLocation(previous.Location())
+	c.converter.SetStringOptions(previous.StringOptions())
+}
+
+// SetStringOptions 设置字符串类型数据的字段长度和填充方式
+func (c *Client) SetStringOptions(opts datatypes.StringOptions) {
+	c.updateConverter(func(converter *datatypes.Converter) {
+		converter.SetStringOptions(opts)
+	})
+}
+
+// SetTimeLocation 设置时间类型数据使用的时区
+func (c *Client) SetTimeLocation(loc *time.Location) {
+	c.updateConverter(func(converter *datatypes.Converter) {
+		converter.SetLocation(loc)
+	})
+}
+
+// updateConverter 复制当前的数据转换器, 修改副本后替换, 不改动其他 goroutine 可能正在使用的转换器
+func (c *Client) updateConverter(update func(*datatypes.Converter)) {
+	c.converterMu.Lock()
+	defer c.converterMu.Unlock()
+	next := *c.converter
+	update(&next)
+	c.converter = &next
+}
+
+// dataConverter 返回当前的数据转换器, 调用方不得修改
+func (c *Client) dataConverter() *datatypes.Converter {
+	c.converterMu.Lock()
+	defer c.converterMu.Unlock()
+	return c.converter
+}
+
+// GetConnectionType 获取当前 (或最近一次) 连接的类型, 决定报文的封装方式
+func (c *Client) GetConnectionType() ConnectionType {
+	return c.connectionType
 }
 
 // IsConnected 检查客户端是否已连接
 func (c *Client) IsConnected() bool {
-	return c.isConnected
+	return c.isConnected.Load()
 }
 
 // ReadHoldingRegisters 读取保持寄存器
 func (c *Client) ReadHoldingRegisters(slaveID byte,address, count uint16, dataType datatypes.DataType) (interface{}, error) {
-	if !c.isConnected {
-		return nil, fmt.Errorf("device not connected")
-	}
-
-
-	if c.connectionType == TCP {
-		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
-			originalSlaveID := tcpHandler.SlaveId
-			tcpHandler.SlaveId = slaveID
-			defer func() {
-				tcpHandler.SlaveId = originalSlaveID
-			}()
-			logger.Debug(fmt.Sprintf("ReadHoldingRegisters (TCP): Setting handler SlaveId to %d", slaveID))
-		} else {
-			logger.Warn("TCP handler type assertion failed in ReadHoldingRegisters. Unit ID might not be set.")
-		}
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	logger.Debug(fmt.Sprintf("Attempting to read holding registers for SlaveID: %d, Address: %d, Count: %d", slaveID, address, count))
 
@@ -207,35 +328,22 @@ func (c *Client) ReadHoldingRegisters(slaveID byte,address, count uint16, dataTy
 		} else {
 			logger.Info(fmt.Sprintf("Modbus Read Error: Received partial/error response bytes: %x. Error: %v", results, err))
 		}
-		c.recordADU(requestPDU, nil, slaveID) // Pass nil for responsePDU on error
 		return nil, fmt.Errorf("failed to read holding registers: %w", err)
 	}
-	c.recordADU(requestPDU, results, slaveID) // Pass results as responsePDU
 
 	// 转换数据类型
 	registers := bytesToUint16Array(results)
-	return c.converter.ConvertFromRegisters(registers, dataType)
+	return c.dataConverter().ConvertFromRegisters(registers, dataType)
 }
 
 // ReadInputRegisters 读取输入寄存器
 func (c *Client) ReadInputRegisters(slaveID byte, address, count uint16, dataType datatypes.DataType) (interface{}, error) {
-	if !c.isConnected {
-		return nil, fmt.Errorf("device not connected")
-	}
 
-	// For TCP connections, ensure the SlaveId (Unit ID) is set on the handler
-	if c.connectionType == TCP {
-		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
-			originalSlaveID := tcpHandler.SlaveId
-			tcpHandler.SlaveId = slaveID
-			defer func() {
-				tcpHandler.SlaveId = originalSlaveID
-			}()
-			logger.Debug(fmt.Sprintf("ReadInputRegisters (TCP): Setting handler SlaveId to %d", slaveID))
-		} else {
-			logger.Warn("TCP handler type assertion failed in ReadInputRegisters. Unit ID might not be set.")
-		}
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	logger.Debug(fmt.Sprintf("Attempting to read input registers for SlaveID: %d, Address: %d, Count: %d", slaveID, address, count))
 
@@ -253,36 +361,23 @@ func (c *Client) ReadInputRegisters(slaveID byte, address, count uint16, dataTyp
 	}
 
 	if err != nil {
-		c.recordADU(requestPDU, nil, slaveID)
 		return nil,  fmt.Errorf("failed to read input registers: %w", err)
 	}
 
 	
-	c.recordADU(requestPDU, results, slaveID)
 	response = results
 	registers := bytesToUint16Array(response)
-	return c.converter.ConvertFromRegisters(registers, dataType)
+	return c.dataConverter().ConvertFromRegisters(registers, dataType)
 }
 
 // ReadCoils 读取线圈
 func (c *Client) ReadCoils(slaveID byte, address, count uint16) ([]bool, error) {
-	if !c.isConnected {
-		return nil, fmt.Errorf("device not connected")
-	}
 
-	// For TCP connections, ensure the SlaveId (Unit ID) is set on the handler
-	if c.connectionType == TCP {
-		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
-			originalSlaveID := tcpHandler.SlaveId
-			tcpHandler.SlaveId = slaveID
-			defer func() {
-				tcpHandler.SlaveId = originalSlaveID
-			}()
-			logger.Debug(fmt.Sprintf("readCoils (TCP): Setting handler SlaveId to %d", slaveID))
-		} else {
-			logger.Warn("TCP handler type assertion failed in ReadCoils. Unit ID might not be set.")
-		}
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	logger.Debug(fmt.Sprintf("attempting to read coils for SlaveID: %d, Address: %d, Count: %d", slaveID, address, count))
 
@@ -300,11 +395,9 @@ func (c *Client) ReadCoils(slaveID byte, address, count uint16) ([]bool, error)
 	}
 
 	if err != nil {
-		c.recordADU(requestPDU, nil, slaveID)
 		return nil, fmt.Errorf("failed to read coils: %w", err)
 	}
 
-	c.recordADU(requestPDU, results, slaveID) // Pass results as responsePDU
 	// response = results
 	// 转换为bool数组
 	var bools []bool
@@ -320,23 +413,12 @@ func (c *Client) ReadCoils(slaveID byte, address, count uint16) ([]bool, error)
 
 // ReadDiscreteInputs 读取离散输入
 func (c *Client) ReadDiscreteInputs(slaveID byte, address, count uint16) ([]bool, error) {
-	if !c.isConnected {
-		return nil, fmt.Errorf("device not connected")
-	}
 
-	// For TCP connections, ensure the SlaveId (Unit ID) is set on the handler
-	if c.connectionType == TCP {
-		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
-			originalSlaveID := tcpHandler.SlaveId
-			tcpHandler.SlaveId = slaveID
-			defer func() {
-				tcpHandler.SlaveId = originalSlaveID
-			}()
-			logger.Debug(fmt.Sprintf("ReadDiscreteInputs (TCP): Setting handler SlaveId to %d", slaveID))
-		} else {
-			logger.Warn("TCP handler type assertion failed in ReadDiscreteInputs. Unit ID might not be set.")
-		}
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	logger.Debug(fmt.Sprintf("Attempting to read discrete inputs for SlaveID: %d, Address: %d, Count: %d", slaveID, address, count))
 
@@ -355,11 +437,9 @@ func (c *Client) ReadDiscreteInputs(slaveID byte, address, count uint16) ([]bool
 	}
 
 	if err != nil {
-		c.recordADU(requestPDU, nil, slaveID)
 		return nil, fmt.Errorf("failed to read discrete inputs: %w", err)
 	}
 	
-	c.recordADU(requestPDU, results, slaveID)
 	// response = results
 	// 转换为bool数组
 	var bools []bool
@@ -373,30 +453,33 @@ func (c *Client) ReadDiscreteInputs(slaveID byte, address, count uint16) ([]bool
 	return bools, nil
 }
 
-// WriteHoldingRegisters 写入保持寄存器
-func (c *Client) WriteHoldingRegisters(slaveID byte, address uint16, values interface{}) error {
-	if !c.isConnected {
-		return fmt.Errorf("device not connected")
-	}
+// WriteHoldingRegisters 写入保持寄存器, dataType 决定值的寄存器编码
+func (c *Client) WriteHoldingRegisters(slaveID byte, address uint16, values interface{}, dataType datatypes.DataType) error {
 
-	// For TCP connections, ensure the SlaveId (Unit ID) is set on the handler
-	if c.connectionType == TCP {
-		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
-			originalSlaveID := tcpHandler.SlaveId
-			tcpHandler.SlaveId = slaveID
-			defer func() {
-				tcpHandler.SlaveId = originalSlaveID
-			}()
-			logger.Debug(fmt.Sprintf("WriteHoldingRegisters (TCP): Setting handler SlaveId to %d", slaveID))
-		} else {
-			logger.Warn("TCP handler type assertion failed in WriteHoldingRegisters. Unit ID might not be set.")
-		}
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return err
 	}
+	defer release()
 
-	registers, err := c.converter.ConvertToRegisters(values)
+	converter := c.dataConverter()
+	registers, err := converter.ConvertToRegistersAs(values, dataType)
 	if err != nil {
 		return fmt.Errorf("unsupported data type or conversion failed: %v", err)
 	}
+
+	// 8位类型只写入所在的字节, 用屏蔽写 (或读-改-写) 保留同一寄存器中的另一个字节
+	if datatypes.IsHalfRegister(dataType) {
+		mask := converter.HalfRegisterMask(dataType)
+		for i, reg := range registers {
+			method, err := c.maskWriteLocked(slaveID, address+uint16(i), ^mask, reg)
+			if err != nil {
+				return fmt.Errorf("failed to write %s to register %d: %w", dataType, address+uint16(i), err)
+			}
+			logger.Info(fmt.Sprintf("successfully wrote %s by %s: Address=%d", dataType, method, address+uint16(i)))
+		}
+		return nil
+	}
 	
 	quantity := uint16(len(registers))
 	
@@ -414,16 +497,14 @@ func (c *Client) WriteHoldingRegisters(slaveID byte, address uint16, values inte
 
 		logger.Debug(fmt.Sprintf("WriteSingleRegister: Constructed Request PDU: %x (Length: %d)", requestPDU, len(requestPDU)))
 		
-		results, err := c.client.WriteSingleRegister(address, registers[0])
+		_, err := c.client.WriteSingleRegister(address, registers[0])
 		if err != nil {
 			if modbusErr, ok := err.(*modbus.ModbusError); ok {
 				response := []byte{modbusErr.ExceptionCode}
 				logger.Debug(fmt.Sprintf("Modbus Write Single Register error response (PDU): %x", response))
 			}
-			c.recordADU(requestPDU, nil, slaveID)
 			return fmt.Errorf("failed to write single holding register: %w", err)
 		}
-		c.recordADU(requestPDU, results, slaveID)
 		logger.Info(fmt.Sprintf("successfully wrote single holding register: Address=%d", address))
 
 	} else {
@@ -441,16 +522,14 @@ func (c *Client) WriteHoldingRegisters(slaveID byte, address uint16, values inte
 
 		logger.Debug(fmt.Sprintf("writeMultipleRegisters: Constructed Request PDU: %x (Length: %d)", requestPDU, len(requestPDU)))
 		
-		results, err := c.client.WriteMultipleRegisters(address, quantity, data)
+		_, err := c.client.WriteMultipleRegisters(address, quantity, data)
 		if err != nil {
 			if modbusErr, ok := err.(*modbus.ModbusError); ok {
 				response := []byte{modbusErr.ExceptionCode}
 				logger.Debug(fmt.Sprintf("modbus Write Holding Registers error response (PDU): %x", response))
 			}
-			c.recordADU(requestPDU, nil, slaveID)
 			return fmt.Errorf("failed to write multiple holding registers: %w", err)
 		}
-		c.recordADU(requestPDU, results, slaveID)
 		logger.Info(fmt.Sprintf("successfully wrote multiple holding registers: Address=%d, Quantity=%d", address, quantity))
 	}
 	return nil
@@ -458,23 +537,12 @@ func (c *Client) WriteHoldingRegisters(slaveID byte, address uint16, values inte
 
 // WriteCoils 写入线圈
 func (c *Client) WriteCoils(slaveID byte, address uint16, values []bool) error {
-	if !c.isConnected {
-		return fmt.Errorf("device not connected")
-	}
 
-	// For TCP connections, ensure the SlaveId (Unit ID) is set on the handler
-	if c.connectionType == TCP {
-		if tcpHandler, ok := c.handler.(*modbus.TCPClientHandler); ok {
-			originalSlaveID := tcpHandler.SlaveId
-			tcpHandler.SlaveId = slaveID
-			defer func() {
-				tcpHandler.SlaveId = originalSlaveID
-			}()
-			logger.Debug(fmt.Sprintf("WriteCoils (TCP): Setting handler SlaveId to %d", slaveID))
-		} else {
-			logger.Warn("TCP handler type assertion failed in WriteCoils. Unit ID might not be set.")
-		}
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return err
 	}
+	defer release()
 
 	quantity := uint16(len(values))
 
@@ -494,16 +562,14 @@ func (c *Client) WriteCoils(slaveID byte, address uint16, values []bool) error {
 
 		logger.Debug(fmt.Sprintf("WriteSingleCoil: Constructed Request PDU: %x (Length: %d)", requestPDU, len(requestPDU)))
 		
-		results, err := c.client.WriteSingleCoil(address, value)
+		_, err := c.client.WriteSingleCoil(address, value)
 		if err != nil {
 			if modbusErr, ok := err.(*modbus.ModbusError); ok {
 				response := []byte{0x85, modbusErr.ExceptionCode}
 				logger.Debug(fmt.Sprintf("Modbus Write Single Coil error response (PDU): %x", response))
 			}
-			c.recordADU(requestPDU, nil, slaveID)
 			return fmt.Errorf("failed to write single coil: %w", err)
 		}
-		c.recordADU(requestPDU, results, slaveID)
 		logger.Info(fmt.Sprintf("successfully wrote single coil: Address=%d, Value=%v", address, values[0]))
 
 	} else {
@@ -530,16 +596,14 @@ func (c *Client) WriteCoils(slaveID byte, address uint16, values []bool) error {
 
 		logger.Debug(fmt.Sprintf("WriteCoils: Constructed Request PDU: %x (Length: %d)", requestPDU, len(requestPDU)))
 		
-		results, err := c.client.WriteMultipleCoils(address, quantity, data)
+		_, err := c.client.WriteMultipleCoils(address, quantity, data)
 		if err != nil {
 			if modbusErr, ok := err.(*modbus.ModbusError); ok {
 				response := []byte{0x8F, modbusErr.ExceptionCode}
 				logger.Debug(fmt.Sprintf("Modbus Write Coils error response (PDU): %x", response))
 			}
-			c.recordADU(requestPDU, nil, slaveID)
 			return fmt.Errorf("failed to write multiple coils: %w", err)
 		}
-		c.recordADU(requestPDU, results, slaveID)
 		logger.Info(fmt.Sprintf("successfully wrote multiple coils: Address=%d, Quantity=%d", address, quantity))
 	}
 	return nil
@@ -563,137 +627,22 @@ func uint16ArrayToBytes(data []uint16) []byte {
 	return result
 }
 
-// GetLastPackets 获取最后的发送和接收报文
-func (c *Client) GetLastPackets() ([]byte, []byte) {
-	c.packetMutex.RLock()
-	defer c.packetMutex.RUnlock()
-	return c.lastSentPacket, c.lastReceivedPacket
-}
-
-
-
-// calculateCRC 计算Modbus RTU的CRC-16校验码
-func calculateCRC(data []byte) uint16 {
-	var crc uint16 = 0xFFFF
-	for i := 0; i < len(data); i++ {
-		crc ^= uint16(data[i])
-		for j := 8; j != 0; j-- {
-			if (crc & 0x0001) != 0 {
-				crc >>= 1
-				crc ^= 0xA001
-			} else {
-				crc >>= 1
-			}
-		}
+// recordExchanges 取走本次事务在传输层记录的报文, 写入日志并记录到句柄的 trace。
+// 在释放 ioMutex 前调用, 记录顺序与线上一致
+func (c *Client) recordExchanges() {
+	if c.transporter == nil {
+		return
 	}
-	// 返回低字节在前，高字节在后的结果
-	return (crc >> 8) | (crc << 8)
-}
-
-// recordADU 构建并记录完整的请求和响应ADU
-// requestPDU: 仅包含功能码和数据部分的PDU (e.g., [0x03, addr_high, addr_low, count_high, count_low])
-// responsePDU: 仅包含数据部分的PDU (e.g., [reg1_high, reg1_low, reg2_high, reg2_low])
-func (c *Client) recordADU(requestPDU, responsePDU []byte, slaveID byte) {
-	c.packetMutex.Lock()
-	defer c.packetMutex.Unlock()
-
-	logger.Debug(fmt.Sprintf("recordADU: Request PDU: %x, Response PDU (data only): %x, Slave ID: %d", requestPDU, responsePDU, slaveID))
-
-	// --- 构建请求ADU ---
-	if c.connectionType == TCP {
-		c.transactionIDMutex.Lock()
-		c.transactionID++
-		tid := c.transactionID
-		c.transactionIDMutex.Unlock()
-
-		// MBAP Header: TransactionID(2) + ProtocolID(2) + Length(2) + UnitID(1)
-		header := make([]byte, 7)
-		binary.BigEndian.PutUint16(header[0:2], tid)
-		binary.BigEndian.PutUint16(header[2:4], 0) // Protocol ID is 0
-		binary.BigEndian.PutUint16(header[4:6], uint16(len(requestPDU)+1))
-		header[6] = slaveID // Use passed slaveID
-		c.lastSentPacket = append(header, requestPDU...)
-		logger.Info(fmt.Sprintf("Modbus TCP Sent ADU: %x", c.lastSentPacket))
-		logger.Debug(fmt.Sprintf("recordADU (TCP): Constructed Sent ADU: %x", c.lastSentPacket))
-
-		// --- 构建响应ADU (TCP) ---
-		if responsePDU != nil {
-			var fullResponsePDU []byte
-			requestFuncCode := requestPDU[0] // Get the function code from the original request
-
-			// Reconstruct the full response PDU (Function Code + Byte Count/Echo Data + Data)
-			switch requestFuncCode {
-			case 0x01, 0x02, 0x03, 0x04: // Read Coils/Inputs
-				byteCount := byte(len(responsePDU))
-				fullResponsePDU = make([]byte, 2+len(responsePDU))
-				fullResponsePDU[0] = requestFuncCode
-				fullResponsePDU[1] = byteCount
-				copy(fullResponsePDU[2:], responsePDU)
-			case 0x05, 0x06, 0x0F, 0x10: // Write Single/Multiple
-				// For write responses, the PDU is echoed back (FC + Addr + Qty/Value)
-				fullResponsePDU = make([]byte, 1+len(responsePDU))
-				fullResponsePDU[0] = requestFuncCode
-				copy(fullResponsePDU[1:], responsePDU)
-			default:
-				logger.Warn(fmt.Sprintf("recordADU (TCP): Unknown Modbus function code %x for response PDU reconstruction. Using raw responsePDU.", requestFuncCode))
-				fullResponsePDU = responsePDU
-			}
-
-			responseHeader := make([]byte, 7)
-			binary.BigEndian.PutUint16(responseHeader[0:2], tid) // Use the same transaction ID
-			binary.BigEndian.PutUint16(responseHeader[2:4], 0)
-			binary.BigEndian.PutUint16(responseHeader[4:6], uint16(len(fullResponsePDU)+1))
-			responseHeader[6] = slaveID
-			c.lastReceivedPacket = append(responseHeader, fullResponsePDU...)
-			logger.Info(fmt.Sprintf("Modbus TCP Received ADU: %x", c.lastReceivedPacket))
-			logger.Debug(fmt.Sprintf("recordADU (TCP): Constructed Received ADU: %x", c.lastReceivedPacket))
+	for _, ex := range c.transporter.exchanges {
+		logger.Info(fmt.Sprintf("%s Sent ADU: %x", c.connectionType, ex.Sent))
+		if ex.Received == nil {
+			logger.Info(fmt.Sprintf("%s Received ADU: (No response received)", c.connectionType))
 		} else {
-			c.lastReceivedPacket = nil // Clear if no response
-			logger.Info("Modbus TCP Received ADU: (No response received)")
-			logger.Debug("recordADU (TCP): No response PDU provided, clearing lastReceivedPacket.")
+			logger.Info(fmt.Sprintf("%s Received ADU: %x", c.connectionType, ex.Received))
 		}
-
-	} else { // RTU
-		// Request ADU
-		adu := append([]byte{slaveID}, requestPDU...) // Use passed slaveID
-		crc := calculateCRC(adu)
-		c.lastSentPacket = append(adu, byte(crc&0xFF), byte(crc>>8))
-		logger.Info(fmt.Sprintf("Modbus RTU Sent ADU: %x", c.lastSentPacket))
-		logger.Debug(fmt.Sprintf("recordADU (RTU): Constructed Sent ADU: %x", c.lastSentPacket))
-
-		// --- 构建响应ADU (RTU) ---
-		if responsePDU != nil {
-			var fullResponsePDU []byte
-			requestFuncCode := requestPDU[0] // Get the function code from the original request
-
-			// Reconstruct the full response PDU (Function Code + Byte Count/Echo Data + Data)
-			switch requestFuncCode {
-			case 0x01, 0x02, 0x03, 0x04: // Read Coils/Inputs
-				byteCount := byte(len(responsePDU))
-				fullResponsePDU = make([]byte, 2+len(responsePDU))
-				fullResponsePDU[0] = requestFuncCode
-				fullResponsePDU[1] = byteCount
-				copy(fullResponsePDU[2:], responsePDU)
-			case 0x05, 0x06, 0x0F, 0x10: // Write Single/Multiple
-				// For write responses, the PDU is echoed back (FC + Addr + Qty/Value)
-				fullResponsePDU = make([]byte, 1+len(responsePDU))
-				fullResponsePDU[0] = requestFuncCode
-				copy(fullResponsePDU[1:], responsePDU)
-			default:
-				logger.Warn(fmt.Sprintf("recordADU (RTU): Unknown Modbus function code %x for response PDU reconstruction. Using raw responsePDU.", requestFuncCode))
-				fullResponsePDU = responsePDU
-			}
-
-			// RTU response: SlaveID(1) + FullPDU(N) + CRC(2)
-			responseADU := append([]byte{slaveID}, fullResponsePDU...)
-			responseCRC := calculateCRC(responseADU)
-			c.lastReceivedPacket = append(responseADU, byte(responseCRC&0xFF), byte(responseCRC>>8))
-			logger.Info(fmt.Sprintf("Modbus RTU Received ADU: %x", c.lastReceivedPacket))
-			logger.Debug(fmt.Sprintf("recordADU (RTU): Constructed Received ADU: %x", c.lastReceivedPacket))
-		} else {
-			c.lastReceivedPacket = nil // Clear if no response
-			logger.Info("Modbus RTU Received ADU: (No response received)")
-			logger.Debug("recordADU (RTU): No response PDU provided, clearing lastReceivedPacket.")
+		if c.trace != nil {
+			c.trace.Exchanges = append(c.trace.Exchanges, ex)
 		}
 	}
-}
\ No newline at end of file
+	c.transporter.exchanges = nil
+}
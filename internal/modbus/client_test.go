@@ -0,0 +1,397 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"modbusbaby/pkg/datatypes"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// fakeServer 最简单的 Modbus TCP 从站: 读保持/输入寄存器时返回写入过的值, 未写入过的寄存器返回其地址;
+// 写单个寄存器时保存并回显, 其他功能码返回非法功能码异常
+type fakeServer struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu        sync.Mutex
+	registers map[uint16]uint16 // 写入过的寄存器
+	writes    []uint16          // 按顺序写入单个寄存器的值
+}
+
+func startFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, registers: make(map[uint16]uint16)}
+	s.wg.Add(1)
+	go s.accept()
+	t.Cleanup(func() {
+		listener.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *fakeServer) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	header := make([]byte, 7)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		pdu := make([]byte, int(binary.BigEndian.Uint16(header[4:6]))-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		response := s.respond(pdu)
+		adu := append([]byte(nil), header...)
+		binary.BigEndian.PutUint16(adu[4:6], uint16(len(response)+1))
+		if _, err := conn.Write(append(adu, response...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) respond(pdu []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch pdu[0] {
+	case 0x03, 0x04:
+		address := binary.BigEndian.Uint16(pdu[1:3])
+		count := int(binary.BigEndian.Uint16(pdu[3:5]))
+		response := []byte{pdu[0], byte(2 * count)}
+		for i := 0; i < count; i++ {
+			value, ok := s.registers[address+uint16(i)]
+			if !ok || pdu[0] == 0x04 {
+				value = address + uint16(i)
+			}
+			response = binary.BigEndian.AppendUint16(response, value)
+		}
+		return response
+	case 0x06:
+		value := binary.BigEndian.Uint16(pdu[3:5])
+		s.registers[binary.BigEndian.Uint16(pdu[1:3])] = value
+		s.writes = append(s.writes, value)
+		return append([]byte(nil), pdu...)
+	}
+	return []byte{pdu[0] | 0x80, 0x01}
+}
+
+func (s *fakeServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func connectFake(t *testing.T) *Client {
+	t.Helper()
+	c, _ := connectFakeServer(t)
+	return c
+}
+
+func connectFakeServer(t *testing.T) (*Client, *fakeServer) {
+	t.Helper()
+	server := startFakeServer(t)
+	c := NewClient()
+	if err := c.ConnectTCP("127.0.0.1", server.port()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	return c, server
+}
+
+func TestSendRawPDU(t *testing.T) {
+	c := connectFake(t)
+	tests := []struct {
+		name string
+		pdu  []byte
+		want string
+	}{
+		{"read", []byte{0x03, 0x00, 0x0A, 0x00, 0x02}, "0304000a000b"},
+		{"exception passes through", []byte{0x2B, 0x0E}, "ab01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.SendRawPDU(1, tt.pdu)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(got); got != tt.want {
+				t.Errorf("response = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendRawPDUDuringDisconnect 网关转发与断开连接同时进行时不能因连接已释放而崩溃
+func TestSendRawPDUDuringDisconnect(t *testing.T) {
+	c := connectFake(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				c.SendRawPDU(1, []byte{0x03, 0x00, 0x00, 0x00, 0x01})
+			}
+		}()
+	}
+	if err := c.Disconnect(); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+
+	if _, err := c.SendRawPDU(1, []byte{0x03, 0x00, 0x00, 0x00, 0x01}); err == nil {
+		t.Error("SendRawPDU after Disconnect succeeded, want an error")
+	}
+	if c.IsConnected() {
+		t.Error("IsConnected after Disconnect = true")
+	}
+}
+
+// TestReadRawRegistersIgnoresConverter 原始寄存器不受界面上字节序设置的影响 (如 SF 寄存器)
+func TestReadRawRegistersIgnoresConverter(t *testing.T) {
+	c := connectFake(t)
+	c.SetDataConverter(datatypes.BA, datatypes.WORD_4321)
+	for _, input := range []bool{false, true} {
+		registers, err := c.ReadRawRegisters(1, input, 0x0102, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []uint16{0x0102, 0x0103}; !reflect.DeepEqual(registers, want) {
+			t.Errorf("input=%v: registers = %04X, want %04X", input, registers, want)
+		}
+	}
+}
+
+// TestConvertRegisters 原始寄存器按当前设置解码, 与 ReadHoldingRegisters 的结果一致
+func TestConvertRegisters(t *testing.T) {
+	c := connectFake(t)
+	c.SetDataConverter(datatypes.BA, datatypes.WORD_4321)
+	want, err := c.ReadHoldingRegisters(1, 0x0102, 4, datatypes.UINT32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registers, err := c.ReadRawRegisters(1, false, 0x0102, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.ConvertRegisters(registers, datatypes.UINT32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertRegisters = %X, want %X", got, want)
+	}
+}
+
+// TestReadRegisterBits 位来自本次读取的原始寄存器, 不受字节序设置和其他同时进行的读取影响
+func TestReadRegisterBits(t *testing.T) {
+	c := connectFake(t)
+	c.SetDataConverter(datatypes.BA, datatypes.WORD_4321)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			c.ReadHoldingRegisters(1, 0xFF00, 2, datatypes.UINT16)
+		}
+	}()
+	defer wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		// 0x0102 的 bit 14、15 为 0, 0x0103 的 bit 0、1 为 1
+		bits, err := c.ReadRegisterBits(1, i%2 == 0, 0x0102, 14, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []bool{false, false, true, true}; !reflect.DeepEqual(bits, want) {
+			t.Fatalf("bits = %v, want %v", bits, want)
+		}
+	}
+}
+
+// TestConverterSettingsDuringIO 界面线程更改字节序、字符串和时区设置时, 后台的读写和解码使用完整的旧设置或新设置
+func TestConverterSettingsDuringIO(t *testing.T) {
+	c := connectFake(t)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			c.SetDataConverter(datatypes.BA, datatypes.WORD_4321)
+			c.SetStringOptions(datatypes.StringOptions{FieldLength: 1})
+			c.SetTimeLocation(time.UTC)
+			c.SetDataConverter(datatypes.AB, datatypes.WORD_1234)
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		values, err := c.ReadHoldingRegisters(1, 0x0102, 1, datatypes.UINT16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := values.([]uint16)[0]; v != 0x0102 && v != 0x0201 {
+			t.Fatalf("value = 0x%04X", v)
+		}
+		if err := c.WriteHoldingRegisters(1, 0x0010, []uint16{1}, datatypes.UINT16); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.ConvertRegisters([]uint16{0x4142}, datatypes.STRING_UTF8); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+// TestWriteHalfRegister 8位类型只改变所在的字节, 从站不支持 0x16 时读-改-写保留另一个字节
+func TestWriteHalfRegister(t *testing.T) {
+	tests := []struct {
+		byteOrder datatypes.ByteOrder
+		dataType  datatypes.DataType
+		values    interface{}
+		want      []uint16
+	}{
+		{datatypes.AB, datatypes.UINT8_LOW, []uint8{0xAB, 0xCD}, []uint16{0x12AB, 0x12CD}},
+		{datatypes.AB, datatypes.INT8_HIGH, []int8{-1}, []uint16{0xFF34}},
+		{datatypes.BA, datatypes.UINT8_LOW, []uint8{0xAB}, []uint16{0xAB34}},
+	}
+	for _, tt := range tests {
+		c, server := connectFakeServer(t)
+		c.SetDataConverter(tt.byteOrder, datatypes.WORD_1234)
+		if err := c.WriteHoldingRegisters(1, 0x1234, tt.values, tt.dataType); err != nil {
+			t.Fatalf("%s %s: %v", tt.byteOrder, tt.dataType, err)
+		}
+		if !reflect.DeepEqual(server.writes, tt.want) {
+			t.Errorf("%s %s: writes = %04X, want %04X", tt.byteOrder, tt.dataType, server.writes, tt.want)
+		}
+	}
+}
+
+// TestWithTrace 句柄只记录经自己进行的事务, 不包含同时在原客户端上进行的读取
+func TestWithTrace(t *testing.T) {
+	c := connectFake(t)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			c.ReadHoldingRegisters(1, 0xFF00, 1, datatypes.UINT16)
+		}
+	}()
+	defer wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		trace := &Trace{}
+		traced := c.WithTrace(trace)
+		if _, err := traced.ReadRawRegisters(1, false, 0x0102, 1); err != nil {
+			t.Fatal(err)
+		}
+		traced.SendRawPDU(1, []byte{0x2B, 0x0E})
+		if len(trace.Exchanges) != 2 {
+			t.Fatalf("%d exchanges recorded, want 2", len(trace.Exchanges))
+		}
+		// MBAP 头之后是单元标识和PDU
+		read, raw := trace.Exchanges[0], trace.Exchanges[1]
+		if got := hex.EncodeToString(read.Sent[6:]); got != "010301020001" {
+			t.Errorf("read request = %s", got)
+		}
+		if got := hex.EncodeToString(read.Received[6:]); got != "0103020102" {
+			t.Errorf("read response = %s", got)
+		}
+		if got := hex.EncodeToString(raw.Received[6:]); got != "01ab01" {
+			t.Errorf("raw response = %s", got)
+		}
+	}
+}
+
+// stubTransporter 对任何请求都返回固定的响应和错误
+type stubTransporter struct {
+	response []byte
+	err      error
+}
+
+func (t stubTransporter) Send([]byte) ([]byte, error) { return t.response, t.err }
+func (t stubTransporter) Close() error                { return nil }
+
+// connectStub 返回经 stubTransporter 通信的 RTU 客户端
+func connectStub(transporter stubTransporter) *Client {
+	packager := modbus.NewRTUClientHandler("")
+	capture := &captureTransporter{Transporter: transporter}
+	c := NewClient()
+	c.client = modbus.NewClient2(packager, capture)
+	c.handler = transporter
+	c.rtuPackager = packager
+	c.packager = packager
+	c.transporter = capture
+	c.connectionType = RTU
+	c.isConnected.Store(true)
+	return c
+}
+
+// TestTraceKeepsFailedFrames 校验失败的响应和超时前收到的字节按线上的原样记录
+func TestTraceKeepsFailedFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		err      error
+		want     string
+	}{
+		{"valid", "01030200017984", nil, "01030200017984"},
+		{"bad crc", "0103020001ffff", nil, "0103020001ffff"},
+		{"wrong slave", "02030200017984", nil, "02030200017984"},
+		{"partial", "010302", errors.New("serial: timeout after 3 bytes: 01 03 02"), "010302"},
+		{"no response", "", errors.New("serial: timeout"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, _ := hex.DecodeString(tt.response)
+			trace := &Trace{}
+			c := connectStub(stubTransporter{response: response, err: tt.err}).WithTrace(trace)
+			_, err := c.ReadRawRegisters(1, false, 0x000A, 1)
+			if (err == nil) != (tt.name == "valid") {
+				t.Errorf("err = %v", err)
+			}
+			if len(trace.Exchanges) != 1 {
+				t.Fatalf("%d exchanges recorded, want 1", len(trace.Exchanges))
+			}
+			ex := trace.Exchanges[0]
+			if got := hex.EncodeToString(ex.Sent); got != "0103000a0001a408" {
+				t.Errorf("sent = %s", got)
+			}
+			if got := hex.EncodeToString(ex.Received); got != tt.want {
+				t.Errorf("received = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
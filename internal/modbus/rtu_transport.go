@@ -0,0 +1,236 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"modbusbaby/internal/config"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+const (
+	rtuMinSize       = 4
+	rtuMaxSize       = 256
+	rtuExceptionSize = 5
+
+	// rtuSilence 变长响应的结束判定时间
+	rtuSilence = 50 * time.Millisecond
+)
+
+// rtuTransporter 基于 go.bug.st/serial 的RTU传输层
+// goburrow/serial 不支持 Mark/Space 校验、1.5 停止位以及手动 RTS 换向,
+// 因此RTU连接使用此传输层, 报文的封装和校验仍交给 goburrow/modbus 的 RTU packager。
+type rtuTransporter struct {
+	cfg     config.RTUConfig
+	timeout time.Duration
+	port    serial.Port
+	mu      sync.Mutex
+}
+
+// newRTUTransporter 打开串口并返回传输层
+func newRTUTransporter(cfg config.RTUConfig, timeout time.Duration) (*rtuTransporter, error) {
+	mode, err := serialMode(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := serial.Open(cfg.Port, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &rtuTransporter{cfg: cfg, timeout: timeout, port: port}
+
+	// RS-485 模式下空闲时保持接收方向
+	if cfg.RS485.Enabled {
+		if err := port.SetRTS(!cfg.RS485.RTSOnSend); err != nil {
+			port.Close()
+			return nil, fmt.Errorf("failed to set RTS: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// serialMode 将 RTUConfig 转换为串口参数
+func serialMode(cfg config.RTUConfig) (*serial.Mode, error) {
+	if cfg.BaudRate <= 0 {
+		return nil, fmt.Errorf("invalid baud rate: %d", cfg.BaudRate)
+	}
+
+	mode := &serial.Mode{
+		BaudRate: cfg.BaudRate,
+		DataBits: cfg.DataBits,
+	}
+	if mode.DataBits == 0 {
+		mode.DataBits = 8
+	}
+
+	switch cfg.Parity {
+	case "", "None", "N":
+		mode.Parity = serial.NoParity
+	case "Even", "E":
+		mode.Parity = serial.EvenParity
+	case "Odd", "O":
+		mode.Parity = serial.OddParity
+	case "Mark", "M":
+		mode.Parity = serial.MarkParity
+	case "Space", "S":
+		mode.Parity = serial.SpaceParity
+	default:
+		return nil, fmt.Errorf("invalid parity: %s", cfg.Parity)
+	}
+
+	switch cfg.StopBits {
+	case 0, 1:
+		mode.StopBits = serial.OneStopBit
+	case 1.5:
+		mode.StopBits = serial.OnePointFiveStopBits
+	case 2:
+		mode.StopBits = serial.TwoStopBits
+	default:
+		return nil, fmt.Errorf("invalid stop bits: %v", cfg.StopBits)
+	}
+	return mode, nil
+}
+
+// Send 发送请求ADU并读取响应ADU
+func (t *rtuTransporter) Send(aduRequest []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.port == nil {
+		return nil, fmt.Errorf("serial port is closed")
+	}
+	if len(aduRequest) < rtuMinSize {
+		return nil, fmt.Errorf("request ADU too short: %d", len(aduRequest))
+	}
+
+	// 丢弃上一次通信残留的字节
+	if err := t.port.ResetInputBuffer(); err != nil {
+		return nil, err
+	}
+
+	if err := t.write(aduRequest); err != nil {
+		return nil, err
+	}
+	return t.readResponse(aduRequest)
+}
+
+// write 写入请求, RS-485 模式下在发送前后切换 RTS
+func (t *rtuTransporter) write(adu []byte) error {
+	rs485 := t.cfg.RS485
+	if rs485.Enabled {
+		if err := t.port.SetRTS(rs485.RTSOnSend); err != nil {
+			return fmt.Errorf("failed to set RTS: %w", err)
+		}
+		if rs485.DelayBeforeSend > 0 {
+			time.Sleep(time.Duration(rs485.DelayBeforeSend) * time.Millisecond)
+		}
+	}
+
+	_, err := t.port.Write(adu)
+	if err == nil && rs485.Enabled {
+		// 必须等数据完全移出发送缓冲区后才能切回接收, 否则最后几个字节会被截断
+		err = t.port.Drain()
+		if rs485.DelayAfterSend > 0 {
+			time.Sleep(time.Duration(rs485.DelayAfterSend) * time.Millisecond)
+		}
+	}
+
+	if rs485.Enabled {
+		if rtsErr := t.port.SetRTS(!rs485.RTSOnSend); rtsErr != nil && err == nil {
+			err = fmt.Errorf("failed to set RTS: %w", rtsErr)
+		}
+	}
+	return err
+}
+
+// readResponse 按功能码推算响应长度并读取, 异常响应只读取5个字节。
+// 无法推算长度的功能码以帧间静默作为结束标志。超时或读取出错时连同错误返回已收到的字节, 供报文记录
+func (t *rtuTransporter) readResponse(aduRequest []byte) ([]byte, error) {
+	function := aduRequest[1]
+	expected := calculateResponseLength(aduRequest)
+
+	var data [rtuMaxSize]byte
+	n := 0
+	deadline := time.Now().Add(t.timeout)
+	for expected == 0 || n < expected {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if n == 0 {
+				return nil, fmt.Errorf("serial: timeout")
+			}
+			return data[:n], fmt.Errorf("serial: timeout after %d bytes: % x", n, data[:n])
+		}
+		wait := remaining
+		if expected == 0 && n > 0 && wait > rtuSilence {
+			wait = rtuSilence
+		}
+		if err := t.port.SetReadTimeout(wait); err != nil {
+			return data[:n], err
+		}
+
+		limit := rtuMaxSize
+		if expected > 0 {
+			limit = expected
+		}
+		read, err := t.port.Read(data[n:limit])
+		if err != nil {
+			return data[:n], err
+		}
+		if read == 0 && expected == 0 && n >= rtuMinSize {
+			break
+		}
+		n += read
+
+		// 收到异常响应时调整期望长度
+		if n >= 2 && data[1] == function|0x80 {
+			expected = rtuExceptionSize
+		}
+		if n == rtuMaxSize {
+			break
+		}
+	}
+	return data[:n], nil
+}
+
+// Close 关闭串口
+func (t *rtuTransporter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.port == nil {
+		return nil
+	}
+	err := t.port.Close()
+	t.port = nil
+	return err
+}
+
+// calculateResponseLength 根据请求推算正常响应的ADU长度, 无法推算 (包括请求过短, 缺少数量字段) 时返回0
+func calculateResponseLength(adu []byte) int {
+	length := rtuMinSize
+	switch adu[1] {
+	case 0x01, 0x02, 0x03, 0x04, 0x17:
+		if len(adu) < 6 {
+			return 0
+		}
+		count := int(binary.BigEndian.Uint16(adu[4:]))
+		if adu[1] <= 0x02 {
+			length += 1 + (count+7)/8
+		} else {
+			length += 1 + count*2
+		}
+	case 0x05, 0x06, 0x0F, 0x10:
+		length += 4
+	case 0x16:
+		length += 6
+	default:
+		return 0
+	}
+	if length > rtuMaxSize {
+		length = rtuMaxSize
+	}
+	return length
+}
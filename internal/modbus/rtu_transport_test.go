@@ -0,0 +1,165 @@
+package modbus
+
+import (
+	"encoding/hex"
+	"errors"
+	"modbusbaby/internal/config"
+	"strings"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// fakePort 按顺序返回预设的数据块, 数据块用完后像真实串口一样等到读超时返回 0 字节
+type fakePort struct {
+	serial.Port // 未用到的方法
+	chunks      [][]byte
+	err         error // 数据块用完后 Read 返回的错误
+	timeout     time.Duration
+	written     []byte
+}
+
+func (p *fakePort) Read(b []byte) (int, error) {
+	if len(p.chunks) == 0 {
+		if p.err != nil {
+			return 0, p.err
+		}
+		time.Sleep(p.timeout)
+		return 0, nil
+	}
+	n := copy(b, p.chunks[0])
+	if p.chunks[0] = p.chunks[0][n:]; len(p.chunks[0]) == 0 {
+		p.chunks = p.chunks[1:]
+	}
+	return n, nil
+}
+
+func (p *fakePort) Write(b []byte) (int, error) {
+	p.written = append(p.written, b...)
+	return len(b), nil
+}
+
+func (p *fakePort) SetReadTimeout(t time.Duration) error {
+	p.timeout = t
+	return nil
+}
+
+func (p *fakePort) ResetInputBuffer() error { return nil }
+
+func TestSerialMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RTUConfig
+		want    serial.Mode
+		wantErr string
+	}{
+		{"defaults", config.RTUConfig{BaudRate: 9600},
+			serial.Mode{BaudRate: 9600, DataBits: 8, Parity: serial.NoParity, StopBits: serial.OneStopBit}, ""},
+		{"even", config.RTUConfig{BaudRate: 19200, DataBits: 7, Parity: "E", StopBits: 2},
+			serial.Mode{BaudRate: 19200, DataBits: 7, Parity: serial.EvenParity, StopBits: serial.TwoStopBits}, ""},
+		{"odd", config.RTUConfig{BaudRate: 1200, Parity: "Odd", StopBits: 1},
+			serial.Mode{BaudRate: 1200, DataBits: 8, Parity: serial.OddParity, StopBits: serial.OneStopBit}, ""},
+		{"mark", config.RTUConfig{BaudRate: 250000, Parity: "Mark", StopBits: 1.5},
+			serial.Mode{BaudRate: 250000, DataBits: 8, Parity: serial.MarkParity, StopBits: serial.OnePointFiveStopBits}, ""},
+		{"space", config.RTUConfig{BaudRate: 4800, Parity: "S"},
+			serial.Mode{BaudRate: 4800, DataBits: 8, Parity: serial.SpaceParity, StopBits: serial.OneStopBit}, ""},
+		{"zero baud rate", config.RTUConfig{}, serial.Mode{}, "invalid baud rate"},
+		{"negative baud rate", config.RTUConfig{BaudRate: -9600}, serial.Mode{}, "invalid baud rate"},
+		{"bad parity", config.RTUConfig{BaudRate: 9600, Parity: "even"}, serial.Mode{}, "invalid parity"},
+		{"bad stop bits", config.RTUConfig{BaudRate: 9600, StopBits: 3}, serial.Mode{}, "invalid stop bits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := serialMode(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *mode != tt.want {
+				t.Errorf("mode = %+v, want %+v", *mode, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateResponseLength(t *testing.T) {
+	tests := []struct {
+		name string
+		adu  string
+		want int
+	}{
+		{"read coils", "01 01 0000 000A C5CD", 7},
+		{"read discrete inputs", "01 02 0000 0010 79C6", 7},
+		{"read holding registers", "01 03 0000 0002 C40B", 9},
+		{"read input registers", "01 04 0000 0001 31CA", 7},
+		{"read/write registers", "01 17 0000 0003 0000 0001 02 0001 0000", 11},
+		{"write single coil", "01 05 0000 FF00 8C3A", 8},
+		{"write multiple registers", "01 10 0000 0001 02 0001 0000", 8},
+		{"mask write", "01 16 0000 00F2 0025 0000", 10},
+		{"clamped to 256 bytes", "01 03 0000 0080 0000", 256},
+		{"unknown function", "01 2B 0E01 00 0000", 0},
+		{"4-byte read", "01 03 0000", 0},
+		{"5-byte read", "01 03 0000 00", 0},
+		{"5-byte coils", "01 01 0000 00", 0},
+	}
+	for _, tt := range tests {
+		if got := calculateResponseLength(mustDecodeHex(t, tt.adu)); got != tt.want {
+			t.Errorf("%s: length = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	failed := errors.New("port closed")
+	tests := []struct {
+		name    string
+		request string
+		chunks  []string
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{"in pieces", "0103 0000 0002 C40B", []string{"0103", "04 0001", "0002 2A32", "FFFF"}, nil, "010304000100022a32", false},
+		{"exception", "0103 0000 0002 C40B", []string{"0183 02C0F1"}, nil, "018302c0f1", false},
+		{"silence terminated", "012B 0E01 00 0000", []string{"012B 0E01", "0000 AABB"}, nil, "012b0e010000aabb", false},
+		{"timeout", "0103 0000 0002 C40B", []string{"0103 04"}, nil, "010304", true},
+		{"no response", "0103 0000 0002 C40B", nil, nil, "", true},
+		{"read error", "0103 0000 0002 C40B", []string{"01"}, failed, "01", true},
+		{"short request", "0103 0000", []string{"0183 02C0F1"}, nil, "018302c0f1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := &fakePort{err: tt.err}
+			for _, chunk := range tt.chunks {
+				port.chunks = append(port.chunks, mustDecodeHex(t, chunk))
+			}
+			transporter := &rtuTransporter{timeout: 100 * time.Millisecond, port: port}
+			request := mustDecodeHex(t, tt.request)
+			response, err := transporter.Send(request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v", err)
+			}
+			if got := hex.EncodeToString(response); got != tt.want {
+				t.Errorf("response = %s, want %s", got, tt.want)
+			}
+			if hex.EncodeToString(port.written) != hex.EncodeToString(request) {
+				t.Errorf("written = %x", port.written)
+			}
+		})
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
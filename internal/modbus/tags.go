@@ -0,0 +1,89 @@
+package modbus
+
+import (
+	"fmt"
+	"modbusbaby/internal/config"
+	"modbusbaby/pkg/datatypes"
+	"time"
+)
+
+// TagReading 点位的一次读取结果
+type TagReading struct {
+	Tag       config.Tag
+	Unit      byte        // 实际使用的从站地址
+	Time      time.Time   // 收到响应的时间
+	Values    interface{} // 解码后的值, 未缩放
+	Registers []uint16    // 原始寄存器, 线圈和离散输入为空
+	Scaled    []float64   // 按点位缩放后的工程值, 字符串和时间类型为空
+}
+
+// ReadTag 按点位定义读取并解码。使用点位自己的字节/字序解码, 不改变客户端的数据转换器,
+// 因此可与界面上的手动读取交替进行。点位未指定从站地址时使用 defaultUnit
+func (c *Client) ReadTag(tag config.Tag, defaultUnit byte) (TagReading, error) {
+	reading := TagReading{Tag: tag, Unit: defaultUnit}
+	if tag.UnitID != 0 {
+		reading.Unit = byte(tag.UnitID)
+	}
+	if !c.isConnected.Load() {
+		return reading, fmt.Errorf("device not connected")
+	}
+	address, count := uint16(tag.Address), uint16(tag.RegisterCount())
+
+	var err error
+	switch tag.Table {
+	case config.TableCoil:
+		reading.Values, err = c.ReadCoils(reading.Unit, address, count)
+	case config.TableDiscrete:
+		reading.Values, err = c.ReadDiscreteInputs(reading.Unit, address, count)
+	case config.TableHolding, config.TableInput:
+		reading.Registers, err = c.ReadRawRegisters(reading.Unit, tag.Table == config.TableInput, address, count)
+		if err == nil {
+			byteOrder, wordOrder := tag.Orders()
+			converter := datatypes.NewConverter(byteOrder, wordOrder)
+			converter.SetLocation(c.dataConverter().Location())
+			reading.Values, err = converter.ConvertFromRegisters(reading.Registers, tag.Type())
+		}
+	default:
+		err = fmt.Errorf("unsupported table: %s", tag.Table)
+	}
+	reading.Time = time.Now()
+	if err != nil {
+		return reading, err
+	}
+
+	if values, ok := datatypes.ToFloat64Slice(reading.Values); ok {
+		if tag.IsBit() {
+			reading.Scaled = values
+		} else if reading.Scaled, err = tag.Scaling().ApplyValues(reading.Values); err != nil {
+			return reading, err
+		}
+	}
+	return reading, nil
+}
+
+// ReadRawRegisters 在一个事务内读取保持或输入寄存器, 返回线上的原始寄存器, 不经过数据转换器。
+// 需要同时使用原始寄存器和解码后的值时, 用此方法读取后再调用 ConvertRegisters
+func (c *Client) ReadRawRegisters(slaveID byte, input bool, address, count uint16) ([]uint16, error) {
+	release, err := c.selectSlave(slaveID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var results []byte
+	if input {
+		results, err = c.client.ReadInputRegisters(address, count)
+	} else {
+		results, err = c.client.ReadHoldingRegisters(address, count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registers: %w", err)
+	}
+	return bytesToUint16Array(results), nil
+}
+
+// ConvertRegisters 按客户端当前的字节/字序、字符串和时区设置解码原始寄存器,
+// 与 ReadHoldingRegisters 返回的值相同。配合 ReadRawRegisters 同时得到原始寄存器和解码后的值
+func (c *Client) ConvertRegisters(registers []uint16, dataType datatypes.DataType) (interface{}, error) {
+	return c.dataConverter().ConvertFromRegisters(registers, dataType)
+}
@@ -0,0 +1,36 @@
+package modbus
+
+import (
+	"bytes"
+
+	"github.com/goburrow/modbus"
+)
+
+// Exchange 一个事务的请求和响应ADU, 没有收到响应时 Received 为空
+type Exchange struct {
+	Sent     []byte
+	Received []byte
+}
+
+// Trace 按顺序记录一次操作中的事务, 见 Client.WithTrace。同一时间只应由一个 goroutine 使用
+type Trace struct {
+	Exchanges []Exchange
+}
+
+// captureTransporter 在传输层记录线上的请求和响应ADU。校验或解码失败的响应 (CRC错误、事务号不符等)
+// 和超时前收到的部分字节也原样保留。只在持有 ioMutex 时使用, 由事务结束时的 recordExchanges 取走
+type captureTransporter struct {
+	modbus.Transporter
+	exchanges []Exchange
+}
+
+// Send 发送请求并记录请求和收到的响应
+func (t *captureTransporter) Send(aduRequest []byte) ([]byte, error) {
+	aduResponse, err := t.Transporter.Send(aduRequest)
+	var received []byte
+	if len(aduResponse) > 0 {
+		received = bytes.Clone(aduResponse)
+	}
+	t.exchanges = append(t.exchanges, Exchange{Sent: bytes.Clone(aduRequest), Received: received})
+	return aduResponse, err
+}
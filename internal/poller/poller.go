@@ -0,0 +1,227 @@
+// Package poller 按各自的周期轮询多组读取
+//
+// 所有组在同一个 goroutine 中按到期先后依次执行, 同一连接 (尤其是串口总线) 上的请求不会交错。
+// 每组的计划时间固定为 起始时间 + n × 周期, 执行耗时不会使周期累积漂移; 可为每组设置随机抖动,
+// 使周期相同的多个设备不会总在同一时刻被访问。一次执行结束时已经错过下一个计划时间视为超时 (overrun),
+// 错过的周期直接跳过而不是连续补读, 并计入统计。
+package poller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Group 一组按同一周期执行的读取
+type Group struct {
+	Name     string
+	Interval time.Duration
+	Jitter   time.Duration                   // 每次在计划时间后随机延迟 [0, Jitter), 须小于周期
+	Read     func(ctx context.Context) error // 执行一次读取, ctx 在停止轮询时取消
+}
+
+// Stats 一组的执行统计
+type Stats struct {
+	Name      string
+	Interval  time.Duration
+	Cycles    int64         // 执行次数
+	Errors    int64         // 返回错误的次数
+	Overruns  int64         // 执行结束时已错过下一个计划时间的次数, 含被其他组推迟的情况
+	Skipped   int64         // 因超时跳过的周期数
+	Last      time.Duration // 最近一次执行耗时
+	Min       time.Duration
+	Max       time.Duration
+	Mean      time.Duration
+	MaxLate   time.Duration // 因其他组占用连接而晚于计划开始的最长时间
+	LastStart time.Time
+	LastError error
+}
+
+// group 运行中的一组
+type group struct {
+	Group
+	next  time.Time // 下一个计划时间 (不含抖动)
+	due   time.Time // 下一次实际开始的时间
+	total time.Duration
+	stats Stats
+}
+
+// Poller 轮询器, 停止后可再次启动, 统计在每次启动时清零
+type Poller struct {
+	// OnOverrun 组的执行超过周期时在轮询 goroutine 中调用, skipped 为本次跳过的周期数
+	OnOverrun func(name string, took time.Duration, skipped int)
+
+	mu     sync.Mutex
+	groups []*group
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New 检查各组设置并创建轮询器
+func New(groups []Group) (*Poller, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("没有要轮询的内容")
+	}
+	p := &Poller{}
+	names := make(map[string]bool)
+	for _, g := range groups {
+		switch {
+		case g.Interval <= 0:
+			return nil, fmt.Errorf("轮询组 %s 的周期无效: %s", g.Name, g.Interval)
+		case g.Jitter < 0 || g.Jitter >= g.Interval:
+			return nil, fmt.Errorf("轮询组 %s 的抖动须小于周期: %s", g.Name, g.Jitter)
+		case g.Read == nil:
+			return nil, fmt.Errorf("轮询组 %s 没有读取函数", g.Name)
+		case names[g.Name]:
+			return nil, fmt.Errorf("轮询组名称重复: %s", g.Name)
+		}
+		names[g.Name] = true
+		p.groups = append(p.groups, &group{Group: g})
+	}
+	return p, nil
+}
+
+// Start 开始轮询, 各组立即执行第一次
+func (p *Poller) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done != nil {
+		return fmt.Errorf("轮询已在运行")
+	}
+
+	now := time.Now()
+	for _, g := range p.groups {
+		g.next = now
+		g.due = now.Add(g.jitter())
+		g.total = 0
+		g.stats = Stats{Name: g.Name, Interval: g.Interval}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel, p.done = cancel, make(chan struct{})
+	go p.run(ctx, p.done)
+	return nil
+}
+
+// Stop 停止轮询并等待正在执行的读取结束, 未运行时直接返回。
+// 可在多个 goroutine 中同时调用, 每次调用都等到轮询真正结束才返回
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	p.mu.Lock()
+	if p.done == done {
+		p.cancel, p.done = nil, nil
+	}
+	p.mu.Unlock()
+}
+
+// Running 判断是否正在轮询, 停止过程中仍返回 true
+func (p *Poller) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done != nil
+}
+
+// Stats 返回各组的统计, 顺序与创建时一致
+func (p *Poller) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]Stats, len(p.groups))
+	for i, g := range p.groups {
+		stats[i] = g.stats
+	}
+	return stats
+}
+
+// run 轮询循环: 等待最早到期的组, 执行后安排下一次
+func (p *Poller) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		p.mu.Lock()
+		g := p.groups[0]
+		for _, other := range p.groups[1:] {
+			if other.due.Before(g.due) {
+				g = other
+			}
+		}
+		due := g.due
+		p.mu.Unlock()
+
+		if wait := time.Until(due); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+
+		start := time.Now()
+		err := g.Read(ctx)
+		end := time.Now()
+		if ctx.Err() != nil {
+			return // 停止时被取消的读取不计入统计
+		}
+		skipped := p.finish(g, start, end, err)
+		if skipped > 0 && p.OnOverrun != nil {
+			p.OnOverrun(g.Name, end.Sub(start), skipped)
+		}
+	}
+}
+
+// finish 更新统计并安排下一次执行, 返回因超时跳过的周期数
+func (p *Poller) finish(g *group, start, end time.Time, err error) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	took := end.Sub(start)
+	s := &g.stats
+	s.Cycles++
+	s.Last, s.LastStart, s.LastError = took, start, err
+	if err != nil {
+		s.Errors++
+	}
+	if s.Cycles == 1 || took < s.Min {
+		s.Min = took
+	}
+	if took > s.Max {
+		s.Max = took
+	}
+	g.total += took
+	s.Mean = g.total / time.Duration(s.Cycles)
+	if late := start.Sub(g.due); late > s.MaxLate {
+		s.MaxLate = late
+	}
+
+	g.next = g.next.Add(g.Interval)
+	skipped := 0
+	if end.After(g.next) {
+		skipped = int(end.Sub(g.next)/g.Interval) + 1
+		g.next = g.next.Add(time.Duration(skipped) * g.Interval)
+		s.Overruns++
+		s.Skipped += int64(skipped)
+	}
+	g.due = g.next.Add(g.jitter())
+	return skipped
+}
+
+func (g *group) jitter() time.Duration {
+	if g.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(g.Jitter)))
+}
@@ -0,0 +1,190 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"modbusbaby/internal/config"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func noop(context.Context) error { return nil }
+
+func TestNewErrors(t *testing.T) {
+	tests := map[string][]Group{
+		"no groups":       nil,
+		"zero interval":   {{Name: "a", Read: noop}},
+		"jitter":          {{Name: "a", Interval: time.Second, Jitter: time.Second, Read: noop}},
+		"negative jitter": {{Name: "a", Interval: time.Second, Jitter: -1, Read: noop}},
+		"no read":         {{Name: "a", Interval: time.Second}},
+		"duplicate name":  {{Name: "a", Interval: time.Second, Read: noop}, {Name: "a", Interval: time.Minute, Read: noop}},
+	}
+	for name, groups := range tests {
+		if _, err := New(groups); err == nil {
+			t.Errorf("%s: New succeeded, want an error", name)
+		}
+	}
+}
+
+// TestSchedule 各组按自己的周期执行, 读取从不交错
+func TestSchedule(t *testing.T) {
+	var inflight, overlapped atomic.Int32
+	counts := make([]atomic.Int32, 2)
+	read := func(i int) func(context.Context) error {
+		return func(context.Context) error {
+			if inflight.Add(1) > 1 {
+				overlapped.Add(1)
+			}
+			counts[i].Add(1)
+			time.Sleep(time.Millisecond)
+			inflight.Add(-1)
+			return nil
+		}
+	}
+	p, err := New([]Group{
+		{Name: "fast", Interval: 10 * time.Millisecond, Read: read(0)},
+		{Name: "slow", Interval: 50 * time.Millisecond, Jitter: 5 * time.Millisecond, Read: read(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); err == nil {
+		t.Error("second Start succeeded")
+	}
+	time.Sleep(245 * time.Millisecond)
+	p.Stop()
+	if p.Running() {
+		t.Error("Running after Stop")
+	}
+
+	if overlapped.Load() > 0 {
+		t.Errorf("%d reads overlapped", overlapped.Load())
+	}
+	// 计时受机器负载影响, 只检查大致的次数
+	fast, slow := counts[0].Load(), counts[1].Load()
+	if fast < 15 || fast > 26 || slow < 3 || slow > 6 {
+		t.Errorf("fast ran %d times, slow %d times; want about 25 and 5", fast, slow)
+	}
+	stats := p.Stats()
+	if stats[0].Name != "fast" || stats[0].Cycles != int64(fast) || stats[1].Cycles != int64(slow) {
+		t.Errorf("stats = %+v", stats)
+	}
+	if s := stats[0]; s.Min > s.Mean || s.Mean > s.Max || s.Min < time.Millisecond {
+		t.Errorf("durations: min %s, mean %s, max %s", s.Min, s.Mean, s.Max)
+	}
+}
+
+// TestOverrun 超过周期的执行跳过错过的周期, 计划时间不漂移
+func TestOverrun(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	var skippedTotal int
+	p, err := New([]Group{{Name: "slow", Interval: 20 * time.Millisecond, Read: func(context.Context) error {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.OnOverrun = func(name string, took time.Duration, skipped int) {
+		if name != "slow" || took < 50*time.Millisecond {
+			t.Errorf("OnOverrun(%s, %s, %d)", name, took, skipped)
+		}
+		skippedTotal += skipped
+	}
+	p.Start()
+	time.Sleep(190 * time.Millisecond)
+	p.Stop()
+
+	s := p.Stats()[0]
+	if s.Cycles < 2 || s.Overruns != s.Cycles || s.Skipped != int64(skippedTotal) || s.Skipped < 2*s.Cycles {
+		t.Errorf("cycles %d, overruns %d, skipped %d (OnOverrun %d)", s.Cycles, s.Overruns, s.Skipped, skippedTotal)
+	}
+	// 每次执行 50ms 跨过两个计划时间, 下一次在第三个计划时间开始, 间隔为周期的整数倍
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(starts); i++ {
+		gap := starts[i].Sub(starts[i-1])
+		if gap < 55*time.Millisecond || gap > 100*time.Millisecond {
+			t.Errorf("start %d came %s after the previous one, want about 60ms", i, gap)
+		}
+	}
+}
+
+// TestStopCancelsRead 停止时取消正在进行的读取, 被取消的读取不计入统计; 再次启动时统计清零
+func TestStopCancelsRead(t *testing.T) {
+	failed := errors.New("timeout")
+	var calls atomic.Int32
+	started := make(chan struct{}, 10)
+	p, err := New([]Group{{Name: "a", Interval: 10 * time.Millisecond, Read: func(ctx context.Context) error {
+		started <- struct{}{}
+		if calls.Add(1) == 1 {
+			return failed
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Stop() // 未运行时直接返回
+
+	for run := 0; run < 2; run++ {
+		calls.Store(0)
+		if err := p.Start(); err != nil {
+			t.Fatal(err)
+		}
+		<-started
+		<-started // 第二次读取阻塞到停止
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.Stop()
+			}()
+		}
+		wg.Wait()
+
+		s := p.Stats()[0]
+		if s.Cycles != 1 || s.Errors != 1 || s.LastError != failed {
+			t.Errorf("run %d: cycles %d, errors %d, last error %v", run, s.Cycles, s.Errors, s.LastError)
+		}
+	}
+}
+
+func TestScheduleTags(t *testing.T) {
+	tags := []config.Tag{
+		{Name: "a", Table: config.TableHolding, PollMs: 5000},
+		{Name: "b", Table: config.TableCoil},
+		{Name: "c", Table: config.TableHolding, PollMs: 500},
+		{Name: "w", Table: config.TableHolding, Access: config.AccessWrite},
+		{Name: "d", Table: config.TableInput, PollMs: 1000},
+		{Name: "e", Table: config.TableHolding, PollMs: 5000},
+	}
+	var got []string
+	for _, s := range ScheduleTags(tags, time.Second) {
+		names := s.Name() + ":"
+		for _, tag := range s.Tags {
+			names += " " + tag.Name
+		}
+		got = append(got, names)
+	}
+	want := []string{"500ms: c", "1s: b d", "5s: a e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScheduleTags = %q, want %q", got, want)
+	}
+	if got := ScheduleTags(tags[3:4], time.Second); len(got) != 0 {
+		t.Errorf("write-only tags scheduled: %+v", got)
+	}
+}
@@ -0,0 +1,48 @@
+package poller
+
+import (
+	"modbusbaby/internal/config"
+	"sort"
+	"time"
+)
+
+// TagSchedule 周期相同的一组点位
+type TagSchedule struct {
+	Interval time.Duration
+	Tags     []config.Tag
+}
+
+// Name 返回以周期命名的组名, 如 "500ms"、"5s"
+func (s TagSchedule) Name() string {
+	return s.Interval.String()
+}
+
+// ScheduleTags 将可读的点位按轮询周期分组, 未设置周期的点位使用 defaultInterval。
+// 组按周期从短到长排列, 组内保持点位表中的顺序
+func ScheduleTags(tags []config.Tag, defaultInterval time.Duration) []TagSchedule {
+	byInterval := make(map[time.Duration]*TagSchedule)
+	var schedules []*TagSchedule
+	for _, tag := range tags {
+		if !tag.Readable() {
+			continue
+		}
+		interval := tag.PollInterval()
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+		s, ok := byInterval[interval]
+		if !ok {
+			s = &TagSchedule{Interval: interval}
+			byInterval[interval] = s
+			schedules = append(schedules, s)
+		}
+		s.Tags = append(s.Tags, tag)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Interval < schedules[j].Interval })
+
+	result := make([]TagSchedule, len(schedules))
+	for i, s := range schedules {
+		result[i] = *s
+	}
+	return result
+}
@@ -0,0 +1,249 @@
+// Package recorder 将轮询结果记录到 CSV 文件
+//
+// 每行一次轮询: 第一列为时间, 其后每个点一列, 本次未读到的点留空。
+// 文件超过大小或时长上限时换新文件; 出现新的点时也换新文件, 使每个文件的表头与各行一致。
+package recorder
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 时间列的特殊格式, 其余按 Go 时间格式 (如 2006-01-02 15:04:05.000) 处理
+const (
+	TimeUnix    = "unix"    // Unix 秒, 带毫秒小数
+	TimeUnixMs  = "unix_ms" // Unix 毫秒
+	TimeRFC3339 = "rfc3339" // RFC 3339, 带毫秒和时区
+	TimeExcel   = "excel"   // Excel 序列日期 (本地时间), 可直接设置单元格格式显示为日期时间
+)
+
+// DefaultTimeFormat 默认的时间列格式
+const DefaultTimeFormat = "2006-01-02 15:04:05.000"
+
+// Options 记录选项
+type Options struct {
+	Dir              string        // 记录文件目录
+	Prefix           string        // 文件名前缀, 默认 modbusbaby
+	MaxSize          int64         // 单个文件的最大字节数, 0 表示不限
+	RotateEvery      time.Duration // 单个文件的最长时长, 0 表示不限
+	DecimalSeparator string        // 小数点, "." 或 ","
+	Delimiter        rune          // 列分隔符, 0 时小数点为 "," 则用 ';', 否则用 ','
+	TimeFormat       string        // 时间列格式, 见 TimeUnix 等常量
+}
+
+// Point 一个点的数值
+type Point struct {
+	Name  string
+	Value float64
+}
+
+// Recorder CSV 记录器, 可被多个 goroutine 同时使用
+type Recorder struct {
+	mu      sync.Mutex
+	opts    Options
+	file    *os.File
+	buf     *bufio.Writer
+	writer  *csv.Writer
+	path    string
+	opened  time.Time
+	size    int64
+	columns []string
+	index   map[string]int
+	rows    int
+}
+
+// New 检查选项并创建记录器, 第一次 Record 时才创建文件
+func New(opts Options) (*Recorder, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("未指定记录目录")
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "modbusbaby"
+	}
+	switch opts.DecimalSeparator {
+	case "":
+		opts.DecimalSeparator = "."
+	case ".", ",":
+	default:
+		return nil, fmt.Errorf("小数点只能为 \".\" 或 \",\": %q", opts.DecimalSeparator)
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+		if opts.DecimalSeparator == "," {
+			opts.Delimiter = ';'
+		}
+	}
+	if string(opts.Delimiter) == opts.DecimalSeparator {
+		return nil, fmt.Errorf("列分隔符不能与小数点相同")
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = DefaultTimeFormat
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建记录目录失败: %w", err)
+	}
+	return &Recorder{opts: opts, index: make(map[string]int)}, nil
+}
+
+// Record 写入一行。需要换文件时先关闭当前文件, 新文件的表头包含此前出现过的所有点
+func (r *Recorder) Record(t time.Time, points []Point) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	grown := false
+	for _, p := range points {
+		if _, ok := r.index[p.Name]; !ok {
+			r.index[p.Name] = len(r.columns)
+			r.columns = append(r.columns, p.Name)
+			grown = true
+		}
+	}
+	if r.file != nil && (grown || r.full(t)) {
+		if err := r.closeFile(); err != nil {
+			return err
+		}
+	}
+	if r.file == nil {
+		if err := r.openFile(t); err != nil {
+			return err
+		}
+	}
+
+	row := make([]string, len(r.columns)+1)
+	row[0] = r.formatTime(t)
+	for _, p := range points {
+		row[r.index[p.Name]+1] = r.formatValue(p.Value)
+	}
+	if err := r.writeRow(row); err != nil {
+		return err
+	}
+	r.rows++
+	return nil
+}
+
+// full 判断当前文件是否达到大小或时长上限
+func (r *Recorder) full(t time.Time) bool {
+	if r.opts.MaxSize > 0 && r.size >= r.opts.MaxSize {
+		return true
+	}
+	return r.opts.RotateEvery > 0 && t.Sub(r.opened) >= r.opts.RotateEvery
+}
+
+// openFile 以时间戳命名创建新文件并写入表头
+func (r *Recorder) openFile(t time.Time) error {
+	base := fmt.Sprintf("%s_%s", r.opts.Prefix, t.Format("20060102_150405"))
+	path := filepath.Join(r.opts.Dir, base+".csv")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(r.opts.Dir, fmt.Sprintf("%s_%d.csv", base, i))
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("创建记录文件失败: %w", err)
+	}
+	r.file, r.path, r.opened, r.size, r.rows = file, path, t, 0, 0
+	r.buf = bufio.NewWriter(&countingWriter{w: file, n: &r.size})
+	r.writer = csv.NewWriter(r.buf)
+	r.writer.Comma = r.opts.Delimiter
+	return r.writeRow(append([]string{"time"}, r.columns...))
+}
+
+// writeRow 写入一行并立即落盘, 程序异常退出时不丢失已记录的数据
+func (r *Recorder) writeRow(row []string) error {
+	r.writer.Write(row)
+	r.writer.Flush()
+	if err := r.writer.Error(); err != nil {
+		return fmt.Errorf("写入记录文件失败: %w", err)
+	}
+	if err := r.buf.Flush(); err != nil {
+		return fmt.Errorf("写入记录文件失败: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) closeFile() error {
+	err := r.file.Close()
+	r.file, r.buf, r.writer = nil, nil, nil
+	if err != nil {
+		return fmt.Errorf("关闭记录文件失败: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭当前文件
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.closeFile()
+}
+
+// Path 返回当前文件路径和已写入的行数 (不含表头)
+func (r *Recorder) Path() (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.path, r.rows
+}
+
+// formatTime 按时间列格式输出时间
+func (r *Recorder) formatTime(t time.Time) string {
+	switch strings.ToLower(r.opts.TimeFormat) {
+	case TimeUnix:
+		return r.formatValue(float64(t.UnixMilli()) / 1000)
+	case TimeUnixMs:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case TimeRFC3339:
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	case TimeExcel:
+		return r.formatValue(excelSerial(t))
+	default:
+		return t.Format(r.opts.TimeFormat)
+	}
+}
+
+// formatValue 按小数点设置输出数值
+func (r *Recorder) formatValue(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ""
+	}
+	text := strconv.FormatFloat(v, 'f', -1, 64)
+	if r.opts.DecimalSeparator != "." {
+		text = strings.Replace(text, ".", r.opts.DecimalSeparator, 1)
+	}
+	return text
+}
+
+// excelEpoch Excel 序列日期的零点 (1900 日期系统)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// excelSerial 将时间转换为 Excel 序列日期, 按本地时间的日期和时刻计算
+func excelSerial(t time.Time) float64 {
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	days := local.Sub(excelEpoch).Hours() / 24
+	return math.Round(days*86400000) / 86400000
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w *os.File
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
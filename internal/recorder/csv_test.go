@@ -0,0 +1,175 @@
+package recorder
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 3, 1, 12, 30, 45, 250e6, time.UTC)
+
+// readFiles 按文件名顺序返回目录中各记录文件的内容
+func readFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	var contents []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, string(data))
+	}
+	return contents
+}
+
+func TestNewOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		delimiter rune
+		wantErr   bool
+	}{
+		{"defaults", Options{}, ',', false},
+		{"decimal comma", Options{DecimalSeparator: ","}, ';', false},
+		{"tab", Options{Delimiter: '\t'}, '\t', false},
+		{"bad separator", Options{DecimalSeparator: "·"}, 0, true},
+		{"same as delimiter", Options{DecimalSeparator: ",", Delimiter: ','}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Dir = t.TempDir()
+			r, err := New(tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("New succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.opts.Delimiter != tt.delimiter || r.opts.Prefix != "modbusbaby" || r.opts.TimeFormat != DefaultTimeFormat {
+				t.Errorf("options = %+v", r.opts)
+			}
+		})
+	}
+	if _, err := New(Options{}); err == nil {
+		t.Error("New without a directory succeeded, want an error")
+	}
+}
+
+func TestRecordColumns(t *testing.T) {
+	dir := t.TempDir()
+	r, err := New(Options{Dir: dir, DecimalSeparator: ","})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Record(start, []Point{{"Voltage", 230.5}, {"Current", 1.25}})
+	r.Record(start.Add(time.Second), []Point{{"Current", math.NaN()}})
+	if path, rows := r.Path(); filepath.Base(path) != "modbusbaby_20240301_123045.csv" || rows != 2 {
+		t.Errorf("Path = %s, %d", path, rows)
+	}
+	// 新的点换新文件, 表头包含此前所有的点
+	r.Record(start.Add(2*time.Second), []Point{{"Power", -7}, {"Voltage", 231}})
+
+	want := []string{
+		"time;Voltage;Current\n" +
+			"2024-03-01 12:30:45.250;230,5;1,25\n" +
+			"2024-03-01 12:30:46.250;;\n",
+		"time;Voltage;Current;Power\n" +
+			"2024-03-01 12:30:47.250;231;;-7\n",
+	}
+	got := readFiles(t, dir)
+	if len(got) != len(want) {
+		t.Fatalf("%d files, want %d: %q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d:\n%s\nwant:\n%s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		step  time.Duration
+		rows  int
+		files int
+	}{
+		{"no limit", Options{}, time.Second, 10, 1},
+		{"by duration", Options{RotateEvery: 5 * time.Second}, time.Second, 10, 2},
+		{"by size", Options{MaxSize: 100}, time.Second, 10, 3}, // 表头 7 字节, 每行 26 字节
+		{"same second", Options{RotateEvery: time.Millisecond}, time.Millisecond, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Dir = t.TempDir()
+			r, err := New(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			for i := 0; i < tt.rows; i++ {
+				if err := r.Record(start.Add(time.Duration(i)*tt.step), []Point{{"p", float64(i)}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files := readFiles(t, tt.opts.Dir)
+			if len(files) != tt.files {
+				t.Errorf("%d files, want %d", len(files), tt.files)
+			}
+			rows := 0
+			for _, content := range files {
+				lines := strings.Split(strings.TrimSpace(content), "\n")
+				if lines[0] != "time,p" {
+					t.Errorf("header = %q", lines[0])
+				}
+				rows += len(lines) - 1
+			}
+			if rows != tt.rows {
+				t.Errorf("%d rows in all files, want %d", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "2024-03-01 12:30:45.250"},
+		{TimeUnix, "1709296245.25"},
+		{TimeUnixMs, "1709296245250"},
+		{TimeRFC3339, "2024-03-01T12:30:45.250Z"},
+		{TimeExcel, "45352.521357"},
+		{"15:04", "12:30"},
+	}
+	for _, tt := range tests {
+		r, err := New(Options{Dir: t.TempDir(), TimeFormat: tt.format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := r.formatTime(start)
+		if tt.format == TimeExcel && len(got) > len(tt.want) {
+			got = got[:len(tt.want)]
+		}
+		if got != tt.want {
+			t.Errorf("format %q: %s, want %s", tt.format, got, tt.want)
+		}
+	}
+}
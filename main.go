@@ -4,9 +4,11 @@ package main
 
 import (
 	"log"
+	"modbusbaby/internal/cli"
 	"modbusbaby/internal/config"
 	"modbusbaby/internal/gui"
 	"modbusbaby/internal/logger"
+	"os"
 )
 
 var (
@@ -18,6 +20,15 @@ func main() {
 	// 初始化日志系统
 	logger.Init()
 
+	// 带参数时以命令行模式运行
+	if len(os.Args) > 1 {
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = config.Default()
+		}
+		os.Exit(cli.Run(os.Args[1:], cfg))
+	}
+
 	log.Printf("ModbusBaby v%s - by %s", version, author)
 	log.Println("Starting ModbusBaby Go Edition (Perfect Layout)...")
 
@@ -0,0 +1,269 @@
+package datatypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BitField 状态字中的一个位或多位字段
+type BitField struct {
+	Name   string            `json:"name"`
+	Bit    int               `json:"bit"`              // 起始位, 0 为最低位
+	Width  int               `json:"width,omitempty"`  // 位宽, 默认为1
+	Values map[uint32]string `json:"values,omitempty"` // 字段值到标签的映射, 单个位默认为 OFF/ON
+}
+
+// width 返回字段位宽
+func (f BitField) width() int {
+	if f.Width <= 0 {
+		return 1
+	}
+	return f.Width
+}
+
+// mask 返回字段在状态字中的掩码
+func (f BitField) mask() uint32 {
+	return (uint32(1)<<uint(f.width()) - 1) << uint(f.Bit)
+}
+
+// label 返回字段值对应的标签
+func (f BitField) label(value uint32) string {
+	if text, ok := f.Values[value]; ok {
+		return text
+	}
+	if f.width() == 1 && len(f.Values) == 0 {
+		if value != 0 {
+			return "ON"
+		}
+		return "OFF"
+	}
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+// BitFieldDef 状态寄存器的位域/枚举定义
+type BitFieldDef struct {
+	Name      string     `json:"name"`
+	Registers int        `json:"registers,omitempty"` // 1 (16位) 或 2 (32位), 默认为1
+	Fields    []BitField `json:"fields"`
+}
+
+// FieldState 解码后的字段状态
+type FieldState struct {
+	Name  string
+	Value uint32
+	Label string
+}
+
+// String 返回 "名称=标签" 形式
+func (s FieldState) String() string {
+	return s.Name + "=" + s.Label
+}
+
+// NewEnumDef 创建整个寄存器作为一个枚举值的定义
+func NewEnumDef(name string, labels map[uint32]string) *BitFieldDef {
+	return &BitFieldDef{
+		Name:   name,
+		Fields: []BitField{{Name: name, Bit: 0, Width: 16, Values: labels}},
+	}
+}
+
+// LoadBitFieldDefs 从JSON文件加载位域定义列表
+func LoadBitFieldDefs(path string) ([]BitFieldDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []BitFieldDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		// 也接受单个定义
+		var def BitFieldDef
+		if err2 := json.Unmarshal(data, &def); err2 != nil {
+			return nil, err
+		}
+		defs = []BitFieldDef{def}
+	}
+	for i := range defs {
+		if err := defs[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return defs, nil
+}
+
+// RegisterCount 返回定义占用的寄存器数量
+func (d *BitFieldDef) RegisterCount() int {
+	if d.Registers == 2 {
+		return 2
+	}
+	return 1
+}
+
+// Validate 检查字段是否越界或重叠, 字段名 (不区分大小写) 和同一字段内的标签是否重复
+func (d *BitFieldDef) Validate() error {
+	if d.Registers != 0 && d.Registers != 1 && d.Registers != 2 {
+		return fmt.Errorf("bitfield %s: registers must be 1 or 2", d.Name)
+	}
+	bits := d.RegisterCount() * 16
+	var used uint32
+	names := make(map[string]bool)
+	for _, f := range d.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("bitfield %s: field at bit %d has no name", d.Name, f.Bit)
+		}
+		// Compose 按名称查找字段时不区分大小写
+		key := strings.ToLower(f.Name)
+		if names[key] {
+			return fmt.Errorf("bitfield %s: duplicate field %s", d.Name, f.Name)
+		}
+		names[key] = true
+		if f.Bit < 0 || f.Bit+f.width() > bits {
+			return fmt.Errorf("bitfield %s: field %s exceeds %d bits", d.Name, f.Name, bits)
+		}
+		if err := f.validateValues(); err != nil {
+			return fmt.Errorf("bitfield %s: field %s: %w", d.Name, f.Name, err)
+		}
+		if used&f.mask() != 0 {
+			return fmt.Errorf("bitfield %s: field %s overlaps another field", d.Name, f.Name)
+		}
+		used |= f.mask()
+	}
+	return nil
+}
+
+// validateValues 检查标签对应的值不超出位宽, 标签 (不区分大小写) 不重复, 否则写入时无法确定标签对应的值
+func (f BitField) validateValues() error {
+	limit := f.mask() >> uint(f.Bit)
+	labels := make(map[string]uint32)
+	for _, v := range f.valueKeys() {
+		if v > limit {
+			return fmt.Errorf("value %d exceeds %d bits", v, f.width())
+		}
+		key := strings.ToLower(f.Values[v])
+		if other, ok := labels[key]; ok {
+			return fmt.Errorf("label %q used for both %d and %d", f.Values[v], other, v)
+		}
+		labels[key] = v
+	}
+	return nil
+}
+
+// valueKeys 返回按大小排序的字段值, 使标签查找的结果确定
+func (f BitField) valueKeys() []uint32 {
+	keys := make([]uint32, 0, len(f.Values))
+	for v := range f.Values {
+		keys = append(keys, v)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Decode 将状态字解码为各字段状态, 按起始位排序
+func (d *BitFieldDef) Decode(value uint32) []FieldState {
+	fields := append([]BitField(nil), d.Fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Bit < fields[j].Bit })
+
+	states := make([]FieldState, 0, len(fields))
+	for _, f := range fields {
+		v := (value & f.mask()) >> uint(f.Bit)
+		states = append(states, FieldState{Name: f.Name, Value: v, Label: f.label(v)})
+	}
+	return states
+}
+
+// ActiveLabels 返回置位的单个位字段和非零多位字段的标签, 适合作为摘要显示
+func (d *BitFieldDef) ActiveLabels(value uint32) []string {
+	var labels []string
+	for _, s := range d.Decode(value) {
+		if s.Value != 0 {
+			labels = append(labels, s.String())
+		}
+	}
+	return labels
+}
+
+// Compose 在 base 的基础上按 "字段名=标签或数值" 设置字段, 未提及的字段保持不变
+func (d *BitFieldDef) Compose(base uint32, assignments map[string]string) (uint32, error) {
+	value := base
+	for name, text := range assignments {
+		field, ok := d.field(name)
+		if !ok {
+			return 0, fmt.Errorf("bitfield %s: unknown field %s", d.Name, name)
+		}
+		v, err := field.parseValue(text)
+		if err != nil {
+			return 0, fmt.Errorf("bitfield %s: field %s: %w", d.Name, name, err)
+		}
+		value = value&^field.mask() | v<<uint(field.Bit)
+	}
+	return value, nil
+}
+
+// ComposeString 解析 "Run=ON, Mode=Auto" 形式的字符串并组合状态字
+func (d *BitFieldDef) ComposeString(base uint32, text string) (uint32, error) {
+	assignments := make(map[string]string)
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return 0, fmt.Errorf("expected name=value, got %q", part)
+		}
+		assignments[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return d.Compose(base, assignments)
+}
+
+func (d *BitFieldDef) field(name string) (BitField, bool) {
+	for _, f := range d.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return BitField{}, false
+}
+
+// parseValue 将标签或数值解析为字段值
+func (f BitField) parseValue(text string) (uint32, error) {
+	for _, v := range f.valueKeys() {
+		if strings.EqualFold(f.Values[v], text) {
+			return v, nil
+		}
+	}
+	if f.width() == 1 {
+		switch strings.ToUpper(text) {
+		case "ON", "TRUE", "1":
+			return 1, nil
+		case "OFF", "FALSE", "0":
+			return 0, nil
+		}
+	}
+	v, err := strconv.ParseUint(text, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown label %q", text)
+	}
+	if uint32(v) > f.mask()>>uint(f.Bit) {
+		return 0, fmt.Errorf("value %d exceeds %d bits", v, f.width())
+	}
+	return uint32(v), nil
+}
+
+// DecodeBitField 按字节序/字序从寄存器读取状态字并解码
+func (c *Converter) DecodeBitField(registers []uint16, def *BitFieldDef) ([]FieldState, error) {
+	n := def.RegisterCount()
+	if len(registers) < n {
+		return nil, fmt.Errorf("bitfield %s needs %d registers, got %d", def.Name, n, len(registers))
+	}
+	return def.Decode(uint32(c.registersToUint64(registers[:n]))), nil
+}
+
+// BitFieldToRegisters 按字节序/字序将状态字转换为寄存器
+func (c *Converter) BitFieldToRegisters(value uint32, def *BitFieldDef) []uint16 {
+	return c.uint64ToRegisters(uint64(value), def.RegisterCount())
+}
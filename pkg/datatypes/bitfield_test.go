@@ -0,0 +1,139 @@
+package datatypes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// statusDef 16位状态字: Run 为单个位, Mode 为带标签的2位字段, Code 为无标签的4位字段
+func statusDef() *BitFieldDef {
+	return &BitFieldDef{
+		Name: "Status",
+		Fields: []BitField{
+			{Name: "Mode", Bit: 4, Width: 2, Values: map[uint32]string{0: "Off", 1: "Manual", 2: "Auto"}},
+			{Name: "Run", Bit: 0},
+			{Name: "Code", Bit: 8, Width: 4},
+		},
+	}
+}
+
+func TestBitFieldValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     BitFieldDef
+		wantErr string
+	}{
+		{"valid", *statusDef(), ""},
+		{"32-bit field", BitFieldDef{Name: "D", Registers: 2, Fields: []BitField{{Name: "All", Width: 32}}}, ""},
+		{"32-bit field in one register", BitFieldDef{Name: "D", Fields: []BitField{{Name: "All", Width: 32}}}, "exceeds 16 bits"},
+		{"past the last bit", BitFieldDef{Name: "D", Fields: []BitField{{Name: "A", Bit: 14, Width: 3}}}, "exceeds 16 bits"},
+		{"negative bit", BitFieldDef{Name: "D", Fields: []BitField{{Name: "A", Bit: -1}}}, "exceeds 16 bits"},
+		{"bad register count", BitFieldDef{Name: "D", Registers: 3}, "registers must be 1 or 2"},
+		{"overlap", BitFieldDef{Name: "D", Fields: []BitField{{Name: "A", Bit: 2, Width: 3}, {Name: "B", Bit: 4}}}, "overlaps"},
+		{"no name", BitFieldDef{Name: "D", Fields: []BitField{{Bit: 1}}}, "has no name"},
+		{"duplicate name", BitFieldDef{Name: "D", Fields: []BitField{{Name: "Run", Bit: 0}, {Name: "RUN", Bit: 1}}}, "duplicate field RUN"},
+		{"duplicate label", BitFieldDef{Name: "D", Fields: []BitField{
+			{Name: "Mode", Width: 2, Values: map[uint32]string{1: "Auto", 2: "auto"}}}}, "used for both 1 and 2"},
+		{"label value too wide", BitFieldDef{Name: "D", Fields: []BitField{
+			{Name: "Mode", Width: 2, Values: map[uint32]string{4: "Auto"}}}}, "value 4 exceeds 2 bits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.def.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBitFieldDecode(t *testing.T) {
+	def := statusDef()
+	states := def.Decode(0x0721)
+	want := []FieldState{
+		{Name: "Run", Value: 1, Label: "ON"},
+		{Name: "Mode", Value: 2, Label: "Auto"},
+		{Name: "Code", Value: 7, Label: "7"},
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("Decode = %+v, want %+v", states, want)
+	}
+	if got := def.ActiveLabels(0x0030); !reflect.DeepEqual(got, []string{"Mode=3"}) {
+		t.Errorf("ActiveLabels = %q", got)
+	}
+
+	wide := &BitFieldDef{Name: "D", Registers: 2, Fields: []BitField{{Name: "All", Width: 32}}}
+	if states := wide.Decode(0xFFFFFFFF); states[0].Value != 0xFFFFFFFF {
+		t.Errorf("32-bit Decode = %+v", states)
+	}
+}
+
+func TestBitFieldCompose(t *testing.T) {
+	def := statusDef()
+	tests := []struct {
+		name    string
+		base    uint32
+		text    string
+		want    uint32
+		wantErr string
+	}{
+		{"labels", 0x0000, "Run=ON, Mode=Auto", 0x0021, ""},
+		{"keeps other bits", 0xF0C0, "run=1", 0xF0C1, ""},
+		{"case-insensitive label", 0x0000, "mode=manual", 0x0010, ""},
+		{"number", 0x0000, "Code=0xF", 0x0F00, ""},
+		{"clear", 0x0F31, "Code=0, Run=off", 0x0030, ""},
+		{"empty parts", 0x0001, " , Run=0,", 0x0000, ""},
+		{"out of range", 0, "Code=16", 0, "value 16 exceeds 4 bits"},
+		{"out of range for labelled field", 0, "Mode=4", 0, "value 4 exceeds 2 bits"},
+		{"unknown label", 0, "Mode=Remote", 0, `unknown label "Remote"`},
+		{"unknown field", 0, "Speed=1", 0, "unknown field Speed"},
+		{"missing value", 0, "Run", 0, "expected name=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := def.ComposeString(tt.base, tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ComposeString = 0x%04X, want 0x%04X", got, tt.want)
+			}
+		})
+	}
+
+	wide := &BitFieldDef{Name: "D", Registers: 2, Fields: []BitField{{Name: "High", Bit: 16, Width: 16}}}
+	got, err := wide.Compose(0x1234, map[string]string{"High": "0xFFFF"})
+	if err != nil || got != 0xFFFF1234 {
+		t.Errorf("32-bit Compose = 0x%08X, %v", got, err)
+	}
+}
+
+// TestBitFieldRegisters 32位状态字按字序分为两个寄存器
+func TestBitFieldRegisters(t *testing.T) {
+	def := &BitFieldDef{Name: "D", Registers: 2, Fields: []BitField{{Name: "Low", Width: 16}, {Name: "High", Bit: 16, Width: 16}}}
+	c := NewConverter(AB, WORD_4321)
+	registers := c.BitFieldToRegisters(0x12345678, def)
+	if want := []uint16{0x5678, 0x1234}; !reflect.DeepEqual(registers, want) {
+		t.Errorf("registers = %04X, want %04X", registers, want)
+	}
+	states, err := c.DecodeBitField(registers, def)
+	if err != nil || states[0].Value != 0x5678 || states[1].Value != 0x1234 {
+		t.Errorf("DecodeBitField = %+v, %v", states, err)
+	}
+	if _, err := c.DecodeBitField(registers[:1], def); err == nil {
+		t.Error("DecodeBitField with one register succeeded, want an error")
+	}
+}
@@ -0,0 +1,156 @@
+package datatypes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 字节/字序组合说明
+//
+// ByteOrder 决定每个16位寄存器内两个字节的顺序, WordOrder 决定多寄存器数值中寄存器的排列顺序。
+// 两者组合覆盖常见的全部排列 (A 为最高字节):
+//
+//	32位: ABCD = AB+1234, BADC = BA+1234, CDAB = AB+4321, DCBA = BA+4321
+//	64位: ABCDEFGH = AB+1234, BADCFEHG = BA+1234, GHEFCDAB = AB+4321, HGFEDCBA = BA+4321,
+//	      CDABGHEF = AB+2143, DCBAHGFE = BA+2143, EFGHABCD = AB+3412, FEHGBADC = BA+3412
+//
+// 32位数值只有两个寄存器, 1234/3412 按原顺序, 4321/2143 交换两个寄存器。
+// 48位数值 (3个寄存器): ABCDEF = 1234/3412, EFCDAB = 4321, CDABEF = 2143 (交换前两个寄存器)。
+
+// ParseByteOrder 解析字节序名称
+func ParseByteOrder(s string) (ByteOrder, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "AB":
+		return AB, nil
+	case "BA":
+		return BA, nil
+	default:
+		return AB, fmt.Errorf("unknown byte order: %s", s)
+	}
+}
+
+// ParseWordOrder 解析字序名称
+func ParseWordOrder(s string) (WordOrder, error) {
+	switch strings.TrimSpace(s) {
+	case "1234":
+		return WORD_1234, nil
+	case "4321":
+		return WORD_4321, nil
+	case "2143":
+		return WORD_2143, nil
+	case "3412":
+		return WORD_3412, nil
+	default:
+		return WORD_1234, fmt.Errorf("unknown word order: %s", s)
+	}
+}
+
+// AllWordOrders 返回所有支持的字序
+func AllWordOrders() []WordOrder {
+	return []WordOrder{WORD_1234, WORD_4321, WORD_2143, WORD_3412}
+}
+
+// orderPresets 排列名称与字节序/字序组合的对应关系
+var orderPresets = map[string]struct {
+	byteOrder ByteOrder
+	wordOrder WordOrder
+}{
+	"AB":       {AB, WORD_1234},
+	"BA":       {BA, WORD_1234},
+	"ABCD":     {AB, WORD_1234},
+	"BADC":     {BA, WORD_1234},
+	"CDAB":     {AB, WORD_4321},
+	"DCBA":     {BA, WORD_4321},
+	"ABCDEFGH": {AB, WORD_1234},
+	"BADCFEHG": {BA, WORD_1234},
+	"GHEFCDAB": {AB, WORD_4321},
+	"HGFEDCBA": {BA, WORD_4321},
+	"CDABGHEF": {AB, WORD_2143},
+	"DCBAHGFE": {BA, WORD_2143},
+	"EFGHABCD": {AB, WORD_3412},
+	"FEHGBADC": {BA, WORD_3412},
+}
+
+// ParseOrderPreset 解析 ABCD/CDAB/GHEFCDAB 等排列名称
+func ParseOrderPreset(name string) (ByteOrder, WordOrder, error) {
+	preset, ok := orderPresets[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return AB, WORD_1234, fmt.Errorf("unknown byte order preset: %s", name)
+	}
+	return preset.byteOrder, preset.wordOrder, nil
+}
+
+// OrderPresetName 返回字节序/字序组合在指定寄存器数量下的排列名称, 如 CDAB
+func OrderPresetName(byteOrder ByteOrder, wordOrder WordOrder, registers int) string {
+	letters := "ABCDEFGH"
+	perm := wordPermutation(wordOrder, registers)
+	var sb strings.Builder
+	for _, word := range perm {
+		hi, lo := letters[word*2], letters[word*2+1]
+		if byteOrder == BA {
+			hi, lo = lo, hi
+		}
+		sb.WriteByte(hi)
+		sb.WriteByte(lo)
+	}
+	return sb.String()
+}
+
+// wordPermutation 返回寄存器排列: 第i个寄存器存放的是第perm[i]个字 (0为最高位字)
+func wordPermutation(wordOrder WordOrder, n int) []int {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	switch wordOrder {
+	case WORD_4321:
+		for i := range perm {
+			perm[i] = n - 1 - i
+		}
+	case WORD_2143:
+		// 每两个寄存器内部交换, 奇数个寄存器时最后一个保持不变
+		for i := 0; i+1 < n; i += 2 {
+			perm[i], perm[i+1] = i+1, i
+		}
+	case WORD_3412:
+		// 高低两半整体交换, 两半内部保持顺序
+		if n%2 == 0 && n > 2 {
+			half := n / 2
+			for i := range perm {
+				perm[i] = (i + half) % n
+			}
+		}
+	}
+	return perm
+}
+
+// swapBytes 按字节序调整单个寄存器
+func (c *Converter) swapBytes(reg uint16) uint16 {
+	if c.byteOrder == BA {
+		return reg<<8 | reg>>8
+	}
+	return reg
+}
+
+// registersToUint64 将n个寄存器 (按线上顺序) 按字节序和字序组合为整数
+func (c *Converter) registersToUint64(registers []uint16) uint64 {
+	n := len(registers)
+	perm := wordPermutation(c.wordOrder, n)
+	var value uint64
+	for i, reg := range registers {
+		shift := uint(16 * (n - 1 - perm[i]))
+		value |= uint64(c.swapBytes(reg)) << shift
+	}
+	return value
+}
+
+// uint64ToRegisters 将整数的低 n*16 位按字节序和字序拆分为n个寄存器
+func (c *Converter) uint64ToRegisters(value uint64, n int) []uint16 {
+	perm := wordPermutation(c.wordOrder, n)
+	registers := make([]uint16, n)
+	for i := range registers {
+		shift := uint(16 * (n - 1 - perm[i]))
+		registers[i] = c.swapBytes(uint16(value >> shift))
+	}
+	return registers
+}
@@ -0,0 +1,131 @@
+package datatypes
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// 各字节序/字序组合下寄存器中的字节排列, A 为数值的最高字节。与 byteorder.go 开头的说明一致
+var wireLayouts = []struct {
+	byteOrder ByteOrder
+	wordOrder WordOrder
+	layout    map[int]string // 寄存器数 -> 排列
+}{
+	{AB, WORD_1234, map[int]string{1: "AB", 2: "ABCD", 3: "ABCDEF", 4: "ABCDEFGH"}},
+	{BA, WORD_1234, map[int]string{1: "BA", 2: "BADC", 3: "BADCFE", 4: "BADCFEHG"}},
+	{AB, WORD_4321, map[int]string{1: "AB", 2: "CDAB", 3: "EFCDAB", 4: "GHEFCDAB"}},
+	{BA, WORD_4321, map[int]string{1: "BA", 2: "DCBA", 3: "FEDCBA", 4: "HGFEDCBA"}},
+	{AB, WORD_2143, map[int]string{1: "AB", 2: "CDAB", 3: "CDABEF", 4: "CDABGHEF"}},
+	{BA, WORD_2143, map[int]string{1: "BA", 2: "DCBA", 3: "DCBAFE", 4: "DCBAHGFE"}},
+	{AB, WORD_3412, map[int]string{1: "AB", 2: "ABCD", 3: "ABCDEF", 4: "EFGHABCD"}},
+	{BA, WORD_3412, map[int]string{1: "BA", 2: "BADC", 3: "BADCFE", 4: "FEHGBADC"}},
+}
+
+// layoutRegisters 按排列将数值的大端字节 (bigEndian) 放入寄存器
+func layoutRegisters(bigEndian []byte, layout string) []uint16 {
+	registers := make([]uint16, len(layout)/2)
+	for i := range registers {
+		hi, lo := bigEndian[layout[2*i]-'A'], bigEndian[layout[2*i+1]-'A']
+		registers[i] = uint16(hi)<<8 | uint16(lo)
+	}
+	return registers
+}
+
+// bigEndianBytes 返回 v 的低 n 个字节, 高字节在前
+func bigEndianBytes(v uint64, n int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b[8-n:]
+}
+
+func TestByteWordOrderRoundTrip(t *testing.T) {
+	tests := []struct {
+		dataType DataType
+		bits     uint64 // 数值的二进制表示, 每个字节都不同以便区分排列
+		value    interface{}
+	}{
+		{INT16, 0x8182, []int16{-32382}},
+		{UINT16, 0x0102, []uint16{0x0102}},
+		{INT32, 0x81828384, []int32{-2122153084}},
+		{UINT32, 0x01020304, []uint32{0x01020304}},
+		{FLOAT32, uint64(math.Float32bits(-1.5e-3)), []float32{-1.5e-3}},
+		{INT48, 0x818283848586, []int64{0x818283848586 - 1<<48}},
+		{UINT48, 0x010203040506, []uint64{0x010203040506}},
+		{INT64, 0x8182838485868788, []int64{-0x7E7D7C7B7A797878}},
+		{UINT64, 0x0102030405060708, []uint64{0x0102030405060708}},
+		{FLOAT64, math.Float64bits(math.Pi * -1e100), []float64{math.Pi * -1e100}},
+	}
+
+	for _, order := range wireLayouts {
+		for _, tt := range tests {
+			n := tt.dataType.RegistersPerValue()
+			layout := order.layout[n]
+			t.Run(fmt.Sprintf("%s/%s%s", tt.dataType, order.byteOrder, order.wordOrder), func(t *testing.T) {
+				c := NewConverter(order.byteOrder, order.wordOrder)
+				want := layoutRegisters(bigEndianBytes(tt.bits, 2*n), layout)
+
+				got, err := c.ConvertToRegistersAs(tt.value, tt.dataType)
+				if err != nil {
+					t.Fatalf("encode: %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("encode = %04X, want %04X (%s)", got, want, layout)
+				}
+
+				decoded, err := c.ConvertFromRegisters(want, tt.dataType)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if !reflect.DeepEqual(decoded, tt.value) {
+					t.Errorf("decode %04X = %v, want %v", want, decoded, tt.value)
+				}
+			})
+		}
+	}
+}
+
+// TestWordOrderMultipleValues 多个值时字序只在每个值内部起作用
+func TestWordOrderMultipleValues(t *testing.T) {
+	c := NewConverter(BA, WORD_4321)
+	values := []uint32{0x01020304, 0x05060708}
+	registers, err := c.ConvertToRegistersAs(values, UINT32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{0x0403, 0x0201, 0x0807, 0x0605}; !reflect.DeepEqual(registers, want) {
+		t.Errorf("encode = %04X, want %04X", registers, want)
+	}
+	decoded, err := c.ConvertFromRegisters(registers, UINT32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, values) {
+		t.Errorf("decode = %X, want %X", decoded, values)
+	}
+}
+
+func TestOrderPresets(t *testing.T) {
+	for _, order := range wireLayouts {
+		for n, layout := range order.layout {
+			if n != 2 && n != 4 {
+				continue
+			}
+			if got := OrderPresetName(order.byteOrder, order.wordOrder, n); got != layout {
+				t.Errorf("OrderPresetName(%s, %s, %d) = %s, want %s", order.byteOrder, order.wordOrder, n, got, layout)
+			}
+			if n != 4 {
+				continue
+			}
+			byteOrder, wordOrder, err := ParseOrderPreset(layout)
+			if err != nil || byteOrder != order.byteOrder || wordOrder != order.wordOrder {
+				t.Errorf("ParseOrderPreset(%s) = %s, %s, %v", layout, byteOrder, wordOrder, err)
+			}
+		}
+	}
+	if _, _, err := ParseOrderPreset("ABDC"); err == nil {
+		t.Error("ParseOrderPreset(ABDC) succeeded, want an error")
+	}
+}
@@ -3,7 +3,6 @@ package datatypes
 import (
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -24,8 +23,53 @@ const (
 	BOOL
 	ASCII
 	UNIX_TIMESTAMP
+	INT8_HIGH         // 寄存器高字节, 有符号
+	INT8_LOW          // 寄存器低字节, 有符号
+	UINT8_HIGH        // 寄存器高字节, 无符号
+	UINT8_LOW         // 寄存器低字节, 无符号
+	FLOAT16           // IEEE 754 半精度浮点
+	BCD16             // 4位压缩BCD (0-9999)
+	BCD32             // 8位压缩BCD (0-99999999)
+	INT48             // 48位有符号整数 (3个寄存器)
+	UINT48            // 48位无符号整数, 常见于电能计数器
+	SM_INT16          // 16位原码整数 (最高位为符号位)
+	SM_INT32          // 32位原码整数 (最高位为符号位)
+	UNIX_TIMESTAMP64  // 64位纪元秒数
+	UNIX_TIMESTAMP_MS // 64位纪元毫秒数
+	BCD_DATETIME      // BCD日期时间 YYMMDDhhmmss (3个寄存器)
+	DATETIME_REGS     // 年月日时分秒各占一个寄存器 (6个寄存器)
+	CP56TIME2A        // IEC 60870-5 CP56Time2a (7字节, 4个寄存器)
+	STRING_UTF8       // UTF-8 字符串
+	STRING_UTF16BE    // UTF-16 大端字符串
+	STRING_UTF16LE    // UTF-16 小端字符串
+	STRING_GBK        // GBK/GB2312 字符串
+	STRING_SHIFT_JIS  // Shift-JIS 字符串
 )
 
+// AllDataTypes 返回所有支持的数据类型, 顺序与界面列表一致
+func AllDataTypes() []DataType {
+	return []DataType{
+		BYTE, INT8_HIGH, INT8_LOW, UINT8_HIGH, UINT8_LOW,
+		INT16, UINT16, SM_INT16, INT32, UINT32, SM_INT32,
+		INT48, UINT48, INT64, UINT64,
+		FLOAT16, FLOAT32, FLOAT64, BCD16, BCD32,
+		BOOL, ASCII, UNIX_TIMESTAMP, UNIX_TIMESTAMP64, UNIX_TIMESTAMP_MS,
+		BCD_DATETIME, DATETIME_REGS, CP56TIME2A,
+		STRING_UTF8, STRING_UTF16BE, STRING_UTF16LE, STRING_GBK, STRING_SHIFT_JIS,
+	}
+}
+
+// ParseDataType 根据名称解析数据类型
+func ParseDataType(s string) (DataType, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	for _, dt := range AllDataTypes() {
+		if dt.String() == name {
+			return dt, nil
+		}
+	}
+	return UINT16, fmt.Errorf("unknown data type: %s", s)
+}
+
 // String 返回数据类型的字符串表示
 func (dt DataType) String() string {
 	switch dt {
@@ -53,6 +97,48 @@ func (dt DataType) String() string {
 		return "ASCII"
 	case UNIX_TIMESTAMP:
 		return "UNIX_TIMESTAMP"
+	case INT8_HIGH:
+		return "INT8_H"
+	case INT8_LOW:
+		return "INT8_L"
+	case UINT8_HIGH:
+		return "UINT8_H"
+	case UINT8_LOW:
+		return "UINT8_L"
+	case FLOAT16:
+		return "FLOAT16"
+	case BCD16:
+		return "BCD16"
+	case BCD32:
+		return "BCD32"
+	case INT48:
+		return "INT48"
+	case UINT48:
+		return "UINT48"
+	case SM_INT16:
+		return "SM_INT16"
+	case SM_INT32:
+		return "SM_INT32"
+	case UNIX_TIMESTAMP64:
+		return "UNIX_TIMESTAMP64"
+	case UNIX_TIMESTAMP_MS:
+		return "UNIX_TIMESTAMP_MS"
+	case BCD_DATETIME:
+		return "BCD_DATETIME"
+	case DATETIME_REGS:
+		return "DATETIME_REGS"
+	case CP56TIME2A:
+		return "CP56TIME2A"
+	case STRING_UTF8:
+		return "UTF8"
+	case STRING_UTF16BE:
+		return "UTF16BE"
+	case STRING_UTF16LE:
+		return "UTF16LE"
+	case STRING_GBK:
+		return "GBK"
+	case STRING_SHIFT_JIS:
+		return "SHIFT_JIS"
 	default:
 		return "UNKNOWN"
 	}
@@ -83,6 +169,8 @@ type WordOrder int
 const (
 	WORD_1234 WordOrder = iota // Big Endian
 	WORD_4321                  // Little Endian
+	WORD_2143                  // 每两个寄存器交换 (64位 CDABGHEF)
+	WORD_3412                  // 高低32位交换 (64位 EFGHABCD)
 )
 
 func (wo WordOrder) String() string {
@@ -91,6 +179,10 @@ func (wo WordOrder) String() string {
 		return "1234"
 	case WORD_4321:
 		return "4321"
+	case WORD_2143:
+		return "2143"
+	case WORD_3412:
+		return "3412"
 	default:
 		return "1234"
 	}
@@ -99,14 +191,18 @@ func (wo WordOrder) String() string {
 // RegistersPerValue 返回每个值需要的寄存器数量
 func (dt DataType) RegistersPerValue() int {
 	switch dt {
-	case BYTE, INT16, UINT16, BOOL:
+	case BYTE, INT16, UINT16, BOOL, INT8_HIGH, INT8_LOW, UINT8_HIGH, UINT8_LOW, FLOAT16, BCD16, SM_INT16:
 		return 1
-	case INT32, UINT32, FLOAT32, UNIX_TIMESTAMP:
+	case INT32, UINT32, FLOAT32, UNIX_TIMESTAMP, BCD32, SM_INT32:
 		return 2
-	case INT64, UINT64, FLOAT64:
+	case INT48, UINT48, BCD_DATETIME:
+		return 3
+	case INT64, UINT64, FLOAT64, UNIX_TIMESTAMP64, UNIX_TIMESTAMP_MS, CP56TIME2A:
 		return 4
-	case ASCII:
-		return 1 // 每个寄存器2个字符
+	case DATETIME_REGS:
+		return 6
+	case ASCII, STRING_UTF8, STRING_UTF16BE, STRING_UTF16LE, STRING_GBK, STRING_SHIFT_JIS:
+		return 1 // 每个寄存器2个字节
 	default:
 		return 1
 	}
@@ -116,6 +212,9 @@ func (dt DataType) RegistersPerValue() int {
 type Converter struct {
 	byteOrder ByteOrder
 	wordOrder WordOrder
+	location  *time.Location // 时间类型使用的时区
+
+	stringOptions StringOptions // 字符串类型的字段设置
 }
 
 // NewConverter 创建新的数据转换器
@@ -123,6 +222,7 @@ func NewConverter(byteOrder ByteOrder, wordOrder WordOrder) *Converter {
 	return &Converter{
 		byteOrder: byteOrder,
 		wordOrder: wordOrder,
+		location:  time.Local,
 	}
 }
 
@@ -155,8 +255,28 @@ func (c *Converter) ConvertFromRegisters(registers []uint16, dataType DataType)
 		return c.convertToBoolArray(registers), nil
 	case ASCII:
 		return c.convertToASCII(registers), nil
-	case UNIX_TIMESTAMP:
-		return c.convertToTimestamp(registers), nil
+	case UNIX_TIMESTAMP, UNIX_TIMESTAMP64, UNIX_TIMESTAMP_MS, BCD_DATETIME, DATETIME_REGS, CP56TIME2A:
+		return c.convertToTimes(registers, dataType)
+	case STRING_UTF8, STRING_UTF16BE, STRING_UTF16LE, STRING_GBK, STRING_SHIFT_JIS:
+		return c.convertToStrings(registers, dataType)
+	case INT8_HIGH, INT8_LOW:
+		return c.convertToInt8Array(registers, dataType == INT8_HIGH), nil
+	case UINT8_HIGH, UINT8_LOW:
+		return c.convertToUint8Array(registers, dataType == UINT8_HIGH), nil
+	case FLOAT16:
+		return c.convertToFloat16Array(registers), nil
+	case BCD16:
+		return c.convertToBCD16Array(registers)
+	case BCD32:
+		return c.convertToBCD32Array(registers)
+	case INT48:
+		return c.convertToInt48Array(registers), nil
+	case UINT48:
+		return c.convertToUint48Array(registers), nil
+	case SM_INT16:
+		return c.convertToSignMagnitude16Array(registers), nil
+	case SM_INT32:
+		return c.convertToSignMagnitude32Array(registers), nil
 	default:
 		return registers, nil
 	}
@@ -168,10 +288,12 @@ func (c *Converter) ConvertToRegisters(value interface{}) ([]uint16, error) {
 	switch v := value.(type) {
 	case []int16:
 		for _, val := range v {
-			registers = append(registers, uint16(val))
+			registers = append(registers, c.swapBytes(uint16(val)))
 		}
 	case []uint16:
-		registers = v
+		for _, val := range v {
+			registers = append(registers, c.swapBytes(val))
+		}
 	case []int32:
 		for _, val := range v {
 			registers = append(registers, c.int32ToRegisters(val)...)
@@ -186,7 +308,7 @@ func (c *Converter) ConvertToRegisters(value interface{}) ([]uint16, error) {
 		}
 	case []uint64:
 		for _, val := range v {
-			registers = append(registers, c.uint64ToRegisters(val)...)
+			registers = append(registers, c.uint64ToRegisters(val, 4)...)
 		}
 	case []float32:
 		for _, val := range v {
@@ -204,106 +326,147 @@ func (c *Converter) ConvertToRegisters(value interface{}) ([]uint16, error) {
 	return registers, nil
 }
 
-// ParseStringToType parses a comma-separated string of values into a slice of the specified data type.
-func ParseStringToType(valueStr string, dataType DataType) (interface{}, error) {
-	parts := strings.Split(valueStr, ",")
-	if len(parts) == 0 {
-		return nil, fmt.Errorf("value string is empty")
+// ConvertToRegistersAs 按指定数据类型将值转换为寄存器数据。
+// 同一种Go类型可能对应多种寄存器编码 (如 []uint16 既可以是 UINT16 也可以是 BCD16), 写入时应使用此方法。
+func (c *Converter) ConvertToRegistersAs(value interface{}, dataType DataType) ([]uint16, error) {
+	switch dataType {
+	case INT8_HIGH, INT8_LOW, UINT8_HIGH, UINT8_LOW, FLOAT16, BCD16, BCD32, INT48, UINT48, SM_INT16, SM_INT32:
+		return c.convertExtendedToRegisters(value, dataType)
+	case UNIX_TIMESTAMP, UNIX_TIMESTAMP64, UNIX_TIMESTAMP_MS, BCD_DATETIME, DATETIME_REGS, CP56TIME2A:
+		return c.timesToRegisters(value, dataType)
+	case STRING_UTF8, STRING_UTF16BE, STRING_UTF16LE, STRING_GBK, STRING_SHIFT_JIS:
+		return c.stringsToRegisters(value, dataType)
+	default:
+		return c.ConvertToRegisters(value)
 	}
+}
 
+// ParseStringToType parses a list of values into a slice of the specified data type.
+// 输入语法见 input.go; 解析失败时返回 *ParseError, 其中包含出错元素的位置。
+func ParseStringToType(valueStr string, dataType DataType) (interface{}, error) {
 	switch dataType {
-	case INT16:
-		var values []int16
-		for _, p := range parts {
-			val, err := strconv.ParseInt(strings.TrimSpace(p), 10, 16)
-			if err != nil {
-				return nil, fmt.Errorf("invalid INT16 value: %s", p)
-			}
-			values = append(values, int16(val))
+	case INT16, SM_INT16:
+		min := int64(math.MinInt16)
+		if dataType == SM_INT16 {
+			min = -math.MaxInt16
 		}
-		return values, nil
-	case UINT16:
-		var values []uint16
-		for _, p := range parts {
-			val, err := strconv.ParseUint(strings.TrimSpace(p), 10, 16)
-			if err != nil {
-				return nil, fmt.Errorf("invalid UINT16 value: %s", p)
-			}
-			values = append(values, uint16(val))
+		parsed, err := parseSignedElements(valueStr, dataType, min, math.MaxInt16)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int16, len(parsed))
+		for i, v := range parsed {
+			values[i] = int16(v)
 		}
 		return values, nil
-	case INT32:
-		var values []int32
-		for _, p := range parts {
-			val, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
-			if err != nil {
-				return nil, fmt.Errorf("invalid INT32 value: %s", p)
-			}
-			values = append(values, int32(val))
+	case UINT16, BCD16:
+		max := uint64(math.MaxUint16)
+		if dataType == BCD16 {
+			max = 9999
+		}
+		parsed, err := parseUnsignedElements(valueStr, dataType, max)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]uint16, len(parsed))
+		for i, v := range parsed {
+			values[i] = uint16(v)
 		}
 		return values, nil
-	case UINT32:
-		var values []uint32
-		for _, p := range parts {
-			val, err := strconv.ParseUint(strings.TrimSpace(p), 10, 32)
-			if err != nil {
-				return nil, fmt.Errorf("invalid UINT32 value: %s", p)
-			}
-			values = append(values, uint32(val))
+	case INT32, SM_INT32:
+		min := int64(math.MinInt32)
+		if dataType == SM_INT32 {
+			min = -math.MaxInt32
+		}
+		parsed, err := parseSignedElements(valueStr, dataType, min, math.MaxInt32)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int32, len(parsed))
+		for i, v := range parsed {
+			values[i] = int32(v)
 		}
 		return values, nil
-	case INT64:
-		var values []int64
-		for _, p := range parts {
-			val, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("invalid INT64 value: %s", p)
-			}
-			values = append(values, val)
+	case UINT32, BCD32:
+		max := uint64(math.MaxUint32)
+		if dataType == BCD32 {
+			max = 99999999
+		}
+		parsed, err := parseUnsignedElements(valueStr, dataType, max)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]uint32, len(parsed))
+		for i, v := range parsed {
+			values[i] = uint32(v)
 		}
 		return values, nil
+	case INT64:
+		return parseSignedElements(valueStr, dataType, math.MinInt64, math.MaxInt64)
+	case INT48:
+		return parseSignedElements(valueStr, dataType, minInt48, maxInt48)
 	case UINT64:
-		var values []uint64
-		for _, p := range parts {
-			val, err := strconv.ParseUint(strings.TrimSpace(p), 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("invalid UINT64 value: %s", p)
-			}
-			values = append(values, val)
+		return parseUnsignedElements(valueStr, dataType, math.MaxUint64)
+	case UINT48:
+		return parseUnsignedElements(valueStr, dataType, maxUint48)
+	case INT8_HIGH, INT8_LOW:
+		parsed, err := parseSignedElements(valueStr, dataType, math.MinInt8, math.MaxInt8)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int8, len(parsed))
+		for i, v := range parsed {
+			values[i] = int8(v)
 		}
 		return values, nil
-	case FLOAT32:
-		var values []float32
-		for _, p := range parts {
-			val, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
-			if err != nil {
-				return nil, fmt.Errorf("invalid FLOAT32 value: %s", p)
-			}
-			values = append(values, float32(val))
+	case UINT8_HIGH, UINT8_LOW:
+		parsed, err := parseUnsignedElements(valueStr, dataType, math.MaxUint8)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]uint8, len(parsed))
+		for i, v := range parsed {
+			values[i] = uint8(v)
 		}
 		return values, nil
-	case FLOAT64:
-		var values []float64
-		for _, p := range parts {
-			val, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
-			if err != nil {
-				return nil, fmt.Errorf("invalid FLOAT64 value: %s", p)
-			}
-			values = append(values, val)
+	case FLOAT16, FLOAT32:
+		maxAbs := float64(math.MaxFloat32)
+		if dataType == FLOAT16 {
+			maxAbs = 65504
+		}
+		parsed, err := parseFloatElements(valueStr, dataType, 32, maxAbs)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]float32, len(parsed))
+		for i, v := range parsed {
+			values[i] = float32(v)
 		}
 		return values, nil
-	case ASCII:
-		return valueStr, nil // Keep as a single string
+	case FLOAT64:
+		return parseFloatElements(valueStr, dataType, 64, math.MaxFloat64)
 	case BOOL:
-		var values []bool
-		for _, p := range parts {
-			val, err := strconv.ParseBool(strings.TrimSpace(p))
-			if err != nil {
-				return nil, fmt.Errorf("invalid BOOL value: %s", p)
-			}
-			values = append(values, val)
+		return parseBoolElements(valueStr)
+	case ASCII:
+		// 以引号开头时按记号解析并拼接, 否则保持原样作为一个字符串
+		values, ok, err := parseTextElements(valueStr, dataType)
+		if err != nil || !ok {
+			return valueStr, err
+		}
+		return strings.Join(values, ""), nil
+	case STRING_UTF8, STRING_UTF16BE, STRING_UTF16LE, STRING_GBK, STRING_SHIFT_JIS:
+		// 以引号开头时每个记号一个字段, 否则每行一个字段, 与读取时的多字段显示对应
+		values, ok, err := parseTextElements(valueStr, dataType)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			values = strings.Split(strings.ReplaceAll(valueStr, "\r\n", "\n"), "\n")
 		}
 		return values, nil
+	case UNIX_TIMESTAMP, UNIX_TIMESTAMP64, UNIX_TIMESTAMP_MS, BCD_DATETIME, DATETIME_REGS, CP56TIME2A:
+		// 时间按转换器时区在写入时解析, 此处只做格式检查
+		return parseTimeElements(valueStr, dataType)
 	default:
 		return nil, fmt.Errorf("unsupported data type for string parsing: %s", dataType.String())
 	}
@@ -325,113 +488,65 @@ func (c *Converter) convertToBytes(registers []uint16) []byte {
 func (c *Converter) convertToInt16Array(registers []uint16) []int16 {
 	result := make([]int16, len(registers))
 	for i, reg := range registers {
-		result[i] = int16(reg)
+		result[i] = int16(c.swapBytes(reg))
 	}
 	return result
 }
 
 func (c *Converter) convertToUint16Array(registers []uint16) []uint16 {
-	return registers
+	result := make([]uint16, len(registers))
+	for i, reg := range registers {
+		result[i] = c.swapBytes(reg)
+	}
+	return result
 }
 
 func (c *Converter) convertToInt32Array(registers []uint16) []int32 {
 	var result []int32
-	for i := 0; i < len(registers); i += 2 {
-		if i+1 < len(registers) {
-			var val uint32
-			if c.wordOrder == WORD_1234 {
-				val = uint32(registers[i])<<16 | uint32(registers[i+1])
-			} else {
-				val = uint32(registers[i+1])<<16 | uint32(registers[i])
-			}
-			result = append(result, int32(val))
-		}
+	for i := 0; i+1 < len(registers); i += 2 {
+		result = append(result, int32(c.registersToUint64(registers[i:i+2])))
 	}
 	return result
 }
 
 func (c *Converter) convertToUint32Array(registers []uint16) []uint32 {
 	var result []uint32
-	for i := 0; i < len(registers); i += 2 {
-		if i+1 < len(registers) {
-			var val uint32
-			if c.wordOrder == WORD_1234 {
-				val = uint32(registers[i])<<16 | uint32(registers[i+1])
-			} else {
-				val = uint32(registers[i+1])<<16 | uint32(registers[i])
-			}
-			result = append(result, val)
-		}
+	for i := 0; i+1 < len(registers); i += 2 {
+		result = append(result, uint32(c.registersToUint64(registers[i:i+2])))
 	}
 	return result
 }
 
 func (c *Converter) convertToInt64Array(registers []uint16) []int64 {
 	var result []int64
-	for i := 0; i < len(registers); i += 4 {
-		if i+3 < len(registers) {
-			var val uint64
-			if c.wordOrder == WORD_1234 {
-				val = uint64(registers[i])<<48 | uint64(registers[i+1])<<32 |
-					uint64(registers[i+2])<<16 | uint64(registers[i+3])
-			} else {
-				val = uint64(registers[i+3])<<48 | uint64(registers[i+2])<<32 |
-					uint64(registers[i+1])<<16 | uint64(registers[i])
-			}
-			result = append(result, int64(val))
-		}
+	for i := 0; i+3 < len(registers); i += 4 {
+		result = append(result, int64(c.registersToUint64(registers[i:i+4])))
 	}
 	return result
 }
 
 func (c *Converter) convertToUint64Array(registers []uint16) []uint64 {
 	var result []uint64
-	for i := 0; i < len(registers); i += 4 {
-		if i+3 < len(registers) {
-			var val uint64
-			if c.wordOrder == WORD_1234 {
-				val = uint64(registers[i])<<48 | uint64(registers[i+1])<<32 |
-					uint64(registers[i+2])<<16 | uint64(registers[i+3])
-			} else {
-				val = uint64(registers[i+3])<<48 | uint64(registers[i+2])<<32 |
-					uint64(registers[i+1])<<16 | uint64(registers[i])
-			}
-			result = append(result, val)
-		}
+	for i := 0; i+3 < len(registers); i += 4 {
+		result = append(result, c.registersToUint64(registers[i:i+4]))
 	}
 	return result
 }
 
 func (c *Converter) convertToFloat32Array(registers []uint16) []float32 {
 	var result []float32
-	for i := 0; i < len(registers); i += 2 {
-		if i+1 < len(registers) {
-			var bits uint32
-			if c.wordOrder == WORD_1234 {
-				bits = uint32(registers[i])<<16 | uint32(registers[i+1])
-			} else {
-				bits = uint32(registers[i+1])<<16 | uint32(registers[i])
-			}
-			result = append(result, math.Float32frombits(bits))
-		}
+	for i := 0; i+1 < len(registers); i += 2 {
+		bits := uint32(c.registersToUint64(registers[i : i+2]))
+		result = append(result, math.Float32frombits(bits))
 	}
 	return result
 }
 
 func (c *Converter) convertToFloat64Array(registers []uint16) []float64 {
 	var result []float64
-	for i := 0; i < len(registers); i += 4 {
-		if i+3 < len(registers) {
-			var bits uint64
-			if c.wordOrder == WORD_1234 {
-				bits = uint64(registers[i])<<48 | uint64(registers[i+1])<<32 |
-					uint64(registers[i+2])<<16 | uint64(registers[i+3])
-			} else {
-				bits = uint64(registers[i+3])<<48 | uint64(registers[i+2])<<32 |
-					uint64(registers[i+1])<<16 | uint64(registers[i])
-			}
-			result = append(result, math.Float64frombits(bits))
-		}
+	for i := 0; i+3 < len(registers); i += 4 {
+		bits := c.registersToUint64(registers[i : i+4])
+		result = append(result, math.Float64frombits(bits))
 	}
 	return result
 }
@@ -459,91 +574,25 @@ func (c *Converter) convertToASCII(registers []uint16) string {
 	return strings.TrimRight(string(chars), "\x00")
 }
 
-func (c *Converter) convertToTimestamp(registers []uint16) string {
-	if len(registers) >= 2 {
-		var timestamp uint32
-		if c.wordOrder == WORD_1234 {
-			timestamp = uint32(registers[0])<<16 | uint32(registers[1])
-		} else {
-			timestamp = uint32(registers[1])<<16 | uint32(registers[0])
-		}
-
-		t := time.Unix(int64(timestamp), 0)
-		return t.Format("2006-01-02 15:04:05")
-	}
-	return "无效时间戳"
-}
-
 // 转换为寄存器的辅助方法
 func (c *Converter) int32ToRegisters(value int32) []uint16 {
-	bits := uint32(value)
-	if c.wordOrder == WORD_1234 {
-		return []uint16{uint16(bits >> 16), uint16(bits & 0xFFFF)}
-	} else {
-		return []uint16{uint16(bits & 0xFFFF), uint16(bits >> 16)}
-	}
+	return c.uint64ToRegisters(uint64(uint32(value)), 2)
 }
 
 func (c *Converter) uint32ToRegisters(value uint32) []uint16 {
-	if c.wordOrder == WORD_1234 {
-		return []uint16{uint16(value >> 16), uint16(value & 0xFFFF)}
-	} else {
-		return []uint16{uint16(value & 0xFFFF), uint16(value >> 16)}
-	}
+	return c.uint64ToRegisters(uint64(value), 2)
 }
 
 func (c *Converter) int64ToRegisters(value int64) []uint16 {
-	bits := uint64(value)
-	if c.wordOrder == WORD_1234 {
-		return []uint16{
-			uint16(bits >> 48), uint16(bits >> 32),
-			uint16(bits >> 16), uint16(bits & 0xFFFF),
-		}
-	} else {
-		return []uint16{
-			uint16(bits & 0xFFFF), uint16(bits >> 16),
-			uint16(bits >> 32), uint16(bits >> 48),
-		}
-	}
-}
-
-func (c *Converter) uint64ToRegisters(value uint64) []uint16 {
-	bits := value
-	if c.wordOrder == WORD_1234 {
-		return []uint16{
-			uint16(bits >> 48), uint16(bits >> 32),
-			uint16(bits >> 16), uint16(bits & 0xFFFF),
-		}
-	} else {
-		return []uint16{
-			uint16(bits & 0xFFFF), uint16(bits >> 16),
-			uint16(bits >> 32), uint16(bits >> 48),
-		}
-	}
+	return c.uint64ToRegisters(uint64(value), 4)
 }
 
 func (c *Converter) float32ToRegisters(value float32) []uint16 {
-	bits := math.Float32bits(value)
-	if c.wordOrder == WORD_1234 {
-		return []uint16{uint16(bits >> 16), uint16(bits & 0xFFFF)}
-	} else {
-		return []uint16{uint16(bits & 0xFFFF), uint16(bits >> 16)}
-	}
+	return c.uint64ToRegisters(uint64(math.Float32bits(value)), 2)
 }
 
 func (c *Converter) float64ToRegisters(value float64) []uint16 {
-	bits := math.Float64bits(value)
-	if c.wordOrder == WORD_1234 {
-		return []uint16{
-			uint16(bits >> 48), uint16(bits >> 32),
-			uint16(bits >> 16), uint16(bits & 0xFFFF),
-		}
-	} else {
-		return []uint16{
-			uint16(bits & 0xFFFF), uint16(bits >> 16),
-			uint16(bits >> 32), uint16(bits >> 48),
-		}
-	}
+	return c.uint64ToRegisters(math.Float64bits(value), 4)
 }
 
 func (c *Converter) asciiToRegisters(value string) []uint16 {
@@ -0,0 +1,215 @@
+package datatypes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 字节/字序自动识别
+//
+// 给定一段原始寄存器和期望值 (或合理范围), 依次尝试每种数值类型、字节/字序组合和起始偏移,
+// 通过 Converter 解码后保留落在期望范围内的解释, 按与期望值的接近程度排序。
+// 单寄存器类型只区分字节序; 2/3个寄存器的类型中 3412 与 1234、2143 与 4321 结果相同, 只尝试一种。
+// 有符号、无符号和原码类型对同一组寄存器解码出相同的值时只保留 detectTypes 中靠前的类型。
+
+// detectTypes 参与识别的数值类型
+var detectTypes = []DataType{
+	FLOAT32, FLOAT64, INT32, UINT32, INT16, UINT16,
+	INT64, UINT64, INT48, UINT48, SM_INT16, SM_INT32,
+	FLOAT16, BCD16, BCD32,
+}
+
+// detectScales 整数类型额外尝试的缩放系数
+var detectScales = []float64{0.1, 0.01, 0.001, 10}
+
+// Expectation 期望值: 目标值加容差, 或上下限范围
+type Expectation struct {
+	Min, Max  float64
+	Target    float64
+	HasTarget bool // 为 false 时只判断是否落在 [Min, Max] 内
+}
+
+// ParseExpectation 解析期望值写法:
+//
+//	230        目标值, 允许 1% 误差
+//	230±2      目标值加绝对容差 (也可写作 230+-2)
+//	230±0.5%   目标值加相对容差
+//	220..240   范围
+func ParseExpectation(text string) (Expectation, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Expectation{}, fmt.Errorf("empty expectation")
+	}
+
+	if lo, hi, ok := strings.Cut(text, ".."); ok {
+		min, err1 := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+		max, err2 := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+		if err1 != nil || err2 != nil {
+			return Expectation{}, fmt.Errorf("invalid range %q", text)
+		}
+		if min > max {
+			min, max = max, min
+		}
+		return Expectation{Min: min, Max: max}, nil
+	}
+
+	valueText, tolText := text, "1%"
+	for _, sep := range []string{"±", "+-", "+/-"} {
+		if v, t, ok := strings.Cut(text, sep); ok {
+			valueText, tolText = v, t
+			break
+		}
+	}
+	target, err := strconv.ParseFloat(strings.TrimSpace(valueText), 64)
+	if err != nil {
+		return Expectation{}, fmt.Errorf("invalid expected value %q", valueText)
+	}
+
+	tolText = strings.TrimSpace(tolText)
+	relative := strings.HasSuffix(tolText, "%")
+	tol, err := strconv.ParseFloat(strings.TrimSuffix(tolText, "%"), 64)
+	if err != nil || tol < 0 {
+		return Expectation{}, fmt.Errorf("invalid tolerance %q", tolText)
+	}
+	if relative {
+		tol = math.Abs(target) * tol / 100
+	}
+	return Expectation{Min: target - tol, Max: target + tol, Target: target, HasTarget: true}, nil
+}
+
+// Match 判断数值是否符合期望
+func (e Expectation) Match(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && v >= e.Min && v <= e.Max
+}
+
+// distance 数值与期望值的相对距离, 0 为最接近: 有目标值时为相对误差, 否则为到范围中点的距离占半宽的比例
+func (e Expectation) distance(v float64) float64 {
+	if e.HasTarget {
+		return math.Abs(v-e.Target) / math.Max(math.Abs(e.Target), 1e-9)
+	}
+	half := (e.Max - e.Min) / 2
+	if half == 0 {
+		return 0
+	}
+	return math.Abs(v-(e.Min+half)) / half
+}
+
+// Candidate 一种符合期望的解释
+type Candidate struct {
+	DataType  DataType
+	ByteOrder ByteOrder
+	WordOrder WordOrder
+	Offset    int     // 值在寄存器块中的起始偏移
+	Scale     float64 // 缩放系数, 1 表示不缩放
+	Value     float64 // 缩放后的值
+	Score     float64 // 越小越可信
+}
+
+// Order 返回排列名称, 如 CDAB
+func (c Candidate) Order() string {
+	return OrderPresetName(c.ByteOrder, c.WordOrder, c.DataType.RegistersPerValue())
+}
+
+// DetectOptions 识别选项
+type DetectOptions struct {
+	TryScales  bool // 整数类型同时尝试 ×0.1、×0.01、×0.001 和 ×10
+	MaxResults int  // 最多返回的结果数, 0 表示不限
+}
+
+// DetectFormats 尝试所有类型、字节/字序和起始偏移, 返回符合期望的解释, 最可信的在前。
+// 排序依据: 与期望值的接近程度, 不缩放优先于缩放, 偏移小的优先。
+func DetectFormats(registers []uint16, exp Expectation, opts DetectOptions) []Candidate {
+	var results []Candidate
+	seen := make(map[string]bool)
+	for _, dataType := range detectTypes {
+		n := dataType.RegistersPerValue()
+		if n > len(registers) {
+			continue
+		}
+		isFloat := dataType == FLOAT16 || dataType == FLOAT32 || dataType == FLOAT64
+		scales := []float64{1}
+		if opts.TryScales && !isFloat {
+			scales = append(scales, detectScales...)
+		}
+
+		for _, byteOrder := range []ByteOrder{AB, BA} {
+			for _, wordOrder := range detectWordOrders(n) {
+				conv := NewConverter(byteOrder, wordOrder)
+				for offset := 0; offset+n <= len(registers); offset++ {
+					decoded, err := conv.ConvertFromRegisters(registers[offset:offset+n], dataType)
+					if err != nil {
+						continue
+					}
+					values, ok := ToFloat64Slice(decoded)
+					if !ok || len(values) == 0 {
+						continue
+					}
+					for _, scale := range scales {
+						v := values[0] * scale
+						if !exp.Match(v) {
+							continue
+						}
+						key := fmt.Sprintf("%d/%d/%d/%d/%g/%g", offset, n, byteOrder, wordOrder, scale, v)
+						if seen[key] {
+							continue
+						}
+						seen[key] = true
+						score := exp.distance(v) + float64(offset)*1e-6
+						if scale != 1 {
+							score += 0.1 * math.Abs(math.Log10(scale))
+						}
+						results = append(results, Candidate{
+							DataType: dataType, ByteOrder: byteOrder, WordOrder: wordOrder,
+							Offset: offset, Scale: scale, Value: v, Score: score,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score < results[j].Score
+	})
+	if opts.MaxResults > 0 && len(results) > opts.MaxResults {
+		results = results[:opts.MaxResults]
+	}
+	return results
+}
+
+// detectWordOrders 返回 n 个寄存器时结果互不相同的字序: 2个寄存器时 3412 同 1234、2143 同 4321,
+// 3个寄存器时 3412 同 1234
+func detectWordOrders(n int) []WordOrder {
+	switch n {
+	case 1:
+		return []WordOrder{WORD_1234}
+	case 2:
+		return []WordOrder{WORD_1234, WORD_4321}
+	case 3:
+		return []WordOrder{WORD_1234, WORD_4321, WORD_2143}
+	default:
+		return AllWordOrders()
+	}
+}
+
+// ParseRegisterList 解析以空格、逗号或换行分隔的寄存器值, 十进制或 0x 十六进制
+func ParseRegisterList(text string) ([]uint16, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '\t' || r == '\n' || r == '\r'
+	})
+	registers := make([]uint16, 0, len(fields))
+	for _, field := range fields {
+		v, err := strconv.ParseUint(field, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid register value %q", field)
+		}
+		registers = append(registers, uint16(v))
+	}
+	if len(registers) == 0 {
+		return nil, fmt.Errorf("no register values")
+	}
+	return registers, nil
+}
@@ -0,0 +1,62 @@
+package datatypes
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestDetectWordOrders 每种寄存器数下返回的字序排列互不相同, 且覆盖所有字序能产生的排列
+func TestDetectWordOrders(t *testing.T) {
+	for n := 1; n <= 4; n++ {
+		seen := make(map[string]WordOrder)
+		for _, wordOrder := range detectWordOrders(n) {
+			layout := OrderPresetName(AB, wordOrder, n)
+			if other, ok := seen[layout]; ok {
+				t.Errorf("%d registers: %s and %s both give %s", n, other, wordOrder, layout)
+			}
+			seen[layout] = wordOrder
+		}
+		for _, wordOrder := range AllWordOrders() {
+			if layout := OrderPresetName(AB, wordOrder, n); !containsLayout(seen, layout) {
+				t.Errorf("%d registers: layout %s (%s) is never tried", n, layout, wordOrder)
+			}
+		}
+	}
+}
+
+func containsLayout(seen map[string]WordOrder, layout string) bool {
+	_, ok := seen[layout]
+	return ok
+}
+
+func TestDetectFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		registers []uint16
+		expect    string
+		scales    bool
+		want      string // 最可信结果: 类型 排列 偏移 缩放
+	}{
+		{"float CDAB", []uint16{0x0000, 0x4366}, "230", false, "FLOAT32 CDAB 0 1"},
+		{"float after offset", []uint16{0xFFFF, 0x4366, 0x0000}, "230", false, "FLOAT32 ABCD 1 1"},
+		{"scaled int16", []uint16{2301}, "230±1%", true, "INT16 AB 0 0.1"},
+		{"int48 CDABEF", []uint16{0x0002, 0x0001, 0x0003}, fmt.Sprint(0x000100020003), false, "INT48 CDABEF 0 1"},
+		{"int48 EFCDAB", []uint16{0x0003, 0x0002, 0x0001}, fmt.Sprint(0x000100020003), false, "INT48 EFCDAB 0 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, err := ParseExpectation(tt.expect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidates := DetectFormats(tt.registers, exp, DetectOptions{TryScales: tt.scales})
+			if len(candidates) == 0 {
+				t.Fatal("no candidates")
+			}
+			c := candidates[0]
+			if got := fmt.Sprintf("%s %s %d %g", c.DataType, c.Order(), c.Offset, c.Scale); got != tt.want {
+				t.Errorf("best candidate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package datatypes
+
+import (
+	"fmt"
+	"math"
+)
+
+// 扩展数据类型的转换: 8位有符号/无符号字节, 半精度浮点, 压缩BCD, 48位计数器以及原码(符号-数值)整数。
+
+// convertToInt8Array 每个寄存器取高字节或低字节作为 INT8
+func (c *Converter) convertToInt8Array(registers []uint16, high bool) []int8 {
+	result := make([]int8, len(registers))
+	for i, reg := range registers {
+		result[i] = int8(pickByte(c.swapBytes(reg), high))
+	}
+	return result
+}
+
+// convertToUint8Array 每个寄存器取高字节或低字节作为 UINT8
+func (c *Converter) convertToUint8Array(registers []uint16, high bool) []uint8 {
+	result := make([]uint8, len(registers))
+	for i, reg := range registers {
+		result[i] = pickByte(c.swapBytes(reg), high)
+	}
+	return result
+}
+
+func pickByte(reg uint16, high bool) uint8 {
+	if high {
+		return uint8(reg >> 8)
+	}
+	return uint8(reg)
+}
+
+func (c *Converter) convertToFloat16Array(registers []uint16) []float32 {
+	result := make([]float32, len(registers))
+	for i, reg := range registers {
+		result[i] = float16ToFloat32(c.swapBytes(reg))
+	}
+	return result
+}
+
+func (c *Converter) convertToBCD16Array(registers []uint16) ([]uint16, error) {
+	result := make([]uint16, len(registers))
+	for i, reg := range registers {
+		val, err := decodeBCD(uint64(c.swapBytes(reg)), 4)
+		if err != nil {
+			return nil, fmt.Errorf("register %d: %w", i, err)
+		}
+		result[i] = uint16(val)
+	}
+	return result, nil
+}
+
+func (c *Converter) convertToBCD32Array(registers []uint16) ([]uint32, error) {
+	var result []uint32
+	for i := 0; i+1 < len(registers); i += 2 {
+		val, err := decodeBCD(c.registersToUint64(registers[i:i+2]), 8)
+		if err != nil {
+			return nil, fmt.Errorf("register %d: %w", i, err)
+		}
+		result = append(result, uint32(val))
+	}
+	return result, nil
+}
+
+func (c *Converter) convertToUint48Array(registers []uint16) []uint64 {
+	var result []uint64
+	for i := 0; i+2 < len(registers); i += 3 {
+		result = append(result, c.registersToUint64(registers[i:i+3]))
+	}
+	return result
+}
+
+func (c *Converter) convertToInt48Array(registers []uint16) []int64 {
+	var result []int64
+	for i := 0; i+2 < len(registers); i += 3 {
+		// 左移后算术右移完成48位符号扩展
+		result = append(result, int64(c.registersToUint64(registers[i:i+3])<<16)>>16)
+	}
+	return result
+}
+
+func (c *Converter) convertToSignMagnitude16Array(registers []uint16) []int16 {
+	result := make([]int16, len(registers))
+	for i, reg := range registers {
+		reg = c.swapBytes(reg)
+		magnitude := int16(reg & 0x7FFF)
+		if reg&0x8000 != 0 {
+			magnitude = -magnitude
+		}
+		result[i] = magnitude
+	}
+	return result
+}
+
+func (c *Converter) convertToSignMagnitude32Array(registers []uint16) []int32 {
+	var result []int32
+	for i := 0; i+1 < len(registers); i += 2 {
+		bits := uint32(c.registersToUint64(registers[i : i+2]))
+		magnitude := int32(bits & 0x7FFFFFFF)
+		if bits&0x80000000 != 0 {
+			magnitude = -magnitude
+		}
+		result = append(result, magnitude)
+	}
+	return result
+}
+
+// convertExtendedToRegisters 将扩展类型的值转换为寄存器, 值的Go类型须与 ParseStringToType 的返回类型一致
+func (c *Converter) convertExtendedToRegisters(value interface{}, dataType DataType) ([]uint16, error) {
+	var registers []uint16
+	switch dataType {
+	case INT8_HIGH, INT8_LOW:
+		values, ok := value.([]int8)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			registers = append(registers, c.swapBytes(placeByte(uint8(val), dataType == INT8_HIGH)))
+		}
+	case UINT8_HIGH, UINT8_LOW:
+		values, ok := value.([]uint8)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			registers = append(registers, c.swapBytes(placeByte(val, dataType == UINT8_HIGH)))
+		}
+	case FLOAT16:
+		values, ok := value.([]float32)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			registers = append(registers, c.swapBytes(float32ToFloat16(val)))
+		}
+	case BCD16:
+		values, ok := value.([]uint16)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			bcd, err := encodeBCD(uint64(val), 4)
+			if err != nil {
+				return nil, err
+			}
+			registers = append(registers, c.swapBytes(uint16(bcd)))
+		}
+	case BCD32:
+		values, ok := value.([]uint32)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			bcd, err := encodeBCD(uint64(val), 8)
+			if err != nil {
+				return nil, err
+			}
+			registers = append(registers, c.uint64ToRegisters(bcd, 2)...)
+		}
+	case UINT48:
+		values, ok := value.([]uint64)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			if val > maxUint48 {
+				return nil, fmt.Errorf("value %d out of UINT48 range", val)
+			}
+			registers = append(registers, c.uint64ToRegisters(val, 3)...)
+		}
+	case INT48:
+		values, ok := value.([]int64)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			if val < minInt48 || val > maxInt48 {
+				return nil, fmt.Errorf("value %d out of INT48 range", val)
+			}
+			registers = append(registers, c.uint64ToRegisters(uint64(val)&maxUint48, 3)...)
+		}
+	case SM_INT16:
+		values, ok := value.([]int16)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			if val == math.MinInt16 {
+				return nil, fmt.Errorf("value %d out of SM_INT16 range", val)
+			}
+			reg := uint16(val)
+			if val < 0 {
+				reg = uint16(-val) | 0x8000
+			}
+			registers = append(registers, c.swapBytes(reg))
+		}
+	case SM_INT32:
+		values, ok := value.([]int32)
+		if !ok {
+			return nil, typeMismatch(value, dataType)
+		}
+		for _, val := range values {
+			if val == math.MinInt32 {
+				return nil, fmt.Errorf("value %d out of SM_INT32 range", val)
+			}
+			bits := uint32(val)
+			if val < 0 {
+				bits = uint32(-val) | 0x80000000
+			}
+			registers = append(registers, c.uint64ToRegisters(uint64(bits), 2)...)
+		}
+	default:
+		return nil, fmt.Errorf("not an extended data type: %s", dataType)
+	}
+	return registers, nil
+}
+
+const (
+	maxUint48 = 1<<48 - 1
+	maxInt48  = 1<<47 - 1
+	minInt48  = -(1 << 47)
+)
+
+func typeMismatch(value interface{}, dataType DataType) error {
+	return fmt.Errorf("unsupported value type %T for %s", value, dataType)
+}
+
+// IsHalfRegister 判断数据类型是否只占寄存器的一个字节 (INT8/UINT8 的高字节或低字节)。
+// 这类值转换出的寄存器中另一字节为0, 写入时须保留设备上的另一字节, 见 HalfRegisterMask
+func IsHalfRegister(dataType DataType) bool {
+	switch dataType {
+	case INT8_HIGH, INT8_LOW, UINT8_HIGH, UINT8_LOW:
+		return true
+	}
+	return false
+}
+
+// HalfRegisterMask 返回半寄存器类型的值在线上寄存器中占用的位, 已考虑字节序
+func (c *Converter) HalfRegisterMask(dataType DataType) uint16 {
+	return c.swapBytes(placeByte(0xFF, dataType == INT8_HIGH || dataType == UINT8_HIGH))
+}
+
+// placeByte 将字节放入寄存器的高字节或低字节, 另一字节为0
+func placeByte(b uint8, high bool) uint16 {
+	if high {
+		return uint16(b) << 8
+	}
+	return uint16(b)
+}
+
+// decodeBCD 解码 digits 位压缩BCD
+func decodeBCD(bcd uint64, digits int) (uint64, error) {
+	var value uint64
+	for i := digits - 1; i >= 0; i-- {
+		nibble := (bcd >> (uint(i) * 4)) & 0xF
+		if nibble > 9 {
+			return 0, fmt.Errorf("invalid BCD value 0x%0*X", digits, bcd)
+		}
+		value = value*10 + nibble
+	}
+	return value, nil
+}
+
+// encodeBCD 将十进制数编码为 digits 位压缩BCD
+func encodeBCD(value uint64, digits int) (uint64, error) {
+	original := value
+	var bcd uint64
+	for i := 0; i < digits; i++ {
+		bcd |= (value % 10) << (uint(i) * 4)
+		value /= 10
+	}
+	if value != 0 {
+		return 0, fmt.Errorf("value %d does not fit in %d BCD digits", original, digits)
+	}
+	return bcd, nil
+}
+
+// float16ToFloat32 IEEE 754 半精度转单精度
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := uint32(h>>10) & 0x1F
+	mantissa := uint32(h) & 0x3FF
+
+	switch {
+	case exponent == 0 && mantissa == 0:
+		return math.Float32frombits(sign)
+	case exponent == 0:
+		// 非规格化数
+		value := float32(mantissa) / 1024 * float32(math.Pow(2, -14))
+		if sign != 0 {
+			value = -value
+		}
+		return value
+	case exponent == 0x1F:
+		return math.Float32frombits(sign | 0x7F800000 | mantissa<<13)
+	default:
+		return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+	}
+}
+
+// float32ToFloat16 单精度转IEEE 754半精度, 就近舍入, 溢出为无穷大
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int32(bits>>23) & 0xFF
+	mantissa := bits & 0x7FFFFF
+
+	if exponent == 0xFF {
+		if mantissa != 0 {
+			return sign | 0x7E00 // NaN
+		}
+		return sign | 0x7C00 // Inf
+	}
+
+	exponent = exponent - 127 + 15
+	switch {
+	case exponent >= 0x1F:
+		return sign | 0x7C00
+	case exponent <= 0:
+		if exponent < -10 {
+			return sign
+		}
+		// 非规格化数
+		mantissa |= 0x800000
+		shift := uint32(14 - exponent)
+		half := uint16(mantissa >> shift)
+		if mantissa>>(shift-1)&1 != 0 && (mantissa&(1<<(shift-1)-1) != 0 || half&1 != 0) {
+			half++
+		}
+		return sign | half
+	default:
+		half := sign | uint16(exponent)<<10 | uint16(mantissa>>13)
+		// 就近舍入 (偶数优先), 进位可自然溢出到指数
+		if mantissa&0x1000 != 0 && (mantissa&0xFFF != 0 || half&1 != 0) {
+			half++
+		}
+		return half
+	}
+}
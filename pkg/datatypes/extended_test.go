@@ -0,0 +1,215 @@
+package datatypes
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExtendedRoundTrip 扩展类型在 AB/WORD_1234 下编码为寄存器, 再解码回原值
+func TestExtendedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		dataType  DataType
+		value     interface{}
+		registers []uint16
+	}{
+		{"int8 high", INT8_HIGH, []int8{-2, 127}, []uint16{0xFE00, 0x7F00}},
+		{"int8 low", INT8_LOW, []int8{-128, 5}, []uint16{0x0080, 0x0005}},
+		{"uint8 high", UINT8_HIGH, []uint8{0xAB}, []uint16{0xAB00}},
+		{"uint8 low", UINT8_LOW, []uint8{0xAB}, []uint16{0x00AB}},
+		{"float16", FLOAT16, []float32{1, -2, 0.5, 65504}, []uint16{0x3C00, 0xC000, 0x3800, 0x7BFF}},
+		{"float16 subnormal", FLOAT16, []float32{float32(math.Pow(2, -24))}, []uint16{0x0001}},
+		{"float16 infinity", FLOAT16, []float32{float32(math.Inf(-1))}, []uint16{0xFC00}},
+		{"bcd16", BCD16, []uint16{1234, 9999, 0}, []uint16{0x1234, 0x9999, 0x0000}},
+		{"bcd32", BCD32, []uint32{12345678}, []uint16{0x1234, 0x5678}},
+		{"uint48", UINT48, []uint64{maxUint48}, []uint16{0xFFFF, 0xFFFF, 0xFFFF}},
+		{"int48", INT48, []int64{-1, minInt48}, []uint16{0xFFFF, 0xFFFF, 0xFFFF, 0x8000, 0x0000, 0x0000}},
+		{"sign-magnitude 16", SM_INT16, []int16{-5, 5, math.MaxInt16}, []uint16{0x8005, 0x0005, 0x7FFF}},
+		{"sign-magnitude 32", SM_INT32, []int32{-0x10002}, []uint16{0x8001, 0x0002}},
+	}
+	c := NewConverter(AB, WORD_1234)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registers, err := c.ConvertToRegistersAs(tt.value, tt.dataType)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !reflect.DeepEqual(registers, tt.registers) {
+				t.Errorf("encode = %04X, want %04X", registers, tt.registers)
+			}
+			decoded, err := c.ConvertFromRegisters(tt.registers, tt.dataType)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.value) {
+				t.Errorf("decode = %v, want %v", decoded, tt.value)
+			}
+		})
+	}
+}
+
+// TestExtendedDecode 只解码的情况: 负零, 舍入和非法值
+func TestExtendedDecode(t *testing.T) {
+	c := NewConverter(AB, WORD_1234)
+	tests := []struct {
+		name      string
+		dataType  DataType
+		registers []uint16
+		want      interface{}
+		wantErr   string
+	}{
+		{"sign-magnitude negative zero", SM_INT16, []uint16{0x8000}, []int16{0}, ""},
+		{"sign-magnitude 32 negative zero", SM_INT32, []uint16{0x8000, 0x0000}, []int32{0}, ""},
+		{"uint8 ignores other byte", UINT8_LOW, []uint16{0xFF12}, []uint8{0x12}, ""},
+		{"int8 ignores other byte", INT8_HIGH, []uint16{0x80FF}, []int8{-128}, ""},
+		{"bcd16 invalid nibble", BCD16, []uint16{0x12A4}, nil, "invalid BCD value"},
+		{"bcd32 invalid nibble", BCD32, []uint16{0x0000, 0x000F}, nil, "invalid BCD value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := c.ConvertFromRegisters(tt.registers, tt.dataType)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, tt.want) {
+				t.Errorf("decode = %v, want %v", decoded, tt.want)
+			}
+		})
+	}
+
+	// NaN 不能用 DeepEqual 比较
+	decoded, err := c.ConvertFromRegisters([]uint16{0x7E00}, FLOAT16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := decoded.([]float32); !math.IsNaN(float64(values[0])) {
+		t.Errorf("decode 0x7E00 = %v, want NaN", values[0])
+	}
+}
+
+// TestFloat16Rounding 半精度按就近偶数舍入, 超出范围为无穷大, 过小为零
+func TestFloat16Rounding(t *testing.T) {
+	tests := []struct {
+		value float32
+		want  uint16
+	}{
+		{1 + 1.0/2048, 0x3C00},            // 正好一半, 舍入到偶数
+		{1 + 3.0/2048, 0x3C02},            // 正好一半, 进位到偶数
+		{1 + 1.0/2048 + 1.0/8192, 0x3C01}, // 超过一半
+		{65520, 0x7C00},                   // 舍入后溢出
+		{1e-10, 0x0000},
+		{-1e-10, 0x8000},
+		{float32(math.NaN()), 0x7E00},
+	}
+	for _, tt := range tests {
+		if got := float32ToFloat16(tt.value); got != tt.want {
+			t.Errorf("float32ToFloat16(%v) = 0x%04X, want 0x%04X", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestExtendedEncodeErrors(t *testing.T) {
+	c := NewConverter(AB, WORD_1234)
+	tests := []struct {
+		name     string
+		dataType DataType
+		value    interface{}
+		wantErr  string
+	}{
+		{"bcd16 overflow", BCD16, []uint16{10000}, "does not fit in 4 BCD digits"},
+		{"bcd32 overflow", BCD32, []uint32{100000000}, "does not fit in 8 BCD digits"},
+		{"uint48 overflow", UINT48, []uint64{1 << 48}, "out of UINT48 range"},
+		{"int48 overflow", INT48, []int64{maxInt48 + 1}, "out of INT48 range"},
+		{"int48 underflow", INT48, []int64{minInt48 - 1}, "out of INT48 range"},
+		{"sign-magnitude 16 minimum", SM_INT16, []int16{math.MinInt16}, "out of SM_INT16 range"},
+		{"sign-magnitude 32 minimum", SM_INT32, []int32{math.MinInt32}, "out of SM_INT32 range"},
+		{"wrong value type", UINT8_HIGH, []int8{1}, "unsupported value type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.ConvertToRegistersAs(tt.value, tt.dataType)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestExtendedByteOrder 16位扩展类型按字节序交换字节, 32/48位类型同时按字序排列
+func TestExtendedByteOrder(t *testing.T) {
+	tests := []struct {
+		byteOrder ByteOrder
+		wordOrder WordOrder
+		dataType  DataType
+		value     interface{}
+		want      []uint16
+	}{
+		{BA, WORD_1234, INT8_HIGH, []int8{-1}, []uint16{0x00FF}},
+		{BA, WORD_1234, UINT8_LOW, []uint8{0x12}, []uint16{0x1200}},
+		{BA, WORD_1234, FLOAT16, []float32{1}, []uint16{0x003C}},
+		{BA, WORD_1234, BCD16, []uint16{1234}, []uint16{0x3412}},
+		{AB, WORD_4321, BCD32, []uint32{12345678}, []uint16{0x5678, 0x1234}},
+		{BA, WORD_4321, SM_INT32, []int32{-1}, []uint16{0x0100, 0x0080}},
+		{AB, WORD_4321, UINT48, []uint64{0x010203040506}, []uint16{0x0506, 0x0304, 0x0102}},
+	}
+	for _, tt := range tests {
+		c := NewConverter(tt.byteOrder, tt.wordOrder)
+		registers, err := c.ConvertToRegistersAs(tt.value, tt.dataType)
+		if err != nil {
+			t.Errorf("%s %s%s: %v", tt.dataType, tt.byteOrder, tt.wordOrder, err)
+			continue
+		}
+		if !reflect.DeepEqual(registers, tt.want) {
+			t.Errorf("%s %s%s: encode = %04X, want %04X", tt.dataType, tt.byteOrder, tt.wordOrder, registers, tt.want)
+		}
+		decoded, err := c.ConvertFromRegisters(registers, tt.dataType)
+		if err != nil || !reflect.DeepEqual(decoded, tt.value) {
+			t.Errorf("%s %s%s: decode = %v, %v, want %v", tt.dataType, tt.byteOrder, tt.wordOrder, decoded, err, tt.value)
+		}
+	}
+}
+
+// TestHalfRegisterMask 掩码覆盖值所在的字节, 交换字节序时随之交换
+func TestHalfRegisterMask(t *testing.T) {
+	tests := []struct {
+		byteOrder ByteOrder
+		dataType  DataType
+		want      uint16
+	}{
+		{AB, INT8_HIGH, 0xFF00},
+		{AB, UINT8_LOW, 0x00FF},
+		{BA, UINT8_HIGH, 0x00FF},
+		{BA, INT8_LOW, 0xFF00},
+	}
+	for _, tt := range tests {
+		c := NewConverter(tt.byteOrder, WORD_1234)
+		mask := c.HalfRegisterMask(tt.dataType)
+		if mask != tt.want {
+			t.Errorf("%s %s: mask = 0x%04X, want 0x%04X", tt.byteOrder, tt.dataType, mask, tt.want)
+		}
+		// 转换出的寄存器只占用掩码内的位
+		var registers []uint16
+		var err error
+		if tt.dataType == UINT8_HIGH || tt.dataType == UINT8_LOW {
+			registers, err = c.ConvertToRegistersAs([]uint8{0xFF}, tt.dataType)
+		} else {
+			registers, err = c.ConvertToRegistersAs([]int8{-1}, tt.dataType)
+		}
+		if err != nil || registers[0] != mask {
+			t.Errorf("%s %s: registers = %04X, %v, want %04X", tt.byteOrder, tt.dataType, registers, err, mask)
+		}
+	}
+	for _, dataType := range []DataType{INT16, UINT16, FLOAT16, BCD16} {
+		if IsHalfRegister(dataType) {
+			t.Errorf("IsHalfRegister(%s) = true", dataType)
+		}
+	}
+}
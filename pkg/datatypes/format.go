@@ -0,0 +1,288 @@
+package datatypes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Radix 读取结果的显示进制
+type Radix int
+
+const (
+	RadixDecimal Radix = iota // 十进制, 与数值框的显示一致
+	RadixHex                  // 十六进制, 有符号数按类型宽度显示补码, 浮点数显示IEEE位模式
+	RadixBinary               // 二进制, 每4位以下划线分组
+	RadixOctal                // 八进制
+	RadixRaw                  // 值对应的原始寄存器
+)
+
+// AllRadixes 返回所有显示进制, 顺序与界面列一致
+func AllRadixes() []Radix {
+	return []Radix{RadixDecimal, RadixHex, RadixBinary, RadixOctal, RadixRaw}
+}
+
+func (r Radix) String() string {
+	switch r {
+	case RadixHex:
+		return "HEX"
+	case RadixBinary:
+		return "BIN"
+	case RadixOctal:
+		return "OCT"
+	case RadixRaw:
+		return "RAW"
+	default:
+		return "DEC"
+	}
+}
+
+// ParseRadix 解析显示进制名称
+func ParseRadix(s string) (Radix, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEC", "DECIMAL", "10":
+		return RadixDecimal, nil
+	case "HEX", "16":
+		return RadixHex, nil
+	case "BIN", "BINARY", "2":
+		return RadixBinary, nil
+	case "OCT", "OCTAL", "8":
+		return RadixOctal, nil
+	case "RAW", "WORDS":
+		return RadixRaw, nil
+	default:
+		return RadixDecimal, fmt.Errorf("unknown radix: %s", s)
+	}
+}
+
+// ParseRadixList 解析逗号分隔的显示进制列表, 如 "dec,hex,raw"
+func ParseRadixList(s string) ([]Radix, error) {
+	var radixes []Radix
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		r, err := ParseRadix(part)
+		if err != nil {
+			return nil, err
+		}
+		radixes = append(radixes, r)
+	}
+	if len(radixes) == 0 {
+		radixes = []Radix{RadixDecimal}
+	}
+	return radixes, nil
+}
+
+// FormattedRow 一个值在各显示进制下的文本
+type FormattedRow struct {
+	Offset  int      // 相对起始地址的寄存器 (或线圈) 偏移
+	Bit     int      // 寄存器按 BOOL 读取时值所在的位, 否则为 -1
+	Columns []string // 与传入的 radixes 顺序一致
+}
+
+// Address 返回带起始地址的地址文本, 位值为 "地址.位"
+func (r FormattedRow) Address(start int) string {
+	if r.Bit >= 0 {
+		return fmt.Sprintf("%d.%d", start+r.Offset, r.Bit)
+	}
+	return strconv.Itoa(start + r.Offset)
+}
+
+// FormatRows 将 ConvertFromRegisters (或读取线圈) 返回的值按各显示进制格式化。
+// registers 为读取到的原始寄存器, 线圈和离散输入传 nil。
+func FormatRows(values interface{}, registers []uint16, dataType DataType, radixes []Radix) ([]FormattedRow, error) {
+	count := valueCount(values)
+	if count < 0 {
+		return nil, fmt.Errorf("cannot format values of type %T", values)
+	}
+
+	rows := make([]FormattedRow, count)
+	for i := range rows {
+		rows[i] = FormattedRow{Offset: i, Bit: -1, Columns: make([]string, len(radixes))}
+		if len(registers) == 0 || count == 0 {
+			continue
+		}
+		if count > len(registers) {
+			// BYTE 每个寄存器2个值, BOOL 每个寄存器16个值
+			perRegister := count / len(registers)
+			rows[i].Offset = i / perRegister
+			if dataType == BOOL {
+				rows[i].Bit = i % perRegister
+			}
+		} else {
+			rows[i].Offset = i * (len(registers) / count)
+		}
+	}
+
+	for col, radix := range radixes {
+		for i := range rows {
+			if radix == RadixRaw {
+				rows[i].Columns[col] = formatRawWords(registers, rows, i)
+			} else {
+				rows[i].Columns[col] = formatValue(values, i, dataType, radix)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// FormatValues 将值按单一进制格式化为文本切片
+func FormatValues(values interface{}, dataType DataType, radix Radix) ([]string, error) {
+	rows, err := FormatRows(values, nil, dataType, []Radix{radix})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(rows))
+	for i, row := range rows {
+		result[i] = row.Columns[0]
+	}
+	return result, nil
+}
+
+// valueCount 返回值的个数, 不支持的类型返回 -1
+func valueCount(values interface{}) int {
+	switch v := values.(type) {
+	case []byte:
+		return len(v)
+	case []int8:
+		return len(v)
+	case []int16:
+		return len(v)
+	case []uint16:
+		return len(v)
+	case []int32:
+		return len(v)
+	case []uint32:
+		return len(v)
+	case []int64:
+		return len(v)
+	case []uint64:
+		return len(v)
+	case []float32:
+		return len(v)
+	case []float64:
+		return len(v)
+	case []bool:
+		return len(v)
+	case []string:
+		return len(v)
+	case string:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// formatRawWords 返回值所占的原始寄存器
+func formatRawWords(registers []uint16, rows []FormattedRow, i int) string {
+	if len(registers) == 0 {
+		return "-"
+	}
+	span := 1
+	if len(rows) <= len(registers) {
+		span = len(registers) / len(rows)
+	}
+	start := rows[i].Offset
+	end := start + span
+	if end > len(registers) {
+		end = len(registers)
+	}
+	words := make([]string, 0, end-start)
+	for _, reg := range registers[start:end] {
+		words = append(words, fmt.Sprintf("%04X", reg))
+	}
+	return strings.Join(words, " ")
+}
+
+// formatValue 格式化第 i 个值
+func formatValue(values interface{}, i int, dataType DataType, radix Radix) string {
+	switch v := values.(type) {
+	case []byte:
+		return formatInteger(int64(v[i]), uint64(v[i]), 8, false, radix)
+	case []int8:
+		return formatInteger(int64(v[i]), uint64(uint8(v[i])), 8, true, radix)
+	case []int16:
+		return formatInteger(int64(v[i]), uint64(uint16(v[i])), 16, true, radix)
+	case []uint16:
+		return formatInteger(int64(v[i]), uint64(v[i]), 16, false, radix)
+	case []int32:
+		return formatInteger(int64(v[i]), uint64(uint32(v[i])), 32, true, radix)
+	case []uint32:
+		return formatInteger(int64(v[i]), uint64(v[i]), 32, false, radix)
+	case []int64:
+		width, bits := 64, uint64(v[i])
+		if dataType == INT48 {
+			width, bits = 48, bits&maxUint48
+		}
+		return formatInteger(v[i], bits, width, true, radix)
+	case []uint64:
+		width := 64
+		if dataType == UINT48 {
+			width = 48
+		}
+		return formatInteger(0, v[i], width, false, radix)
+	case []float32:
+		if radix == RadixDecimal {
+			return strconv.FormatFloat(float64(v[i]), 'f', -1, 32)
+		}
+		if dataType == FLOAT16 {
+			return formatBits(uint64(float32ToFloat16(v[i])), 16, radix)
+		}
+		return formatBits(uint64(math.Float32bits(v[i])), 32, radix)
+	case []float64:
+		if radix == RadixDecimal {
+			return strconv.FormatFloat(v[i], 'f', -1, 64)
+		}
+		return formatBits(math.Float64bits(v[i]), 64, radix)
+	case []bool:
+		if radix == RadixDecimal {
+			return strconv.FormatBool(v[i])
+		}
+		if v[i] {
+			return "1"
+		}
+		return "0"
+	case []string:
+		return v[i]
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", values)
+	}
+}
+
+// formatInteger 格式化整数, 十进制按有符号/无符号显示, 其他进制显示 width 位的位模式
+func formatInteger(signed int64, bits uint64, width int, isSigned bool, radix Radix) string {
+	if radix == RadixDecimal {
+		if isSigned {
+			return strconv.FormatInt(signed, 10)
+		}
+		return strconv.FormatUint(bits, 10)
+	}
+	return formatBits(bits, width, radix)
+}
+
+// formatBits 按进制格式化 width 位的位模式, 十六进制和二进制补足前导0
+func formatBits(bits uint64, width int, radix Radix) string {
+	switch radix {
+	case RadixHex:
+		return fmt.Sprintf("0x%0*X", width/4, bits)
+	case RadixBinary:
+		digits := fmt.Sprintf("%0*b", width, bits)
+		var sb strings.Builder
+		sb.WriteString("0b")
+		for i := 0; i < len(digits); i += 4 {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteString(digits[i : i+4])
+		}
+		return sb.String()
+	case RadixOctal:
+		return "0o" + strconv.FormatUint(bits, 8)
+	default:
+		return strconv.FormatUint(bits, 10)
+	}
+}
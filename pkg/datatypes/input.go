@@ -0,0 +1,451 @@
+package datatypes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 写入值的输入语法:
+//
+//	分隔符   逗号、分号、空白或换行 (时间类型只用逗号、分号和换行, 因为时间本身含空格)
+//	进制     0x1F、0b1010、0o17, 允许下划线分组如 0xFFFF_0000
+//	科学计数 1.5e3, 整数类型要求结果为整数
+//	重复     0*10 表示10个0, "abc"*2 表示两个字符串
+//	范围     1..5 表示 1,2,3,4,5, 5..1 为递减
+//	布尔     on/off、true/false、yes/no、1/0
+//	字符串   "带,逗号的文本" 或 'text', 支持 \" \\ \n \t \xHH 转义
+
+// maxInputElements 展开重复和范围后允许的最大元素数量
+const maxInputElements = 65536
+
+// ParseError 输入解析错误, 记录出错元素的位置以便界面高亮
+type ParseError struct {
+	Index  int    // 元素序号, 从0开始 (重复和范围展开后)
+	Offset int    // 出错记号在输入中的字节偏移
+	Length int    // 出错记号的字节长度
+	Line   int    // 行号, 从1开始
+	Column int    // 列号 (按字符计), 从1开始
+	Token  string // 出错的记号
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("element %d (line %d, column %d) %q: %v", e.Index+1, e.Line, e.Column, e.Token, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// inputElement 输入中的一个元素
+type inputElement struct {
+	text   string
+	quoted bool
+	offset int // 来源记号的字节偏移, 重复和范围展开的元素共享同一记号
+	length int
+}
+
+// inputParser 将输入拆分为元素
+type inputParser struct {
+	input      string
+	whitespace bool // 空白是否作为分隔符
+}
+
+// newParseError 根据偏移计算行列号
+func (p *inputParser) newParseError(index, offset, length int, err error) *ParseError {
+	before := p.input[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return &ParseError{
+		Index:  index,
+		Offset: offset,
+		Length: length,
+		Line:   line,
+		Column: column,
+		Token:  p.input[offset : offset+length],
+		Err:    err,
+	}
+}
+
+func (p *inputParser) isSeparator(r byte) bool {
+	switch r {
+	case ',', ';', '\n', '\r':
+		return true
+	case ' ', '\t':
+		return p.whitespace
+	}
+	return false
+}
+
+// split 拆分输入并展开重复和范围
+func (p *inputParser) split() ([]inputElement, error) {
+	var elements []inputElement
+	s := p.input
+	for i := 0; i < len(s); {
+		if p.isSeparator(s[i]) || s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		var text, suffix string
+		quoted := s[i] == '"' || s[i] == '\''
+		if quoted {
+			unquoted, end, err := unquoteInput(s, i)
+			if err != nil {
+				return nil, p.newParseError(len(elements), start, len(s)-start, err)
+			}
+			text, i = unquoted, end
+		}
+		// 记号延伸到下一个分隔符, 引号之后只允许重复后缀
+		suffixStart := i
+		for i < len(s) && !p.isSeparator(s[i]) {
+			i++
+		}
+		token := strings.TrimSpace(s[start:i])
+		if quoted {
+			suffix = strings.TrimSpace(s[suffixStart:i])
+		} else {
+			text = token
+		}
+
+		expanded, err := p.expand(text, quoted, suffix)
+		if err != nil {
+			return nil, p.newParseError(len(elements), start, len(token), err)
+		}
+		if len(elements)+len(expanded) > maxInputElements {
+			return nil, p.newParseError(len(elements), start, len(token), fmt.Errorf("more than %d values", maxInputElements))
+		}
+		for _, value := range expanded {
+			elements = append(elements, inputElement{text: value, quoted: quoted, offset: start, length: len(token)})
+		}
+	}
+	return elements, nil
+}
+
+// expand 处理 值*次数 和 起始..结束 语法
+func (p *inputParser) expand(text string, quoted bool, suffix string) ([]string, error) {
+	count := 1
+	if quoted {
+		// suffix 为引号之后的部分, 只能为空或 *N
+		if suffix != "" {
+			if !strings.HasPrefix(suffix, "*") {
+				return nil, fmt.Errorf("unexpected %q after quoted string", suffix)
+			}
+			n, err := parseRepeatCount(suffix[1:])
+			if err != nil {
+				return nil, err
+			}
+			count = n
+		}
+	} else if i := strings.LastIndexByte(text, '*'); i >= 0 {
+		n, err := parseRepeatCount(text[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		text, count = strings.TrimSpace(text[:i]), n
+		if text == "" {
+			return nil, fmt.Errorf("missing value before *")
+		}
+	}
+
+	values := []string{text}
+	if !quoted {
+		if from, to, ok := strings.Cut(text, ".."); ok {
+			rangeValues, err := expandRange(from, to)
+			if err != nil {
+				return nil, err
+			}
+			values = rangeValues
+		}
+	}
+
+	if count == 1 {
+		return values, nil
+	}
+	if len(values)*count > maxInputElements {
+		return nil, fmt.Errorf("more than %d values", maxInputElements)
+	}
+	repeated := make([]string, 0, len(values)*count)
+	for i := 0; i < count; i++ {
+		repeated = append(repeated, values...)
+	}
+	return repeated, nil
+}
+
+func parseRepeatCount(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > maxInputElements {
+		return 0, fmt.Errorf("invalid repeat count %q", s)
+	}
+	return n, nil
+}
+
+// expandRange 展开整数范围, 包含两端
+func expandRange(from, to string) ([]string, error) {
+	start, err := parseIntToken(strings.TrimSpace(from))
+	if err != nil {
+		return nil, fmt.Errorf("invalid range start %q", from)
+	}
+	end, err := parseIntToken(strings.TrimSpace(to))
+	if err != nil {
+		return nil, fmt.Errorf("invalid range end %q", to)
+	}
+
+	// 元素个数按 uint64 计算, 避免 end-start 超出 int64 时溢出绕过检查
+	step := int64(1)
+	span := uint64(end) - uint64(start)
+	if end < start {
+		step = -1
+		span = uint64(start) - uint64(end)
+	}
+	if span >= maxInputElements {
+		return nil, fmt.Errorf("range %d..%d has more than %d values", start, end, maxInputElements)
+	}
+	var values []string
+	for v := start; ; v += step {
+		values = append(values, strconv.FormatInt(v, 10))
+		if v == end {
+			break
+		}
+	}
+	return values, nil
+}
+
+// unquoteInput 解析从 start 开始的引号字符串, 返回内容和结束引号之后的位置
+func unquoteInput(s string, start int) (string, int, error) {
+	quote := s[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return sb.String(), i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '0':
+				sb.WriteByte(0)
+			case 'x':
+				if i+2 >= len(s) {
+					return "", 0, fmt.Errorf("invalid \\x escape")
+				}
+				b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid \\x escape")
+				}
+				sb.WriteByte(byte(b))
+				i += 2
+			default:
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// hasRadixPrefix 判断整数记号是否带 0x/0b/0o 前缀
+func hasRadixPrefix(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	if len(s) < 2 || s[0] != '0' {
+		return false
+	}
+	switch s[1] {
+	case 'x', 'X', 'b', 'B', 'o', 'O':
+		return true
+	}
+	return false
+}
+
+// parseIntToken 解析有符号整数记号, 十进制数的前导0不视为八进制
+func parseIntToken(s string) (int64, error) {
+	if hasRadixPrefix(s) {
+		return strconv.ParseInt(s, 0, 64)
+	}
+	v, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), 10, 64)
+	if err == nil {
+		return v, nil
+	}
+	f, ferr := parseIntegralFloat(s)
+	if ferr != nil || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, err
+	}
+	return int64(f), nil
+}
+
+// parseUintToken 解析无符号整数记号
+func parseUintToken(s string) (uint64, error) {
+	if hasRadixPrefix(s) {
+		return strconv.ParseUint(strings.TrimPrefix(s, "+"), 0, 64)
+	}
+	v, err := strconv.ParseUint(strings.ReplaceAll(strings.TrimPrefix(s, "+"), "_", ""), 10, 64)
+	if err == nil {
+		return v, nil
+	}
+	f, ferr := parseIntegralFloat(s)
+	if ferr != nil || f < 0 || f >= math.MaxUint64 {
+		return 0, err
+	}
+	return uint64(f), nil
+}
+
+// parseIntegralFloat 解析科学计数法表示的整数, 如 1e3
+func parseIntegralFloat(s string) (float64, error) {
+	if !strings.ContainsAny(s, "eE") {
+		return 0, strconv.ErrSyntax
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%s is not an integer", s)
+	}
+	return f, nil
+}
+
+// parseBoolToken 解析布尔记号
+func parseBoolToken(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "1", "on", "true", "t", "yes", "y":
+		return true, nil
+	case "0", "off", "false", "f", "no", "n":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid BOOL value: %s", s)
+}
+
+// parseInputElements 按数据类型拆分输入
+func parseInputElements(valueStr string, dataType DataType) ([]inputElement, *inputParser, error) {
+	p := &inputParser{input: valueStr, whitespace: !dataType.IsTimeType()}
+	elements, err := p.split()
+	if err != nil {
+		return nil, p, err
+	}
+	if len(elements) == 0 {
+		return nil, p, fmt.Errorf("value string is empty")
+	}
+	return elements, p, nil
+}
+
+// parseSignedElements 解析有符号整数元素并检查范围
+func parseSignedElements(valueStr string, dataType DataType, min, max int64) ([]int64, error) {
+	elements, p, err := parseInputElements(valueStr, dataType)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int64, len(elements))
+	for i, e := range elements {
+		v, err := parseIntToken(e.text)
+		if err != nil || e.quoted || v < min || v > max {
+			return nil, p.newParseError(i, e.offset, e.length, fmt.Errorf("invalid %s value: %s", dataType, e.text))
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseUnsignedElements 解析无符号整数元素并检查范围
+func parseUnsignedElements(valueStr string, dataType DataType, max uint64) ([]uint64, error) {
+	elements, p, err := parseInputElements(valueStr, dataType)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint64, len(elements))
+	for i, e := range elements {
+		v, err := parseUintToken(e.text)
+		if err != nil || e.quoted || v > max {
+			return nil, p.newParseError(i, e.offset, e.length, fmt.Errorf("invalid %s value: %s", dataType, e.text))
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseFloatElements 解析浮点元素, 超出 maxAbs 视为无效
+func parseFloatElements(valueStr string, dataType DataType, bitSize int, maxAbs float64) ([]float64, error) {
+	elements, p, err := parseInputElements(valueStr, dataType)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(elements))
+	for i, e := range elements {
+		text := e.text
+		var v float64
+		if hasRadixPrefix(text) {
+			// 0x/0b 前缀按整数解析, 便于输入 0x10 这样的值
+			var iv int64
+			iv, err = parseIntToken(text)
+			v = float64(iv)
+		} else {
+			v, err = strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), bitSize)
+		}
+		if err != nil || e.quoted || math.Abs(v) > maxAbs {
+			return nil, p.newParseError(i, e.offset, e.length, fmt.Errorf("invalid %s value: %s", dataType, text))
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseBoolElements 解析布尔元素
+func parseBoolElements(valueStr string) ([]bool, error) {
+	elements, p, err := parseInputElements(valueStr, BOOL)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]bool, len(elements))
+	for i, e := range elements {
+		v, err := parseBoolToken(e.text)
+		if err != nil || e.quoted {
+			return nil, p.newParseError(i, e.offset, e.length, fmt.Errorf("invalid BOOL value: %s", e.text))
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseTextElements 解析字符串或时间元素, 只有以引号开头的输入才按记号拆分
+func parseTextElements(valueStr string, dataType DataType) ([]string, bool, error) {
+	trimmed := strings.TrimSpace(valueStr)
+	if !dataType.IsTimeType() && !strings.HasPrefix(trimmed, "\"") && !strings.HasPrefix(trimmed, "'") {
+		return nil, false, nil
+	}
+	elements, _, err := parseInputElements(valueStr, dataType)
+	if err != nil {
+		return nil, false, err
+	}
+	values := make([]string, len(elements))
+	for i, e := range elements {
+		values[i] = e.text
+	}
+	return values, true, nil
+}
+
+// parseTimeElements 拆分时间元素并检查格式
+func parseTimeElements(valueStr string, dataType DataType) ([]string, error) {
+	elements, p, err := parseInputElements(valueStr, dataType)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(elements))
+	for i, e := range elements {
+		if _, err := ParseTime(e.text, dataType, time.UTC); err != nil {
+			return nil, p.newParseError(i, e.offset, e.length, fmt.Errorf("invalid %s value: %s", dataType, e.text))
+		}
+		values[i] = e.text
+	}
+	return values, nil
+}
@@ -0,0 +1,109 @@
+package datatypes
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseStringToType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dataType DataType
+		want     interface{}
+	}{
+		{"separators", "1, 2;3 4\n5", INT16, []int16{1, 2, 3, 4, 5}},
+		{"radix", "0x1F 0b1010 0o17 0xFF_FF", UINT16, []uint16{31, 10, 15, 0xFFFF}},
+		{"negative hex", "-0x10", INT32, []int32{-16}},
+		{"leading zero is decimal", "010", UINT16, []uint16{10}},
+		{"scientific", "1.5e3", INT32, []int32{1500}},
+		{"repeat", "7*3", UINT16, []uint16{7, 7, 7}},
+		{"range", "1..5", INT16, []int16{1, 2, 3, 4, 5}},
+		{"descending range", "3..-1", INT16, []int16{3, 2, 1, 0, -1}},
+		{"single value range", "4..4", UINT16, []uint16{4}},
+		{"hex range", "0x0A..0x0C", UINT16, []uint16{10, 11, 12}},
+		{"repeated range", "1..2*2", UINT16, []uint16{1, 2, 1, 2}},
+		{"range at int64 limit", "9223372036854775806..9223372036854775807", INT64, []int64{math.MaxInt64 - 1, math.MaxInt64}},
+		{"range at int64 minimum", "-9223372036854775807..-9223372036854775808", INT64, []int64{math.MinInt64 + 1, math.MinInt64}},
+		{"uint64 max", "0xFFFFFFFFFFFFFFFF", UINT64, []uint64{math.MaxUint64}},
+		{"float", "1.5, -2e-3, 0x10", FLOAT32, []float32{1.5, -2e-3, 16}},
+		{"bool", "on off true 0 1", BOOL, []bool{true, false, true, false, true}},
+		{"ascii as is", "a,b c", ASCII, "a,b c"},
+		{"quoted ascii", `"a,b" 'c'`, ASCII, "a,bc"},
+		{"quoted strings", `"x,y"*2 "\x41\n"`, STRING_UTF8, []string{"x,y", "x,y", "A\n"}},
+		{"string lines", "one\ntwo", STRING_UTF8, []string{"one", "two"}},
+		{"time keeps spaces", "2024-01-02 03:04:05; now", UNIX_TIMESTAMP, []string{"2024-01-02 03:04:05", "now"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStringToType(tt.input, tt.dataType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStringToType(%q, %s) = %#v, want %#v", tt.input, tt.dataType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStringToTypeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dataType DataType
+		index    int
+		line     int
+		column   int
+		token    string
+	}{
+		{"out of range", "1, 70000", UINT16, 1, 1, 4, "70000"},
+		{"second line", "1\n 2 x3", INT16, 2, 2, 4, "x3"},
+		{"after range", "1..3, bad", INT16, 3, 1, 7, "bad"},
+		{"range out of type range", "65534..65536", UINT16, 2, 1, 1, "65534..65536"},
+		{"bad repeat", "1*0", UINT16, 0, 1, 1, "1*0"},
+		{"missing repeat value", "*3", UINT16, 0, 1, 1, "*3"},
+		{"bad range end", "1..x", UINT16, 0, 1, 1, "1..x"},
+		{"too many values", "0..65536", UINT16, 0, 1, 1, "0..65536"},
+		{"too many repeats", "1..300*300", UINT16, 0, 1, 1, "1..300*300"},
+		{"overflowing range", "-9223372036854775808..9223372036854775807", INT64, 0, 1, 1, "-9223372036854775808..9223372036854775807"},
+		{"overflowing descending range", "9223372036854775807..-9223372036854775808", INT64, 0, 1, 1, "9223372036854775807..-9223372036854775808"},
+		{"non-integer", "1.5", INT32, 0, 1, 1, "1.5"},
+		{"unterminated string", `"abc`, STRING_UTF8, 0, 1, 1, `"abc`},
+		{"text after quote", `"a"b`, STRING_UTF8, 0, 1, 1, `"a"b`},
+		{"unicode column", "中文, x", INT16, 0, 1, 1, "中文"},
+		{"unicode column after", "1, 中文", INT16, 1, 1, 4, "中文"},
+		{"bool", "on, maybe", BOOL, 1, 1, 5, "maybe"},
+		{"time", "2024-01-02; 2024-99-01", UNIX_TIMESTAMP, 1, 1, 13, "2024-99-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() {
+				_, err := ParseStringToType(tt.input, tt.dataType)
+				done <- err
+			}()
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("ParseStringToType(%q) did not return", tt.input)
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseStringToType(%q) error = %v, want *ParseError", tt.input, err)
+			}
+			if parseErr.Index != tt.index || parseErr.Line != tt.line || parseErr.Column != tt.column || parseErr.Token != tt.token {
+				t.Errorf("error at index %d, line %d, column %d, token %q; want %d, %d, %d, %q (%v)",
+					parseErr.Index, parseErr.Line, parseErr.Column, parseErr.Token, tt.index, tt.line, tt.column, tt.token, err)
+			}
+			if got := tt.input[parseErr.Offset : parseErr.Offset+parseErr.Length]; got != parseErr.Token {
+				t.Errorf("Offset/Length select %q, want %q", got, parseErr.Token)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package datatypes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// sunSpecSFNotImplemented SunSpec 中未实现的比例因子寄存器值
+const sunSpecSFNotImplemented = 0x8000
+
+// Scaling 原始值与工程值之间的线性换算: 工程值 = 原始值 × Scale × 10^ScaleFactor + Offset
+type Scaling struct {
+	Scale       float64  `json:"scale,omitempty"`        // 线性系数, 0 视为 1
+	Offset      float64  `json:"offset,omitempty"`       // 偏移量
+	ScaleFactor int      `json:"scale_factor,omitempty"` // 10的幂次比例因子, 如 SunSpec 的 *_SF
+	SFRegister  *uint16  `json:"sf_register,omitempty"`  // 比例因子所在寄存器地址, 读取后覆盖 ScaleFactor
+	Min         *float64 `json:"min,omitempty"`          // 工程值下限, 超出时截断
+	Max         *float64 `json:"max,omitempty"`          // 工程值上限, 超出时截断
+	Unit        string   `json:"unit,omitempty"`         // 工程单位, 如 kWh、°C
+}
+
+// IsIdentity 判断换算是否不改变数值 (忽略单位)
+func (s Scaling) IsIdentity() bool {
+	return s.factor() == 1 && s.Offset == 0 && s.SFRegister == nil && s.Min == nil && s.Max == nil
+}
+
+// factor 返回总的乘数
+func (s Scaling) factor() float64 {
+	scale := s.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	if s.ScaleFactor != 0 {
+		scale *= math.Pow10(s.ScaleFactor)
+	}
+	return scale
+}
+
+// WithScaleFactorRegister 使用从比例因子寄存器读到的值设置 ScaleFactor
+func (s Scaling) WithScaleFactorRegister(reg uint16) (Scaling, error) {
+	if reg == sunSpecSFNotImplemented {
+		return s, fmt.Errorf("scale factor register not implemented (0x8000)")
+	}
+	sf := int(int16(reg))
+	if sf < -10 || sf > 10 {
+		return s, fmt.Errorf("scale factor %d out of range [-10, 10]", sf)
+	}
+	s.ScaleFactor = sf
+	return s, nil
+}
+
+// Apply 原始值换算为工程值
+func (s Scaling) Apply(raw float64) float64 {
+	return s.clamp(raw*s.factor() + s.Offset)
+}
+
+// Inverse 工程值换算为原始值, 超出上下限的工程值先截断
+func (s Scaling) Inverse(value float64) float64 {
+	return (s.clamp(value) - s.Offset) / s.factor()
+}
+
+func (s Scaling) clamp(value float64) float64 {
+	if s.Min != nil && value < *s.Min {
+		return *s.Min
+	}
+	if s.Max != nil && value > *s.Max {
+		return *s.Max
+	}
+	return value
+}
+
+// ApplyValues 将 ConvertFromRegisters 返回的数值切片换算为工程值
+func (s Scaling) ApplyValues(values interface{}) ([]float64, error) {
+	raw, ok := ToFloat64Slice(values)
+	if !ok {
+		return nil, fmt.Errorf("cannot scale values of type %T", values)
+	}
+	result := make([]float64, len(raw))
+	for i, v := range raw {
+		result[i] = s.Apply(v)
+	}
+	return result, nil
+}
+
+// InverseValues 将工程值换算为原始值, 并转换为写入 dataType 所需的切片类型
+func (s Scaling) InverseValues(values []float64, dataType DataType) (interface{}, error) {
+	raw := make([]float64, len(values))
+	for i, v := range values {
+		raw[i] = s.Inverse(v)
+	}
+	return FromFloat64Slice(raw, dataType)
+}
+
+// Format 格式化工程值并附加单位
+func (s Scaling) Format(value float64) string {
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	if s.Unit != "" {
+		return text + " " + s.Unit
+	}
+	return text
+}
+
+// ToFloat64Slice 将数值切片转换为 []float64, 非数值类型返回 false
+func ToFloat64Slice(values interface{}) ([]float64, bool) {
+	var result []float64
+	switch v := values.(type) {
+	case []int8:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []uint8:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []int16:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []uint16:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []int32:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []uint32:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []int64:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []uint64:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []float32:
+		for _, val := range v {
+			result = append(result, float64(val))
+		}
+	case []float64:
+		result = append(result, v...)
+	case []bool:
+		for _, val := range v {
+			if val {
+				result = append(result, 1)
+			} else {
+				result = append(result, 0)
+			}
+		}
+	default:
+		return nil, false
+	}
+	return result, true
+}
+
+// FromFloat64Slice 将 []float64 转换为 dataType 对应的切片类型 (与 ParseStringToType 的返回类型一致),
+// 整数类型四舍五入并检查范围
+func FromFloat64Slice(values []float64, dataType DataType) (interface{}, error) {
+	toInt := func(v float64, min, max float64) (float64, error) {
+		r := math.Round(v)
+		if math.IsNaN(v) || r < min || r > max {
+			return 0, fmt.Errorf("value %v out of %s range", v, dataType)
+		}
+		return r, nil
+	}
+
+	switch dataType {
+	case INT8_HIGH, INT8_LOW:
+		result := make([]int8, len(values))
+		for i, v := range values {
+			r, err := toInt(v, math.MinInt8, math.MaxInt8)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = int8(r)
+		}
+		return result, nil
+	case UINT8_HIGH, UINT8_LOW:
+		result := make([]uint8, len(values))
+		for i, v := range values {
+			r, err := toInt(v, 0, math.MaxUint8)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = uint8(r)
+		}
+		return result, nil
+	case INT16, SM_INT16:
+		min := float64(math.MinInt16)
+		if dataType == SM_INT16 {
+			min = -math.MaxInt16
+		}
+		result := make([]int16, len(values))
+		for i, v := range values {
+			r, err := toInt(v, min, math.MaxInt16)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = int16(r)
+		}
+		return result, nil
+	case UINT16, BCD16:
+		max := float64(math.MaxUint16)
+		if dataType == BCD16 {
+			max = 9999
+		}
+		result := make([]uint16, len(values))
+		for i, v := range values {
+			r, err := toInt(v, 0, max)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = uint16(r)
+		}
+		return result, nil
+	case INT32, SM_INT32:
+		min := float64(math.MinInt32)
+		if dataType == SM_INT32 {
+			min = -math.MaxInt32
+		}
+		result := make([]int32, len(values))
+		for i, v := range values {
+			r, err := toInt(v, min, math.MaxInt32)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = int32(r)
+		}
+		return result, nil
+	case UINT32, BCD32:
+		max := float64(math.MaxUint32)
+		if dataType == BCD32 {
+			max = 99999999
+		}
+		result := make([]uint32, len(values))
+		for i, v := range values {
+			r, err := toInt(v, 0, max)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = uint32(r)
+		}
+		return result, nil
+	case INT48, INT64:
+		min, max := float64(minInt48), float64(maxInt48)
+		if dataType == INT64 {
+			// float64 无法精确表示 MaxInt64, 取其下方最近的可表示值
+			min, max = math.MinInt64, math.Nextafter(math.MaxInt64, 0)
+		}
+		result := make([]int64, len(values))
+		for i, v := range values {
+			r, err := toInt(v, min, max)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = int64(r)
+		}
+		return result, nil
+	case UINT48, UINT64:
+		max := float64(maxUint48)
+		if dataType == UINT64 {
+			max = math.Nextafter(math.MaxUint64, 0)
+		}
+		result := make([]uint64, len(values))
+		for i, v := range values {
+			r, err := toInt(v, 0, max)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = uint64(r)
+		}
+		return result, nil
+	case FLOAT16, FLOAT32:
+		result := make([]float32, len(values))
+		for i, v := range values {
+			result[i] = float32(v)
+		}
+		return result, nil
+	case FLOAT64:
+		return append([]float64(nil), values...), nil
+	case BOOL:
+		result := make([]bool, len(values))
+		for i, v := range values {
+			result[i] = v != 0
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("data type %s does not support scaling", dataType)
+	}
+}
@@ -0,0 +1,173 @@
+package datatypes
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+
+func TestScalingApplyInverse(t *testing.T) {
+	tests := []struct {
+		name    string
+		scaling Scaling
+		raw     float64
+		value   float64
+	}{
+		{"identity", Scaling{}, 123, 123},
+		{"scale", Scaling{Scale: 0.1}, 235, 23.5},
+		{"offset", Scaling{Scale: 0.5, Offset: -40}, 100, 10},
+		{"scale factor", Scaling{ScaleFactor: -2}, 12345, 123.45},
+		{"scale and scale factor", Scaling{Scale: 2, ScaleFactor: 3}, 7, 14000},
+		{"negative raw", Scaling{Scale: 0.01, Offset: 1}, -250, -1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scaling.Apply(tt.raw); math.Abs(got-tt.value) > 1e-9 {
+				t.Errorf("Apply(%v) = %v, want %v", tt.raw, got, tt.value)
+			}
+			if got := tt.scaling.Inverse(tt.value); math.Abs(got-tt.raw) > 1e-9 {
+				t.Errorf("Inverse(%v) = %v, want %v", tt.value, got, tt.raw)
+			}
+		})
+	}
+}
+
+// TestScalingClamp 工程值超出上下限时截断, 写入时先截断再换算为原始值
+func TestScalingClamp(t *testing.T) {
+	s := Scaling{Scale: 0.1, Min: float64Ptr(0), Max: float64Ptr(100)}
+	tests := []struct {
+		raw, applied   float64
+		value, inverse float64
+	}{
+		{-50, 0, -5, 0},
+		{500, 50, 50, 500},
+		{1000, 100, 100, 1000},
+		{5000, 100, 150, 1000},
+	}
+	for _, tt := range tests {
+		if got := s.Apply(tt.raw); math.Abs(got-tt.applied) > 1e-9 {
+			t.Errorf("Apply(%v) = %v, want %v", tt.raw, got, tt.applied)
+		}
+		if got := s.Inverse(tt.value); math.Abs(got-tt.inverse) > 1e-9 {
+			t.Errorf("Inverse(%v) = %v, want %v", tt.value, got, tt.inverse)
+		}
+	}
+	if s.IsIdentity() || !(Scaling{Unit: "kWh"}).IsIdentity() || (Scaling{Max: float64Ptr(1)}).IsIdentity() {
+		t.Error("IsIdentity wrong")
+	}
+}
+
+func TestWithScaleFactorRegister(t *testing.T) {
+	tests := []struct {
+		reg     uint16
+		want    int
+		wantErr string
+	}{
+		{0x0000, 0, ""},
+		{0xFFFE, -2, ""},
+		{0x0003, 3, ""},
+		{0xFFF6, -10, ""},
+		{0x000A, 10, ""},
+		{0x8000, 0, "not implemented"},
+		{0xFFF5, 0, "scale factor -11 out of range"},
+		{0x000B, 0, "scale factor 11 out of range"},
+		{0x7FFF, 0, "out of range"},
+	}
+	base := Scaling{Scale: 2, ScaleFactor: 1}
+	for _, tt := range tests {
+		s, err := base.WithScaleFactorRegister(tt.reg)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("0x%04X: err = %v, want %q", tt.reg, err, tt.wantErr)
+			}
+			if s != base {
+				t.Errorf("0x%04X: scaling changed to %+v on error", tt.reg, s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("0x%04X: %v", tt.reg, err)
+			continue
+		}
+		if s.ScaleFactor != tt.want || s.Scale != 2 {
+			t.Errorf("0x%04X: scaling = %+v, want scale factor %d", tt.reg, s, tt.want)
+		}
+	}
+}
+
+func TestFromFloat64Slice(t *testing.T) {
+	tests := []struct {
+		dataType DataType
+		values   []float64
+		want     interface{}
+		wantErr  bool
+	}{
+		{INT8_HIGH, []float64{-128, 127.4}, []int8{-128, 127}, false},
+		{INT8_LOW, []float64{127.5}, nil, true},
+		{UINT8_LOW, []float64{255, 0.4}, []uint8{255, 0}, false},
+		{UINT8_HIGH, []float64{-0.6}, nil, true},
+		{INT16, []float64{-32768, 32767}, []int16{-32768, 32767}, false},
+		{INT16, []float64{32768}, nil, true},
+		{SM_INT16, []float64{-32767}, []int16{-32767}, false},
+		{SM_INT16, []float64{-32768}, nil, true},
+		{UINT16, []float64{65535, 1.5}, []uint16{65535, 2}, false},
+		{UINT16, []float64{-1}, nil, true},
+		{BCD16, []float64{9999}, []uint16{9999}, false},
+		{BCD16, []float64{10000}, nil, true},
+		{INT32, []float64{math.MinInt32, math.MaxInt32}, []int32{math.MinInt32, math.MaxInt32}, false},
+		{SM_INT32, []float64{math.MinInt32}, nil, true},
+		{UINT32, []float64{math.MaxUint32}, []uint32{math.MaxUint32}, false},
+		{BCD32, []float64{99999999}, []uint32{99999999}, false},
+		{BCD32, []float64{100000000}, nil, true},
+		{INT48, []float64{minInt48, maxInt48}, []int64{minInt48, maxInt48}, false},
+		{INT48, []float64{maxInt48 + 1}, nil, true},
+		{UINT48, []float64{maxUint48}, []uint64{maxUint48}, false},
+		{UINT48, []float64{maxUint48 + 1}, nil, true},
+		{INT64, []float64{math.MinInt64}, []int64{math.MinInt64}, false},
+		{INT64, []float64{math.MaxInt64}, nil, true},
+		{UINT64, []float64{math.MaxUint64}, nil, true},
+		{UINT64, []float64{1 << 63}, []uint64{1 << 63}, false},
+		{UINT32, []float64{math.NaN()}, nil, true},
+		{INT32, []float64{math.Inf(1)}, nil, true},
+		{FLOAT32, []float64{1.5}, []float32{1.5}, false},
+		{FLOAT16, []float64{-2}, []float32{-2}, false},
+		{FLOAT64, []float64{math.Pi}, []float64{math.Pi}, false},
+		{BOOL, []float64{0, 2}, []bool{false, true}, false},
+		{ASCII, []float64{1}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := FromFloat64Slice(tt.values, tt.dataType)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %v: err = %v", tt.dataType, tt.values, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %v = %v, want %v", tt.dataType, tt.values, got, tt.want)
+		}
+	}
+}
+
+// TestScalingValues 读取时按寄存器类型换算, 写入时换算回 dataType 所需的类型
+func TestScalingValues(t *testing.T) {
+	s := Scaling{ScaleFactor: -1, Unit: "V"}
+	values, err := s.ApplyValues([]uint16{2305, 0})
+	if err != nil || !reflect.DeepEqual(values, []float64{230.5, 0}) {
+		t.Errorf("ApplyValues = %v, %v", values, err)
+	}
+	if _, err := s.ApplyValues([]string{"a"}); err == nil {
+		t.Error("ApplyValues([]string) succeeded, want an error")
+	}
+	raw, err := s.InverseValues([]float64{230.5}, UINT16)
+	if err != nil || !reflect.DeepEqual(raw, []uint16{2305}) {
+		t.Errorf("InverseValues = %v, %v", raw, err)
+	}
+	if _, err := s.InverseValues([]float64{7000}, UINT16); err == nil {
+		t.Error("InverseValues out of range succeeded, want an error")
+	}
+	if got := s.Format(230.5); got != "230.5 V" {
+		t.Errorf("Format = %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package datatypes
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 字符串类型的转换。寄存器按字节序展开为字节流 (BA 即每个寄存器内字节交换), 再按字符编码解码。
+// GB2312 是 GBK 的子集, 使用 STRING_GBK 读写。
+
+// StringPadding 字符串字段的填充字符
+type StringPadding int
+
+const (
+	PadNUL   StringPadding = iota // 以 0x00 填充
+	PadSpace                      // 以空格填充
+)
+
+func (p StringPadding) String() string {
+	if p == PadSpace {
+		return "SPACE"
+	}
+	return "NUL"
+}
+
+// ParseStringPadding 解析填充字符名称
+func ParseStringPadding(s string) (StringPadding, error) {
+	if s == " " {
+		return PadSpace, nil
+	}
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", "NUL", "NULL", "0":
+		return PadNUL, nil
+	case "SPACE":
+		return PadSpace, nil
+	default:
+		return PadNUL, fmt.Errorf("unknown string padding: %s", s)
+	}
+}
+
+// StringOptions 字符串字段设置
+type StringOptions struct {
+	FieldLength int           `json:"field_length,omitempty"` // 每个字段的寄存器数量, 0 表示整段读取为一个字符串
+	Padding     StringPadding `json:"padding,omitempty"`
+}
+
+// IsStringType 判断数据类型是否为带编码的字符串类型
+func (dt DataType) IsStringType() bool {
+	switch dt {
+	case STRING_UTF8, STRING_UTF16BE, STRING_UTF16LE, STRING_GBK, STRING_SHIFT_JIS:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetStringOptions 设置字符串类型的字段长度和填充方式
+func (c *Converter) SetStringOptions(opts StringOptions) {
+	c.stringOptions = opts
+}
+
+// StringOptions 返回字符串类型的字段设置
+func (c *Converter) StringOptions() StringOptions {
+	return c.stringOptions
+}
+
+// textEncoding 返回数据类型对应的字符编码, UTF-8 返回 nil
+func textEncoding(dataType DataType) encoding.Encoding {
+	switch dataType {
+	case STRING_UTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case STRING_UTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case STRING_GBK:
+		return simplifiedchinese.GBK
+	case STRING_SHIFT_JIS:
+		return japanese.ShiftJIS
+	default:
+		return nil
+	}
+}
+
+// convertToStrings 将寄存器按字段长度拆分并解码为字符串
+func (c *Converter) convertToStrings(registers []uint16, dataType DataType) ([]string, error) {
+	fieldLength := c.stringOptions.FieldLength
+	if fieldLength <= 0 || fieldLength > len(registers) {
+		fieldLength = len(registers)
+	}
+
+	var result []string
+	for i := 0; i+fieldLength <= len(registers); i += fieldLength {
+		text, err := c.decodeText(c.registersToBytes(registers[i:i+fieldLength]), dataType)
+		if err != nil {
+			return nil, fmt.Errorf("register %d: %w", i, err)
+		}
+		result = append(result, text)
+	}
+	return result, nil
+}
+
+// decodeText 解码一个字段, 截断到第一个NUL并去掉尾部填充
+func (c *Converter) decodeText(data []byte, dataType DataType) (string, error) {
+	var text string
+	if enc := textEncoding(dataType); enc != nil {
+		decoded, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", err
+		}
+		text = string(decoded)
+	} else {
+		text = strings.ToValidUTF8(string(data), string(utf8.RuneError))
+	}
+
+	if i := strings.IndexByte(text, 0); i >= 0 {
+		text = text[:i]
+	}
+	if c.stringOptions.Padding == PadSpace {
+		text = strings.TrimRight(text, " ")
+	}
+	return text, nil
+}
+
+// stringsToRegisters 编码字符串并填充到字段长度, 每个字符串占一个字段
+func (c *Converter) stringsToRegisters(value interface{}, dataType DataType) ([]uint16, error) {
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = []string{v}
+	case []string:
+		values = v
+	default:
+		return nil, typeMismatch(value, dataType)
+	}
+
+	var registers []uint16
+	for _, s := range values {
+		data, err := c.encodeText(s, dataType)
+		if err != nil {
+			return nil, err
+		}
+		registers = append(registers, c.bytesToRegisters(data)...)
+	}
+	return registers, nil
+}
+
+// encodeText 编码一个字段并按填充方式补齐
+func (c *Converter) encodeText(s string, dataType DataType) ([]byte, error) {
+	pad := []byte{0}
+	if c.stringOptions.Padding == PadSpace {
+		pad = []byte{' '}
+	}
+
+	data := []byte(s)
+	if enc := textEncoding(dataType); enc != nil {
+		var err error
+		if data, err = enc.NewEncoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("cannot encode %q as %s: %w", s, dataType, err)
+		}
+		if pad, err = enc.NewEncoder().Bytes(pad); err != nil {
+			return nil, err
+		}
+	}
+
+	size := c.stringOptions.FieldLength * 2
+	if size == 0 {
+		size = len(data) + len(data)%2
+	}
+	if len(data) > size {
+		return nil, fmt.Errorf("%q needs %d bytes, field is %d bytes", s, len(data), size)
+	}
+	for len(data) < size {
+		data = append(data, pad...)
+	}
+	return data[:size], nil
+}
@@ -0,0 +1,126 @@
+package datatypes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		dataType  DataType
+		byteOrder ByteOrder
+		opts      StringOptions
+		value     []string
+		registers []uint16
+	}{
+		{"utf8", STRING_UTF8, AB, StringOptions{}, []string{"AB€"}, []uint16{0x4142, 0xE282, 0xAC00}},
+		{"gbk", STRING_GBK, AB, StringOptions{FieldLength: 3}, []string{"中文"}, []uint16{0xD6D0, 0xCEC4, 0x0000}},
+		{"shift-jis", STRING_SHIFT_JIS, AB, StringOptions{FieldLength: 2}, []string{"日本"}, []uint16{0x93FA, 0x967B}},
+		{"shift-jis half-width", STRING_SHIFT_JIS, AB, StringOptions{FieldLength: 2}, []string{"ｱA"}, []uint16{0xB141, 0x0000}},
+		{"utf16be", STRING_UTF16BE, AB, StringOptions{FieldLength: 3}, []string{"Aé"}, []uint16{0x0041, 0x00E9, 0x0000}},
+		{"utf16le", STRING_UTF16LE, AB, StringOptions{FieldLength: 2}, []string{"中"}, []uint16{0x2D4E, 0x0000}},
+		{"utf16 surrogate pair", STRING_UTF16BE, AB, StringOptions{}, []string{"😀"}, []uint16{0xD83D, 0xDE00}},
+		{"space padding", STRING_UTF8, AB, StringOptions{FieldLength: 3, Padding: PadSpace}, []string{"ab"}, []uint16{0x6162, 0x2020, 0x2020}},
+		{"utf16 space padding", STRING_UTF16BE, AB, StringOptions{FieldLength: 2, Padding: PadSpace}, []string{"A"}, []uint16{0x0041, 0x0020}},
+		{"gbk space padding", STRING_GBK, AB, StringOptions{FieldLength: 2, Padding: PadSpace}, []string{"中"}, []uint16{0xD6D0, 0x2020}},
+		{"ba byte swap", STRING_UTF8, BA, StringOptions{FieldLength: 2}, []string{"abc"}, []uint16{0x6261, 0x0063}},
+		{"ba gbk", STRING_GBK, BA, StringOptions{FieldLength: 1}, []string{"中"}, []uint16{0xD0D6}},
+		{"several fields", STRING_UTF8, AB, StringOptions{FieldLength: 1}, []string{"ab", "c", ""}, []uint16{0x6162, 0x6300, 0x0000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConverter(tt.byteOrder, WORD_1234)
+			c.SetStringOptions(tt.opts)
+			registers, err := c.ConvertToRegistersAs(tt.value, tt.dataType)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !reflect.DeepEqual(registers, tt.registers) {
+				t.Errorf("encode = %04X, want %04X", registers, tt.registers)
+			}
+			decoded, err := c.ConvertFromRegisters(tt.registers, tt.dataType)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.value) {
+				t.Errorf("decode = %q, want %q", decoded, tt.value)
+			}
+		})
+	}
+}
+
+// TestStringDecodePadding NUL 处截断; 只有空格填充时才去掉尾部空格
+func TestStringDecodePadding(t *testing.T) {
+	registers := []uint16{0x6120, 0x2000, 0x7A7A}
+	tests := []struct {
+		padding StringPadding
+		want    []string
+	}{
+		{PadNUL, []string{"a  "}},
+		{PadSpace, []string{"a"}},
+	}
+	for _, tt := range tests {
+		c := NewConverter(AB, WORD_1234)
+		c.SetStringOptions(StringOptions{Padding: tt.padding})
+		decoded, err := c.ConvertFromRegisters(registers, STRING_UTF8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, tt.want) {
+			t.Errorf("%s: decode = %q, want %q", tt.padding, decoded, tt.want)
+		}
+	}
+
+	// 字段长度超过寄存器数时整段作为一个字段, 不足一个字段的剩余寄存器被忽略
+	c := NewConverter(AB, WORD_1234)
+	c.SetStringOptions(StringOptions{FieldLength: 2})
+	decoded, err := c.ConvertFromRegisters([]uint16{0x6162, 0x6364, 0x6500}, STRING_UTF8)
+	if err != nil || !reflect.DeepEqual(decoded, []string{"abcd"}) {
+		t.Errorf("decode = %q, %v", decoded, err)
+	}
+	c.SetStringOptions(StringOptions{FieldLength: 10})
+	decoded, err = c.ConvertFromRegisters([]uint16{0x6162, 0x6300}, STRING_UTF8)
+	if err != nil || !reflect.DeepEqual(decoded, []string{"abc"}) {
+		t.Errorf("decode = %q, %v", decoded, err)
+	}
+}
+
+func TestStringEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType DataType
+		opts     StringOptions
+		value    interface{}
+		wantErr  string
+	}{
+		{"longer than field", STRING_UTF8, StringOptions{FieldLength: 2}, "abcde", "needs 5 bytes, field is 4 bytes"},
+		{"gbk longer than field", STRING_GBK, StringOptions{FieldLength: 1}, "中文", "needs 4 bytes, field is 2 bytes"},
+		{"utf16 longer than field", STRING_UTF16BE, StringOptions{FieldLength: 1}, "ab", "needs 4 bytes, field is 2 bytes"},
+		{"not representable in gbk", STRING_GBK, StringOptions{}, "日本語😀", "cannot encode"},
+		{"not representable in shift-jis", STRING_SHIFT_JIS, StringOptions{}, "中文简体", "cannot encode"},
+		{"wrong value type", STRING_UTF8, StringOptions{}, []int{1}, "unsupported value type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConverter(AB, WORD_1234)
+			c.SetStringOptions(tt.opts)
+			_, err := c.ConvertToRegistersAs(tt.value, tt.dataType)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseStringPadding(t *testing.T) {
+	for text, want := range map[string]StringPadding{"": PadNUL, "nul": PadNUL, "NULL": PadNUL, "Space": PadSpace, " ": PadSpace} {
+		if got, err := ParseStringPadding(text); err != nil || got != want {
+			t.Errorf("ParseStringPadding(%q) = %s, %v", text, got, err)
+		}
+	}
+	if _, err := ParseStringPadding("tab"); err == nil {
+		t.Error("ParseStringPadding(tab) succeeded, want an error")
+	}
+}
@@ -0,0 +1,280 @@
+package datatypes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 时间类型的转换。纪元时间 (UNIX_TIMESTAMP 等) 表示绝对时刻, 按转换器时区显示;
+// BCD、分寄存器和 CP56Time2a 保存的是设备本地的年月日时分秒, 按转换器时区解释。
+
+const (
+	timeLayout   = "2006-01-02 15:04:05"
+	timeLayoutMs = "2006-01-02 15:04:05.000"
+)
+
+// inputTimeLayouts 写入时接受的时间格式
+var inputTimeLayouts = []string{
+	time.RFC3339Nano,
+	timeLayoutMs,
+	timeLayout,
+	"2006-01-02T15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02",
+}
+
+// IsTimeType 判断数据类型是否为时间类型
+func (dt DataType) IsTimeType() bool {
+	switch dt {
+	case UNIX_TIMESTAMP, UNIX_TIMESTAMP64, UNIX_TIMESTAMP_MS, BCD_DATETIME, DATETIME_REGS, CP56TIME2A:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseLocation 解析时区: Local、UTC、IANA名称 (Asia/Shanghai) 或固定偏移 (UTC+8、+08:00)
+func ParseLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	switch strings.ToUpper(name) {
+	case "", "LOCAL":
+		return time.Local, nil
+	case "UTC", "GMT", "Z":
+		return time.UTC, nil
+	}
+
+	offset := strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(name), "UTC"), "GMT")
+	if offset != "" && (offset[0] == '+' || offset[0] == '-') {
+		sign := 1
+		if offset[0] == '-' {
+			sign = -1
+		}
+		hours, minutes := offset[1:], "0"
+		if h, m, ok := strings.Cut(hours, ":"); ok {
+			hours, minutes = h, m
+		} else if len(hours) == 4 {
+			hours, minutes = hours[:2], hours[2:]
+		}
+		h, err1 := strconv.Atoi(hours)
+		m, err2 := strconv.Atoi(minutes)
+		if err1 != nil || err2 != nil || h > 14 || m > 59 {
+			return nil, fmt.Errorf("invalid time zone offset: %s", name)
+		}
+		return time.FixedZone(name, sign*(h*3600+m*60)), nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// SetLocation 设置时间类型使用的时区, nil 表示本地时区
+func (c *Converter) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.Local
+	}
+	c.location = loc
+}
+
+// Location 返回时间类型使用的时区
+func (c *Converter) Location() *time.Location {
+	if c.location == nil {
+		return time.Local
+	}
+	return c.location
+}
+
+// ParseTime 按时区解析写入 dataType 的时间字符串。支持 "now"、纪元时间、带时区的 RFC3339 以及不带时区的常用格式。
+// 纪元时间与寄存器中的单位一致: UNIX_TIMESTAMP_MS 为毫秒, 其他类型为秒
+func ParseTime(s string, dataType DataType, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "now") {
+		return time.Now(), nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if dataType == UNIX_TIMESTAMP_MS {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range inputTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", s)
+}
+
+// convertToTimes 将寄存器解码为时间字符串, 多个值以逗号分隔
+func (c *Converter) convertToTimes(registers []uint16, dataType DataType) (string, error) {
+	n := dataType.RegistersPerValue()
+	if len(registers) < n {
+		return "", fmt.Errorf("%s needs %d registers, got %d", dataType, n, len(registers))
+	}
+
+	var values []string
+	for i := 0; i+n <= len(registers); i += n {
+		text, err := c.decodeTime(registers[i:i+n], dataType)
+		if err != nil {
+			return "", fmt.Errorf("register %d: %w", i, err)
+		}
+		values = append(values, text)
+	}
+	return strings.Join(values, ","), nil
+}
+
+func (c *Converter) decodeTime(registers []uint16, dataType DataType) (string, error) {
+	loc := c.Location()
+	switch dataType {
+	case UNIX_TIMESTAMP:
+		return time.Unix(int64(uint32(c.registersToUint64(registers))), 0).In(loc).Format(timeLayout), nil
+	case UNIX_TIMESTAMP64:
+		return time.Unix(int64(c.registersToUint64(registers)), 0).In(loc).Format(timeLayout), nil
+	case UNIX_TIMESTAMP_MS:
+		return time.UnixMilli(int64(c.registersToUint64(registers))).In(loc).Format(timeLayoutMs), nil
+	case BCD_DATETIME:
+		// YY MM | DD hh | mm ss, 每个字节两位BCD
+		bytes := c.registersToBytes(registers)
+		var fields [6]int
+		for i, b := range bytes {
+			v, err := decodeBCD(uint64(b), 2)
+			if err != nil {
+				return "", err
+			}
+			fields[i] = int(v)
+		}
+		return formatFields(2000+fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], 0, loc, timeLayout)
+	case DATETIME_REGS:
+		// 年 月 日 时 分 秒 各占一个寄存器, 两位年份按 20xx 处理
+		var fields [6]int
+		for i, reg := range registers {
+			fields[i] = int(c.swapBytes(reg))
+		}
+		year := fields[0]
+		if year < 100 {
+			year += 2000
+		}
+		return formatFields(year, fields[1], fields[2], fields[3], fields[4], fields[5], 0, loc, timeLayout)
+	case CP56TIME2A:
+		b := c.registersToBytes(registers)
+		ms := int(b[0]) | int(b[1])<<8
+		text, err := formatFields(2000+int(b[6]&0x7F), int(b[5]&0x0F), int(b[4]&0x1F), int(b[3]&0x1F), int(b[2]&0x3F), ms/1000, ms%1000, loc, timeLayoutMs)
+		if err == nil && b[2]&0x80 != 0 {
+			text += " IV" // 时标无效
+		}
+		return text, err
+	default:
+		return "", fmt.Errorf("not a time data type: %s", dataType)
+	}
+}
+
+// formatFields 校验年月日时分秒并按时区格式化
+func formatFields(year, month, day, hour, minute, second, ms int, loc *time.Location, layout string) (string, error) {
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return "", fmt.Errorf("invalid date/time %04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, minute, second)
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, second, ms*int(time.Millisecond), loc)
+	if t.Day() != day {
+		return "", fmt.Errorf("invalid date %04d-%02d-%02d", year, month, day)
+	}
+	return t.Format(layout), nil
+}
+
+// timesToRegisters 将时间字符串编码为寄存器
+func (c *Converter) timesToRegisters(value interface{}, dataType DataType) ([]uint16, error) {
+	values, ok := value.([]string)
+	if !ok {
+		return nil, typeMismatch(value, dataType)
+	}
+
+	var registers []uint16
+	for _, s := range values {
+		t, err := ParseTime(s, dataType, c.Location())
+		if err != nil {
+			return nil, err
+		}
+		regs, err := c.encodeTime(t, dataType)
+		if err != nil {
+			return nil, err
+		}
+		registers = append(registers, regs...)
+	}
+	return registers, nil
+}
+
+func (c *Converter) encodeTime(t time.Time, dataType DataType) ([]uint16, error) {
+	switch dataType {
+	case UNIX_TIMESTAMP:
+		if t.Unix() < 0 || t.Unix() > 1<<32-1 {
+			return nil, fmt.Errorf("time %s out of 32-bit UNIX_TIMESTAMP range", t.Format(timeLayout))
+		}
+		return c.uint64ToRegisters(uint64(t.Unix()), 2), nil
+	case UNIX_TIMESTAMP64:
+		return c.uint64ToRegisters(uint64(t.Unix()), 4), nil
+	case UNIX_TIMESTAMP_MS:
+		return c.uint64ToRegisters(uint64(t.UnixMilli()), 4), nil
+	}
+
+	t = t.In(c.Location())
+	if dataType != DATETIME_REGS && (t.Year() < 2000 || t.Year() > 2099) {
+		return nil, fmt.Errorf("year %d out of %s range 2000-2099", t.Year(), dataType)
+	}
+
+	switch dataType {
+	case BCD_DATETIME:
+		fields := []int{t.Year() % 100, int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()}
+		bytes := make([]byte, len(fields))
+		for i, v := range fields {
+			bcd, _ := encodeBCD(uint64(v), 2)
+			bytes[i] = byte(bcd)
+		}
+		return c.bytesToRegisters(bytes), nil
+	case DATETIME_REGS:
+		fields := []int{t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()}
+		registers := make([]uint16, len(fields))
+		for i, v := range fields {
+			registers[i] = c.swapBytes(uint16(v))
+		}
+		return registers, nil
+	case CP56TIME2A:
+		ms := t.Second()*1000 + t.Nanosecond()/int(time.Millisecond)
+		weekday := int(t.Weekday())
+		if weekday == 0 {
+			weekday = 7 // CP56Time2a 中周一为1, 周日为7
+		}
+		bytes := []byte{
+			byte(ms), byte(ms >> 8),
+			byte(t.Minute()),
+			byte(t.Hour()),
+			byte(t.Day()) | byte(weekday)<<5,
+			byte(t.Month()),
+			byte(t.Year() % 100),
+			0, // 补齐到4个寄存器
+		}
+		return c.bytesToRegisters(bytes), nil
+	default:
+		return nil, fmt.Errorf("not a time data type: %s", dataType)
+	}
+}
+
+// registersToBytes 按字节序将寄存器展开为字节流
+func (c *Converter) registersToBytes(registers []uint16) []byte {
+	bytes := make([]byte, 0, len(registers)*2)
+	for _, reg := range registers {
+		reg = c.swapBytes(reg)
+		bytes = append(bytes, byte(reg>>8), byte(reg))
+	}
+	return bytes
+}
+
+// bytesToRegisters 按字节序将字节流打包为寄存器, 奇数长度时末尾补0
+func (c *Converter) bytesToRegisters(bytes []byte) []uint16 {
+	if len(bytes)%2 != 0 {
+		bytes = append(bytes, 0)
+	}
+	registers := make([]uint16, 0, len(bytes)/2)
+	for i := 0; i < len(bytes); i += 2 {
+		registers = append(registers, c.swapBytes(uint16(bytes[i])<<8|uint16(bytes[i+1])))
+	}
+	return registers
+}
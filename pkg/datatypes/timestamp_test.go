@@ -0,0 +1,86 @@
+package datatypes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	tests := []struct {
+		input    string
+		dataType DataType
+		loc      *time.Location
+		want     time.Time
+	}{
+		{"1700000000", UNIX_TIMESTAMP, time.UTC, time.Unix(1700000000, 0)},
+		{"1700000000", UNIX_TIMESTAMP64, time.UTC, time.Unix(1700000000, 0)},
+		{"1700000000123", UNIX_TIMESTAMP_MS, time.UTC, time.UnixMilli(1700000000123)},
+		{" 1700000000 ", BCD_DATETIME, time.UTC, time.Unix(1700000000, 0)},
+		{"2024-03-01 12:30:45", UNIX_TIMESTAMP, shanghai, time.Date(2024, 3, 1, 4, 30, 45, 0, time.UTC)},
+		{"2024-03-01 12:30:45.250", UNIX_TIMESTAMP_MS, time.UTC, time.Date(2024, 3, 1, 12, 30, 45, 250e6, time.UTC)},
+		{"2024-03-01T12:30:45+02:00", UNIX_TIMESTAMP, shanghai, time.Date(2024, 3, 1, 10, 30, 45, 0, time.UTC)},
+		{"2024/03/01 12:30:45", DATETIME_REGS, time.UTC, time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)},
+		{"2024-03-01", CP56TIME2A, time.UTC, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.input, tt.dataType, tt.loc)
+		if err != nil {
+			t.Errorf("ParseTime(%q, %s): %v", tt.input, tt.dataType, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q, %s) = %s, want %s", tt.input, tt.dataType, got.UTC(), tt.want.UTC())
+		}
+	}
+
+	for _, input := range []string{"", "yesterday", "2024-13-01", "1.5"} {
+		if _, err := ParseTime(input, UNIX_TIMESTAMP, time.UTC); err == nil {
+			t.Errorf("ParseTime(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+// TestTimeRoundTrip 写入的纪元时间按类型的单位编码, 读回后得到同一时刻
+func TestTimeRoundTrip(t *testing.T) {
+	tests := []struct {
+		dataType  DataType
+		input     string
+		registers []uint16
+		want      string
+	}{
+		{UNIX_TIMESTAMP, "1700000000", []uint16{0x6553, 0xF100}, "2023-11-14 22:13:20"},
+		{UNIX_TIMESTAMP64, "1700000000", []uint16{0, 0, 0x6553, 0xF100}, "2023-11-14 22:13:20"},
+		{UNIX_TIMESTAMP_MS, "1700000000123", []uint16{0, 0x018B, 0xCFE5, 0x687B}, "2023-11-14 22:13:20.123"},
+		{UNIX_TIMESTAMP_MS, "2023-11-14 22:13:20.123", []uint16{0, 0x018B, 0xCFE5, 0x687B}, "2023-11-14 22:13:20.123"},
+		{BCD_DATETIME, "2023-11-14 22:13:20", []uint16{0x2311, 0x1422, 0x1320}, "2023-11-14 22:13:20"},
+		{DATETIME_REGS, "2023-11-14 22:13:20", []uint16{2023, 11, 14, 22, 13, 20}, "2023-11-14 22:13:20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dataType.String()+"/"+tt.input, func(t *testing.T) {
+			c := NewConverter(AB, WORD_1234)
+			c.SetLocation(time.UTC)
+
+			values, err := ParseStringToType(tt.input, tt.dataType)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			registers, err := c.ConvertToRegistersAs(values, tt.dataType)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !reflect.DeepEqual(registers, tt.registers) {
+				t.Errorf("encode = %04X, want %04X", registers, tt.registers)
+			}
+
+			decoded, err := c.ConvertFromRegisters(registers, tt.dataType)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded != tt.want {
+				t.Errorf("decode = %v, want %s", decoded, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Package dissect 逐字段解析 Modbus TCP 和 RTU 报文 (ADU), 用于报文分析
+//
+// 每个字段记录它在报文中的字节范围, 界面可据此高亮原始字节。TCP 报文检查 MBAP 头中的协议号和长度,
+// RTU 报文校验 CRC, 数量和字节数超出协议范围或与数据长度不符时同样标记为无效; 遇到无效字段时解析尽量继续。
+// 响应本身不含起始地址, 解析时提供对应的请求即可为寄存器和位编号。
+package dissect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Framing 报文的封装方式
+type Framing int
+
+const (
+	TCP Framing = iota
+	RTU
+)
+
+func (f Framing) String() string {
+	if f == RTU {
+		return "Modbus RTU"
+	}
+	return "Modbus TCP"
+}
+
+// Field 报文中的一个字段
+type Field struct {
+	Name     string
+	Value    string
+	Offset   int    // 在报文中的起始字节
+	Length   int    // 字节数
+	Invalid  string // 非空时字段无效, 内容为原因
+	Children []*Field
+}
+
+// Frame 一个报文的解析结果
+type Frame struct {
+	Raw          []byte
+	Framing      Framing
+	Response     bool
+	Unit         byte
+	FunctionCode byte // 异常响应为原功能码 | 0x80
+	Exception    byte // 异常码, 非异常响应为 0
+	Address      uint16
+	Quantity     uint16 // 请求中的数量, 没有数量字段的功能码为 0
+	Fields       []*Field
+}
+
+// DecodeRequest 解析请求报文
+func DecodeRequest(raw []byte, framing Framing) *Frame {
+	return decode(raw, framing, false, nil)
+}
+
+// DecodeResponse 解析响应报文, request 为对应的请求 (可为 nil), 用于给响应中的寄存器和位编号
+func DecodeResponse(raw []byte, framing Framing, request *Frame) *Frame {
+	return decode(raw, framing, true, request)
+}
+
+// Valid 判断报文的所有字段是否都有效
+func (f *Frame) Valid() bool {
+	return len(f.Problems()) == 0
+}
+
+// Problems 返回所有无效字段的说明, 如 "CRC: 应为 0x4A0B"
+func (f *Frame) Problems() []string {
+	var problems []string
+	var walk func(fields []*Field)
+	walk = func(fields []*Field) {
+		for _, field := range fields {
+			if field.Invalid != "" {
+				problems = append(problems, field.Name+": "+field.Invalid)
+			}
+			walk(field.Children)
+		}
+	}
+	walk(f.Fields)
+	return problems
+}
+
+// Summary 返回一行摘要, 如 "读保持寄存器: 地址 0, 数量 4"
+func (f *Frame) Summary() string {
+	if len(f.Raw) == 0 {
+		return "(无数据)"
+	}
+	name := FunctionName(f.FunctionCode &^ 0x80)
+	var detail string
+	switch {
+	case f.FunctionCode == 0 && len(f.Fields) <= 1:
+		return "报文格式错误"
+	case f.Exception != 0:
+		detail = fmt.Sprintf("异常 %d (%s)", f.Exception, ExceptionName(f.Exception))
+	case f.Response && isRead(f.FunctionCode):
+		detail = fmt.Sprintf("%d 字节数据", f.dataBytes())
+	case f.Quantity > 0:
+		detail = fmt.Sprintf("地址 %d, 数量 %d", f.Address, f.Quantity)
+	case hasAddress(f.FunctionCode):
+		detail = fmt.Sprintf("地址 %d", f.Address)
+	}
+	summary := name
+	if detail != "" {
+		summary += ": " + detail
+	}
+	if problems := f.Problems(); len(problems) > 0 {
+		summary += " [" + strings.Join(problems, "; ") + "]"
+	}
+	return summary
+}
+
+// dataBytes 读取响应中的字节数字段, 字段缺失或无效时返回 0
+func (f *Frame) dataBytes() int {
+	for _, field := range f.Fields {
+		for _, child := range field.Children {
+			if child.Name == byteCountName && child.Length == 1 && child.Invalid == "" {
+				return int(f.Raw[child.Offset])
+			}
+		}
+	}
+	return 0
+}
+
+// decode 解析报文头, PDU 交给 decodePDU
+func decode(raw []byte, framing Framing, response bool, request *Frame) *Frame {
+	f := &Frame{Raw: raw, Framing: framing, Response: response}
+	if len(raw) == 0 {
+		return f
+	}
+
+	pduStart, pduEnd := 0, len(raw)
+	if framing == TCP {
+		if len(raw) < 8 {
+			f.Fields = append(f.Fields, &Field{Name: "报文", Value: hexBytes(raw), Length: len(raw),
+				Invalid: fmt.Sprintf("Modbus TCP 报文至少 8 字节, 只有 %d 字节", len(raw))})
+			return f
+		}
+		header := &Field{Name: "MBAP 头", Length: 7}
+		tid := binary.BigEndian.Uint16(raw[0:2])
+		protocol := binary.BigEndian.Uint16(raw[2:4])
+		length := binary.BigEndian.Uint16(raw[4:6])
+		f.Unit = raw[6]
+		header.Value = fmt.Sprintf("事务 %d, 单元 %d", tid, f.Unit)
+		protocolField := &Field{Name: "协议标识", Value: fmt.Sprint(protocol), Offset: 2, Length: 2}
+		if protocol != 0 {
+			protocolField.Invalid = "应为 0 (Modbus)"
+		}
+		lengthField := &Field{Name: "长度", Value: fmt.Sprint(length), Offset: 4, Length: 2}
+		if int(length) != len(raw)-6 {
+			lengthField.Invalid = fmt.Sprintf("长度字段之后实际有 %d 字节", len(raw)-6)
+		}
+		header.Children = []*Field{
+			{Name: "事务标识", Value: fmt.Sprint(tid), Offset: 0, Length: 2},
+			protocolField,
+			lengthField,
+			{Name: "单元标识", Value: fmt.Sprint(f.Unit), Offset: 6, Length: 1},
+		}
+		f.Fields = append(f.Fields, header)
+		pduStart = 7
+	} else {
+		if len(raw) < 4 {
+			f.Fields = append(f.Fields, &Field{Name: "报文", Value: hexBytes(raw), Length: len(raw),
+				Invalid: fmt.Sprintf("Modbus RTU 报文至少 4 字节, 只有 %d 字节", len(raw))})
+			return f
+		}
+		f.Unit = raw[0]
+		f.Fields = append(f.Fields, &Field{Name: "从站地址", Value: fmt.Sprint(f.Unit), Offset: 0, Length: 1})
+		pduStart, pduEnd = 1, len(raw)-2
+	}
+
+	f.Fields = append(f.Fields, decodePDU(f, pduStart, pduEnd, request))
+
+	if framing == RTU {
+		got := binary.LittleEndian.Uint16(raw[pduEnd:])
+		want := CRC16(raw[:pduEnd])
+		crc := &Field{Name: "CRC", Value: fmt.Sprintf("0x%04X (正确)", got), Offset: pduEnd, Length: 2}
+		if got != want {
+			crc.Value = fmt.Sprintf("0x%04X (错误)", got)
+			crc.Invalid = fmt.Sprintf("应为 0x%04X", want)
+		}
+		f.Fields = append(f.Fields, crc)
+	}
+	return f
+}
+
+// CRC16 计算 Modbus RTU 的 CRC-16, 报文中低字节在前
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+func hexBytes(b []byte) string {
+	return strings.TrimSpace(fmt.Sprintf("% X", b))
+}
@@ -0,0 +1,159 @@
+package dissect
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustHex(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// names 返回字段及其子字段的名称, 子字段以 "父/子" 表示
+func names(fields []*Field) []string {
+	var result []string
+	for _, field := range fields {
+		result = append(result, field.Name)
+		for _, child := range names(field.Children) {
+			result = append(result, field.Name+"/"+child)
+		}
+	}
+	return result
+}
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint16
+	}{
+		{"01 03 00 00 00 02", 0x0BC4},
+		{"01 03 00 0A 00 01", 0x08A4},
+		{"", 0xFFFF},
+	}
+	for _, tt := range tests {
+		if got := CRC16(mustHex(t, tt.data)); got != tt.want {
+			t.Errorf("CRC16(%s) = 0x%04X, want 0x%04X", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		framing  Framing
+		request  string
+		response string // 为空时只解析请求
+		want     string // 最后一个报文的摘要
+	}{
+		{"tcp read", TCP, "0001 0000 0006 01 03 0000 0004", "0001 0000 000B 01 03 08 0001 0002 0003 000A",
+			"读保持寄存器: 8 字节数据"},
+		{"tcp request", TCP, "0001 0000 000B 01 10 0064 0002 04 0001 0002", "",
+			"写多个寄存器: 地址 100, 数量 2"},
+		{"rtu read", RTU, "01 03 0000 0002 C40B", "01 03 04 0001 0002 2A32",
+			"读保持寄存器: 4 字节数据"},
+		{"exception", TCP, "0002 0000 0006 01 03 0000 0004", "0002 0000 0003 01 83 02",
+			"读保持寄存器: 异常 2 (非法数据地址)"},
+		{"bad crc", RTU, "01 03 0000 0002 FFFF", "",
+			"读保持寄存器: 地址 0, 数量 2 [CRC: 应为 0x0BC4]"},
+		{"bad protocol and length", TCP, "0001 0001 0009 01 03 0000 0004", "",
+			"读保持寄存器: 地址 0, 数量 4 [协议标识: 应为 0 (Modbus); 长度: 长度字段之后实际有 6 字节]"},
+		{"quantity out of range", TCP, "0001 0000 0006 01 03 0000 007E", "",
+			"读保持寄存器: 地址 0, 数量 126 [数量: 应为 1..125]"},
+		{"byte count mismatch", TCP, "0001 0000 0006 01 03 0000 0002", "0001 0000 0005 01 03 02 0001",
+			"读保持寄存器: 0 字节数据 [字节数: 按请求的数量应为 4]"},
+		{"truncated response", TCP, "0001 0000 0006 01 02 0000 0004", "3030 3030 3030 30 02",
+			"读离散输入: 0 字节数据 [协议标识: 应为 0 (Modbus); 长度: 长度字段之后实际有 2 字节; 字节数: 数据不足: 需要 1 字节, 只剩 0 字节]"},
+		{"extra data", TCP, "0001 0000 0007 01 06 0001 0002 FF", "",
+			"写单个寄存器: 地址 1 [多余数据: PDU 之后有多余的字节]"},
+		{"invalid coil value", TCP, "0001 0000 0006 01 05 0001 1234", "",
+			"写单个线圈: 地址 1 [输出值: 应为 0xFF00 或 0x0000]"},
+		{"too short", TCP, "0001", "", "报文格式错误"},
+		{"empty", RTU, "", "", "(无数据)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := DecodeRequest(mustHex(t, tt.request), tt.framing)
+			if tt.response != "" {
+				frame = DecodeResponse(mustHex(t, tt.response), tt.framing, frame)
+			}
+			if got := frame.Summary(); got != tt.want {
+				t.Errorf("Summary = %q, want %q", got, tt.want)
+			}
+			if valid := !strings.Contains(tt.want, "[") && tt.want != "报文格式错误"; frame.Valid() != valid {
+				t.Errorf("Valid = %v, problems %q", frame.Valid(), frame.Problems())
+			}
+		})
+	}
+}
+
+// TestDecodeFields 响应按请求的地址为寄存器和位编号, 字段覆盖整个报文
+func TestDecodeFields(t *testing.T) {
+	request := DecodeRequest(mustHex(t, "01 01 0013 000A 4DC8"), RTU)
+	response := DecodeResponse(mustHex(t, "01 01 02 CD 01 2CAC"), RTU, request)
+	if response.Unit != 1 || response.Address != 19 || response.Quantity != 10 {
+		t.Errorf("unit %d, address %d, quantity %d", response.Unit, response.Address, response.Quantity)
+	}
+	want := []string{"从站地址", "PDU", "PDU/功能码", "PDU/字节数", "PDU/线圈状态"}
+	for i := 19; i < 29; i++ {
+		want = append(want, fmt.Sprintf("PDU/线圈状态/线圈 %d", i))
+	}
+	want = append(want, "CRC")
+	if got := names(response.Fields); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+	if bits := response.Fields[1].Children[2].Children; bits[0].Value != "ON" || bits[1].Value != "OFF" || bits[8].Value != "ON" {
+		t.Errorf("coil 19 %s, coil 20 %s, coil 27 %s", bits[0].Value, bits[1].Value, bits[8].Value)
+	}
+
+	// 不知道请求时按序号编号
+	response = DecodeResponse(mustHex(t, "0001 0000 0007 01 04 04 0001 0002"), TCP, nil)
+	registers := response.Fields[1].Children[2].Children
+	if len(registers) != 2 || registers[1].Name != "寄存器 [1]" || registers[1].Value != "0x0002 (2)" {
+		t.Errorf("registers = %+v", registers)
+	}
+}
+
+// FuzzDecode 任意输入都不能使解析崩溃, 字段的字节范围不超出报文
+func FuzzDecode(f *testing.F) {
+	for _, seed := range []string{
+		"000100000006010300000004",
+		"00010000000b010308000100020003000a",
+		"3030303030303002",
+		"010300000002c40b",
+		"010102cd012cac",
+		"000100000003018302",
+		"01",
+	} {
+		f.Add(mustHex(f, seed), false)
+		f.Add(mustHex(f, seed), true)
+	}
+	f.Fuzz(func(t *testing.T, raw []byte, rtu bool) {
+		framing := TCP
+		if rtu {
+			framing = RTU
+		}
+		request := DecodeRequest(raw, framing)
+		request.Summary()
+		for _, frame := range []*Frame{DecodeResponse(raw, framing, nil), DecodeResponse(raw, framing, request)} {
+			frame.Summary()
+			var walk func(fields []*Field)
+			walk = func(fields []*Field) {
+				for _, field := range fields {
+					if field.Offset < 0 || field.Length < 0 || field.Offset+field.Length > len(raw) {
+						t.Fatalf("%s covers bytes %d+%d of a %d-byte frame", field.Name, field.Offset, field.Length, len(raw))
+					}
+					walk(field.Children)
+				}
+			}
+			walk(frame.Fields)
+		}
+	})
+}
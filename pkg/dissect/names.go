@@ -0,0 +1,53 @@
+package dissect
+
+// functionNames 公共功能码的名称 (Modbus Application Protocol V1.1b3)
+var functionNames = map[byte]string{
+	0x01: "读线圈",
+	0x02: "读离散输入",
+	0x03: "读保持寄存器",
+	0x04: "读输入寄存器",
+	0x05: "写单个线圈",
+	0x06: "写单个寄存器",
+	0x07: "读异常状态",
+	0x08: "诊断",
+	0x0B: "读通信事件计数",
+	0x0C: "读通信事件记录",
+	0x0F: "写多个线圈",
+	0x10: "写多个寄存器",
+	0x11: "报告从站ID",
+	0x14: "读文件记录",
+	0x15: "写文件记录",
+	0x16: "屏蔽写寄存器",
+	0x17: "读/写多个寄存器",
+	0x18: "读FIFO队列",
+	0x2B: "封装接口传输",
+}
+
+// exceptionNames 异常码的名称
+var exceptionNames = map[byte]string{
+	0x01: "非法功能码",
+	0x02: "非法数据地址",
+	0x03: "非法数据值",
+	0x04: "从站设备故障",
+	0x05: "确认",
+	0x06: "从站设备忙",
+	0x08: "存储奇偶校验错误",
+	0x0A: "网关路径不可用",
+	0x0B: "网关目标设备无响应",
+}
+
+// FunctionName 返回功能码的名称, 未知功能码返回 "未知功能码"
+func FunctionName(fc byte) string {
+	if name, ok := functionNames[fc]; ok {
+		return name
+	}
+	return "未知功能码"
+}
+
+// ExceptionName 返回异常码的名称, 未知异常码返回 "未知异常"
+func ExceptionName(code byte) string {
+	if name, ok := exceptionNames[code]; ok {
+		return name
+	}
+	return "未知异常"
+}
@@ -0,0 +1,341 @@
+package dissect
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// 各功能码一次允许的最大数量
+const (
+	maxReadBits       = 2000
+	maxReadRegisters  = 125
+	maxWriteBits      = 1968
+	maxWriteRegisters = 123
+	maxRWWrite        = 121
+)
+
+// byteCountName 字节数字段的名称, 摘要据此找到响应的数据长度
+const byteCountName = "字节数"
+
+// decodePDU 解析功能码及其后的数据
+func decodePDU(f *Frame, start, end int, request *Frame) *Field {
+	pdu := &Field{Name: "PDU", Offset: start, Length: end - start}
+	r := &pduReader{raw: f.Raw, pos: start, end: end}
+
+	fc, fcField, ok := r.u8("功能码")
+	if ok {
+		f.FunctionCode = fc
+		switch {
+		case f.Response && fc&0x80 != 0:
+			fcField.Value = fmt.Sprintf("%d (0x%02X) %s, 异常响应", fc, fc, FunctionName(fc&^0x80))
+			if code, codeField, ok := r.u8("异常码"); ok {
+				f.Exception = code
+				codeField.Value = fmt.Sprintf("%d %s", code, ExceptionName(code))
+			}
+		case f.Response:
+			fcField.Value = fmt.Sprintf("%d (0x%02X) %s", fc, fc, FunctionName(fc))
+			decodeResponse(f, r, request)
+		default:
+			fcField.Value = fmt.Sprintf("%d (0x%02X) %s", fc, fc, FunctionName(fc))
+			decodeRequest(f, r)
+		}
+		pdu.Value = FunctionName(fc &^ 0x80)
+	}
+	r.trailing()
+	pdu.Children = r.fields
+	return pdu
+}
+
+// decodeRequest 按功能码解析请求数据
+func decodeRequest(f *Frame, r *pduReader) {
+	switch fc := f.FunctionCode; fc {
+	case 0x01, 0x02, 0x03, 0x04:
+		max := maxReadRegisters
+		if fc <= 0x02 {
+			max = maxReadBits
+		}
+		f.Address, _ = r.u16("起始地址")
+		f.Quantity, _ = r.quantity("数量", max)
+	case 0x05:
+		f.Address, _ = r.u16("输出地址")
+		r.coilValue()
+	case 0x06:
+		f.Address, _ = r.u16("寄存器地址")
+		r.u16("寄存器值")
+	case 0x0F:
+		f.Address, _ = r.u16("起始地址")
+		var ok bool
+		if f.Quantity, ok = r.quantity("输出数量", maxWriteBits); !ok {
+			return
+		}
+		if data, ok := r.byteCount(byteCountName, (int(f.Quantity)+7)/8); ok {
+			r.bits("输出值", "线圈", f.Address, int(f.Quantity), data)
+		}
+	case 0x10:
+		f.Address, _ = r.u16("起始地址")
+		var ok bool
+		if f.Quantity, ok = r.quantity("寄存器数量", maxWriteRegisters); !ok {
+			return
+		}
+		if data, ok := r.byteCount(byteCountName, 2*int(f.Quantity)); ok {
+			r.registers("寄存器值", f.Address, true, data)
+		}
+	case 0x16:
+		f.Address, _ = r.u16("参考地址")
+		r.mask("AND 掩码")
+		r.mask("OR 掩码")
+	case 0x17:
+		f.Address, _ = r.u16("读起始地址")
+		f.Quantity, _ = r.quantity("读数量", maxReadRegisters)
+		writeAddress, _ := r.u16("写起始地址")
+		writeQuantity, ok := r.quantity("写数量", maxRWWrite)
+		if !ok {
+			return
+		}
+		if data, ok := r.byteCount("写字节数", 2*int(writeQuantity)); ok {
+			r.registers("写寄存器值", writeAddress, true, data)
+		}
+	default:
+		r.rest("数据")
+	}
+}
+
+// decodeResponse 按功能码解析正常响应的数据, 请求与响应的功能码一致时按请求的地址编号
+func decodeResponse(f *Frame, r *pduReader, request *Frame) {
+	fc := f.FunctionCode
+	known := request != nil && request.FunctionCode == fc && request.Quantity > 0
+	if known {
+		f.Address, f.Quantity = request.Address, request.Quantity
+	}
+
+	switch fc {
+	case 0x01, 0x02:
+		want, count := -1, 0
+		if known {
+			want, count = (int(f.Quantity)+7)/8, int(f.Quantity)
+		}
+		data, ok := r.byteCount(byteCountName, want)
+		if !ok {
+			return
+		}
+		if !known {
+			count = len(data) * 8
+		}
+		name, label := "线圈状态", "线圈"
+		if fc == 0x02 {
+			name, label = "输入状态", "输入"
+		}
+		r.bits(name, label, f.Address, count, data)
+	case 0x03, 0x04, 0x17:
+		want := -1
+		if known {
+			want = 2 * int(f.Quantity)
+		}
+		if data, ok := r.byteCount(byteCountName, want); ok {
+			r.registers("寄存器值", f.Address, known, data)
+		}
+	case 0x05:
+		f.Address, _ = r.u16("输出地址")
+		r.coilValue()
+	case 0x06:
+		f.Address, _ = r.u16("寄存器地址")
+		r.u16("寄存器值")
+	case 0x0F:
+		f.Address, _ = r.u16("起始地址")
+		f.Quantity, _ = r.quantity("输出数量", maxWriteBits)
+	case 0x10:
+		f.Address, _ = r.u16("起始地址")
+		f.Quantity, _ = r.quantity("寄存器数量", maxWriteRegisters)
+	case 0x16:
+		f.Address, _ = r.u16("参考地址")
+		r.mask("AND 掩码")
+		r.mask("OR 掩码")
+	default:
+		r.rest("数据")
+	}
+}
+
+// pduReader 顺序读取 PDU 中的字段
+type pduReader struct {
+	raw    []byte
+	pos    int
+	end    int
+	fields []*Field
+}
+
+func (r *pduReader) remaining() int {
+	return r.end - r.pos
+}
+
+func (r *pduReader) add(field *Field) *Field {
+	r.fields = append(r.fields, field)
+	return field
+}
+
+// missing 数据不足时将剩余字节记为一个无效字段
+func (r *pduReader) missing(name string, need int) {
+	r.add(&Field{
+		Name:    name,
+		Value:   hexBytes(r.raw[r.pos:r.end]),
+		Offset:  r.pos,
+		Length:  r.remaining(),
+		Invalid: fmt.Sprintf("数据不足: 需要 %d 字节, 只剩 %d 字节", need, r.remaining()),
+	})
+	r.pos = r.end
+}
+
+func (r *pduReader) u8(name string) (byte, *Field, bool) {
+	if r.remaining() < 1 {
+		r.missing(name, 1)
+		return 0, nil, false
+	}
+	v := r.raw[r.pos]
+	field := r.add(&Field{Name: name, Value: fmt.Sprint(v), Offset: r.pos, Length: 1})
+	r.pos++
+	return v, field, true
+}
+
+// u16 读取一个大端16位字段, 显示为十进制和十六进制
+func (r *pduReader) u16(name string) (uint16, bool) {
+	v, _, ok := r.u16Field(name)
+	return v, ok
+}
+
+func (r *pduReader) u16Field(name string) (uint16, *Field, bool) {
+	if r.remaining() < 2 {
+		r.missing(name, 2)
+		return 0, nil, false
+	}
+	v := binary.BigEndian.Uint16(r.raw[r.pos:])
+	field := r.add(&Field{Name: name, Value: fmt.Sprintf("%d (0x%04X)", v, v), Offset: r.pos, Length: 2})
+	r.pos += 2
+	return v, field, true
+}
+
+// quantity 读取数量字段, 超出 1..max 时标记为无效
+func (r *pduReader) quantity(name string, max int) (uint16, bool) {
+	v, field, ok := r.u16Field(name)
+	if !ok {
+		return 0, false
+	}
+	field.Value = fmt.Sprint(v)
+	if v == 0 || int(v) > max {
+		field.Invalid = fmt.Sprintf("应为 1..%d", max)
+	}
+	return v, true
+}
+
+// byteCount 读取字节数字段并返回其后的数据。字节数与剩余数据或期望值 (want >= 0 时) 不符时标记为无效,
+// 这时按实际剩余的数据解析
+func (r *pduReader) byteCount(name string, want int) ([]byte, bool) {
+	n, field, ok := r.u8(name)
+	if !ok {
+		return nil, false
+	}
+	switch {
+	case want >= 0 && int(n) != want:
+		field.Invalid = fmt.Sprintf("按请求的数量应为 %d", want)
+	case int(n) != r.remaining():
+		field.Invalid = fmt.Sprintf("其后实际有 %d 字节数据", r.remaining())
+	}
+	count := int(n)
+	if count > r.remaining() {
+		count = r.remaining()
+	}
+	data := r.raw[r.pos : r.pos+count]
+	return data, true
+}
+
+// bits 将 data 中的前 count 位 (每字节低位在前) 记为一个字段, 每位一个子字段
+func (r *pduReader) bits(name, label string, address uint16, count int, data []byte) {
+	field := r.add(&Field{Name: name, Value: hexBytes(data), Offset: r.pos, Length: len(data)})
+	for i := 0; i < count && i/8 < len(data); i++ {
+		state := "OFF"
+		if data[i/8]&(1<<(i%8)) != 0 {
+			state = "ON"
+		}
+		field.Children = append(field.Children, &Field{
+			Name:   fmt.Sprintf("%s %d", label, int(address)+i),
+			Value:  state,
+			Offset: r.pos + i/8,
+			Length: 1,
+		})
+	}
+	r.pos += len(data)
+}
+
+// registers 将 data 记为一个字段, 每个寄存器一个子字段; 地址未知时按序号编号
+func (r *pduReader) registers(name string, address uint16, known bool, data []byte) {
+	field := r.add(&Field{Name: name, Value: fmt.Sprintf("%d 个寄存器", len(data)/2), Offset: r.pos, Length: len(data)})
+	if len(data)%2 != 0 {
+		field.Invalid = "数据字节数为奇数"
+	}
+	for i := 0; i+1 < len(data); i += 2 {
+		v := binary.BigEndian.Uint16(data[i:])
+		child := &Field{Value: fmt.Sprintf("0x%04X (%d)", v, v), Offset: r.pos + i, Length: 2}
+		if known {
+			child.Name = fmt.Sprintf("寄存器 %d", int(address)+i/2)
+		} else {
+			child.Name = fmt.Sprintf("寄存器 [%d]", i/2)
+		}
+		field.Children = append(field.Children, child)
+	}
+	r.pos += len(data)
+}
+
+// coilValue 读取写单个线圈的值, 只允许 0xFF00 (ON) 和 0x0000 (OFF)
+func (r *pduReader) coilValue() {
+	v, field, ok := r.u16Field("输出值")
+	if !ok {
+		return
+	}
+	switch v {
+	case 0xFF00:
+		field.Value = "ON (0xFF00)"
+	case 0x0000:
+		field.Value = "OFF (0x0000)"
+	default:
+		field.Invalid = "应为 0xFF00 或 0x0000"
+	}
+}
+
+// mask 读取屏蔽写的掩码, 显示为十六进制和二进制
+func (r *pduReader) mask(name string) {
+	if v, field, ok := r.u16Field(name); ok {
+		field.Value = fmt.Sprintf("0x%04X (%016b)", v, v)
+	}
+}
+
+// rest 将剩余数据记为一个字段
+func (r *pduReader) rest(name string) {
+	if r.remaining() > 0 {
+		r.add(&Field{Name: name, Value: hexBytes(r.raw[r.pos:r.end]), Offset: r.pos, Length: r.remaining()})
+		r.pos = r.end
+	}
+}
+
+// trailing 解析完成后仍有剩余数据时记为无效字段
+func (r *pduReader) trailing() {
+	if r.remaining() > 0 {
+		r.add(&Field{
+			Name:    "多余数据",
+			Value:   hexBytes(r.raw[r.pos:r.end]),
+			Offset:  r.pos,
+			Length:  r.remaining(),
+			Invalid: "PDU 之后有多余的字节",
+		})
+		r.pos = r.end
+	}
+}
+
+func isRead(fc byte) bool {
+	return fc >= 0x01 && fc <= 0x04 || fc == 0x17
+}
+
+func hasAddress(fc byte) bool {
+	switch fc {
+	case 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x0F, 0x10, 0x16, 0x17:
+		return true
+	}
+	return false
+}
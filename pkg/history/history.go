@@ -0,0 +1,172 @@
+// Package history 按点保存有界的内存时间序列
+//
+// 每个点 (以名称区分) 一个固定容量的环形缓冲区, 写满后覆盖最旧的样本, 内存占用与运行时长无关。
+// 样本须按时间顺序追加, Range 据此用二分查找截取时间窗口。
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity 每个点默认保留的样本数
+const DefaultCapacity = 3600
+
+// Sample 一个带时间戳的数值
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// series 单个点的环形缓冲区
+type series struct {
+	samples []Sample
+	start   int // 最旧样本的下标
+	count   int
+}
+
+func (s *series) add(sample Sample) {
+	if s.count < len(s.samples) {
+		s.samples[(s.start+s.count)%len(s.samples)] = sample
+		s.count++
+		return
+	}
+	s.samples[s.start] = sample
+	s.start = (s.start + 1) % len(s.samples)
+}
+
+// at 返回第 i 旧的样本
+func (s *series) at(i int) Sample {
+	return s.samples[(s.start+i)%len(s.samples)]
+}
+
+// copyRange 按时间顺序复制下标 [from, to) 的样本
+func (s *series) copyRange(from, to int) []Sample {
+	result := make([]Sample, 0, to-from)
+	for i := from; i < to; i++ {
+		result = append(result, s.at(i))
+	}
+	return result
+}
+
+// Store 多个点的时间序列, 可被多个 goroutine 同时使用
+type Store struct {
+	mu       sync.RWMutex
+	capacity int
+	series   map[string]*series
+	names    []string // 按首次出现的顺序
+}
+
+// NewStore 创建每个点最多保留 capacity 个样本的存储, capacity <= 0 时使用 DefaultCapacity
+func NewStore(capacity int) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Store{capacity: capacity, series: make(map[string]*series)}
+}
+
+// Capacity 返回每个点保留的样本数
+func (s *Store) Capacity() int {
+	return s.capacity
+}
+
+// Add 追加一个样本, 返回该点是否为首次出现
+func (s *Store) Add(name string, t time.Time, value float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sr, ok := s.series[name]
+	if !ok {
+		sr = &series{samples: make([]Sample, s.capacity)}
+		s.series[name] = sr
+		s.names = append(s.names, name)
+	}
+	sr.add(Sample{Time: t, Value: value})
+	return !ok
+}
+
+// Names 返回所有点的名称, 按首次出现的顺序
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.names...)
+}
+
+// Len 返回点的样本数
+func (s *Store) Len(name string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if sr, ok := s.series[name]; ok {
+		return sr.count
+	}
+	return 0
+}
+
+// Samples 按时间顺序返回点的所有样本
+func (s *Store) Samples(name string) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sr, ok := s.series[name]
+	if !ok {
+		return nil
+	}
+	return sr.copyRange(0, sr.count)
+}
+
+// Range 返回时间在 [from, to] 内的样本, 另外包含窗口两侧各一个相邻样本, 便于画出穿过窗口边界的曲线
+func (s *Store) Range(name string, from, to time.Time) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sr, ok := s.series[name]
+	if !ok || sr.count == 0 {
+		return nil
+	}
+	first := sort.Search(sr.count, func(i int) bool { return !sr.at(i).Time.Before(from) })
+	last := sort.Search(sr.count, func(i int) bool { return sr.at(i).Time.After(to) })
+	if first > 0 {
+		first--
+	}
+	if last < sr.count {
+		last++
+	}
+	return sr.copyRange(first, last)
+}
+
+// Latest 返回点的最新样本
+func (s *Store) Latest(name string) (Sample, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sr, ok := s.series[name]
+	if !ok || sr.count == 0 {
+		return Sample{}, false
+	}
+	return sr.at(sr.count - 1), true
+}
+
+// Bounds 返回所有点中最早和最新样本的时间
+func (s *Store) Bounds() (first, last time.Time, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sr := range s.series {
+		if sr.count == 0 {
+			continue
+		}
+		oldest, newest := sr.at(0).Time, sr.at(sr.count-1).Time
+		if !ok || oldest.Before(first) {
+			first = oldest
+		}
+		if !ok || newest.After(last) {
+			last = newest
+		}
+		ok = true
+	}
+	return first, last, ok
+}
+
+// Clear 删除所有点
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series = make(map[string]*series)
+	s.names = nil
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// at 返回 base 之后第 i 秒
+func at(i int) time.Time {
+	return base.Add(time.Duration(i) * time.Second)
+}
+
+// values 返回样本的数值
+func values(samples []Sample) []float64 {
+	result := make([]float64, len(samples))
+	for i, s := range samples {
+		result[i] = s.Value
+	}
+	return result
+}
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		added    int
+		want     []float64
+	}{
+		{"empty", 3, 0, []float64{}},
+		{"partial", 3, 2, []float64{0, 1}},
+		{"full", 3, 3, []float64{0, 1, 2}},
+		{"wrapped", 3, 5, []float64{2, 3, 4}},
+		{"wrapped twice", 3, 7, []float64{4, 5, 6}},
+		{"capacity one", 1, 4, []float64{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(tt.capacity)
+			for i := 0; i < tt.added; i++ {
+				s.Add("p", at(i), float64(i))
+			}
+			if got := s.Len("p"); got != len(tt.want) {
+				t.Errorf("Len = %d, want %d", got, len(tt.want))
+			}
+			got := values(s.Samples("p"))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Samples = %v, want %v", got, tt.want)
+			}
+			latest, ok := s.Latest("p")
+			if ok != (tt.added > 0) || (ok && latest.Value != tt.want[len(tt.want)-1]) {
+				t.Errorf("Latest = %v, %v", latest, ok)
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	s := NewStore(5)
+	for i := 0; i < 8; i++ { // 保留 3..7
+		s.Add("p", at(i*10), float64(i))
+	}
+	tests := []struct {
+		name     string
+		from, to int
+		want     []float64
+	}{
+		{"inside", 45, 55, []float64{4, 5, 6}},
+		{"exact bounds", 40, 60, []float64{3, 4, 5, 6, 7}},
+		{"whole buffer", 0, 100, []float64{3, 4, 5, 6, 7}},
+		{"before buffer", 0, 10, []float64{3}},
+		{"after buffer", 80, 90, []float64{7}},
+		{"between samples", 41, 49, []float64{4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := values(s.Range("p", at(tt.from), at(tt.to))); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Range(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+	if got := s.Range("missing", at(0), at(100)); got != nil {
+		t.Errorf("Range of a missing point = %v, want nil", got)
+	}
+}
+
+func TestStoreNamesAndBounds(t *testing.T) {
+	s := NewStore(0)
+	if s.Capacity() != DefaultCapacity {
+		t.Errorf("Capacity = %d, want %d", s.Capacity(), DefaultCapacity)
+	}
+	if _, _, ok := s.Bounds(); ok {
+		t.Error("Bounds of an empty store ok = true")
+	}
+
+	if !s.Add("b", at(5), 1) || s.Add("b", at(6), 2) || !s.Add("a", at(2), 3) {
+		t.Error("Add should report only the first sample of each point")
+	}
+	s.Add("a", at(9), 4)
+	if got, want := s.Names(), []string{"b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+	first, last, ok := s.Bounds()
+	if !ok || !first.Equal(at(2)) || !last.Equal(at(9)) {
+		t.Errorf("Bounds = %v, %v, %v; want %v, %v", first, last, ok, at(2), at(9))
+	}
+
+	s.Clear()
+	if len(s.Names()) != 0 || s.Len("a") != 0 {
+		t.Error("Clear left points behind")
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	s := NewStore(100)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				s.Add(name, at(i), float64(i))
+				s.Range(name, at(i-10), at(i))
+				s.Bounds()
+			}
+		}(string(rune('a' + w)))
+	}
+	wg.Wait()
+	for _, name := range s.Names() {
+		if got := values(s.Samples(name)); len(got) != 100 || got[0] != 400 || got[99] != 499 {
+			t.Errorf("%s: %d samples from %v to %v", name, len(got), got[0], got[len(got)-1])
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package modicon 解析和转换 Modbus 地址写法
+//
+// 同一个寄存器常见多种写法, 例如第101个保持寄存器 (协议地址 100):
+//
+//	40101     5位 Modicon 写法, 首位 4 表示保持寄存器, 后4位从1开始 (最大 9999)
+//	400101    6位 Modicon 写法, 后5位从1开始 (最大 65536)
+//	HR100     类型前缀 + 0起始的协议地址, 前缀 HR/IR/CO/DI
+//	4x0101    类型前缀 4x/3x/1x + 从1开始的编号
+//	0x0064    十六进制协议地址, 寄存器类型由界面选择
+//	100       十进制协议地址, 寄存器类型由界面选择
+//
+// Modicon 写法的首位: 0 线圈, 1 离散输入, 3 输入寄存器, 4 保持寄存器。
+//
+// 以上写法后加 ".位号" 表示寄存器中的一位, 如 40010.3 为第10个保持寄存器的 bit 3 (0 为最低位)。
+package modicon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Table 寄存器类型
+type Table int
+
+const (
+	Coil Table = iota
+	DiscreteInput
+	InputRegister
+	HoldingRegister
+)
+
+// Name 返回寄存器类型的规范名称, 与点位表中的 table 字段一致
+func (t Table) Name() string {
+	switch t {
+	case Coil:
+		return "coil"
+	case DiscreteInput:
+		return "discrete"
+	case InputRegister:
+		return "input"
+	default:
+		return "holding"
+	}
+}
+
+// Prefix 返回寄存器类型的地址前缀, 如 HR
+func (t Table) Prefix() string {
+	switch t {
+	case Coil:
+		return "CO"
+	case DiscreteInput:
+		return "DI"
+	case InputRegister:
+		return "IR"
+	default:
+		return "HR"
+	}
+}
+
+// digit 返回 Modicon 写法的首位数字
+func (t Table) digit() byte {
+	switch t {
+	case Coil:
+		return '0'
+	case DiscreteInput:
+		return '1'
+	case InputRegister:
+		return '3'
+	default:
+		return '4'
+	}
+}
+
+func (t Table) String() string {
+	return t.Prefix()
+}
+
+// ParseTable 解析寄存器类型名称, 接受规范名称、前缀和 Modicon 首位数字
+func ParseTable(s string) (Table, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "coil", "coils", "co", "0":
+		return Coil, nil
+	case "discrete", "discrete input", "di", "1", "1x":
+		return DiscreteInput, nil
+	case "input", "input register", "ir", "3", "3x":
+		return InputRegister, nil
+	case "holding", "holding register", "hr", "4", "4x":
+		return HoldingRegister, nil
+	default:
+		return HoldingRegister, fmt.Errorf("未知寄存器类型: %s", s)
+	}
+}
+
+// digitTables Modicon 首位数字对应的寄存器类型
+var digitTables = map[byte]Table{'0': Coil, '1': DiscreteInput, '3': InputRegister, '4': HoldingRegister}
+
+// prefixes 地址前缀。4x/3x/1x 后接从1开始的编号, 0x 始终表示十六进制, 不作为线圈前缀
+var prefixes = []struct {
+	prefix   string
+	table    Table
+	oneBased bool
+}{
+	{"HR", HoldingRegister, false}, {"IR", InputRegister, false}, {"DI", DiscreteInput, false}, {"CO", Coil, false},
+	{"4X", HoldingRegister, true}, {"3X", InputRegister, true}, {"1X", DiscreteInput, true},
+}
+
+// Address 解析后的地址
+type Address struct {
+	Table    Table
+	Offset   uint16 // 0起始的协议地址
+	Explicit bool   // 写法中包含寄存器类型 (Modicon 或前缀写法)
+	HasBit   bool   // 写法中带位号, 表示寄存器中的一位
+	Bit      uint8  // 位号 0-15, 0 为最低位
+}
+
+// Parse 解析地址。不含寄存器类型的写法使用 table, 返回的 Explicit 为 false。
+//
+// 5位或6位且首位为 0/1/3/4 的十进制数按 Modicon 写法解析; 需要输入 10000 以上的协议地址时,
+// 请使用十六进制或前缀写法 (如 HR12345)。
+func Parse(s string, table Table) (Address, error) {
+	return parse(s, table, true)
+}
+
+// ParseIn 与 Parse 相同, 但纯数字总是 table 中的协议地址, 不按 Modicon 写法解析。
+// 寄存器类型已另行指定 (如厂商表的类型列) 时使用, 此时只有前缀写法能改变寄存器类型。
+func ParseIn(s string, table Table) (Address, error) {
+	return parse(s, table, false)
+}
+
+func parse(s string, table Table, modicon bool) (Address, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	if text == "" {
+		return Address{}, fmt.Errorf("地址为空")
+	}
+
+	if i := strings.LastIndexByte(text, '.'); i >= 0 {
+		bit, err := strconv.ParseUint(text[i+1:], 10, 8)
+		if err != nil || bit > 15 || strings.IndexByte(text[:i], '.') >= 0 {
+			return Address{}, fmt.Errorf("位号无效 (0-15): %s", s)
+		}
+		addr, err := parse(text[:i], table, modicon)
+		if err != nil {
+			return addr, err
+		}
+		if addr.Table == Coil || addr.Table == DiscreteInput {
+			return addr, fmt.Errorf("线圈和离散输入不支持位号: %s", s)
+		}
+		addr.HasBit, addr.Bit = true, uint8(bit)
+		return addr, nil
+	}
+
+	if modicon {
+		if addr, ok, err := parseModicon(text); ok || err != nil {
+			return addr, err
+		}
+	}
+
+	for _, p := range prefixes {
+		rest := strings.TrimPrefix(text, p.prefix)
+		if rest == text || rest == "" {
+			continue
+		}
+		if p.oneBased {
+			n, err := strconv.ParseUint(rest, 10, 32)
+			if err != nil || n < 1 || n > 65536 {
+				return Address{}, fmt.Errorf("地址无效: %s", s)
+			}
+			return Address{Table: p.table, Offset: uint16(n - 1), Explicit: true}, nil
+		}
+		offset, err := parseOffset(strings.TrimLeft(rest, ":-"))
+		if err != nil {
+			return Address{}, fmt.Errorf("地址无效: %s", s)
+		}
+		return Address{Table: p.table, Offset: offset, Explicit: true}, nil
+	}
+
+	offset, err := parseOffset(text)
+	if err != nil {
+		return Address{}, fmt.Errorf("地址无效: %s", s)
+	}
+	return Address{Table: table, Offset: offset}, nil
+}
+
+// parseModicon 解析5位/6位 Modicon 写法, ok 表示文本符合该写法
+func parseModicon(text string) (addr Address, ok bool, err error) {
+	if (len(text) != 5 && len(text) != 6) || !isDigits(text) {
+		return addr, false, nil
+	}
+	table, known := digitTables[text[0]]
+	if !known {
+		return addr, false, nil
+	}
+	n, _ := strconv.Atoi(text[1:])
+	if n < 1 || n > 65536 {
+		return addr, true, fmt.Errorf("Modicon 地址超出范围: %s", text)
+	}
+	return Address{Table: table, Offset: uint16(n - 1), Explicit: true}, true, nil
+}
+
+// parseOffset 解析十进制、0x 十六进制或 h 后缀十六进制的协议地址
+func parseOffset(text string) (uint16, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(text, "0X"):
+		text, base = text[2:], 16
+	case strings.HasSuffix(text, "H"):
+		text, base = text[:len(text)-1], 16
+	}
+	n, err := strconv.ParseUint(strings.ReplaceAll(text, "_", ""), base, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(n), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Modicon5 返回5位 Modicon 写法, 协议地址超过 9998 时无此写法
+func (a Address) Modicon5() (string, bool) {
+	if a.Offset > 9998 {
+		return "", false
+	}
+	return fmt.Sprintf("%c%04d", a.Table.digit(), int(a.Offset)+1) + a.bitSuffix(), true
+}
+
+// Modicon6 返回6位 Modicon 写法
+func (a Address) Modicon6() string {
+	return fmt.Sprintf("%c%05d", a.Table.digit(), int(a.Offset)+1) + a.bitSuffix()
+}
+
+// Prefixed 返回前缀写法, 如 HR100
+func (a Address) Prefixed() string {
+	return fmt.Sprintf("%s%d", a.Table.Prefix(), a.Offset) + a.bitSuffix()
+}
+
+// Hex 返回十六进制协议地址
+func (a Address) Hex() string {
+	return fmt.Sprintf("0x%04X", a.Offset) + a.bitSuffix()
+}
+
+// bitSuffix 返回位号后缀, 如 .3
+func (a Address) bitSuffix() string {
+	if !a.HasBit {
+		return ""
+	}
+	return "." + strconv.Itoa(int(a.Bit))
+}
+
+// Notations 返回地址的各种等价写法: 5位 Modicon (如有)、6位 Modicon、前缀写法和十六进制
+func (a Address) Notations() []string {
+	var notations []string
+	if m5, ok := a.Modicon5(); ok {
+		notations = append(notations, m5)
+	}
+	return append(notations, a.Modicon6(), a.Prefixed(), a.Hex())
+}
+
+func (a Address) String() string {
+	return a.Prefixed()
+}
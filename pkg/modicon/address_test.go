@@ -0,0 +1,143 @@
+package modicon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		table Table
+		want  Address
+	}{
+		{"40001", Coil, Address{Table: HoldingRegister, Offset: 0, Explicit: true}},
+		{"30775", HoldingRegister, Address{Table: InputRegister, Offset: 774, Explicit: true}},
+		{"00005", HoldingRegister, Address{Table: Coil, Offset: 4, Explicit: true}},
+		{"10010", HoldingRegister, Address{Table: DiscreteInput, Offset: 9, Explicit: true}},
+		{"465536", Coil, Address{Table: HoldingRegister, Offset: 65535, Explicit: true}},
+		{"hr100", Coil, Address{Table: HoldingRegister, Offset: 100, Explicit: true}},
+		{"IR30775", HoldingRegister, Address{Table: InputRegister, Offset: 30775, Explicit: true}},
+		{"CO:0x10", HoldingRegister, Address{Table: Coil, Offset: 16, Explicit: true}},
+		{"3x0101", HoldingRegister, Address{Table: InputRegister, Offset: 100, Explicit: true}},
+		{"4X65536", Coil, Address{Table: HoldingRegister, Offset: 65535, Explicit: true}},
+		{" 100 ", InputRegister, Address{Table: InputRegister, Offset: 100}},
+		{"0x0064", Coil, Address{Table: Coil, Offset: 100}},
+		{"64h", HoldingRegister, Address{Table: HoldingRegister, Offset: 100}},
+		{"1_000", HoldingRegister, Address{Table: HoldingRegister, Offset: 1000}},
+		{"20001", HoldingRegister, Address{Table: HoldingRegister, Offset: 20001}},
+		{"40010.3", Coil, Address{Table: HoldingRegister, Offset: 9, Explicit: true, HasBit: true, Bit: 3}},
+		{"HR5.15", Coil, Address{Table: HoldingRegister, Offset: 5, Explicit: true, HasBit: true, Bit: 15}},
+		{"7.0", InputRegister, Address{Table: InputRegister, Offset: 7, HasBit: true}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input, tt.table)
+		if err != nil {
+			t.Errorf("Parse(%q, %s): %v", tt.input, tt.table, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %s) = %+v, want %+v", tt.input, tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"", "abc", "40000", "400000", "465537", "HR", "HR65536", "3x0", "0x10000", "40001.16", "40001.x", "00001.1", "HR1.2.3"} {
+		if got, err := Parse(input, HoldingRegister); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", input, got)
+		}
+	}
+}
+
+// TestParseIn 寄存器类型已指定时纯数字不按 Modicon 写法解析, 前缀写法仍然有效
+func TestParseIn(t *testing.T) {
+	tests := []struct {
+		input string
+		table Table
+		want  Address
+	}{
+		{"30775", HoldingRegister, Address{Table: HoldingRegister, Offset: 30775}},
+		{"40001", InputRegister, Address{Table: InputRegister, Offset: 40001}},
+		{"40010.3", HoldingRegister, Address{Table: HoldingRegister, Offset: 40010, HasBit: true, Bit: 3}},
+		{"IR30775", HoldingRegister, Address{Table: InputRegister, Offset: 30775, Explicit: true}},
+		{"3x0775", HoldingRegister, Address{Table: InputRegister, Offset: 774, Explicit: true}},
+		{"0x7837", Coil, Address{Table: Coil, Offset: 30775}},
+	}
+	for _, tt := range tests {
+		got, err := ParseIn(tt.input, tt.table)
+		if err != nil {
+			t.Errorf("ParseIn(%q, %s): %v", tt.input, tt.table, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseIn(%q, %s) = %+v, want %+v", tt.input, tt.table, got, tt.want)
+		}
+	}
+	if _, err := ParseIn("400000", HoldingRegister); err == nil {
+		t.Error("ParseIn(400000) succeeded, want an error")
+	}
+}
+
+func TestNotations(t *testing.T) {
+	tests := []struct {
+		addr Address
+		want []string
+	}{
+		{Address{Table: HoldingRegister, Offset: 100}, []string{"40101", "400101", "HR100", "0x0064"}},
+		{Address{Table: Coil, Offset: 0}, []string{"00001", "000001", "CO0", "0x0000"}},
+		{Address{Table: InputRegister, Offset: 9999}, []string{"310000", "IR9999", "0x270F"}},
+		{Address{Table: HoldingRegister, Offset: 9, HasBit: true, Bit: 3}, []string{"40010.3", "400010.3", "HR9.3", "0x0009.3"}},
+	}
+	for _, tt := range tests {
+		if got := tt.addr.Notations(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.Notations() = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+// TestRoundTrip 每种等价写法解析后都得到同一地址, 界面写回地址后不会改变含义
+func TestRoundTrip(t *testing.T) {
+	for _, table := range []Table{Coil, DiscreteInput, InputRegister, HoldingRegister} {
+		for _, offset := range []uint16{0, 1, 774, 9998, 9999, 30775, 65535} {
+			addr := Address{Table: table, Offset: offset}
+			for _, notation := range addr.Notations() {
+				other := HoldingRegister
+				if table == HoldingRegister {
+					other = InputRegister
+				}
+				got, err := Parse(notation, table)
+				if err != nil || got.Table != table || got.Offset != offset {
+					t.Errorf("Parse(%q) = %+v, %v, want %s %d", notation, got, err, table, offset)
+				}
+				if notation != addr.Hex() {
+					continue
+				}
+				// 十六进制不带寄存器类型, 使用传入的类型
+				if got, _ := Parse(notation, other); got.Table != other {
+					t.Errorf("Parse(%q, %s) table = %s", notation, other, got.Table)
+				}
+			}
+			if got, err := ParseIn(addr.Prefixed(), InputRegister); err != nil || got.Table != table || got.Offset != offset {
+				t.Errorf("ParseIn(%q) = %+v, %v", addr.Prefixed(), got, err)
+			}
+		}
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	tests := map[string]Table{
+		"holding": HoldingRegister, "HR": HoldingRegister, "4x": HoldingRegister,
+		"input register": InputRegister, "3": InputRegister,
+		"Coils": Coil, "0": Coil,
+		"di": DiscreteInput, "1x": DiscreteInput,
+	}
+	for input, want := range tests {
+		if got, err := ParseTable(input); err != nil || got != want {
+			t.Errorf("ParseTable(%q) = %s, %v, want %s", input, got, err, want)
+		}
+	}
+	if _, err := ParseTable("register"); err == nil {
+		t.Error("ParseTable(register) succeeded, want an error")
+	}
+}
@@ -0,0 +1,382 @@
+package sunspec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PointValue 解码后的点位
+type PointValue struct {
+	Def         *PointDef
+	Address     uint16 // 点位首个寄存器的协议地址
+	Raw         []uint16
+	Implemented bool        // 为 false 表示设备未实现该点位 (SunSpec 约定的无效值)
+	Value       interface{} // int64、uint64、float64 或 string
+	SF          *int        // 应用的比例因子, 无比例因子时为 nil
+}
+
+// GroupValue 解码后的点位组
+type GroupValue struct {
+	Def    *GroupDef
+	Index  int // 重复组的序号 (从1开始), 非重复组为 0
+	Points []PointValue
+	Groups []GroupValue
+}
+
+// ModelValue 解码后的模型
+type ModelValue struct {
+	Block Block
+	Def   *ModelDef // 未知模型为 nil
+	Root  GroupValue
+}
+
+// Name 返回组的显示名称, 重复组带序号
+func (g *GroupValue) Name() string {
+	if g.Index > 0 {
+		return fmt.Sprintf("%s[%d]", g.Def.Name, g.Index)
+	}
+	return g.Def.Name
+}
+
+// Point 按名称查找组内的点位
+func (g *GroupValue) Point(name string) (*PointValue, bool) {
+	for i := range g.Points {
+		if g.Points[i].Def.Name == name {
+			return &g.Points[i], true
+		}
+	}
+	return nil, false
+}
+
+// DecodeDevice 解码设备中的全部模型, 未知模型的 Def 为 nil, Root 为空
+func DecodeDevice(dev *Device, models Models) []ModelValue {
+	values := make([]ModelValue, 0, len(dev.Blocks))
+	for _, block := range dev.Blocks {
+		def, ok := models[block.ID]
+		if !ok {
+			values = append(values, ModelValue{Block: block})
+			continue
+		}
+		mv, err := Decode(block, def)
+		if err != nil {
+			values = append(values, ModelValue{Block: block})
+			continue
+		}
+		values = append(values, *mv)
+	}
+	return values
+}
+
+// Decode 按模型定义解码模型寄存器。模型定义的前两个点位为 ID 和 L, 对应 Block 中的前两个寄存器。
+func Decode(block Block, def *ModelDef) (*ModelValue, error) {
+	if def.ID != block.ID {
+		return nil, fmt.Errorf("model definition %d does not match block %d", def.ID, block.ID)
+	}
+	d := &decoder{regs: block.Registers, base: block.Address}
+	root, err := d.group(&def.Group, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("model %d: %w", block.ID, err)
+	}
+	return &ModelValue{Block: block, Def: def, Root: root}, nil
+}
+
+// decoder 按顺序消费模型寄存器
+type decoder struct {
+	regs []uint16
+	base uint16
+	pos  int
+}
+
+// scope 比例因子和计数点位的查找范围: 先在当前组, 再到上级组
+type scope struct {
+	group  *GroupValue
+	parent *scope
+}
+
+func (s *scope) lookup(name string) (*PointValue, bool) {
+	for ; s != nil; s = s.parent {
+		if p, ok := s.group.Point(name); ok {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// group 解码一个组: 先解码本组点位, 再应用比例因子, 最后解码嵌套组
+func (d *decoder) group(def *GroupDef, index int, parent *scope) (GroupValue, error) {
+	gv := GroupValue{Def: def, Index: index}
+	for i := range def.Points {
+		p := &def.Points[i]
+		if d.pos+p.Size > len(d.regs) {
+			// 模型长度可能短于定义 (可选点位在末尾被省略)
+			break
+		}
+		raw := d.regs[d.pos : d.pos+p.Size]
+		gv.Points = append(gv.Points, decodePoint(p, raw, d.base+uint16(d.pos)))
+		d.pos += p.Size
+	}
+
+	sc := &scope{group: &gv, parent: parent}
+	for i := range gv.Points {
+		applyScaleFactor(&gv.Points[i], sc)
+	}
+
+	for i := range def.Groups {
+		sub := &def.Groups[i]
+		count, err := d.repeatCount(sub, sc, i == len(def.Groups)-1)
+		if err != nil {
+			return gv, err
+		}
+		for n := 0; n < count; n++ {
+			idx := 0
+			if count > 1 || sub.Count != nil {
+				idx = n + 1
+			}
+			child, err := d.group(sub, idx, sc)
+			if err != nil {
+				return gv, err
+			}
+			gv.Groups = append(gv.Groups, child)
+		}
+	}
+	return gv, nil
+}
+
+// repeatCount 计算组的重复次数。计数点位缺失时, 末尾的重复组按剩余寄存器数推算。
+// 计数点位的值来自设备, 不可信, 结果不超过剩余寄存器能容纳的组数
+func (d *decoder) repeatCount(def *GroupDef, sc *scope, last bool) (int, error) {
+	n, name, err := def.countSpec()
+	if err != nil {
+		return 0, err
+	}
+	size := def.Size()
+	fit := func(n int) int {
+		if size > 0 && n > (len(d.regs)-d.pos)/size {
+			return (len(d.regs) - d.pos) / size
+		}
+		return n
+	}
+	if name != "" {
+		if v, ok := countValue(sc, name); ok {
+			return fit(v), nil
+		}
+		n = 0
+		if !last {
+			return 0, fmt.Errorf("group %s: count point %s not available", def.Name, name)
+		}
+	}
+	if n == 0 && last && size > 0 {
+		return (len(d.regs) - d.pos) / size, nil
+	}
+	return fit(n), nil
+}
+
+// countValue 返回计数点位的值, 点位缺失、未实现或为负数时返回 false
+func countValue(sc *scope, name string) (int, bool) {
+	p, ok := sc.lookup(name)
+	if !ok || !p.Implemented {
+		return 0, false
+	}
+	switch v := p.Value.(type) {
+	case uint64:
+		return int(min(v, math.MaxInt32)), true
+	case int64:
+		if v >= 0 {
+			return int(min(v, math.MaxInt32)), true
+		}
+	}
+	return 0, false
+}
+
+// applyScaleFactor 解析点位的比例因子, 比例因子点位未实现时不缩放
+func applyScaleFactor(p *PointValue, sc *scope) {
+	if !p.Implemented {
+		return
+	}
+	n, name, ok := p.Def.sfSpec()
+	if !ok {
+		return
+	}
+	if name != "" {
+		sf, found := sc.lookup(name)
+		if !found || !sf.Implemented {
+			return
+		}
+		v, isInt := sf.Value.(int64)
+		if !isInt {
+			return
+		}
+		n = int(v)
+	}
+	p.SF = &n
+}
+
+// decodePoint 按点位类型解码原始寄存器
+func decodePoint(def *PointDef, raw []uint16, addr uint16) PointValue {
+	pv := PointValue{Def: def, Address: addr, Raw: raw, Implemented: true}
+	switch def.Type {
+	case "int16", "sunssf":
+		v := int16(raw[0])
+		pv.Value, pv.Implemented = int64(v), raw[0] != 0x8000
+	case "uint16", "enum16", "bitfield16", "count":
+		pv.Value, pv.Implemented = uint64(raw[0]), raw[0] != 0xFFFF
+	case "acc16":
+		pv.Value, pv.Implemented = uint64(raw[0]), raw[0] != 0
+	case "int32":
+		v := uint32(raw[0])<<16 | uint32(raw[1])
+		pv.Value, pv.Implemented = int64(int32(v)), v != 0x80000000
+	case "uint32", "enum32", "bitfield32":
+		v := uint32(raw[0])<<16 | uint32(raw[1])
+		pv.Value, pv.Implemented = uint64(v), v != 0xFFFFFFFF
+	case "acc32":
+		v := uint32(raw[0])<<16 | uint32(raw[1])
+		pv.Value, pv.Implemented = uint64(v), v != 0
+	case "int64":
+		v := join64(raw)
+		pv.Value, pv.Implemented = int64(v), v != 0x8000000000000000
+	case "uint64", "bitfield64":
+		v := join64(raw)
+		pv.Value, pv.Implemented = v, v != math.MaxUint64
+	case "acc64":
+		v := join64(raw)
+		pv.Value, pv.Implemented = v, v != 0
+	case "float32":
+		v := math.Float32frombits(uint32(raw[0])<<16 | uint32(raw[1]))
+		pv.Value, pv.Implemented = float64(v), !math.IsNaN(float64(v))
+	case "float64":
+		v := math.Float64frombits(join64(raw))
+		pv.Value, pv.Implemented = v, !math.IsNaN(v)
+	case "string":
+		b := registerBytes(raw)
+		if i := strings.IndexByte(string(b), 0); i >= 0 {
+			b = b[:i]
+		}
+		s := strings.TrimSpace(string(b))
+		pv.Value, pv.Implemented = s, s != ""
+	case "ipaddr":
+		b := registerBytes(raw)
+		pv.Value, pv.Implemented = net.IP(b).String(), binary.BigEndian.Uint32(b) != 0
+	case "ipv6addr":
+		b := registerBytes(raw)
+		pv.Value, pv.Implemented = net.IP(b).String(), !net.IP(b).Equal(net.IPv6zero)
+	case "eui48":
+		b := registerBytes(raw)
+		if len(b) > 6 {
+			b = b[len(b)-6:]
+		}
+		pv.Value, pv.Implemented = net.HardwareAddr(b).String(), true
+	case "pad":
+		pv.Implemented = false
+	default:
+		pv.Value = hexWords(raw)
+	}
+	return pv
+}
+
+// Scaled 返回应用比例因子后的数值, 非数值或未实现的点位返回 false
+func (p *PointValue) Scaled() (float64, bool) {
+	if !p.Implemented {
+		return 0, false
+	}
+	var v float64
+	switch x := p.Value.(type) {
+	case int64:
+		v = float64(x)
+	case uint64:
+		v = float64(x)
+	case float64:
+		v = x
+	default:
+		return 0, false
+	}
+	if p.SF != nil {
+		v *= math.Pow10(*p.SF)
+	}
+	return v, true
+}
+
+// Text 返回点位的显示文本: 枚举显示符号名, 位域显示置位的符号名, 数值按比例因子缩放
+func (p *PointValue) Text() string {
+	if !p.Implemented {
+		return "N/A"
+	}
+	switch p.Def.Type {
+	case "enum16", "enum32":
+		v := p.Value.(uint64)
+		for _, s := range p.Def.Symbols {
+			if uint64(s.Value) == v {
+				return fmt.Sprintf("%s (%d)", s.Name, v)
+			}
+		}
+		return strconv.FormatUint(v, 10)
+	case "bitfield16", "bitfield32", "bitfield64":
+		v := p.Value.(uint64)
+		var names []string
+		for _, s := range p.Def.Symbols {
+			if s.Value >= 0 && s.Value < 64 && v&(1<<uint(s.Value)) != 0 {
+				names = append(names, s.Name)
+			}
+		}
+		hex := fmt.Sprintf("0x%X", v)
+		if len(names) == 0 {
+			return hex
+		}
+		return strings.Join(names, "|") + " (" + hex + ")"
+	}
+
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+	v, ok := p.Scaled()
+	if !ok {
+		return fmt.Sprint(p.Value)
+	}
+	prec := -1
+	if p.SF != nil && *p.SF < 0 {
+		prec = -*p.SF
+	}
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+func join64(raw []uint16) uint64 {
+	var v uint64
+	for _, r := range raw {
+		v = v<<16 | uint64(r)
+	}
+	return v
+}
+
+func registerBytes(raw []uint16) []byte {
+	b := make([]byte, len(raw)*2)
+	for i, r := range raw {
+		binary.BigEndian.PutUint16(b[i*2:], r)
+	}
+	return b
+}
+
+func hexWords(raw []uint16) string {
+	parts := make([]string, len(raw))
+	for i, r := range raw {
+		parts[i] = fmt.Sprintf("%04X", r)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Walk 按顺序遍历组及嵌套组中的点位, path 为带组名的点位路径 (如 curve[1].V1)
+func (g *GroupValue) Walk(fn func(path string, p *PointValue)) {
+	g.walk("", fn)
+}
+
+func (g *GroupValue) walk(prefix string, fn func(path string, p *PointValue)) {
+	for i := range g.Points {
+		fn(prefix+g.Points[i].Def.Name, &g.Points[i])
+	}
+	for i := range g.Groups {
+		sub := &g.Groups[i]
+		sub.walk(prefix+sub.Name()+".", fn)
+	}
+}
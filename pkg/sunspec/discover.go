@@ -0,0 +1,136 @@
+package sunspec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReadFunc 读取保持寄存器, address 为0起始的协议地址
+type ReadFunc func(address, count uint16) ([]uint16, error)
+
+// DefaultBases 标准的 SunSpec 基地址 (协议地址), 按常见程度排列
+var DefaultBases = []uint16{40000, 0, 50000}
+
+const (
+	markerHi  = 0x5375 // "Su"
+	markerLo  = 0x6E53 // "nS"
+	endID     = 0xFFFF // 模型链结束标记
+	maxRead   = 125    // 单次读取的最大寄存器数
+	maxModels = 256    // 防止设备返回错误数据时无限遍历
+)
+
+// Block 设备中的一个模型实例
+type Block struct {
+	ID        int
+	Length    int      // 模型长度 L, 不含 ID 和 L 两个寄存器
+	Address   uint16   // 模型ID寄存器的协议地址
+	Registers []uint16 // 含 ID 和 L 的全部寄存器
+}
+
+// Device 发现的 SunSpec 设备
+type Device struct {
+	Base   uint16 // "SunS" 标记的协议地址
+	Blocks []Block
+}
+
+// ParseBases 解析逗号分隔的基地址列表 (0起始的协议地址, 可用 0x 十六进制)
+func ParseBases(text string) ([]uint16, error) {
+	var bases []uint16
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(part, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("基地址无效: %s", part)
+		}
+		bases = append(bases, uint16(n))
+	}
+	return bases, nil
+}
+
+// FindBase 在候选基地址中查找 "SunS" 标记, bases 为空时使用 DefaultBases
+func FindBase(read ReadFunc, bases []uint16) (uint16, error) {
+	if len(bases) == 0 {
+		bases = DefaultBases
+	}
+	var lastErr error
+	for _, base := range bases {
+		regs, err := read(base, 2)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(regs) == 2 && regs[0] == markerHi && regs[1] == markerLo {
+			return base, nil
+		}
+	}
+	if lastErr != nil {
+		return 0, fmt.Errorf("SunS marker not found (last error: %w)", lastErr)
+	}
+	return 0, fmt.Errorf("SunS marker not found at %v", bases)
+}
+
+// Discover 查找 "SunS" 标记并遍历模型链, 读取每个模型的全部寄存器
+func Discover(read ReadFunc, bases []uint16) (*Device, error) {
+	base, err := FindBase(read, bases)
+	if err != nil {
+		return nil, err
+	}
+
+	dev := &Device{Base: base}
+	addr := int(base) + 2
+	for len(dev.Blocks) < maxModels {
+		if addr+2 > 0x10000 {
+			return dev, fmt.Errorf("model chain exceeds address space at %d", addr)
+		}
+		header, err := read(uint16(addr), 2)
+		if err != nil {
+			return dev, fmt.Errorf("read model header at %d: %w", addr, err)
+		}
+		if len(header) < 2 || header[0] == endID || header[0] == 0 {
+			return dev, nil
+		}
+
+		length := int(header[1])
+		if addr+2+length > 0x10000 {
+			return dev, fmt.Errorf("model %d at %d: length %d exceeds address space", header[0], addr, length)
+		}
+		regs, err := readRange(read, addr, 2+length)
+		if err != nil {
+			return dev, fmt.Errorf("read model %d at %d: %w", header[0], addr, err)
+		}
+		dev.Blocks = append(dev.Blocks, Block{
+			ID:        int(header[0]),
+			Length:    length,
+			Address:   uint16(addr),
+			Registers: regs,
+		})
+		addr += 2 + length
+	}
+	return dev, fmt.Errorf("more than %d models, stopping", maxModels)
+}
+
+// readRange 分段读取 count 个寄存器
+func readRange(read ReadFunc, addr, count int) ([]uint16, error) {
+	regs := make([]uint16, 0, count)
+	for count > 0 {
+		n := count
+		if n > maxRead {
+			n = maxRead
+		}
+		chunk, err := read(uint16(addr), uint16(n))
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) < n {
+			return nil, fmt.Errorf("short read at %d: got %d of %d registers", addr, len(chunk), n)
+		}
+		regs = append(regs, chunk[:n]...)
+		addr += n
+		count -= n
+	}
+	return regs, nil
+}
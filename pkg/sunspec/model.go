@@ -0,0 +1,165 @@
+// Package sunspec 发现并解码 SunSpec 设备的模型
+//
+// SunSpec 设备在基地址 (通常为 0、40000 或 50000) 放置 "SunS" 标记, 之后依次排列各模型:
+// 每个模型以模型ID和长度 L 两个寄存器开头, 随后是 L 个寄存器的数据, 以 ID 0xFFFF 结束。
+// 模型的点位定义使用 SunSpec 官方的 JSON 格式 (github.com/sunspec/models), 内置常用的
+// 通用 (1)、逆变器 (101-103) 和电表 (201-204) 模型, 也可以从目录加载其他模型。
+package sunspec
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+//go:embed models/*.json
+var builtinFS embed.FS
+
+// ModelDef 模型定义
+type ModelDef struct {
+	ID    int      `json:"id"`
+	Group GroupDef `json:"group"`
+}
+
+// GroupDef 点位组定义, 模型本身是顶层组
+type GroupDef struct {
+	Name   string          `json:"name"`
+	Label  string          `json:"label"`
+	Desc   string          `json:"desc"`
+	Type   string          `json:"type"`
+	Count  json.RawMessage `json:"count"` // 重复次数: 整数或计数点位名称, 省略为 1
+	Points []PointDef      `json:"points"`
+	Groups []GroupDef      `json:"groups"`
+}
+
+// PointDef 点位定义
+type PointDef struct {
+	Name      string          `json:"name"`
+	Label     string          `json:"label"`
+	Desc      string          `json:"desc"`
+	Type      string          `json:"type"`
+	Size      int             `json:"size"`
+	SF        json.RawMessage `json:"sf"` // 比例因子: 点位名称或整数
+	Units     string          `json:"units"`
+	Access    string          `json:"access"`
+	Mandatory string          `json:"mandatory"`
+	Symbols   []Symbol        `json:"symbols"`
+}
+
+// Symbol 枚举值或位域的符号
+type Symbol struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+// Size 返回组一次重复占用的寄存器数 (含嵌套组, 嵌套组按一次计算)
+func (g *GroupDef) Size() int {
+	size := 0
+	for _, p := range g.Points {
+		size += p.Size
+	}
+	for i := range g.Groups {
+		size += g.Groups[i].Size()
+	}
+	return size
+}
+
+// countSpec 解析组的重复次数: 返回固定次数或计数点位名称
+func (g *GroupDef) countSpec() (int, string, error) {
+	if len(g.Count) == 0 {
+		return 1, "", nil
+	}
+	var n int
+	if err := json.Unmarshal(g.Count, &n); err == nil {
+		return n, "", nil
+	}
+	var name string
+	if err := json.Unmarshal(g.Count, &name); err != nil {
+		return 0, "", fmt.Errorf("group %s: invalid count %s", g.Name, g.Count)
+	}
+	return 0, name, nil
+}
+
+// sfSpec 解析点位的比例因子: 返回固定值或比例因子点位名称
+func (p *PointDef) sfSpec() (int, string, bool) {
+	if len(p.SF) == 0 {
+		return 0, "", false
+	}
+	var n int
+	if err := json.Unmarshal(p.SF, &n); err == nil {
+		return n, "", true
+	}
+	var name string
+	if err := json.Unmarshal(p.SF, &name); err != nil || name == "" {
+		return 0, "", false
+	}
+	return 0, name, true
+}
+
+// Models 按模型ID索引的模型定义
+type Models map[int]*ModelDef
+
+// DefaultModels 返回内置的模型定义
+func DefaultModels() Models {
+	models := make(Models)
+	entries, _ := builtinFS.ReadDir("models")
+	for _, entry := range entries {
+		data, err := builtinFS.ReadFile("models/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		if def, err := ParseModel(data); err == nil {
+			models[def.ID] = def
+		}
+	}
+	return models
+}
+
+// ParseModel 解析 SunSpec JSON 模型定义
+func ParseModel(data []byte) (*ModelDef, error) {
+	var def ModelDef
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+	if def.ID == 0 || len(def.Group.Points) < 2 {
+		return nil, fmt.Errorf("invalid SunSpec model definition")
+	}
+	return &def, nil
+}
+
+// LoadDir 从目录加载 model_*.json, 覆盖同ID的已有定义
+func (m Models) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "model_*.json"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s 中没有 model_*.json", dir)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		def, err := ParseModel(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		m[def.ID] = def
+	}
+	return nil
+}
+
+// Name 返回模型的名称, 未知模型返回 "model_ID"
+func (m Models) Name(id int) string {
+	if def, ok := m[id]; ok {
+		if def.Group.Label != "" {
+			return def.Group.Label
+		}
+		return def.Group.Name
+	}
+	return "model_" + strconv.Itoa(id)
+}
@@ -0,0 +1,225 @@
+package sunspec
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDevice 按地址保存寄存器的设备, 读取未写入的寄存器时像从站一样返回非法数据地址异常
+type fakeDevice map[uint16]uint16
+
+func (d fakeDevice) read(address, count uint16) ([]uint16, error) {
+	regs := make([]uint16, count)
+	for i := range regs {
+		v, ok := d[address+uint16(i)]
+		if !ok {
+			return nil, fmt.Errorf("illegal data address %d", address+uint16(i))
+		}
+		regs[i] = v
+	}
+	return regs, nil
+}
+
+// put 从 address 开始写入寄存器, 返回下一个地址
+func (d fakeDevice) put(address uint16, regs ...uint16) uint16 {
+	for _, r := range regs {
+		d[address] = r
+		address++
+	}
+	return address
+}
+
+// newFakeDevice 在 base 放置 "SunS" 标记和模型链, 每个模型为 ID、L 和数据, endMarker 为 false 时不写结束标记
+func newFakeDevice(base uint16, endMarker bool, models ...[]uint16) fakeDevice {
+	d := make(fakeDevice)
+	addr := d.put(base, markerHi, markerLo)
+	for _, m := range models {
+		addr = d.put(addr, m...)
+	}
+	if endMarker {
+		d.put(addr, endID, 0)
+	}
+	return d
+}
+
+// model 返回带 ID 和 L 的模型寄存器
+func model(id uint16, data ...uint16) []uint16 {
+	return append([]uint16{id, uint16(len(data))}, data...)
+}
+
+func TestDiscover(t *testing.T) {
+	long := make([]uint16, 300) // 超过单次读取上限, 分段读取
+	for i := range long {
+		long[i] = uint16(i)
+	}
+	tests := []struct {
+		name    string
+		dev     fakeDevice
+		bases   []uint16
+		base    uint16
+		ids     []int
+		wantErr string
+	}{
+		{"base 40000", newFakeDevice(40000, true, model(1, 1, 2), model(101, 3)), nil, 40000, []int{1, 101}, ""},
+		{"base 0", newFakeDevice(0, true, model(1, 1)), nil, 0, []int{1}, ""},
+		{"base 50000", newFakeDevice(50000, true, model(1, 1)), nil, 50000, []int{1}, ""},
+		{"custom base", newFakeDevice(1000, true, model(1, 1)), []uint16{1000}, 1000, []int{1}, ""},
+		{"long model", newFakeDevice(40000, true, model(64001, long...)), nil, 40000, []int{64001}, ""},
+		{"chain ends with zero", newFakeDevice(40000, false, model(1, 1), []uint16{0, 0}), nil, 40000, []int{1}, ""},
+		{"no marker", fakeDevice{40000: 1, 40001: 2}, nil, 0, nil, "SunS marker not found"},
+		{"no end marker", newFakeDevice(40000, false, model(1, 1)), nil, 40000, []int{1}, "read model header at 40005"},
+		{"truncated model", newFakeDevice(40000, false, model(1, 1), []uint16{101, 50, 1, 2}), nil, 40000, []int{1}, "read model 101 at 40005"},
+		{"exceeds address space", newFakeDevice(65530, false, []uint16{1, 10}), []uint16{65530}, 65530, nil, "exceeds address space"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, err := Discover(tt.dev.read, tt.bases)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if dev == nil {
+				if tt.ids != nil {
+					t.Fatal("no device returned")
+				}
+				return
+			}
+			var ids []int
+			for _, block := range dev.Blocks {
+				ids = append(ids, block.ID)
+				if len(block.Registers) != 2+block.Length {
+					t.Errorf("model %d: %d registers, length %d", block.ID, len(block.Registers), block.Length)
+				}
+			}
+			if dev.Base != tt.base || !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("base %d, models %v, want base %d, models %v", dev.Base, ids, tt.base, tt.ids)
+			}
+		})
+	}
+}
+
+// testModel 带比例因子和重复组的模型: N 为重复组数, 每组 2 个寄存器
+const testModel = `{
+  "id": 64100,
+  "group": {
+    "name": "test",
+    "points": [
+      {"name": "ID", "type": "uint16", "size": 1},
+      {"name": "L", "type": "uint16", "size": 1},
+      {"name": "W", "type": "int16", "size": 1, "sf": "W_SF"},
+      {"name": "W_SF", "type": "sunssf", "size": 1},
+      {"name": "N", "type": "count", "size": 1}
+    ],
+    "groups": [
+      {"name": "curve", "count": "N", "points": [
+        {"name": "V", "type": "uint16", "size": 1, "sf": -1},
+        {"name": "St", "type": "enum16", "size": 1, "symbols": [{"name": "OFF", "value": 0}, {"name": "ON", "value": 1}]}
+      ]}
+    ]
+  }
+}`
+
+func TestDecode(t *testing.T) {
+	def, err := ParseModel([]byte(testModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := Models{def.ID: def}
+	tests := []struct {
+		name   string
+		data   []uint16 // L 之后的寄存器
+		want   map[string]string
+		groups int
+	}{
+		{"scaled", []uint16{1234, 0xFFFE, 2, 2301, 1, 2302, 0},
+			map[string]string{"W": "12.34", "curve[1].V": "230.1", "curve[1].St": "ON (1)", "curve[2].St": "OFF (0)"}, 2},
+		{"sf not implemented", []uint16{1234, 0x8000, 0},
+			map[string]string{"W": "1234", "W_SF": "N/A"}, 0},
+		{"count larger than model", []uint16{1, 0, 1000, 2301, 1, 2302, 0, 7},
+			map[string]string{"curve[2].V": "230.2"}, 2},
+		{"count not implemented", []uint16{1, 0, 0xFFFF, 2301, 1, 2302, 0},
+			map[string]string{"curve[2].V": "230.2"}, 2},
+		{"short model", []uint16{1234},
+			map[string]string{"W": "1234"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev := &Device{Blocks: []Block{{ID: def.ID, Length: len(tt.data), Address: 100, Registers: model(uint16(def.ID), tt.data...)}}}
+			values := DecodeDevice(dev, models)
+			if len(values) != 1 || values[0].Def == nil {
+				t.Fatalf("values = %+v", values)
+			}
+			got := make(map[string]string)
+			values[0].Root.Walk(func(path string, p *PointValue) {
+				got[path] = p.Text()
+			})
+			for path, want := range tt.want {
+				if got[path] != want {
+					t.Errorf("%s = %q, want %q", path, got[path], want)
+				}
+			}
+			if n := len(values[0].Root.Groups); n != tt.groups {
+				t.Errorf("%d groups, want %d", n, tt.groups)
+			}
+		})
+	}
+}
+
+// TestDecodeAddresses 点位地址按模型内的偏移计算
+func TestDecodeAddresses(t *testing.T) {
+	def, err := ParseModel([]byte(testModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mv, err := Decode(Block{ID: def.ID, Address: 40002, Registers: model(uint16(def.ID), 1, 0, 1, 5, 1)}, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := mv.Root.Point("N"); p.Address != 40006 {
+		t.Errorf("N at %d, want 40006", p.Address)
+	}
+	if p, _ := mv.Root.Groups[0].Point("St"); p.Address != 40008 {
+		t.Errorf("curve[1].St at %d, want 40008", p.Address)
+	}
+	if _, err := Decode(Block{ID: 1}, def); err == nil {
+		t.Error("Decode with a different model ID succeeded, want an error")
+	}
+}
+
+// TestDecodeDeviceUnknownModel 未知模型只保留原始寄存器, 内置模型正常解码
+func TestDecodeDeviceUnknownModel(t *testing.T) {
+	common := make([]uint16, 66)
+	copy(common, []uint16{0x4163, 0x6D65}) // Mn = "Acme"
+	dev, err := Discover(newFakeDevice(40000, true, model(1, common...), model(64999, 1, 2)).read, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := DecodeDevice(dev, DefaultModels())
+	if len(values) != 2 {
+		t.Fatalf("%d models", len(values))
+	}
+	if p, ok := values[0].Root.Point("Mn"); !ok || p.Text() != "Acme" {
+		t.Errorf("Mn = %+v", p)
+	}
+	if values[1].Def != nil || values[1].Block.ID != 64999 || len(values[1].Root.Points) != 0 {
+		t.Errorf("unknown model = %+v", values[1])
+	}
+	if name := DefaultModels().Name(64999); name != "model_64999" {
+		t.Errorf("Name = %s", name)
+	}
+}
+
+func TestParseBases(t *testing.T) {
+	bases, err := ParseBases(" 40000, 0x9C40,,0 ")
+	if err != nil || !reflect.DeepEqual(bases, []uint16{40000, 40000, 0}) {
+		t.Errorf("ParseBases = %v, %v", bases, err)
+	}
+	if _, err := ParseBases("70000"); err == nil {
+		t.Error("ParseBases(70000) succeeded, want an error")
+	}
+}
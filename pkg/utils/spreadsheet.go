@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ReadSpreadsheet 读取 CSV 或 XLSX 文件 (XLSX 只读第一个工作表), 返回按行排列的单元格文本
+func ReadSpreadsheet(filename string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx", ".xlsm":
+		return readXLSX(filename)
+	case ".csv", ".txt":
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return ParseCSV(data)
+	default:
+		return nil, fmt.Errorf("不支持的表格格式: %s", filepath.Ext(filename))
+	}
+}
+
+// ParseCSV 解析CSV, 自动识别逗号、分号或制表符分隔, 并去除 UTF-8 BOM
+func ParseCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	delimiter := ','
+	for _, d := range []rune{';', '\t'} {
+		if bytes.Count(firstLine, []byte(string(d))) > bytes.Count(firstLine, []byte(string(delimiter))) {
+			delimiter = d
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// xlsx 文件中用到的 XML 结构
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText 纯文本 <t> 或多段富文本 <r><t>
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r xlsxRichText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var sb strings.Builder
+	for _, run := range r.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Ref   int `xml:"r,attr"` // 1起始的行号, 空行不出现在文件中
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取 XLSX 第一个工作表, 空单元格以空字符串补齐, 空行保留以便按行号报告错误
+func readXLSX(filename string) ([][]string, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	readXML := func(name string, v interface{}) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("XLSX 缺少 %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		return xml.Unmarshal(data, v)
+	}
+
+	sheetPath := firstSheetPath(readXML)
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXML("xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxWorksheet
+	if err := readXML(sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		for row.Ref > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for _, c := range row.Cells {
+			col := len(cells)
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+
+			switch c.Type {
+			case "s":
+				var i int
+				if _, err := fmt.Sscan(c.Value, &i); err == nil && i >= 0 && i < len(shared.Items) {
+					cells[col] = shared.Items[i].String()
+				}
+			case "inlineStr":
+				cells[col] = c.Inline.String()
+			default:
+				cells[col] = c.Value
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// firstSheetPath 通过 workbook.xml 和关系文件找到第一个工作表的路径, 找不到时使用 sheet1.xml
+func firstSheetPath(readXML func(string, interface{}) error) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook xlsxWorkbook
+	if err := readXML("xl/workbook.xml", &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	var rels xlsxRelationships
+	if err := readXML("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return fallback
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+	return fallback
+}
+
+// columnIndex 将单元格引用 (如 "AB12") 的列字母转换为0起始的列号
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}